@@ -16,9 +16,14 @@ package olric
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/buraksezer/olric/internal/dmap"
+	"github.com/buraksezer/olric/internal/protocol"
 	"github.com/buraksezer/olric/pkg/storage"
 	"github.com/buraksezer/olric/stats"
 )
@@ -50,6 +55,10 @@ type Iterator interface {
 	// Key returns a key name from the distributed map.
 	Key() string
 
+	// Value returns the value of the current key. It's only available if the
+	// iterator is created with the WithValues option, otherwise it returns nil.
+	Value() []byte
+
 	// Close stops the iteration and releases allocated resources.
 	Close()
 }
@@ -63,6 +72,106 @@ type LockContext interface {
 	// Lease sets or updates the timeout of the acquired lock for the given key.
 	// It returns ErrNoSuchLock if there is no lock for the given key.
 	Lease(ctx context.Context, duration time.Duration) error
+
+	// Token returns the fencing token of the lock. The token increases every
+	// time the lock changes hands, so the resources protected by the lock can
+	// reject the requests that carry an older token.
+	Token() uint64
+
+	// KeepAlive renews the lease of the lock periodically until ctx is
+	// cancelled. It returns nil immediately if the lock has no timeout, and it
+	// returns the error if a renewal fails, e.g. ErrNoSuchLock after Unlock.
+	KeepAlive(ctx context.Context) error
+}
+
+// RWLock is a distributed read-write lock. Many readers or a single writer
+// hold the lock at the same time. A lock is not upgraded or downgraded, so
+// the same owner cannot hold it for reading and writing at the same time.
+type RWLock interface {
+	// RLock acquires the lock for reading. If the lock is still unreleased
+	// the end of timeout, it's released automatically. Zero timeout means
+	// that the lock has no expiry.
+	//
+	// It returns immediately if no writer holds the lock. Otherwise, it waits
+	// until deadline.
+	RLock(ctx context.Context, timeout, deadline time.Duration, options ...LockOption) (LockContext, error)
+
+	// Lock acquires the lock for writing. If the lock is still unreleased
+	// the end of timeout, it's released automatically. Zero timeout means
+	// that the lock has no expiry.
+	//
+	// It returns immediately if nobody holds the lock. Otherwise, it waits
+	// until deadline.
+	Lock(ctx context.Context, timeout, deadline time.Duration, options ...LockOption) (LockContext, error)
+}
+
+// Semaphore is a distributed counting semaphore. It limits the number of
+// holders, e.g. the workers running a job concurrently across the cluster.
+type Semaphore interface {
+	// Acquire acquires a permit of the semaphore. If the permit is still
+	// unreleased the end of timeout, it's released automatically. Zero
+	// timeout means that the permit has no expiry. Use LockContext.Unlock to
+	// release the permit.
+	//
+	// It returns immediately if a permit is available. Otherwise, it waits
+	// until deadline.
+	Acquire(ctx context.Context, timeout, deadline time.Duration, options ...LockOption) (LockContext, error)
+}
+
+type lockConfig struct {
+	Owner string
+}
+
+// LockOption is a function for defining options to control behavior of the Lock
+// and LockWithTimeout methods.
+type LockOption func(*lockConfig)
+
+// LockOwner sets the owner id of the lock. The lock is reentrant for the same
+// owner id: Lock returns immediately with the same fencing token if the owner
+// already holds the lock, and the lock is released after the same number of
+// Unlock calls. A random owner id is used by default.
+func LockOwner(owner string) LockOption {
+	return func(cfg *lockConfig) {
+		cfg.Owner = owner
+	}
+}
+
+func newLockConfig(options ...LockOption) (*lockConfig, error) {
+	var lc lockConfig
+	for _, opt := range options {
+		opt(&lc)
+	}
+	if lc.Owner == "" {
+		owner := make([]byte, 16)
+		if _, err := rand.Read(owner); err != nil {
+			return nil, err
+		}
+		lc.Owner = hex.EncodeToString(owner)
+	}
+	return &lc, nil
+}
+
+// keepLockAlive calls lease with the current timeout of the lock three times
+// per timeout until ctx is cancelled.
+func keepLockAlive(ctx context.Context, timeout func() time.Duration, lease func(ctx context.Context, duration time.Duration) error) error {
+	for {
+		duration := timeout()
+		if duration <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(duration / 3)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		if err := lease(ctx, duration); err != nil {
+			return err
+		}
+	}
 }
 
 // PutOption is a function for define options to control behavior of the Put command.
@@ -114,8 +223,20 @@ func XX() PutOption {
 	}
 }
 
+// IfVersion only sets the key if the timestamp of the current entry equals to
+// version. See GetResponse.Timestamp. A missing key has version 0. Put returns
+// ErrVersionMismatch if the versions don't match.
+func IfVersion(version int64) PutOption {
+	return func(cfg *dmap.PutConfig) {
+		cfg.HasIfVersion = true
+		cfg.IfVersion = version
+	}
+}
+
 type dmapConfig struct {
 	storageEntryImplementation func() storage.Entry
+	nearCacheSize              int
+	nearCacheTTL               time.Duration
 }
 
 // DMapOption is a function for defining options to control behavior of distributed map instances.
@@ -128,6 +249,24 @@ func StorageEntryImplementation(e func() storage.Entry) DMapOption {
 	}
 }
 
+// WithNearCache keeps the recently read entries in a bounded LRU cache on the
+// client side. size is the maximum number of keys in the cache and ttl is the
+// maximum lifetime of a cached entry.
+//
+// Cached keys are invalidated by the keyspace notifications of the DMap. Enable
+// put, expire, del, expired and evicted events in the DMap configuration, see
+// config.KeyspaceEvent. Otherwise, a cached entry may be stale until its TTL is
+// reached. The notifications may be lost if the connection is broken, ttl bounds
+// the staleness in that case too.
+//
+// It's only implemented by ClusterClient. EmbeddedClient ignores it.
+func WithNearCache(size int, ttl time.Duration) DMapOption {
+	return func(cfg *dmapConfig) {
+		cfg.nearCacheSize = size
+		cfg.nearCacheTTL = ttl
+	}
+}
+
 // ScanOption is a function for defining options to control behavior of the SCAN command.
 type ScanOption func(*dmap.ScanConfig)
 
@@ -150,6 +289,210 @@ func Match(s string) ScanOption {
 	}
 }
 
+// ScanOperator is the comparison operator of a scan predicate.
+type ScanOperator string
+
+// Operators of the scan predicates.
+const (
+	OpEqual          ScanOperator = protocol.ScanOpEqual
+	OpNotEqual       ScanOperator = protocol.ScanOpNotEqual
+	OpLess           ScanOperator = protocol.ScanOpLess
+	OpLessOrEqual    ScanOperator = protocol.ScanOpLessOrEqual
+	OpGreater        ScanOperator = protocol.ScanOpGreater
+	OpGreaterOrEqual ScanOperator = protocol.ScanOpGreaterOrEqual
+)
+
+// Where filters the entries by a field of their JSON values. Nested fields
+// are separated by dots, like "address.city". value can be a string, a
+// number or a boolean. Numbers are compared numerically, and the rest is
+// compared lexicographically. The predicates are evaluated on the partition
+// owners, and an entry has to satisfy all of them.
+func Where(field string, op ScanOperator, value interface{}) ScanOption {
+	return func(cfg *dmap.ScanConfig) {
+		dmap.Where(field, string(op), predicateValue(value))(cfg)
+	}
+}
+
+func predicateValue(value interface{}) string {
+	indexed, ok := dmap.IndexValue(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	return indexed
+}
+
+// WhereTTL filters the entries by their remaining time to live. The entries
+// without a TTL are skipped.
+func WhereTTL(op ScanOperator, ttl time.Duration) ScanOption {
+	return func(cfg *dmap.ScanConfig) {
+		dmap.Where(protocol.ScanFieldTTL, string(op), strconv.FormatInt(ttl.Milliseconds(), 10))(cfg)
+	}
+}
+
+// NewerThan filters the entries that are written after t.
+func NewerThan(t time.Time) ScanOption {
+	return func(cfg *dmap.ScanConfig) {
+		ms := t.UnixNano() / 1000000
+		dmap.Where(protocol.ScanFieldTimestamp, protocol.ScanOpGreater, strconv.FormatInt(ms, 10))(cfg)
+	}
+}
+
+// WithValues returns the values along with the keys, so Iterator.Value can be
+// used instead of calling Get for every key.
+func WithValues() ScanOption {
+	return func(cfg *dmap.ScanConfig) {
+		cfg.WithValues = true
+	}
+}
+
+// Aggregator describes an aggregation on the JSON values of a DMap. Fields
+// are dotted paths, like "address.city".
+type Aggregator struct {
+	config dmap.AggregateConfig
+}
+
+// NewCountAggregator counts the entries.
+func NewCountAggregator() *Aggregator {
+	return &Aggregator{config: dmap.AggregateConfig{Op: protocol.AggregateCount}}
+}
+
+// NewSumAggregator sums the numeric values of field.
+func NewSumAggregator(field string) *Aggregator {
+	return &Aggregator{config: dmap.AggregateConfig{Op: protocol.AggregateSum, Field: field}}
+}
+
+// NewMinAggregator finds the minimum of the numeric values of field.
+func NewMinAggregator(field string) *Aggregator {
+	return &Aggregator{config: dmap.AggregateConfig{Op: protocol.AggregateMin, Field: field}}
+}
+
+// NewMaxAggregator finds the maximum of the numeric values of field.
+func NewMaxAggregator(field string) *Aggregator {
+	return &Aggregator{config: dmap.AggregateConfig{Op: protocol.AggregateMax, Field: field}}
+}
+
+// GroupBy groups the entries by the value of field. The entries that don't
+// have the field are skipped.
+func (a *Aggregator) GroupBy(field string) *Aggregator {
+	a.config.GroupBy = field
+	return a
+}
+
+// Where only aggregates the entries that satisfy the predicate. See the
+// Where scan option for details.
+func (a *Aggregator) Where(field string, op ScanOperator, value interface{}) *Aggregator {
+	a.config.Where = append(a.config.Where, protocol.ScanPredicate{
+		Field: field,
+		Op:    string(op),
+		Value: predicateValue(value),
+	})
+	return a
+}
+
+// AggregateResult maps the groups to the results of an aggregation. The
+// result is stored with an empty group if GroupBy is not used.
+type AggregateResult map[string]float64
+
+// QueryOption is a function for defining options to control behavior of the Query method.
+type QueryOption func(*dmap.QueryConfig)
+
+// QueryCount sets the maximum number of entries in a page of Query results.
+// The default value is 100.
+func QueryCount(c int) QueryOption {
+	return func(cfg *dmap.QueryConfig) {
+		cfg.Count = c
+	}
+}
+
+// QueryAfter sets the cursor of Query. Only the entries with a key greater
+// than the cursor are returned.
+func QueryAfter(cursor string) QueryOption {
+	return func(cfg *dmap.QueryConfig) {
+		cfg.After = cursor
+	}
+}
+
+// QueryEntry is an entry returned by Query.
+type QueryEntry struct {
+	Key   string
+	Value []byte
+}
+
+// QueryResult is a page of Query results. Entries are sorted by key. Cursor
+// is the key of the last entry if there may be more entries, otherwise it's
+// empty. Pass it to QueryAfter to fetch the next page.
+type QueryResult struct {
+	Entries []QueryEntry
+	Cursor  string
+}
+
+func newQueryConfig(options []QueryOption) *dmap.QueryConfig {
+	cfg := &dmap.QueryConfig{Count: dmap.DefaultQueryCount}
+	for _, opt := range options {
+		opt(cfg)
+	}
+	if cfg.Count <= 0 {
+		cfg.Count = dmap.DefaultQueryCount
+	}
+	return cfg
+}
+
+func newQueryResult(entries []QueryEntry, cfg *dmap.QueryConfig) *QueryResult {
+	result := &QueryResult{Entries: entries}
+	if len(entries) > 0 && len(entries) == cfg.Count {
+		result.Cursor = entries[len(entries)-1].Key
+	}
+	return result
+}
+
+func indexValue(value interface{}) (string, error) {
+	indexed, ok := dmap.IndexValue(value)
+	if !ok {
+		return "", fmt.Errorf("%w: %T cannot be indexed", protocol.ErrInvalidArgument, value)
+	}
+	return indexed, nil
+}
+
+// ZMember is a member of a sorted set with its score.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// StreamNewEntries is the ID to read the entries that were never delivered to
+// the consumer group. See XReadGroup.
+const StreamNewEntries = dmap.StreamNewEntries
+
+// StreamEntry is an entry of a stream. Fields are keyed by the field names.
+type StreamEntry struct {
+	ID     string
+	Fields map[string]*GetResponse
+}
+
+// PendingEntry is a stream entry that is delivered to a consumer of a group
+// but not acknowledged yet.
+type PendingEntry struct {
+	ID         string
+	Consumer   string
+	Idle       time.Duration
+	Deliveries int
+}
+
+type xaddConfig struct {
+	maxLen int
+}
+
+// XAddOption is a function for defining options to control behavior of the XADD command.
+type XAddOption func(*xaddConfig)
+
+// MaxLen is an XAddOption to trim the oldest entries of the stream. The length
+// of the stream is kept at most n.
+func MaxLen(n int) XAddOption {
+	return func(cfg *xaddConfig) {
+		cfg.maxLen = n
+	}
+}
+
 // DMap defines methods to access and manipulate distributed maps.
 type DMap interface {
 	// Name exposes name of the DMap.
@@ -165,11 +508,142 @@ type DMap interface {
 	// of the returned value. See GetResponse for the details.
 	Get(ctx context.Context, key string) (*GetResponse, error)
 
+	// CompareAndSwap sets the value for the given key if the timestamp of the
+	// current entry equals to expected. See GetResponse.Timestamp. A missing key
+	// has version 0. The comparison is done on the partition owner under the
+	// fragment lock. It returns false if the versions don't match.
+	CompareAndSwap(ctx context.Context, key string, expected int64, value interface{}) (bool, error)
+
 	// Delete deletes values for the given keys. Delete will not return error
 	// if key doesn't exist. It's thread-safe. It is safe to modify the contents
 	// of the argument after Delete returns.
 	Delete(ctx context.Context, keys ...string) (int, error)
 
+	// MGet gets the values for the given keys. Keys are grouped by their partition
+	// owners and the owners are queried in parallel. It returns the values of the
+	// found keys and the errors of the failed ones. A missing key is reported
+	// with ErrKeyNotFound. The error map is nil if every key is found.
+	MGet(ctx context.Context, keys ...string) (map[string]*GetResponse, map[string]error)
+
+	// MPut sets the given key/value pairs. Keys are grouped by their partition
+	// owners and the owners are called in parallel. It returns the errors of the
+	// failed keys. The error map is nil if every key is set. It is safe to modify
+	// the contents of the argument after MPut returns but not before.
+	MPut(ctx context.Context, entries map[string]interface{}) map[string]error
+
+	// HSet sets the given fields of the hash stored at key. A new hash is created
+	// if the key doesn't exist. It returns the number of fields that were added.
+	// It returns ErrWrongType if the key holds a value that is not a hash.
+	HSet(ctx context.Context, key string, fields map[string]interface{}) (int, error)
+
+	// HGet gets the value of field in the hash stored at key. It returns
+	// ErrKeyNotFound if the hash or the field doesn't exist. The response
+	// carries the TTL and the timestamp of the hash.
+	HGet(ctx context.Context, key, field string) (*GetResponse, error)
+
+	// HDel removes the given fields from the hash stored at key. The key is
+	// deleted when the last field is removed. It returns the number of fields
+	// that were removed.
+	HDel(ctx context.Context, key string, fields ...string) (int, error)
+
+	// HGetAll gets all fields of the hash stored at key. It returns an empty map
+	// if the key doesn't exist.
+	HGetAll(ctx context.Context, key string) (map[string]*GetResponse, error)
+
+	// HIncrBy atomically increments the integer value of field in the hash
+	// stored at key by delta. It returns ErrHashValueNotInteger if the field
+	// holds a value that is not an integer.
+	HIncrBy(ctx context.Context, key, field string, delta int) (int, error)
+
+	// ZAdd adds the members to the sorted set stored at key. The score is updated
+	// if the member already exists. It returns the number of members that were
+	// added. It returns ErrWrongType if the key holds a value that is not a
+	// sorted set.
+	ZAdd(ctx context.Context, key string, members ...ZMember) (int, error)
+
+	// ZRange gets the members of the sorted set stored at key between the ranks
+	// start and stop, both inclusive, ordered from the lowest to the highest
+	// score. Negative ranks are counted from the end, -1 is the last member.
+	ZRange(ctx context.Context, key string, start, stop int) ([]ZMember, error)
+
+	// ZRangeByScore gets the members of the sorted set stored at key with a
+	// score between min and max, both inclusive. Use math.Inf for open ranges.
+	ZRangeByScore(ctx context.Context, key string, min, max float64) ([]ZMember, error)
+
+	// ZRem removes the members from the sorted set stored at key. The key is
+	// deleted when the last member is removed. It returns the number of members
+	// that were removed.
+	ZRem(ctx context.Context, key string, members ...string) (int, error)
+
+	// ZRank gets the rank of member in the sorted set stored at key. The member
+	// with the lowest score has rank 0. It returns ErrKeyNotFound if the sorted
+	// set or the member doesn't exist.
+	ZRank(ctx context.Context, key, member string) (int, error)
+
+	// ZIncrBy atomically increments the score of member in the sorted set stored
+	// at key by delta. It returns the new score.
+	ZIncrBy(ctx context.Context, key, member string, delta float64) (float64, error)
+
+	// LPush inserts the values at the head of the list stored at key. The last
+	// value becomes the head. It returns the length of the list. It returns
+	// ErrWrongType if the key holds a value that is not a list.
+	LPush(ctx context.Context, key string, values ...interface{}) (int, error)
+
+	// RPush inserts the values at the tail of the list stored at key. It returns
+	// the length of the list.
+	RPush(ctx context.Context, key string, values ...interface{}) (int, error)
+
+	// LPop removes and returns the head of the list stored at key. It returns
+	// ErrKeyNotFound if the list doesn't exist.
+	LPop(ctx context.Context, key string) (*GetResponse, error)
+
+	// RPop removes and returns the tail of the list stored at key. It returns
+	// ErrKeyNotFound if the list doesn't exist.
+	RPop(ctx context.Context, key string) (*GetResponse, error)
+
+	// LRange gets the elements of the list stored at key between start and stop,
+	// both inclusive. Negative indexes are counted from the tail, -1 is the last
+	// element.
+	LRange(ctx context.Context, key string, start, stop int) ([]*GetResponse, error)
+
+	// LLen returns the length of the list stored at key. It returns zero if the
+	// key doesn't exist.
+	LLen(ctx context.Context, key string) (int, error)
+
+	// BLPop removes and returns the head of the list stored at key. It blocks
+	// until an element is pushed if the list is empty. It returns ErrKeyNotFound
+	// if the list is still empty after timeout. Zero timeout blocks until the
+	// context is done. The blocked callers are not served in order.
+	BLPop(ctx context.Context, key string, timeout time.Duration) (*GetResponse, error)
+
+	// XAdd appends an entry with the given fields to the stream stored at key
+	// and returns its ID. The entries are stored on the partition owner and
+	// replicated like the other entries, so they can be read after the
+	// consumers reconnect. The stream grows without a limit unless MaxLen is
+	// given, but adding an entry costs the same regardless of its length.
+	XAdd(ctx context.Context, key string, fields map[string]interface{}, options ...XAddOption) (string, error)
+
+	// XRead gets the entries of the stream stored at key with an ID greater
+	// than id. An empty id or "0" reads from the beginning. Zero count returns
+	// all the entries.
+	XRead(ctx context.Context, key, id string, count int) ([]StreamEntry, error)
+
+	// XReadGroup reads the entries of the stream stored at key for a consumer
+	// of the group. The group is created on the first call and it starts from
+	// the beginning of the stream. If id is StreamNewEntries, it returns the
+	// entries that were never delivered to the group. Otherwise, it returns the
+	// pending entries of the consumer with an ID greater than id. The returned
+	// entries are pending until they are acknowledged with XAck.
+	XReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]StreamEntry, error)
+
+	// XAck acknowledges the entries of the group. It returns the number of the
+	// acknowledged entries. It returns ErrNoSuchGroup if the group doesn't exist.
+	XAck(ctx context.Context, key, group string, ids ...string) (int, error)
+
+	// XPending gets the pending entries of the group ordered by their IDs. It
+	// returns ErrNoSuchGroup if the group doesn't exist.
+	XPending(ctx context.Context, key, group string) ([]PendingEntry, error)
+
 	// Incr atomically increments the key by delta. The return value is the new value
 	// after being incremented or an error.
 	Incr(ctx context.Context, key string, delta int) (int, error)
@@ -194,23 +668,77 @@ type DMap interface {
 	// this dmap.
 	//
 	// It returns immediately if it acquires the lock for the given key. Otherwise,
-	// it waits until deadline.
+	// it waits until deadline. The waiters are woken up when the lock is released.
 	//
-	// You should know that the locks are approximate, and only to be used for
-	// non-critical purposes.
-	Lock(ctx context.Context, key string, deadline time.Duration) (LockContext, error)
+	// Every acquisition comes with a fencing token, see LockContext.Token. The
+	// lock is reentrant for the same owner id, see LockOwner. The lock is kept
+	// apart from the values of this dmap, so it doesn't touch the value of the
+	// key.
+	Lock(ctx context.Context, key string, deadline time.Duration, options ...LockOption) (LockContext, error)
 
 	// LockWithTimeout sets a lock for the given key. If the lock is still unreleased
 	// the end of given period of time,
 	// it automatically releases the lock. Acquired lock is only for the key in
-	// this dmap.
+	// this dmap. Use LockContext.KeepAlive to renew the lease automatically.
 	//
 	// It returns immediately if it acquires the lock for the given key. Otherwise,
-	// it waits until deadline.
+	// it waits until deadline. The waiters are woken up when the lock is released.
+	//
+	// Every acquisition comes with a fencing token, see LockContext.Token. The
+	// lock is reentrant for the same owner id, see LockOwner.
+	LockWithTimeout(ctx context.Context, key string, timeout, deadline time.Duration, options ...LockOption) (LockContext, error)
+
+	// RWLock returns a read-write lock for the given key. The lock is kept
+	// apart from the values of this dmap, so it doesn't touch the value of the
+	// key. It's stored on a partition owner and moves with the partition.
+	RWLock(key string) RWLock
+
+	// Semaphore returns a counting semaphore with the given name. At most
+	// permits holders acquire the semaphore at the same time. All the callers
+	// should use the same number of permits for a semaphore. The semaphore is
+	// kept apart from the values of this dmap, so it doesn't touch the value
+	// of the key with the same name. It's stored on a partition owner and
+	// moves with the partition.
+	Semaphore(name string, permits int) Semaphore
+
+	// Eval runs the Lua script atomically on the partition owner of the keys.
+	// All the keys have to belong to the same partition, otherwise it returns
+	// ErrCrossPartition. The script reads the keys and the arguments from KEYS
+	// and ARGV tables, and it accesses the keys with the following functions:
+	//
+	// * olric.get(key) returns the value or nil
+	// * olric.put(key, value [, px]) sets the value with an optional timeout in milliseconds
+	// * olric.delete(key) returns 1 if the key is deleted, otherwise 0
+	// * olric.expire(key, px) sets the timeout in milliseconds and returns 1 if the key exists
+	//
+	// The return value of the script is converted like Redis does: numbers
+	// are truncated to int64, strings are returned as string, true is 1,
+	// false is nil, and tables are converted to []interface{}. It returns
+	// ErrScript if the script cannot be compiled or it fails.
+	Eval(ctx context.Context, script string, keys []string, args ...string) (interface{}, error)
+
+	// Execute runs the entry processor that is registered with the given name
+	// in config.Config on the partition owner of the key, and returns its
+	// result. The entry processor reads and modifies the entry atomically, so
+	// it avoids Lock, Get, Put and Unlock cycles. It returns ErrNoSuchProcessor
+	// if the processor is not registered.
+	Execute(ctx context.Context, key, processor string, args []byte) ([]byte, error)
+
+	// Aggregate runs the aggregator on the primary partitions of every member,
+	// and merges the partial results on the caller. It's much cheaper than
+	// scanning the keys and fetching the values over the network.
+	Aggregate(ctx context.Context, aggregator *Aggregator) (AggregateResult, error)
+
+	// Query returns the entries whose indexed field equals value. The index
+	// has to be declared in the configuration of the DMap. value can be a
+	// string, a number or a boolean. It fans out to all the members and merges
+	// the results.
+	//
+	// Available query options:
 	//
-	// You should know that the locks are approximate, and only to be used for
-	// non-critical purposes.
-	LockWithTimeout(ctx context.Context, key string, timeout, deadline time.Duration) (LockContext, error)
+	// * QueryCount
+	// * QueryAfter
+	Query(ctx context.Context, index string, value interface{}, options ...QueryOption) (*QueryResult, error)
 
 	// Scan returns an iterator to loop over the keys.
 	//
@@ -218,6 +746,10 @@ type DMap interface {
 	//
 	// * Count
 	// * Match
+	// * Where
+	// * WhereTTL
+	// * NewerThan
+	// * WithValues
 	Scan(ctx context.Context, options ...ScanOption) (Iterator, error)
 
 	// Destroy flushes the given DMap on the cluster. You should know that there
@@ -279,7 +811,8 @@ type PubSubOption func(option *pubsubConfig)
 
 // Client is an interface that denotes an Olric client.
 type Client interface {
-	// NewDMap returns a new DMap client with the given options.
+	// NewDMap returns a new DMap client with the given options. The names that
+	// start with "olric.internal." are reserved.
 	NewDMap(name string, options ...DMapOption) (DMap, error)
 
 	// NewPubSub returns a new PubSub client with the given options.
@@ -22,6 +22,7 @@ import (
 	"log"
 	"net"
 	"os"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -34,7 +35,9 @@ import (
 	"github.com/buraksezer/olric/internal/discovery"
 	"github.com/buraksezer/olric/internal/dmap"
 	"github.com/buraksezer/olric/internal/kvstore/entry"
+	"github.com/buraksezer/olric/internal/nearcache"
 	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/pubsub"
 	"github.com/buraksezer/olric/internal/resp"
 	"github.com/buraksezer/olric/internal/server"
 	"github.com/buraksezer/olric/pkg/storage"
@@ -48,10 +51,19 @@ var pool = bufpool.New()
 // fetches the routing table from the cluster to route requests to the right partition.
 const DefaultRoutingTableFetchInterval = time.Minute
 
+// blockingPopStep is the longest period that a single BLPOP command blocks on
+// the server. It's shorter than the default read timeout of the client.
+const blockingPopStep = time.Second
+
 type ClusterLockContext struct {
-	key   string
-	token string
-	dm    *ClusterDMap
+	// timeout is accessed atomically, keep it 64-bit aligned.
+	timeout int64
+	key     string
+	owner   string
+	token   uint64
+	// shared is true for the read-write locks and the semaphores.
+	shared bool
+	dm     *ClusterDMap
 }
 
 // ClusterDMap implements a client for DMaps.
@@ -61,6 +73,7 @@ type ClusterDMap struct {
 	config        *dmapConfig
 	client        *server.Client
 	clusterClient *ClusterClient
+	nearCache     *nearcache.Cache
 }
 
 // Name exposes name of the DMap.
@@ -79,7 +92,7 @@ func processProtocolError(err error) error {
 		opErr := err.(*net.OpError)
 		return fmt.Errorf("%s %s %s: %w", opErr.Op, opErr.Net, opErr.Addr, ErrConnRefused)
 	}
-	return convertDMapError(protocol.ConvertError(err))
+	return convertDMapError(dmap.ConvertScriptError(err))
 }
 
 func (dm *ClusterDMap) writePutCommand(c *dmap.PutConfig, key string, value []byte) *protocol.Put {
@@ -102,26 +115,37 @@ func (dm *ClusterDMap) writePutCommand(c *dmap.PutConfig, key string, value []by
 		cmd.SetXX()
 	}
 
+	if c.HasIfVersion {
+		cmd.SetIfVersion(c.IfVersion)
+	}
+
 	return cmd
 }
 
-func (cl *ClusterClient) clientByPartID(partID uint64) (*redis.Client, error) {
+func (cl *ClusterClient) primaryOwnerByPartID(partID uint64) (string, error) {
 	raw := cl.routingTable.Load()
 	if raw == nil {
-		return nil, fmt.Errorf("routing table is empty")
+		return "", fmt.Errorf("routing table is empty")
 	}
 
 	routingTable, ok := raw.(RoutingTable)
 	if !ok {
-		return nil, fmt.Errorf("routing table is corrupt")
+		return "", fmt.Errorf("routing table is corrupt")
 	}
 
 	route := routingTable[partID]
 	if len(route.PrimaryOwners) == 0 {
-		return nil, fmt.Errorf("primary owners list for %d is empty", partID)
+		return "", fmt.Errorf("primary owners list for %d is empty", partID)
 	}
 
-	primaryOwner := route.PrimaryOwners[len(route.PrimaryOwners)-1]
+	return route.PrimaryOwners[len(route.PrimaryOwners)-1], nil
+}
+
+func (cl *ClusterClient) clientByPartID(partID uint64) (*redis.Client, error) {
+	primaryOwner, err := cl.primaryOwnerByPartID(partID)
+	if err != nil {
+		return nil, err
+	}
 	return cl.client.Get(primaryOwner), nil
 }
 
@@ -131,36 +155,616 @@ func (cl *ClusterClient) smartPick(dmap, key string) (*redis.Client, error) {
 	return cl.clientByPartID(partID)
 }
 
+func (cl *ClusterClient) smartPickChannel(channel string) (*redis.Client, error) {
+	partID := pubsub.ChannelHKey(channel) % cl.partitionCount
+	return cl.clientByPartID(partID)
+}
+
 // Put sets the value for the given key. It overwrites any previous value for
 // that key, and it's thread-safe. The key has to be a string. value type is arbitrary.
 // It is safe to modify the contents of the arguments after Put returns but not before.
 func (dm *ClusterDMap) Put(ctx context.Context, key string, value interface{}, options ...PutOption) error {
+	defer dm.invalidate(key)
+
 	rc, err := dm.clusterClient.smartPick(dm.name, key)
 	if err != nil {
 		return err
 	}
 
-	valueBuf := pool.Get()
-	defer pool.Put(valueBuf)
+	valueBuf := pool.Get()
+	defer pool.Put(valueBuf)
+
+	enc := resp.New(valueBuf)
+	err = enc.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	var pc dmap.PutConfig
+	for _, opt := range options {
+		opt(&pc)
+	}
+	putCmd := dm.writePutCommand(&pc, key, valueBuf.Bytes())
+	cmd := putCmd.Command(ctx)
+
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return processProtocolError(err)
+	}
+	return processProtocolError(cmd.Err())
+}
+
+// CompareAndSwap sets the value for the given key if the timestamp of the
+// current entry equals to expected. See GetResponse.Timestamp. A missing key
+// has version 0. The comparison is done on the partition owner under the
+// fragment lock. It returns false if the versions don't match.
+func (dm *ClusterDMap) CompareAndSwap(ctx context.Context, key string, expected int64, value interface{}) (bool, error) {
+	defer dm.invalidate(key)
+
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return false, err
+	}
+
+	valueBuf := pool.Get()
+	defer pool.Put(valueBuf)
+
+	enc := resp.New(valueBuf)
+	err = enc.Encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	cmd := protocol.NewCompareAndSwap(dm.name, key, expected, valueBuf.Bytes()).Command(ctx)
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return false, processProtocolError(err)
+	}
+	res, err := cmd.Result()
+	if err != nil {
+		return false, processProtocolError(err)
+	}
+	return res == 1, nil
+}
+
+func (dm *ClusterDMap) processIntCommand(ctx context.Context, key string, cmd *redis.IntCmd) (int, error) {
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return 0, err
+	}
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return 0, processProtocolError(err)
+	}
+	res, err := cmd.Result()
+	if err != nil {
+		return 0, processProtocolError(err)
+	}
+	return int(res), nil
+}
+
+// HSet sets the given fields of the hash stored at key. A new hash is created
+// if the key doesn't exist. It returns the number of fields that were added.
+// It returns ErrWrongType if the key holds a value that is not a hash.
+func (dm *ClusterDMap) HSet(ctx context.Context, key string, fields map[string]interface{}) (int, error) {
+	defer dm.invalidate(key)
+
+	hsetCmd := protocol.NewHSet(dm.name, key)
+	for field, value := range fields {
+		valueBuf := pool.Get()
+		enc := resp.New(valueBuf)
+		err := enc.Encode(value)
+		if err != nil {
+			pool.Put(valueBuf)
+			return 0, err
+		}
+		// Copy the encoded value, the buffer goes back to the pool.
+		hsetCmd.Add(field, append([]byte(nil), valueBuf.Bytes()...))
+		pool.Put(valueBuf)
+	}
+	return dm.processIntCommand(ctx, key, hsetCmd.Command(ctx))
+}
+
+// HGet gets the value of field in the hash stored at key. It returns
+// ErrKeyNotFound if the hash or the field doesn't exist.
+func (dm *ClusterDMap) HGet(ctx context.Context, key, field string) (*GetResponse, error) {
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := protocol.NewHGet(dm.name, key, field).Command(ctx)
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+	raw, err := cmd.Bytes()
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+
+	e := dm.newEntry()
+	e.SetKey(field)
+	e.SetValue(raw)
+	return &GetResponse{
+		entry: e,
+	}, nil
+}
+
+// HDel removes the given fields from the hash stored at key. The key is
+// deleted when the last field is removed. It returns the number of fields
+// that were removed.
+func (dm *ClusterDMap) HDel(ctx context.Context, key string, fields ...string) (int, error) {
+	defer dm.invalidate(key)
+
+	return dm.processIntCommand(ctx, key, protocol.NewHDel(dm.name, key, fields...).Command(ctx))
+}
+
+// HGetAll gets all fields of the hash stored at key. It returns an empty map
+// if the key doesn't exist.
+func (dm *ClusterDMap) HGetAll(ctx context.Context, key string) (map[string]*GetResponse, error) {
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := protocol.NewHGetAll(dm.name, key).Command(ctx)
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+	res, err := cmd.Result()
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+
+	// The reply is a flat array of field and value pairs.
+	result := make(map[string]*GetResponse, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		field, ok := res[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid field type: %T", res[i])
+		}
+		value, ok := res[i+1].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid value type: %T", res[i+1])
+		}
+		e := dm.newEntry()
+		e.SetKey(field)
+		e.SetValue([]byte(value))
+		result[field] = &GetResponse{
+			entry: e,
+		}
+	}
+	return result, nil
+}
+
+// HIncrBy atomically increments the integer value of field in the hash
+// stored at key by delta. It returns ErrHashValueNotInteger if the field
+// holds a value that is not an integer.
+func (dm *ClusterDMap) HIncrBy(ctx context.Context, key, field string, delta int) (int, error) {
+	defer dm.invalidate(key)
+
+	return dm.processIntCommand(ctx, key, protocol.NewHIncrBy(dm.name, key, field, delta).Command(ctx))
+}
+
+func (dm *ClusterDMap) processZRangeCommand(ctx context.Context, key string, cmd *redis.SliceCmd) ([]ZMember, error) {
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return nil, err
+	}
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+	res, err := cmd.Result()
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+
+	// The reply is a flat array of member and score pairs.
+	members := make([]ZMember, 0, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		member, ok := res[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid member type: %T", res[i])
+		}
+		rawScore, ok := res[i+1].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid score type: %T", res[i+1])
+		}
+		score, err := strconv.ParseFloat(rawScore, 64)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, ZMember{Member: member, Score: score})
+	}
+	return members, nil
+}
+
+// ZAdd adds the members to the sorted set stored at key. The score is updated
+// if the member already exists. It returns the number of members that were
+// added. It returns ErrWrongType if the key holds a value that is not a
+// sorted set.
+func (dm *ClusterDMap) ZAdd(ctx context.Context, key string, members ...ZMember) (int, error) {
+	defer dm.invalidate(key)
+
+	zaddCmd := protocol.NewZAdd(dm.name, key)
+	for _, m := range members {
+		zaddCmd.Add(m.Score, m.Member)
+	}
+	return dm.processIntCommand(ctx, key, zaddCmd.Command(ctx))
+}
+
+// ZRange gets the members of the sorted set stored at key between the ranks
+// start and stop, both inclusive, ordered from the lowest to the highest
+// score. Negative ranks are counted from the end, -1 is the last member.
+func (dm *ClusterDMap) ZRange(ctx context.Context, key string, start, stop int) ([]ZMember, error) {
+	return dm.processZRangeCommand(ctx, key, protocol.NewZRange(dm.name, key, start, stop).Command(ctx))
+}
+
+// ZRangeByScore gets the members of the sorted set stored at key with a
+// score between min and max, both inclusive. Use math.Inf for open ranges.
+func (dm *ClusterDMap) ZRangeByScore(ctx context.Context, key string, min, max float64) ([]ZMember, error) {
+	return dm.processZRangeCommand(ctx, key, protocol.NewZRangeByScore(dm.name, key, min, max).Command(ctx))
+}
+
+// ZRem removes the members from the sorted set stored at key. The key is
+// deleted when the last member is removed. It returns the number of members
+// that were removed.
+func (dm *ClusterDMap) ZRem(ctx context.Context, key string, members ...string) (int, error) {
+	defer dm.invalidate(key)
+
+	return dm.processIntCommand(ctx, key, protocol.NewZRem(dm.name, key, members...).Command(ctx))
+}
+
+// ZRank gets the rank of member in the sorted set stored at key. The member
+// with the lowest score has rank 0. It returns ErrKeyNotFound if the sorted
+// set or the member doesn't exist.
+func (dm *ClusterDMap) ZRank(ctx context.Context, key, member string) (int, error) {
+	return dm.processIntCommand(ctx, key, protocol.NewZRank(dm.name, key, member).Command(ctx))
+}
+
+// ZIncrBy atomically increments the score of member in the sorted set stored
+// at key by delta. It returns the new score.
+func (dm *ClusterDMap) ZIncrBy(ctx context.Context, key, member string, delta float64) (float64, error) {
+	defer dm.invalidate(key)
+
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := protocol.NewZIncrBy(dm.name, key, delta, member).Command(ctx)
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return 0, processProtocolError(err)
+	}
+	score, err := cmd.Result()
+	if err != nil {
+		return 0, processProtocolError(err)
+	}
+	return score, nil
+}
+
+func (dm *ClusterDMap) encodeListValues(values []interface{}) ([][]byte, error) {
+	encoded := make([][]byte, 0, len(values))
+	for _, value := range values {
+		valueBuf := pool.Get()
+		enc := resp.New(valueBuf)
+		err := enc.Encode(value)
+		if err != nil {
+			pool.Put(valueBuf)
+			return nil, err
+		}
+		// Copy the encoded value, the buffer goes back to the pool.
+		encoded = append(encoded, append([]byte(nil), valueBuf.Bytes()...))
+		pool.Put(valueBuf)
+	}
+	return encoded, nil
+}
+
+// LPush inserts the values at the head of the list stored at key. The last
+// value becomes the head. It returns the length of the list. It returns
+// ErrWrongType if the key holds a value that is not a list.
+func (dm *ClusterDMap) LPush(ctx context.Context, key string, values ...interface{}) (int, error) {
+	defer dm.invalidate(key)
+
+	encoded, err := dm.encodeListValues(values)
+	if err != nil {
+		return 0, err
+	}
+	return dm.processIntCommand(ctx, key, protocol.NewLPush(dm.name, key, encoded...).Command(ctx))
+}
+
+// RPush inserts the values at the tail of the list stored at key. It returns
+// the length of the list.
+func (dm *ClusterDMap) RPush(ctx context.Context, key string, values ...interface{}) (int, error) {
+	defer dm.invalidate(key)
+
+	encoded, err := dm.encodeListValues(values)
+	if err != nil {
+		return 0, err
+	}
+	return dm.processIntCommand(ctx, key, protocol.NewRPush(dm.name, key, encoded...).Command(ctx))
+}
+
+func (dm *ClusterDMap) processPopCommand(ctx context.Context, key string, cmd *redis.StringCmd) (*GetResponse, error) {
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return nil, err
+	}
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+	raw, err := cmd.Bytes()
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+
+	e := dm.newEntry()
+	e.SetKey(key)
+	e.SetValue(raw)
+	return &GetResponse{
+		entry: e,
+	}, nil
+}
+
+// LPop removes and returns the head of the list stored at key. It returns
+// ErrKeyNotFound if the list doesn't exist.
+func (dm *ClusterDMap) LPop(ctx context.Context, key string) (*GetResponse, error) {
+	defer dm.invalidate(key)
+
+	return dm.processPopCommand(ctx, key, protocol.NewLPop(dm.name, key).Command(ctx))
+}
+
+// RPop removes and returns the tail of the list stored at key. It returns
+// ErrKeyNotFound if the list doesn't exist.
+func (dm *ClusterDMap) RPop(ctx context.Context, key string) (*GetResponse, error) {
+	defer dm.invalidate(key)
+
+	return dm.processPopCommand(ctx, key, protocol.NewRPop(dm.name, key).Command(ctx))
+}
+
+// LRange gets the elements of the list stored at key between start and stop,
+// both inclusive. Negative indexes are counted from the tail, -1 is the last
+// element.
+func (dm *ClusterDMap) LRange(ctx context.Context, key string, start, stop int) ([]*GetResponse, error) {
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := protocol.NewLRange(dm.name, key, start, stop).Command(ctx)
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+	res, err := cmd.Result()
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+
+	result := make([]*GetResponse, 0, len(res))
+	for _, item := range res {
+		value, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid element type: %T", item)
+		}
+		e := dm.newEntry()
+		e.SetKey(key)
+		e.SetValue([]byte(value))
+		result = append(result, &GetResponse{
+			entry: e,
+		})
+	}
+	return result, nil
+}
+
+// LLen returns the length of the list stored at key. It returns zero if the
+// key doesn't exist.
+func (dm *ClusterDMap) LLen(ctx context.Context, key string) (int, error) {
+	return dm.processIntCommand(ctx, key, protocol.NewLLen(dm.name, key).Command(ctx))
+}
+
+// BLPop removes and returns the head of the list stored at key. It blocks
+// until an element is pushed if the list is empty. It returns ErrKeyNotFound
+// if the list is still empty after timeout. Zero timeout blocks until the
+// context is done. The blocked callers are not served in order.
+func (dm *ClusterDMap) BLPop(ctx context.Context, key string, timeout time.Duration) (*GetResponse, error) {
+	defer dm.invalidate(key)
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	// Blocks in short steps to stay within the read timeout of the client.
+	for {
+		wait := blockingPopStep
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return nil, ErrKeyNotFound
+			}
+			if remaining < wait {
+				wait = remaining
+			}
+		}
+
+		gr, err := dm.processPopCommand(ctx, key, protocol.NewBLPop(dm.name, key, wait.Seconds()).Command(ctx))
+		if !errors.Is(err, ErrKeyNotFound) {
+			return gr, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+	}
+}
+
+// XAdd appends an entry with the given fields to the stream stored at key
+// and returns its ID. The entries are stored on the partition owner and
+// replicated like the other entries, so they can be read after the
+// consumers reconnect.
+func (dm *ClusterDMap) XAdd(ctx context.Context, key string, fields map[string]interface{}, options ...XAddOption) (string, error) {
+	defer dm.invalidate(key)
+
+	var xc xaddConfig
+	for _, opt := range options {
+		opt(&xc)
+	}
+
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return "", err
+	}
+
+	xaddCmd := protocol.NewXAdd(dm.name, key).SetMaxLen(xc.maxLen)
+	for field, value := range fields {
+		valueBuf := pool.Get()
+		enc := resp.New(valueBuf)
+		err := enc.Encode(value)
+		if err != nil {
+			pool.Put(valueBuf)
+			return "", err
+		}
+		// Copy the encoded value, the buffer goes back to the pool.
+		xaddCmd.Add(field, append([]byte(nil), valueBuf.Bytes()...))
+		pool.Put(valueBuf)
+	}
+
+	cmd := xaddCmd.Command(ctx)
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return "", processProtocolError(err)
+	}
+	id, err := cmd.Result()
+	if err != nil {
+		return "", processProtocolError(err)
+	}
+	return id, nil
+}
+
+func (dm *ClusterDMap) processStreamCommand(ctx context.Context, key string, cmd *redis.SliceCmd) ([]StreamEntry, error) {
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
+	if err != nil {
+		return nil, err
+	}
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+	res, err := cmd.Result()
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+
+	// Every entry is an array of ID and field-value pairs.
+	entries := make([]StreamEntry, 0, len(res))
+	for _, item := range res {
+		raw, ok := item.([]interface{})
+		if !ok || len(raw) != 2 {
+			return nil, fmt.Errorf("invalid stream entry: %v", item)
+		}
+		id, ok := raw[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid stream entry ID type: %T", raw[0])
+		}
+
+		se := StreamEntry{ID: id}
+		if raw[1] != nil {
+			pairs, ok := raw[1].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid stream entry fields type: %T", raw[1])
+			}
+			se.Fields = make(map[string]*GetResponse, len(pairs)/2)
+			for i := 0; i+1 < len(pairs); i += 2 {
+				field, _ := pairs[i].(string)
+				value, _ := pairs[i+1].(string)
+				e := dm.newEntry()
+				e.SetKey(field)
+				e.SetValue([]byte(value))
+				se.Fields[field] = &GetResponse{
+					entry: e,
+				}
+			}
+		}
+		entries = append(entries, se)
+	}
+	return entries, nil
+}
+
+// XRead gets the entries of the stream stored at key with an ID greater
+// than id. An empty id or "0" reads from the beginning. Zero count returns
+// all the entries.
+func (dm *ClusterDMap) XRead(ctx context.Context, key, id string, count int) ([]StreamEntry, error) {
+	cmd := protocol.NewXRead(dm.name, key, id).SetCount(count).Command(ctx)
+	return dm.processStreamCommand(ctx, key, cmd)
+}
+
+// XReadGroup reads the entries of the stream stored at key for a consumer
+// of the group. The group is created on the first call and it starts from
+// the beginning of the stream. If id is StreamNewEntries, it returns the
+// entries that were never delivered to the group. Otherwise, it returns the
+// pending entries of the consumer with an ID greater than id. The returned
+// entries are pending until they are acknowledged with XAck.
+func (dm *ClusterDMap) XReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]StreamEntry, error) {
+	defer dm.invalidate(key)
+
+	cmd := protocol.NewXReadGroup(dm.name, key, group, consumer, id).SetCount(count).Command(ctx)
+	return dm.processStreamCommand(ctx, key, cmd)
+}
+
+// XAck acknowledges the entries of the group. It returns the number of the
+// acknowledged entries. It returns ErrNoSuchGroup if the group doesn't exist.
+func (dm *ClusterDMap) XAck(ctx context.Context, key, group string, ids ...string) (int, error) {
+	defer dm.invalidate(key)
+
+	return dm.processIntCommand(ctx, key, protocol.NewXAck(dm.name, key, group, ids...).Command(ctx))
+}
 
-	enc := resp.New(valueBuf)
-	err = enc.Encode(value)
+// XPending gets the pending entries of the group ordered by their IDs. It
+// returns ErrNoSuchGroup if the group doesn't exist.
+func (dm *ClusterDMap) XPending(ctx context.Context, key, group string) ([]PendingEntry, error) {
+	rc, err := dm.clusterClient.smartPick(dm.name, key)
 	if err != nil {
-		return err
-	}
-
-	var pc dmap.PutConfig
-	for _, opt := range options {
-		opt(&pc)
+		return nil, err
 	}
-	putCmd := dm.writePutCommand(&pc, key, valueBuf.Bytes())
-	cmd := putCmd.Command(ctx)
 
+	cmd := protocol.NewXPending(dm.name, key, group).Command(ctx)
 	err = rc.Process(ctx, cmd)
 	if err != nil {
-		return processProtocolError(err)
+		return nil, processProtocolError(err)
 	}
-	return processProtocolError(cmd.Err())
+	res, err := cmd.Result()
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+
+	// Every item is an array of ID, consumer, idle time in milliseconds and
+	// delivery count.
+	pending := make([]PendingEntry, 0, len(res))
+	for _, item := range res {
+		raw, ok := item.([]interface{})
+		if !ok || len(raw) != 4 {
+			return nil, fmt.Errorf("invalid pending entry: %v", item)
+		}
+		id, _ := raw[0].(string)
+		consumer, _ := raw[1].(string)
+		idle, _ := raw[2].(int64)
+		deliveries, _ := raw[3].(int64)
+		pending = append(pending, PendingEntry{
+			ID:         id,
+			Consumer:   consumer,
+			Idle:       time.Duration(idle) * time.Millisecond,
+			Deliveries: int(deliveries),
+		})
+	}
+	return pending, nil
 }
 
 func (dm *ClusterDMap) makeGetResponse(cmd *redis.StringCmd) (*GetResponse, error) {
@@ -180,6 +784,27 @@ func (dm *ClusterDMap) makeGetResponse(cmd *redis.StringCmd) (*GetResponse, erro
 // does not contain the key. It's thread-safe. It is safe to modify the contents
 // of the returned value. See GetResponse for the details.
 func (dm *ClusterDMap) Get(ctx context.Context, key string) (*GetResponse, error) {
+	if dm.nearCache == nil {
+		return dm.get(ctx, key)
+	}
+
+	if e, ok := dm.nearCache.Get(key); ok {
+		return &GetResponse{
+			entry: e,
+		}, nil
+	}
+
+	lease := dm.nearCache.Lease(key)
+	gr, err := dm.get(ctx, key)
+	if err != nil {
+		dm.nearCache.Invalidate(key)
+		return nil, err
+	}
+	dm.nearCache.Fill(key, lease, gr.entry)
+	return gr, nil
+}
+
+func (dm *ClusterDMap) get(ctx context.Context, key string) (*GetResponse, error) {
 	cmd := protocol.NewGet(dm.name, key).SetRaw().Command(ctx)
 	rc, err := dm.clusterClient.smartPick(dm.name, key)
 	if err != nil {
@@ -192,10 +817,256 @@ func (dm *ClusterDMap) Get(ctx context.Context, key string) (*GetResponse, error
 	return dm.makeGetResponse(cmd)
 }
 
+// groupByOwner groups the given keys by the primary owners of their partitions.
+// Keys without a known owner are returned with their errors.
+func (dm *ClusterDMap) groupByOwner(keys []string) (map[string][]string, map[string]error) {
+	var failed map[string]error
+	owners := make(map[string][]string)
+	for _, key := range keys {
+		partID := partitions.HKey(dm.name, key) % dm.clusterClient.partitionCount
+		owner, err := dm.clusterClient.primaryOwnerByPartID(partID)
+		if err != nil {
+			if failed == nil {
+				failed = make(map[string]error)
+			}
+			failed[key] = err
+			continue
+		}
+		owners[owner] = append(owners[owner], key)
+	}
+	return owners, failed
+}
+
+func (dm *ClusterDMap) mgetOnOwner(ctx context.Context, owner string, keys []string) (map[string]*GetResponse, map[string]error) {
+	result := make(map[string]*GetResponse)
+	failed := make(map[string]error)
+	setError := func(err error) {
+		for _, key := range keys {
+			failed[key] = err
+		}
+	}
+
+	cmd := protocol.NewMGet(dm.name, keys...).Command(ctx)
+	rc := dm.client.Get(owner)
+	err := rc.Process(ctx, cmd)
+	if err != nil {
+		setError(processProtocolError(err))
+		return result, failed
+	}
+
+	values, err := cmd.Result()
+	if err != nil {
+		setError(processProtocolError(err))
+		return result, failed
+	}
+	if len(values) != len(keys) {
+		setError(fmt.Errorf("invalid response length from %s: %d != %d", owner, len(values), len(keys)))
+		return result, failed
+	}
+
+	for i, key := range keys {
+		switch value := values[i].(type) {
+		case nil:
+			failed[key] = ErrKeyNotFound
+		case string:
+			e := dm.newEntry()
+			e.Decode([]byte(value))
+			result[key] = &GetResponse{
+				entry: e,
+			}
+		case error:
+			failed[key] = processProtocolError(value)
+		default:
+			failed[key] = fmt.Errorf("invalid response type from %s: %T", owner, value)
+		}
+	}
+	return result, failed
+}
+
+// MGet gets the values for the given keys. Keys are grouped by their partition
+// owners and the owners are queried in parallel. It returns the values of the
+// found keys and the errors of the failed ones. A missing key is reported
+// with ErrKeyNotFound. The error map is nil if every key is found.
+func (dm *ClusterDMap) MGet(ctx context.Context, keys ...string) (map[string]*GetResponse, map[string]error) {
+	if dm.nearCache == nil {
+		return dm.mget(ctx, keys...)
+	}
+
+	var missing []string
+	cached := make(map[string]*GetResponse)
+	leases := make(map[string]uint64)
+	for _, key := range keys {
+		if e, ok := dm.nearCache.Get(key); ok {
+			cached[key] = &GetResponse{
+				entry: e,
+			}
+			continue
+		}
+		if _, ok := leases[key]; !ok {
+			leases[key] = dm.nearCache.Lease(key)
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return cached, nil
+	}
+
+	result, failed := dm.mget(ctx, missing...)
+	for key, gr := range result {
+		dm.nearCache.Fill(key, leases[key], gr.entry)
+	}
+	for key := range failed {
+		dm.nearCache.Invalidate(key)
+	}
+	for key, gr := range cached {
+		result[key] = gr
+	}
+	return result, failed
+}
+
+func (dm *ClusterDMap) mget(ctx context.Context, keys ...string) (map[string]*GetResponse, map[string]error) {
+	owners, failed := dm.groupByOwner(keys)
+	result := make(map[string]*GetResponse)
+
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for owner, ownerKeys := range owners {
+		wg.Add(1)
+		go func(owner string, ownerKeys []string) {
+			defer wg.Done()
+
+			found, errs := dm.mgetOnOwner(ctx, owner, ownerKeys)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			for key, value := range found {
+				result[key] = value
+			}
+			for key, err := range errs {
+				if failed == nil {
+					failed = make(map[string]error)
+				}
+				failed[key] = err
+			}
+		}(owner, ownerKeys)
+	}
+	wg.Wait()
+
+	return result, failed
+}
+
+func (dm *ClusterDMap) mputOnOwner(ctx context.Context, owner string, keys []string, values [][]byte) map[string]error {
+	failed := make(map[string]error)
+	setError := func(err error) {
+		for _, key := range keys {
+			failed[key] = err
+		}
+	}
+
+	mputCmd := protocol.NewMPut(dm.name)
+	for i, key := range keys {
+		mputCmd.Add(key, values[i])
+	}
+	cmd := mputCmd.Command(ctx)
+	rc := dm.client.Get(owner)
+	err := rc.Process(ctx, cmd)
+	if err != nil {
+		setError(processProtocolError(err))
+		return failed
+	}
+
+	results, err := cmd.Result()
+	if err != nil {
+		setError(processProtocolError(err))
+		return failed
+	}
+	if len(results) != len(keys) {
+		setError(fmt.Errorf("invalid response length from %s: %d != %d", owner, len(results), len(keys)))
+		return failed
+	}
+
+	for i, key := range keys {
+		if err, ok := results[i].(error); ok {
+			failed[key] = processProtocolError(err)
+		}
+	}
+	return failed
+}
+
+// MPut sets the given key/value pairs. Keys are grouped by their partition
+// owners and the owners are called in parallel. It returns the errors of the
+// failed keys. The error map is nil if every key is set. It is safe to modify
+// the contents of the argument after MPut returns but not before.
+func (dm *ClusterDMap) MPut(ctx context.Context, entries map[string]interface{}) map[string]error {
+	defer func() {
+		for key := range entries {
+			dm.invalidate(key)
+		}
+	}()
+
+	var failed map[string]error
+	setError := func(key string, err error) {
+		if failed == nil {
+			failed = make(map[string]error)
+		}
+		failed[key] = err
+	}
+
+	keys := make([]string, 0, len(entries))
+	values := make(map[string][]byte)
+	for key, value := range entries {
+		valueBuf := pool.Get()
+		enc := resp.New(valueBuf)
+		if err := enc.Encode(value); err != nil {
+			pool.Put(valueBuf)
+			setError(key, err)
+			continue
+		}
+		encoded := make([]byte, valueBuf.Len())
+		copy(encoded, valueBuf.Bytes())
+		pool.Put(valueBuf)
+
+		keys = append(keys, key)
+		values[key] = encoded
+	}
+
+	owners, errs := dm.groupByOwner(keys)
+	for key, err := range errs {
+		setError(key, err)
+	}
+
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for owner, ownerKeys := range owners {
+		ownerValues := make([][]byte, 0, len(ownerKeys))
+		for _, key := range ownerKeys {
+			ownerValues = append(ownerValues, values[key])
+		}
+
+		wg.Add(1)
+		go func(owner string, ownerKeys []string, ownerValues [][]byte) {
+			defer wg.Done()
+
+			errs := dm.mputOnOwner(ctx, owner, ownerKeys, ownerValues)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			for key, err := range errs {
+				setError(key, err)
+			}
+		}(owner, ownerKeys, ownerValues)
+	}
+	wg.Wait()
+
+	return failed
+}
+
 // Delete deletes values for the given keys. Delete will not return error
 // if key doesn't exist. It's thread-safe. It is safe to modify the contents
 // of the argument after Delete returns.
 func (dm *ClusterDMap) Delete(ctx context.Context, keys ...string) (int, error) {
+	defer dm.invalidate(keys...)
+
 	rc, err := dm.client.Pick()
 	if err != nil {
 		return 0, err
@@ -217,6 +1088,8 @@ func (dm *ClusterDMap) Delete(ctx context.Context, keys ...string) (int, error)
 // Incr atomically increments the key by delta. The return value is the new value
 // after being incremented or an error.
 func (dm *ClusterDMap) Incr(ctx context.Context, key string, delta int) (int, error) {
+	defer dm.invalidate(key)
+
 	rc, err := dm.clusterClient.smartPick(dm.name, key)
 	if err != nil {
 		return 0, err
@@ -237,6 +1110,8 @@ func (dm *ClusterDMap) Incr(ctx context.Context, key string, delta int) (int, er
 // Decr atomically decrements the key by delta. The return value is the new value
 // after being decremented or an error.
 func (dm *ClusterDMap) Decr(ctx context.Context, key string, delta int) (int, error) {
+	defer dm.invalidate(key)
+
 	rc, err := dm.clusterClient.smartPick(dm.name, key)
 	if err != nil {
 		return 0, err
@@ -257,6 +1132,8 @@ func (dm *ClusterDMap) Decr(ctx context.Context, key string, delta int) (int, er
 // GetPut atomically sets the key to value and returns the old value stored at key. It returns nil if there is no
 // previous value.
 func (dm *ClusterDMap) GetPut(ctx context.Context, key string, value interface{}) (*GetResponse, error) {
+	defer dm.invalidate(key)
+
 	rc, err := dm.clusterClient.smartPick(dm.name, key)
 	if err != nil {
 		return nil, err
@@ -297,6 +1174,8 @@ func (dm *ClusterDMap) GetPut(ctx context.Context, key string, value interface{}
 // IncrByFloat atomically increments the key by delta. The return value is the new value
 // after being incremented or an error.
 func (dm *ClusterDMap) IncrByFloat(ctx context.Context, key string, delta float64) (float64, error) {
+	defer dm.invalidate(key)
+
 	rc, err := dm.clusterClient.smartPick(dm.name, key)
 	if err != nil {
 		return 0, err
@@ -317,6 +1196,8 @@ func (dm *ClusterDMap) IncrByFloat(ctx context.Context, key string, delta float6
 // Expire updates the expiry for the given key. It returns ErrKeyNotFound if
 // the DB does not contain the key. It's thread-safe.
 func (dm *ClusterDMap) Expire(ctx context.Context, key string, timeout time.Duration) error {
+	defer dm.invalidate(key)
+
 	rc, err := dm.clusterClient.smartPick(dm.name, key)
 	if err != nil {
 		return err
@@ -336,62 +1217,286 @@ func (dm *ClusterDMap) Expire(ctx context.Context, key string, timeout time.Dura
 // It returns immediately if it acquires the lock for the given key. Otherwise,
 // it waits until deadline.
 //
-// You should know that the locks are approximate, and only to be used for
-// non-critical purposes.
-func (dm *ClusterDMap) Lock(ctx context.Context, key string, deadline time.Duration) (LockContext, error) {
-	rc, err := dm.clusterClient.smartPick(dm.name, key)
+// Every acquisition comes with a fencing token, see LockContext.Token. The
+// lock is reentrant for the same owner id, see LockOwner.
+func (dm *ClusterDMap) Lock(ctx context.Context, key string, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	return dm.LockWithTimeout(ctx, key, 0, deadline, options...)
+}
+
+// LockWithTimeout sets a lock for the given key. If the lock is still unreleased
+// the end of given period of time, it automatically releases the lock.
+// Acquired lock is only for the key in this DMap.
+//
+// It returns immediately if it acquires the lock for the given key. Otherwise,
+// it waits until deadline.
+//
+// Every acquisition comes with a fencing token, see LockContext.Token. The
+// lock is reentrant for the same owner id, see LockOwner.
+func (dm *ClusterDMap) LockWithTimeout(ctx context.Context, key string, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	lc, err := newLockConfig(options...)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd := protocol.NewLock(dm.name, key, deadline.Seconds()).Command(ctx)
-	err = rc.Process(ctx, cmd)
+	token, err := dm.acquireLock(ctx, key, deadline, func(wait time.Duration) *redis.IntCmd {
+		return protocol.NewFencedLock(dm.name, key, lc.Owner, wait.Seconds()).
+			SetPX(timeout.Milliseconds()).
+			Command(ctx)
+	})
 	if err != nil {
-		return nil, processProtocolError(err)
+		return nil, err
+	}
+	return &ClusterLockContext{
+		key:     key,
+		owner:   lc.Owner,
+		token:   token,
+		timeout: int64(timeout),
+		dm:      dm,
+	}, nil
+}
+
+// acquireLock sends the command built by lock to the partition owner of the
+// key until it acquires the lock or deadline passes. It returns the fencing
+// token of the lock.
+func (dm *ClusterDMap) acquireLock(ctx context.Context, key string, deadline time.Duration, lock func(wait time.Duration) *redis.IntCmd) (uint64, error) {
+	until := time.Now().Add(deadline)
+	for {
+		// Wait on the server in short steps to stay within the read timeout.
+		wait := time.Until(until)
+		if wait < 0 {
+			wait = 0
+		}
+		if wait > blockingPopStep {
+			wait = blockingPopStep
+		}
+
+		rc, err := dm.clusterClient.smartPick(dm.name, key)
+		if err != nil {
+			return 0, err
+		}
+
+		cmd := lock(wait)
+		err = rc.Process(ctx, cmd)
+		if err == nil {
+			var token int64
+			token, err = cmd.Result()
+			if err == nil {
+				return uint64(token), nil
+			}
+		}
+		err = processProtocolError(err)
+		if err != ErrLockNotAcquired || time.Until(until) <= 0 {
+			return 0, err
+		}
 	}
+}
+
+// ClusterRWLock is a read-write lock implementation for cluster-member
+// scenario.
+type ClusterRWLock struct {
+	key string
+	dm  *ClusterDMap
+}
 
-	token, err := cmd.Bytes()
+func (l *ClusterRWLock) lock(ctx context.Context, mode string, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	lc, err := newLockConfig(options...)
 	if err != nil {
-		return nil, processProtocolError(err)
+		return nil, err
+	}
+
+	token, err := l.dm.acquireLock(ctx, l.key, deadline, func(wait time.Duration) *redis.IntCmd {
+		return protocol.NewRWLock(l.dm.name, l.key, lc.Owner, mode, wait.Seconds()).
+			SetPX(timeout.Milliseconds()).
+			Command(ctx)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return &ClusterLockContext{
-		key:   key,
-		token: string(token),
-		dm:    dm,
+		key:     l.key,
+		owner:   lc.Owner,
+		token:   token,
+		timeout: int64(timeout),
+		shared:  true,
+		dm:      l.dm,
 	}, nil
 }
 
-// LockWithTimeout sets a lock for the given key. If the lock is still unreleased
-// the end of given period of time, it automatically releases the lock.
-// Acquired lock is only for the key in this DMap.
-//
-// It returns immediately if it acquires the lock for the given key. Otherwise,
-// it waits until deadline.
-//
-// You should know that the locks are approximate, and only to be used for
-// non-critical purposes.
-func (dm *ClusterDMap) LockWithTimeout(ctx context.Context, key string, timeout, deadline time.Duration) (LockContext, error) {
+// RLock acquires the lock for reading. It waits until deadline if a writer
+// holds the lock.
+func (l *ClusterRWLock) RLock(ctx context.Context, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	return l.lock(ctx, protocol.RWLockModeRead, timeout, deadline, options...)
+}
+
+// Lock acquires the lock for writing. It waits until deadline if anybody holds
+// the lock.
+func (l *ClusterRWLock) Lock(ctx context.Context, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	return l.lock(ctx, protocol.RWLockModeWrite, timeout, deadline, options...)
+}
+
+// RWLock returns a read-write lock for the given key.
+func (dm *ClusterDMap) RWLock(key string) RWLock {
+	return &ClusterRWLock{
+		key: key,
+		dm:  dm,
+	}
+}
+
+// ClusterSemaphore is a counting semaphore implementation for cluster-member
+// scenario.
+type ClusterSemaphore struct {
+	name    string
+	permits int
+	dm      *ClusterDMap
+}
+
+// Acquire acquires a permit of the semaphore. It waits until deadline if no
+// permit is available.
+func (s *ClusterSemaphore) Acquire(ctx context.Context, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	lc, err := newLockConfig(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.dm.acquireLock(ctx, s.name, deadline, func(wait time.Duration) *redis.IntCmd {
+		return protocol.NewSemAcquire(s.dm.name, s.name, lc.Owner, s.permits, wait.Seconds()).
+			SetPX(timeout.Milliseconds()).
+			Command(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ClusterLockContext{
+		key:     s.name,
+		owner:   lc.Owner,
+		token:   token,
+		timeout: int64(timeout),
+		shared:  true,
+		dm:      s.dm,
+	}, nil
+}
+
+// Semaphore returns a counting semaphore with the given name.
+func (dm *ClusterDMap) Semaphore(name string, permits int) Semaphore {
+	return &ClusterSemaphore{
+		name:    name,
+		permits: permits,
+		dm:      dm,
+	}
+}
+
+// Eval runs the Lua script atomically on the partition owner of the keys.
+// All the keys have to belong to the same partition. See DMap.Eval for the
+// available functions.
+func (dm *ClusterDMap) Eval(ctx context.Context, script string, keys []string, args ...string) (interface{}, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: at least one key is required", protocol.ErrInvalidArgument)
+	}
+
+	rc, err := dm.clusterClient.smartPick(dm.name, keys[0])
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := protocol.NewEval(dm.name, script, keys, args...).Command(ctx)
+	err = rc.Process(ctx, cmd)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+	return cmd.Val(), nil
+}
+
+// Execute runs the entry processor on the partition owner of the key. See
+// DMap.Execute for details.
+func (dm *ClusterDMap) Execute(ctx context.Context, key, processor string, args []byte) ([]byte, error) {
+	defer dm.invalidate(key)
+
 	rc, err := dm.clusterClient.smartPick(dm.name, key)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd := protocol.NewLock(dm.name, key, deadline.Seconds()).SetPX(timeout.Milliseconds()).Command(ctx)
+	cmd := protocol.NewExecute(dm.name, key, processor).SetArgs(args).Command(ctx)
 	err = rc.Process(ctx, cmd)
+	if err == redis.Nil {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, processProtocolError(err)
 	}
+	return cmd.Bytes()
+}
+
+// Aggregate runs the aggregator on the primary partitions of every member,
+// and merges the partial results.
+func (dm *ClusterDMap) Aggregate(ctx context.Context, aggregator *Aggregator) (AggregateResult, error) {
+	rc, err := dm.client.Pick()
+	if err != nil {
+		return nil, err
+	}
 
-	token, err := cmd.Bytes()
+	a := protocol.NewAggregate(dm.name, aggregator.config.Op).
+		SetField(aggregator.config.Field).
+		SetGroupBy(aggregator.config.GroupBy)
+	for _, p := range aggregator.config.Where {
+		a.AddWhere(p.Field, p.Op, p.Value)
+	}
+	cmd := a.Command(ctx)
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+	pairs, err := cmd.Result()
 	if err != nil {
 		return nil, processProtocolError(err)
 	}
 
-	return &ClusterLockContext{
-		key:   key,
-		token: string(token),
-		dm:    dm,
-	}, nil
+	result := make(AggregateResult)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		value, err := strconv.ParseFloat(pairs[i+1], 64)
+		if err != nil {
+			return nil, err
+		}
+		result[pairs[i]] = value
+	}
+	return result, nil
+}
+
+// Query returns the entries whose indexed field equals value. See DMap.Query
+// for details.
+func (dm *ClusterDMap) Query(ctx context.Context, index string, value interface{}, options ...QueryOption) (*QueryResult, error) {
+	indexed, err := indexValue(value)
+	if err != nil {
+		return nil, err
+	}
+	cfg := newQueryConfig(options)
+
+	rc, err := dm.client.Pick()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := protocol.NewQuery(dm.name, index, indexed).
+		SetCount(cfg.Count).
+		SetAfter(cfg.After).
+		Command(ctx)
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+	pairs, err := cmd.Result()
+	if err != nil {
+		return nil, processProtocolError(err)
+	}
+
+	entries := make([]QueryEntry, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		entries = append(entries, QueryEntry{Key: pairs[i], Value: []byte(pairs[i+1])})
+	}
+	return newQueryResult(entries, cfg), nil
 }
 
 func (c *ClusterLockContext) Unlock(ctx context.Context) error {
@@ -399,7 +1504,12 @@ func (c *ClusterLockContext) Unlock(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	cmd := protocol.NewUnlock(c.dm.name, c.key, c.token).Command(ctx)
+	var cmd *redis.StatusCmd
+	if c.shared {
+		cmd = protocol.NewSharedRelease(c.dm.name, c.key, c.owner).Command(ctx)
+	} else {
+		cmd = protocol.NewFencedUnlock(c.dm.name, c.key, c.owner).Command(ctx)
+	}
 	err = rc.Process(ctx, cmd)
 	if err != nil {
 		return processProtocolError(err)
@@ -412,12 +1522,34 @@ func (c *ClusterLockContext) Lease(ctx context.Context, duration time.Duration)
 	if err != nil {
 		return err
 	}
-	cmd := protocol.NewLockLease(c.dm.name, c.key, c.token, duration.Seconds()).Command(ctx)
+	var cmd *redis.StatusCmd
+	if c.shared {
+		cmd = protocol.NewSharedLease(c.dm.name, c.key, c.owner, duration.Milliseconds()).Command(ctx)
+	} else {
+		cmd = protocol.NewFencedLockLease(c.dm.name, c.key, c.owner, duration.Milliseconds()).Command(ctx)
+	}
 	err = rc.Process(ctx, cmd)
 	if err != nil {
 		return processProtocolError(err)
 	}
-	return processProtocolError(cmd.Err())
+	err = processProtocolError(cmd.Err())
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&c.timeout, int64(duration))
+	return nil
+}
+
+// Token returns the fencing token of the lock.
+func (c *ClusterLockContext) Token() uint64 {
+	return c.token
+}
+
+// KeepAlive renews the lease of the lock periodically until ctx is cancelled.
+func (c *ClusterLockContext) KeepAlive(ctx context.Context) error {
+	return keepLockAlive(ctx, func() time.Duration {
+		return time.Duration(atomic.LoadInt64(&c.timeout))
+	}, c.Lease)
 }
 
 // Scan returns an iterator to loop over the keys.
@@ -426,6 +1558,10 @@ func (c *ClusterLockContext) Lease(ctx context.Context, duration time.Duration)
 //
 // * Count
 // * Match
+// * Where
+// * WhereTTL
+// * NewerThan
+// * WithValues
 func (dm *ClusterDMap) Scan(ctx context.Context, options ...ScanOption) (Iterator, error) {
 	var sc dmap.ScanConfig
 	for _, opt := range options {
@@ -466,6 +1602,8 @@ func (dm *ClusterDMap) Scan(ctx context.Context, options ...ScanOption) (Iterato
 // is no global lock on DMaps. So if you call Put/PutEx and Destroy methods
 // concurrently on the cluster, Put call may set new values to the DMap.
 func (dm *ClusterDMap) Destroy(ctx context.Context) error {
+	defer dm.purgeNearCache()
+
 	rc, err := dm.client.Pick()
 	if err != nil {
 		return err
@@ -662,6 +1800,7 @@ func (cl *ClusterClient) Close(ctx context.Context) error {
 
 	// Wait for the background workers:
 	// * fetchRoutingTablePeriodically
+	// * listenNearCacheInvalidations
 	cl.wg.Wait()
 
 	// Close the underlying TCP sockets gracefully.
@@ -670,11 +1809,15 @@ func (cl *ClusterClient) Close(ctx context.Context) error {
 
 // NewPubSub returns a new PubSub client with the given options.
 func (cl *ClusterClient) NewPubSub(options ...PubSubOption) (*PubSub, error) {
-	return newPubSub(cl.client, options...)
+	return newPubSub(cl.client, cl.smartPickChannel, options...)
 }
 
 // NewDMap returns a new DMap client with the given options.
 func (cl *ClusterClient) NewDMap(name string, options ...DMapOption) (DMap, error) {
+	if dmap.IsReservedDMap(name) {
+		return nil, fmt.Errorf("%w: %s is reserved for internal use", protocol.ErrInvalidArgument, name)
+	}
+
 	var dc dmapConfig
 	for _, opt := range options {
 		opt(&dc)
@@ -686,12 +1829,18 @@ func (cl *ClusterClient) NewDMap(name string, options ...DMapOption) (DMap, erro
 		}
 	}
 
-	return &ClusterDMap{name: name,
+	dm := &ClusterDMap{name: name,
 		config:        &dc,
 		newEntry:      dc.storageEntryImplementation,
 		client:        cl.client,
 		clusterClient: cl,
-	}, nil
+	}
+	if dc.nearCacheSize > 0 {
+		if err := dm.enableNearCache(); err != nil {
+			return nil, err
+		}
+	}
+	return dm, nil
 }
 
 type ClusterClientOption func(c *clusterClientConfig)
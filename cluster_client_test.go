@@ -16,8 +16,11 @@ package olric
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"math"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -221,6 +224,40 @@ func TestClusterClient_Destroy(t *testing.T) {
 	require.ErrorIs(t, err, ErrKeyNotFound)
 }
 
+func TestClusterClient_MPut_MGet(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+	cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	entries := make(map[string]interface{})
+	var keys []string
+	for i := 0; i < 100; i++ {
+		entries[testutil.ToKey(i)] = i
+		keys = append(keys, testutil.ToKey(i))
+	}
+	require.Nil(t, dm.MPut(ctx, entries))
+
+	result, errs := dm.MGet(ctx, append(keys, "missing-key")...)
+	require.Len(t, result, 100)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs["missing-key"], ErrKeyNotFound)
+	for i := 0; i < 100; i++ {
+		value, err := result[testutil.ToKey(i)].Int()
+		require.NoError(t, err)
+		require.Equal(t, i, value)
+	}
+}
+
 func TestClusterClient_Incr(t *testing.T) {
 	cluster := newTestOlricCluster(t)
 	db := cluster.addMember(t)
@@ -523,6 +560,193 @@ func TestClusterClient_LockWithTimeout_ErrLockNotAcquired(t *testing.T) {
 	require.Equal(t, err, ErrLockNotAcquired)
 }
 
+func TestClusterClient_Lock_Fencing(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	lx, err := dm.Lock(ctx, "lock.foo.key", time.Second, LockOwner("worker-1"))
+	require.NoError(t, err)
+
+	reentrant, err := dm.Lock(ctx, "lock.foo.key", time.Millisecond, LockOwner("worker-1"))
+	require.NoError(t, err)
+	require.Equal(t, lx.Token(), reentrant.Token())
+
+	go func() {
+		<-time.After(100 * time.Millisecond)
+		require.NoError(t, reentrant.Unlock(ctx))
+		require.NoError(t, lx.Unlock(ctx))
+	}()
+
+	// Waits across several server-side steps until the lock is released.
+	waiter, err := dm.Lock(ctx, "lock.foo.key", 5*time.Second, LockOwner("worker-2"))
+	require.NoError(t, err)
+	require.Greater(t, waiter.Token(), lx.Token())
+}
+
+func TestClusterClient_Lock_KeepAlive(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	kctx, cancel := context.WithCancel(ctx)
+	lx, err := dm.LockWithTimeout(ctx, "lock.foo.key", 300*time.Millisecond, time.Second)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- lx.KeepAlive(kctx)
+	}()
+
+	<-time.After(time.Second)
+
+	_, err = dm.Lock(ctx, "lock.foo.key", time.Millisecond)
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.NoError(t, lx.Unlock(ctx))
+}
+
+func TestClusterClient_RWLock(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	rw := dm.RWLock("lock.foo.key")
+	r, err := rw.RLock(ctx, 0, time.Second)
+	require.NoError(t, err)
+
+	go func() {
+		<-time.After(100 * time.Millisecond)
+		require.NoError(t, r.Unlock(ctx))
+	}()
+
+	w, err := rw.Lock(ctx, time.Second, 5*time.Second)
+	require.NoError(t, err)
+	require.Greater(t, w.Token(), r.Token())
+	require.NoError(t, w.Lease(ctx, time.Hour))
+
+	_, err = rw.RLock(ctx, 0, time.Millisecond)
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+	require.NoError(t, w.Unlock(ctx))
+}
+
+func TestClusterClient_Semaphore(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	const permits = 3
+	var running, maxRunning int32
+
+	var errGr errgroup.Group
+	for i := 0; i < 10; i++ {
+		errGr.Go(func() error {
+			lx, err := dm.Semaphore("semaphore.test", permits).Acquire(ctx, time.Minute, time.Minute)
+			if err != nil {
+				return err
+			}
+
+			n := atomic.AddInt32(&running, 1)
+			for {
+				current := atomic.LoadInt32(&maxRunning)
+				if n <= current || atomic.CompareAndSwapInt32(&maxRunning, current, n) {
+					break
+				}
+			}
+			<-time.After(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+
+			return lx.Unlock(ctx)
+		})
+	}
+	require.NoError(t, errGr.Wait())
+
+	require.LessOrEqual(t, maxRunning, int32(permits))
+}
+
+func TestClusterClient_Eval(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+	cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	script := `
+local current = tonumber(olric.get(KEYS[1]) or "0")
+if current >= tonumber(ARGV[1]) then
+	return 0
+end
+olric.put(KEYS[1], current + 1, 60000)
+return 1
+`
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+		for j := 0; j < 3; j++ {
+			allowed, err := dm.Eval(ctx, script, []string{key}, "2")
+			require.NoError(t, err)
+			if j < 2 {
+				require.Equal(t, int64(1), allowed)
+			} else {
+				require.Equal(t, int64(0), allowed)
+			}
+		}
+	}
+
+	result, err := dm.Eval(ctx, `return olric.get(KEYS[1])`, []string{"missing-key"})
+	require.NoError(t, err)
+	require.Nil(t, result)
+
+	_, err = dm.Eval(ctx, `error("boom")`, []string{"mykey"})
+	require.ErrorIs(t, err, ErrScript)
+	require.Contains(t, err.Error(), "boom")
+}
+
 func TestClusterClient_Put_Ex(t *testing.T) {
 	cluster := newTestOlricCluster(t)
 	db := cluster.addMember(t)
@@ -661,6 +885,315 @@ func TestClusterClient_Put_XX(t *testing.T) {
 	require.ErrorIs(t, err, ErrKeyNotFound)
 }
 
+func TestClusterClient_Put_IfVersion(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	err = dm.Put(ctx, "mykey", "myvalue", IfVersion(0))
+	require.NoError(t, err)
+
+	gr, err := dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+
+	err = dm.Put(ctx, "mykey", "myvalue-2", IfVersion(gr.Timestamp()+1))
+	require.ErrorIs(t, err, ErrVersionMismatch)
+
+	err = dm.Put(ctx, "mykey", "myvalue-2", IfVersion(gr.Timestamp()))
+	require.NoError(t, err)
+}
+
+func TestClusterClient_CompareAndSwap(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	swapped, err := dm.CompareAndSwap(ctx, "mykey", 0, "myvalue")
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	gr, err := dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+
+	swapped, err = dm.CompareAndSwap(ctx, "mykey", gr.Timestamp(), "myvalue-2")
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	swapped, err = dm.CompareAndSwap(ctx, "mykey", gr.Timestamp(), "myvalue-3")
+	require.NoError(t, err)
+	require.False(t, swapped)
+
+	gr, err = dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+	value, err := gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "myvalue-2", value)
+}
+
+func TestClusterClient_Hash(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	added, err := dm.HSet(ctx, "myhash", map[string]interface{}{
+		"name":  "olric",
+		"count": 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, added)
+
+	gr, err := dm.HGet(ctx, "myhash", "name")
+	require.NoError(t, err)
+	name, err := gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "olric", name)
+
+	count, err := dm.HIncrBy(ctx, "myhash", "count", 10)
+	require.NoError(t, err)
+	require.Equal(t, 11, count)
+
+	fields, err := dm.HGetAll(ctx, "myhash")
+	require.NoError(t, err)
+	require.Len(t, fields, 2)
+	value, err := fields["count"].Int()
+	require.NoError(t, err)
+	require.Equal(t, 11, value)
+
+	removed, err := dm.HDel(ctx, "myhash", "name", "count", "missing")
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+
+	_, err = dm.HGet(ctx, "myhash", "name")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	err = dm.Put(ctx, "mykey", "myvalue")
+	require.NoError(t, err)
+	_, err = dm.HSet(ctx, "mykey", map[string]interface{}{"name": "olric"})
+	require.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestClusterClient_SortedSet(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	added, err := dm.ZAdd(ctx, "leaderboard",
+		ZMember{Member: "alice", Score: 30},
+		ZMember{Member: "bob", Score: 10},
+		ZMember{Member: "carol", Score: 20},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 3, added)
+
+	score, err := dm.ZIncrBy(ctx, "leaderboard", "bob", 25.5)
+	require.NoError(t, err)
+	require.Equal(t, 35.5, score)
+
+	members, err := dm.ZRange(ctx, "leaderboard", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []ZMember{
+		{Member: "carol", Score: 20},
+		{Member: "alice", Score: 30},
+		{Member: "bob", Score: 35.5},
+	}, members)
+
+	members, err = dm.ZRangeByScore(ctx, "leaderboard", math.Inf(-1), 30)
+	require.NoError(t, err)
+	require.Equal(t, []ZMember{
+		{Member: "carol", Score: 20},
+		{Member: "alice", Score: 30},
+	}, members)
+
+	rank, err := dm.ZRank(ctx, "leaderboard", "bob")
+	require.NoError(t, err)
+	require.Equal(t, 2, rank)
+
+	_, err = dm.ZRank(ctx, "leaderboard", "missing")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	removed, err := dm.ZRem(ctx, "leaderboard", "alice", "missing")
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+
+	_, err = dm.ZAdd(ctx, "leaderboard", ZMember{Member: "dave", Score: math.NaN()})
+	require.ErrorIs(t, err, ErrScoreNaN)
+
+	err = dm.Put(ctx, "mykey", "myvalue")
+	require.NoError(t, err)
+	_, err = dm.ZAdd(ctx, "mykey", ZMember{Member: "alice", Score: 1})
+	require.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestClusterClient_List(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	length, err := dm.RPush(ctx, "jobs", "job-2", "job-3")
+	require.NoError(t, err)
+	require.Equal(t, 2, length)
+
+	length, err = dm.LPush(ctx, "jobs", "job-1")
+	require.NoError(t, err)
+	require.Equal(t, 3, length)
+
+	elements, err := dm.LRange(ctx, "jobs", 0, -1)
+	require.NoError(t, err)
+	require.Len(t, elements, 3)
+	for i, element := range elements {
+		value, err := element.String()
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("job-%d", i+1), value)
+	}
+
+	gr, err := dm.RPop(ctx, "jobs")
+	require.NoError(t, err)
+	value, err := gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "job-3", value)
+
+	gr, err = dm.LPop(ctx, "jobs")
+	require.NoError(t, err)
+	value, err = gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "job-1", value)
+
+	length, err = dm.LLen(ctx, "jobs")
+	require.NoError(t, err)
+	require.Equal(t, 1, length)
+
+	gr, err = dm.BLPop(ctx, "jobs", time.Second)
+	require.NoError(t, err)
+	value, err = gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "job-2", value)
+
+	_, err = dm.BLPop(ctx, "jobs", 100*time.Millisecond)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	go func() {
+		<-time.After(100 * time.Millisecond)
+		_, err := dm.RPush(ctx, "jobs", "job-4")
+		require.NoError(t, err)
+	}()
+
+	gr, err = dm.BLPop(ctx, "jobs", 5*time.Second)
+	require.NoError(t, err)
+	value, err = gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "job-4", value)
+}
+
+func TestClusterClient_Stream(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := dm.XAdd(ctx, "events", map[string]interface{}{"seq": i})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	entries, err := dm.XRead(ctx, "events", ids[0], 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, ids[1], entries[0].ID)
+	seq, err := entries[0].Fields["seq"].Int()
+	require.NoError(t, err)
+	require.Equal(t, 1, seq)
+
+	entries, err = dm.XReadGroup(ctx, "events", "workers", "worker-1", StreamNewEntries, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	acked, err := dm.XAck(ctx, "events", "workers", entries[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, acked)
+
+	pending, err := dm.XPending(ctx, "events", "workers")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, ids[1], pending[0].ID)
+	require.Equal(t, "worker-1", pending[0].Consumer)
+	require.Equal(t, 1, pending[0].Deliveries)
+
+	// The consumer resumes from its pending entries.
+	entries, err = dm.XReadGroup(ctx, "events", "workers", "worker-1", "0", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, ids[1], entries[0].ID)
+
+	_, err = dm.XAck(ctx, "events", "missing", ids[0])
+	require.ErrorIs(t, err, ErrNoSuchGroup)
+
+	_, err = dm.XRead(ctx, "events", "foobar", 0)
+	require.ErrorIs(t, err, ErrInvalidStreamID)
+
+	for i := 0; i < 5; i++ {
+		_, err = dm.XAdd(ctx, "events", map[string]interface{}{"seq": i}, MaxLen(2))
+		require.NoError(t, err)
+	}
+	entries, err = dm.XRead(ctx, "events", "0", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
+
 func TestClusterClient_Stats(t *testing.T) {
 	cluster := newTestOlricCluster(t)
 	db := cluster.addMember(t)
@@ -791,3 +1324,211 @@ func TestClusterClient_smartPick(t *testing.T) {
 	}
 	require.Len(t, clients, 4)
 }
+
+func TestClusterClient_ACL(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.ACL = &config.ACL{Users: []*config.User{
+			{
+				Name:     "olric",
+				Password: "secret",
+				Commands: []string{"*"},
+			},
+			{
+				Name:     "reader",
+				Password: "pass",
+				Commands: []string{"cluster.*", "dm.get"},
+				DMaps:    []string{"users.*"},
+			},
+		}}
+		// Cluster members call each other with these credentials.
+		c.Client.Username = "olric"
+		c.Client.Password = "secret"
+		return c
+	}
+
+	cluster := newTestOlricCluster(t)
+	cluster.addMemberWithConfig(t, newConfig())
+	db := cluster.addMemberWithConfig(t, newConfig())
+
+	ctx := context.Background()
+
+	t.Run("Authenticated", func(t *testing.T) {
+		cc := config.NewClient()
+		cc.Username = "olric"
+		cc.Password = "secret"
+		c, err := NewClusterClient([]string{db.name}, WithConfig(cc))
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, c.Close(ctx))
+		}()
+
+		dm, err := c.NewDMap("users.1")
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, dm.Put(ctx, testutil.ToKey(i), i))
+		}
+		for i := 0; i < 10; i++ {
+			gr, err := dm.Get(ctx, testutil.ToKey(i))
+			require.NoError(t, err)
+			value, err := gr.Int()
+			require.NoError(t, err)
+			require.Equal(t, i, value)
+		}
+	})
+
+	t.Run("No permission", func(t *testing.T) {
+		cc := config.NewClient()
+		cc.Username = "reader"
+		cc.Password = "pass"
+		c, err := NewClusterClient([]string{db.name}, WithConfig(cc))
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, c.Close(ctx))
+		}()
+
+		dm, err := c.NewDMap("users.1")
+		require.NoError(t, err)
+		err = dm.Put(ctx, "mykey", "myvalue")
+		require.ErrorIs(t, err, ErrNoPermission)
+
+		dm, err = c.NewDMap("orders.1")
+		require.NoError(t, err)
+		_, err = dm.Get(ctx, "mykey")
+		require.ErrorIs(t, err, ErrNoPermission)
+	})
+
+	t.Run("Authentication required", func(t *testing.T) {
+		_, err := NewClusterClient([]string{db.name})
+		require.ErrorIs(t, err, ErrAuthRequired)
+	})
+}
+
+func TestClusterClient_Query(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.DMaps.Custom = map[string]config.DMap{
+			"mydmap": {
+				Indexes: []config.Index{{Name: "by-city", Field: "city"}},
+			},
+		}
+		return c
+	}
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMemberWithConfig(t, newConfig())
+	cluster.addMemberWithConfig(t, newConfig())
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	var expected []string
+	for i := 0; i < 20; i++ {
+		city := "ankara"
+		if i%4 == 0 {
+			city = "istanbul"
+			expected = append(expected, testutil.ToKey(i))
+		}
+		value := fmt.Sprintf(`{"city": %q}`, city)
+		require.NoError(t, dm.Put(ctx, testutil.ToKey(i), []byte(value)))
+	}
+
+	result, err := dm.Query(ctx, "by-city", "istanbul")
+	require.NoError(t, err)
+	require.Empty(t, result.Cursor)
+
+	var keys []string
+	for _, entry := range result.Entries {
+		keys = append(keys, entry.Key)
+		require.JSONEq(t, `{"city": "istanbul"}`, string(entry.Value))
+	}
+	require.ElementsMatch(t, expected, keys)
+
+	_, err = dm.Query(ctx, "by-name", "foobar")
+	require.ErrorIs(t, err, ErrNoSuchIndex)
+}
+
+func TestClusterClient_Aggregate(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+	cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("sessions")
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		value := fmt.Sprintf(`{"tenant": "tenant-%d", "duration": %d}`, i%2, i)
+		require.NoError(t, dm.Put(ctx, testutil.ToKey(i), []byte(value)))
+	}
+
+	result, err := dm.Aggregate(ctx, NewCountAggregator().GroupBy("tenant"))
+	require.NoError(t, err)
+	require.Equal(t, AggregateResult{"tenant-0": 10, "tenant-1": 10}, result)
+
+	result, err = dm.Aggregate(ctx, NewSumAggregator("duration").GroupBy("tenant"))
+	require.NoError(t, err)
+	require.Equal(t, AggregateResult{"tenant-0": 90, "tenant-1": 100}, result)
+
+	result, err = dm.Aggregate(ctx, NewMinAggregator("duration").Where("duration", OpGreater, 4.5))
+	require.NoError(t, err)
+	require.Equal(t, AggregateResult{"": 5}, result)
+}
+
+func TestClusterClient_Execute(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.EntryProcessors = map[string]config.EntryProcessor{
+			"getdel": func(entry config.MutableEntry, _ []byte) ([]byte, error) {
+				value := entry.Value()
+				entry.Delete()
+				return value, nil
+			},
+		}
+		return c
+	}
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMemberWithConfig(t, newConfig())
+	cluster.addMemberWithConfig(t, newConfig())
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+		require.NoError(t, dm.Put(ctx, key, testutil.ToVal(i)))
+
+		result, err := dm.Execute(ctx, key, "getdel", nil)
+		require.NoError(t, err)
+		require.Equal(t, testutil.ToVal(i), result)
+
+		result, err = dm.Execute(ctx, key, "getdel", nil)
+		require.NoError(t, err)
+		require.Nil(t, result)
+
+		_, err = dm.Get(ctx, key)
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	}
+
+	_, err = dm.Execute(ctx, "mykey", "no-such-processor", nil)
+	require.ErrorIs(t, err, ErrNoSuchProcessor)
+}
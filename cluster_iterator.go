@@ -39,6 +39,7 @@ type ClusterIterator struct {
 	clusterClient  *ClusterClient
 	pos            int
 	page           []string
+	values         [][]byte
 	route          *Route
 	partitionKeys  map[string]struct{}
 	cursors        map[uint64]map[string]*currentCursor
@@ -101,12 +102,23 @@ func (i *ClusterIterator) loadCursor(owner string) uint64 {
 	return cc.primary
 }
 
-func (i *ClusterIterator) updateIterator(keys []string, cursor uint64, owner string) {
-	for _, key := range keys {
-		if _, ok := i.partitionKeys[key]; !ok {
-			i.page = append(i.page, key)
-			i.partitionKeys[key] = struct{}{}
+// updateIterator adds the scanned items to the page. The items are key and
+// value pairs if the values are requested.
+func (i *ClusterIterator) updateIterator(items []string, cursor uint64, owner string) {
+	step := 1
+	if i.config.WithValues {
+		step = 2
+	}
+	for idx := 0; idx+step <= len(items); idx += step {
+		key := items[idx]
+		if _, ok := i.partitionKeys[key]; ok {
+			continue
+		}
+		i.page = append(i.page, key)
+		if i.config.WithValues {
+			i.values = append(i.values, []byte(items[idx+1]))
 		}
+		i.partitionKeys[key] = struct{}{}
 	}
 	i.updateCursor(owner, cursor)
 }
@@ -138,25 +150,34 @@ func (i *ClusterIterator) removeScannedOwner(idx int) {
 	}
 }
 
+// newScan builds a scan command for the current partition.
+func (i *ClusterIterator) newScan(cursor uint64) *protocol.Scan {
+	s := protocol.NewScan(i.partID, i.dm.Name(), cursor)
+	if i.config.HasCount {
+		s.SetCount(i.config.Count)
+	}
+	if i.config.HasMatch {
+		s.SetMatch(i.config.Match)
+	}
+	for _, p := range i.config.Where {
+		s.AddWhere(p.Field, p.Op, p.Value)
+	}
+	if i.config.WithValues {
+		s.SetWithValues()
+	}
+	if i.config.Replica {
+		s.SetReplica()
+	}
+	return s
+}
+
 func (i *ClusterIterator) scanOnOwners() error {
 	owners := i.getOwners()
 
 	for idx, owner := range owners {
 		cursor := i.loadCursor(owner)
 
-		// Build a scan command here
-		s := protocol.NewScan(i.partID, i.dm.Name(), cursor)
-		if i.config.HasCount {
-			s.SetCount(i.config.Count)
-		}
-		if i.config.HasMatch {
-			s.SetMatch(i.config.Match)
-		}
-		if i.config.Replica {
-			s.SetReplica()
-		}
-
-		scanCmd := s.Command(i.ctx)
+		scanCmd := i.newScan(cursor).Command(i.ctx)
 		// Fetch a Redis client for the given owner.
 		rc := i.clusterClient.client.Get(owner)
 		err := rc.Process(i.ctx, scanCmd)
@@ -179,6 +200,7 @@ func (i *ClusterIterator) scanOnOwners() error {
 func (i *ClusterIterator) resetPage() {
 	if len(i.page) != 0 {
 		i.page = []string{}
+		i.values = nil
 	}
 	i.pos = 0
 }
@@ -264,6 +286,19 @@ func (i *ClusterIterator) Key() string {
 	return key
 }
 
+// Value returns the value of the current key. It's only available if the
+// iterator is created with the WithValues option, otherwise it returns nil.
+func (i *ClusterIterator) Value() []byte {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	var value []byte
+	if i.pos > 0 && i.pos <= len(i.values) {
+		value = i.values[i.pos-1]
+	}
+	return value
+}
+
 func (i *ClusterIterator) fetchRoutingTablePeriodically() {
 	defer i.wg.Done()
 
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/buraksezer/olric/internal/testutil"
 	"github.com/stretchr/testify/require"
@@ -95,3 +96,61 @@ func TestClusterClient_Scan(t *testing.T) {
 	}
 	require.Equal(t, 100, count)
 }
+
+func TestClusterClient_Scan_Where(t *testing.T) {
+	cl := newTestOlricCluster(t)
+	db := cl.addMember(t)
+	cl.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		value := fmt.Sprintf(`{"age": %d, "active": %t}`, i, i%2 == 0)
+		var options []PutOption
+		if i < 10 {
+			options = append(options, EX(time.Hour))
+		}
+		err = dm.Put(ctx, testutil.ToKey(i), []byte(value), options...)
+		require.NoError(t, err)
+	}
+
+	count := func(options ...ScanOption) int {
+		i, err := dm.Scan(ctx, options...)
+		require.NoError(t, err)
+		defer i.Close()
+
+		var count int
+		for i.Next() {
+			count++
+		}
+		return count
+	}
+
+	require.Equal(t, 25, count(Where("active", OpEqual, true), Where("age", OpGreaterOrEqual, 50)))
+	require.Equal(t, 10, count(WhereTTL(OpGreater, time.Minute)))
+	require.Equal(t, 100, count(NewerThan(start.Add(-time.Second))))
+	require.Equal(t, 0, count(NewerThan(time.Now().Add(time.Second))))
+
+	i, err := dm.Scan(ctx, Where("age", OpLess, 3), WithValues())
+	require.NoError(t, err)
+	defer i.Close()
+
+	values := make(map[string]string)
+	for i.Next() {
+		values[i.Key()] = string(i.Value())
+	}
+	require.Equal(t, map[string]string{
+		testutil.ToKey(0): `{"age": 0, "active": true}`,
+		testutil.ToKey(1): `{"age": 1, "active": false}`,
+		testutil.ToKey(2): `{"age": 2, "active": true}`,
+	}, values)
+}
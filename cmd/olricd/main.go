@@ -32,9 +32,14 @@ import (
 
 func usage() {
 	var msg = `Usage: olricd [options] ...
+       olricd snapshot|restore [options] ...
 
 Distributed cache and in-memory data structure server.
 
+Commands:
+  snapshot      Take a snapshot of the DMaps on every member of the cluster.
+  restore       Restore a snapshot to the cluster.
+
 Options:
   -h, --help    Print this message and exit.
   -v, --version Print the version number and exit.
@@ -65,6 +70,11 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 && (os.Args[1] == "snapshot" || os.Args[1] == "restore") {
+		runSnapshotCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	args := &arguments{}
 
 	// Parse command line parameters
@@ -17,9 +17,13 @@ package server
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/buraksezer/olric"
@@ -29,10 +33,11 @@ import (
 
 // Olricd represents a new Olricd instance.
 type Olricd struct {
-	log    *log.Logger
-	config *config.Config
-	db     *olric.Olric
-	errGr  errgroup.Group
+	log     *log.Logger
+	config  *config.Config
+	db      *olric.Olric
+	metrics *http.Server
+	errGr   errgroup.Group
 }
 
 // New creates a new Server instance
@@ -41,11 +46,37 @@ func New(c *config.Config) (*Olricd, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Olricd{
+	s := &Olricd{
 		config: c,
 		log:    c.Logger,
 		db:     db,
-	}, nil
+	}
+	if c.Metrics != nil && c.Metrics.Enabled() {
+		mux := http.NewServeMux()
+		mux.Handle(c.Metrics.Path, db.MetricsHandler())
+		s.metrics = &http.Server{
+			Addr:    net.JoinHostPort(c.Metrics.BindAddr, strconv.Itoa(c.Metrics.BindPort)),
+			Handler: mux,
+		}
+	}
+	return s, nil
+}
+
+// serveMetrics runs the HTTP server of the metrics endpoint.
+func (s *Olricd) serveMetrics() error {
+	s.log.Printf("[INFO] Metrics are served on http://%s%s", s.metrics.Addr, s.config.Metrics.Path)
+	err := s.metrics.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *Olricd) shutdownMetrics(ctx context.Context) error {
+	if s.metrics == nil {
+		return nil
+	}
+	return s.metrics.Shutdown(ctx)
 }
 
 func (s *Olricd) waitForInterrupt() {
@@ -59,6 +90,10 @@ func (s *Olricd) waitForInterrupt() {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		if err := s.shutdownMetrics(ctx); err != nil {
+			s.log.Printf("[ERROR] Failed to shutdown the metrics endpoint: %v", err)
+		}
+
 		if err := s.db.Shutdown(ctx); err != nil {
 			s.log.Printf("[ERROR] Failed to shutdown Olric: %v", err)
 			return err
@@ -92,10 +127,17 @@ func (s *Olricd) Start() error {
 		return s.db.Start()
 	})
 
+	if s.metrics != nil {
+		s.errGr.Go(s.serveMetrics)
+	}
+
 	return s.errGr.Wait()
 }
 
 // Shutdown stops background servers and leaves the cluster.
 func (s *Olricd) Shutdown(ctx context.Context) error {
+	if err := s.shutdownMetrics(ctx); err != nil {
+		s.log.Printf("[ERROR] Failed to shutdown the metrics endpoint: %v", err)
+	}
 	return s.db.Shutdown(ctx)
 }
@@ -0,0 +1,184 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/buraksezer/olric"
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultAddress is the default address of an olricd instance.
+const DefaultAddress = "127.0.0.1:3320"
+
+type snapshotArguments struct {
+	addr     string
+	dir      string
+	username string
+	password string
+	help     bool
+}
+
+func parseSnapshotArguments(name string, arguments []string) (*snapshotArguments, error) {
+	args := &snapshotArguments{}
+
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	f.BoolVar(&args.help, "h", false, "")
+	f.BoolVar(&args.help, "help", false, "")
+
+	f.StringVar(&args.addr, "addr", DefaultAddress, "")
+	f.StringVar(&args.addr, "a", DefaultAddress, "")
+
+	f.StringVar(&args.dir, "dir", "", "")
+	f.StringVar(&args.dir, "d", "", "")
+
+	f.StringVar(&args.username, "username", "", "")
+	f.StringVar(&args.username, "u", "", "")
+
+	f.StringVar(&args.password, "password", "", "")
+	f.StringVar(&args.password, "p", "", "")
+
+	if err := f.Parse(arguments); err != nil {
+		return nil, err
+	}
+	if !args.help && args.dir == "" {
+		return nil, errors.New("dir cannot be empty")
+	}
+	return args, nil
+}
+
+func (args *snapshotArguments) clientConfig() *config.Client {
+	c := config.NewClient()
+	c.Username = args.username
+	c.Password = args.password
+	return c
+}
+
+func processCommand(ctx context.Context, args *snapshotArguments, addr string, cmd *redis.StatusCmd) error {
+	opt := args.clientConfig().RedisOptions()
+	opt.Addr = addr
+	rc := redis.NewClient(opt)
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	if err := rc.Process(ctx, cmd); err != nil {
+		return err
+	}
+	return cmd.Err()
+}
+
+// forEachMember runs cmd on every member of the cluster.
+func forEachMember(ctx context.Context, args *snapshotArguments, cmd func() *redis.StatusCmd, done func(member string)) error {
+	c, err := olric.NewClusterClient([]string{args.addr}, olric.WithConfig(args.clientConfig()))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = c.Close(ctx)
+	}()
+
+	members, err := c.Members(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if err = processCommand(ctx, args, member.Name, cmd()); err != nil {
+			return fmt.Errorf("%s: %w", member.Name, err)
+		}
+		done(member.Name)
+	}
+	return nil
+}
+
+// snapshot asks every member of the cluster to write its primary partitions to dir.
+// dir is relative to the snapshot directory of the members.
+func snapshot(ctx context.Context, args *snapshotArguments) error {
+	err := forEachMember(ctx, args, func() *redis.StatusCmd {
+		return protocol.NewClusterSnapshot(args.dir).Command(ctx)
+	}, func(member string) {
+		_, _ = fmt.Fprintf(os.Stdout, "Snapshot has been taken on %s\n", member)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to take a snapshot on %w", err)
+	}
+	return nil
+}
+
+// restore asks every member of the cluster to read the snapshot files in dir and
+// import them to the cluster. Every member restores the files that it has written.
+func restore(ctx context.Context, args *snapshotArguments) error {
+	err := forEachMember(ctx, args, func() *redis.StatusCmd {
+		return protocol.NewClusterRestore(args.dir).Command(ctx)
+	}, func(member string) {
+		_, _ = fmt.Fprintf(os.Stdout, "Snapshot has been restored on %s\n", member)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore the snapshot on %w", err)
+	}
+	return nil
+}
+
+func snapshotUsage(name string) {
+	var msg = `Usage: olricd %s [options] ...
+
+Options:
+  -h, --help    Print this message and exit.
+  -a, --addr    Address of an olricd instance. Default is %s.
+  -d, --dir     Directory of the snapshot files, relative to dmaps.snapshotDir
+                of the olricd instances.
+  -u, --username  Username to authenticate, if the ACL is enabled.
+  -p, --password  Password to authenticate, if the ACL is enabled.
+`
+	_, err := fmt.Fprintf(os.Stdout, msg, name, DefaultAddress)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// runSnapshotCommand runs the snapshot and restore subcommands.
+func runSnapshotCommand(name string, arguments []string) {
+	args, err := parseSnapshotArguments(name, arguments)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "parsing error: %v\n", err)
+		snapshotUsage(name)
+		os.Exit(1)
+	}
+	if args.help {
+		snapshotUsage(name)
+		return
+	}
+
+	ctx := context.Background()
+	if name == "snapshot" {
+		err = snapshot(ctx, args)
+	} else {
+		err = restore(ctx, args)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
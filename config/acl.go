@@ -0,0 +1,84 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// User denotes a user of the RESP server and its permissions.
+type User struct {
+	// Name is the username. A client which sends AUTH with only a password is
+	// authenticated as the user named "default".
+	Name string
+
+	// Password of the user.
+	Password string
+
+	// Commands is a list of command patterns that the user is allowed to run,
+	// such as "dm.*", "cluster.*" or "*". Patterns are matched against the command
+	// names by path.Match. "pubsub" denotes all the Publish-Subscribe commands,
+	// including the shard channel commands.
+	Commands []string
+
+	// DMaps is a list of DMap name patterns that the user is allowed to access,
+	// such as "users.*". Patterns are matched by path.Match. The user can access
+	// all DMaps if it's empty.
+	DMaps []string
+}
+
+// ACL denotes the access control list of the RESP server. Authentication is
+// enabled if there is at least one user. Olric nodes call each other with the
+// credentials in Config.Client, so that user has to be allowed to run every
+// command.
+type ACL struct {
+	Users []*User
+}
+
+// Enabled returns true if authentication is required to run commands.
+func (a *ACL) Enabled() bool {
+	return len(a.Users) > 0
+}
+
+// Sanitize sets default values to empty configuration variables, if it's possible.
+func (a *ACL) Sanitize() error {
+	return nil
+}
+
+// Validate finds errors in the current configuration.
+func (a *ACL) Validate() error {
+	names := make(map[string]struct{})
+	for _, user := range a.Users {
+		if user.Name == "" {
+			return fmt.Errorf("username cannot be empty")
+		}
+		if _, ok := names[user.Name]; ok {
+			return fmt.Errorf("duplicate user: %s", user.Name)
+		}
+		names[user.Name] = struct{}{}
+
+		patterns := append(append([]string{}, user.Commands...), user.DMaps...)
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern: %s for user: %s: %w", pattern, user.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Interface guard
+var _ IConfig = (*ACL)(nil)
@@ -0,0 +1,49 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_ACL(t *testing.T) {
+	a := &ACL{}
+	require.NoError(t, a.Sanitize())
+	require.NoError(t, a.Validate())
+	require.False(t, a.Enabled())
+
+	a.Users = append(a.Users, &User{Name: "olric", Password: "secret", Commands: []string{"*"}})
+	require.NoError(t, a.Validate())
+	require.True(t, a.Enabled())
+}
+
+func TestConfig_ACL_Validate(t *testing.T) {
+	t.Run("Empty username", func(t *testing.T) {
+		a := &ACL{Users: []*User{{Password: "secret"}}}
+		require.Error(t, a.Validate())
+	})
+
+	t.Run("Duplicate user", func(t *testing.T) {
+		a := &ACL{Users: []*User{{Name: "olric"}, {Name: "olric"}}}
+		require.Error(t, a.Validate())
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		a := &ACL{Users: []*User{{Name: "olric", DMaps: []string{"users.["}}}}
+		require.Error(t, a.Validate())
+	})
+}
@@ -102,6 +102,11 @@ type Client struct {
 
 	// Limiter interface used to implemented circuit breaker or rate limiter.
 	Limiter redis.Limiter
+
+	// Username and Password are sent with the AUTH command if the server
+	// requires authentication. Username is optional.
+	Username string
+	Password string
 }
 
 // NewClient returns a new configuration object for clients.
@@ -198,6 +203,8 @@ func (c *Client) RedisOptions() *redis.Options {
 		ConnMaxIdleTime: c.IdleTimeout,
 		TLSConfig:       c.TLSConfig,
 		Limiter:         c.Limiter,
+		Username:        c.Username,
+		Password:        c.Password,
 	}
 }
 
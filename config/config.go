@@ -25,6 +25,7 @@ import (
 
 	"github.com/buraksezer/olric/hasher"
 	"github.com/hashicorp/memberlist"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // IConfig is an interface that has to be implemented by Config and its nested
@@ -49,6 +50,17 @@ const (
 	AsyncReplicationMode = 1
 )
 
+const (
+	// BroadcastPubSubRoutingMode forwards every published message to all
+	// members of the cluster. The default mode is BroadcastPubSubRoutingMode.
+	BroadcastPubSubRoutingMode = 0
+
+	// PartitionedPubSubRoutingMode hashes channels to partition owners. Members
+	// report their subscriber interest to the owners, and a published message is
+	// only delivered to the members that have subscribers for the channel.
+	PartitionedPubSubRoutingMode = 1
+)
+
 const (
 	LogLevelDebug = "DEBUG"
 	LogLevelWarn  = "WARN"
@@ -132,6 +144,10 @@ const (
 	// Olric project.
 	DefaultStorageEngine = "kvstore"
 
+	// DiskStorageEngine denotes the persistent storage engine implementation provided
+	// by Olric project. It requires dataDir in the engine configuration.
+	DiskStorageEngine = "diskstore"
+
 	// DefaultRoutingTablePushInterval is interval between routing table push events.
 	DefaultRoutingTablePushInterval = time.Minute
 
@@ -189,6 +205,25 @@ type Config struct {
 	// Golang client.
 	Client *Client
 
+	// ACL denotes the users of the RESP server and their permissions. Authentication
+	// is disabled by default.
+	ACL *ACL
+
+	// TLS denotes the TLS configuration of the RESP server. It's disabled by default.
+	// See Client.TLSConfig for the client side.
+	TLS *TLS
+
+	// Metrics denotes the configuration of the HTTP endpoint that exposes the
+	// metrics in Prometheus text format. It's only served by olricd and it's
+	// disabled by default.
+	Metrics *Metrics
+
+	// TracerProvider creates the spans of the commands and the internal
+	// operations, such as quorum reads, replication and fragment migration.
+	// Plug in an exporter by setting a TracerProvider of the OpenTelemetry SDK.
+	// Tracing is disabled if it's nil.
+	TracerProvider trace.TracerProvider
+
 	// KeepAlivePeriod denotes whether the operating system should send
 	// keep-alive messages on the connection.
 	KeepAlivePeriod time.Duration
@@ -239,6 +274,10 @@ type Config struct {
 	// Default value is SyncReplicationMode.
 	ReplicationMode int
 
+	// PubSubRoutingMode controls how published messages are routed in the
+	// cluster. Default value is BroadcastPubSubRoutingMode.
+	PubSubRoutingMode int
+
 	// LoadFactor is used by consistent hashing function. It determines the maximum
 	// load for a server in the cluster. Keep it small.
 	LoadFactor float64
@@ -289,6 +328,10 @@ type Config struct {
 	// interface. See pkg/service_discovery/service_discovery.go for details.
 	ServiceDiscovery map[string]interface{}
 
+	// EntryProcessors is a map of the entry processors that can be executed
+	// with DMap.Execute. See EntryProcessor for details.
+	EntryProcessors map[string]EntryProcessor
+
 	// Interface denotes a binding interface. It can be used instead of
 	// memberlist.Loader.BindAddr if the interface is known but not the address.
 	// If both are provided, then Olric verifies that the interface has the bind
@@ -345,6 +388,12 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	switch c.PubSubRoutingMode {
+	case BroadcastPubSubRoutingMode, PartitionedPubSubRoutingMode:
+	default:
+		return fmt.Errorf("invalid PubSubRoutingMode: %d", c.PubSubRoutingMode)
+	}
+
 	if c.MemberCountQuorum < MinimumMemberCountQuorum {
 		return fmt.Errorf("cannot specify MemberCountQuorum smaller than MinimumMemberCountQuorum")
 	}
@@ -374,6 +423,22 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := validateEntryProcessors(c.EntryProcessors); err != nil {
+		return err
+	}
+
+	if err := c.ACL.Validate(); err != nil {
+		return fmt.Errorf("failed to validate ACL configuration: %w", err)
+	}
+
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("failed to validate TLS configuration: %w", err)
+	}
+
+	if err := c.Metrics.Validate(); err != nil {
+		return fmt.Errorf("failed to validate metrics configuration: %w", err)
+	}
+
 	switch c.LogLevel {
 	case LogLevelDebug, LogLevelWarn, LogLevelInfo, LogLevelError:
 	default:
@@ -483,6 +548,18 @@ func (c *Config) Sanitize() error {
 		c.DMaps = &DMaps{}
 	}
 
+	if c.ACL == nil {
+		c.ACL = &ACL{}
+	}
+
+	if c.TLS == nil {
+		c.TLS = &TLS{}
+	}
+
+	if c.Metrics == nil {
+		c.Metrics = &Metrics{}
+	}
+
 	if err := c.Client.Sanitize(); err != nil {
 		return fmt.Errorf("failed to sanitize TCP client configuration: %w", err)
 	}
@@ -491,6 +568,18 @@ func (c *Config) Sanitize() error {
 		return fmt.Errorf("failed to sanitize DMap configuration: %w", err)
 	}
 
+	if err := c.ACL.Sanitize(); err != nil {
+		return fmt.Errorf("failed to sanitize ACL configuration: %w", err)
+	}
+
+	if err := c.TLS.Sanitize(); err != nil {
+		return fmt.Errorf("failed to sanitize TLS configuration: %w", err)
+	}
+
+	if err := c.Metrics.Sanitize(); err != nil {
+		return fmt.Errorf("failed to sanitize metrics configuration: %w", err)
+	}
+
 	return nil
 }
 
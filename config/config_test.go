@@ -37,6 +37,7 @@ var testConfig = `olricd:
   readQuorum: 1
   readRepair: false
   replicationMode: 0 # sync mode. for async, set 1
+  pubSubRoutingMode: 1 # partitioned mode
   memberCountQuorum: 1
   enableClusterEventsChannel: true
 
@@ -53,6 +54,8 @@ client:
   maxConnAge: 2h
   poolTimeout: 4s
   idleTimeout: 6m
+  username: "olric"
+  password: "secret"
 
 logging:
   verbosity: 6
@@ -93,6 +96,12 @@ dmaps:
   maxInuse: 2000000
   lruSamples: 20
   evictionPolicy: "LRU"
+  wal:
+    enabled: true
+    path: "/var/lib/olricd/dmaps.wal"
+    fsyncPolicy: "always"
+    fsyncInterval: "2s"
+  keyspaceNotifications: ["del", "expired"]
   custom:
     foobar:
       maxIdleDuration: "30s"
@@ -100,6 +109,21 @@ dmaps:
       maxKeys: 600000
       lruSamples: 60
       evictionPolicy: "NONE"
+      keyspaceNotifications: ["put", "del"]
+      indexes:
+        - name: "by-city"
+          field: "address.city"
+          format: "json"
+
+acl:
+  users:
+    - name: "olric"
+      password: "secret"
+      commands: ["*"]
+    - name: "reader"
+      password: "pass"
+      commands: ["dm.get", "dm.scan"]
+      dmaps: ["users.*"]
 
 serviceDiscovery:
   path: "/usr/lib/olric-consul-plugin.so"
@@ -149,6 +173,7 @@ func TestConfig(t *testing.T) {
 	c.ReadQuorum = 1
 	c.ReadRepair = false
 	c.ReplicationMode = SyncReplicationMode
+	c.PubSubRoutingMode = PartitionedPubSubRoutingMode
 	c.MemberCountQuorum = 1
 	c.EnableClusterEventsChannel = true
 
@@ -164,6 +189,8 @@ func TestConfig(t *testing.T) {
 	c.Client.PoolSize = 10
 	c.Client.MinIdleConns = 5
 	c.Client.MaxConnAge = 2 * time.Hour
+	c.Client.Username = "olric"
+	c.Client.Password = "secret"
 	c.Client.PoolTimeout = 4 * time.Second
 	c.Client.IdleTimeout = 6 * time.Minute
 
@@ -196,13 +223,36 @@ func TestConfig(t *testing.T) {
 	c.DMaps.EvictionPolicy = LRUEviction
 	c.DMaps.Engine.Name = DefaultStorageEngine
 	c.DMaps.Engine.Config = map[string]interface{}{"tableSize": 202134}
+	c.DMaps.WAL = &WAL{
+		Enabled:       true,
+		Path:          "/var/lib/olricd/dmaps.wal",
+		FsyncPolicy:   FsyncAlways,
+		FsyncInterval: 2 * time.Second,
+	}
+	c.DMaps.KeyspaceNotifications = []KeyspaceEvent{KeyspaceEventDel, KeyspaceEventExpired}
 
 	c.DMaps.Custom = map[string]DMap{"foobar": {
-		MaxIdleDuration: 30 * time.Second,
-		TTLDuration:     500 * time.Second,
-		MaxKeys:         600000,
-		LRUSamples:      60,
-		EvictionPolicy:  "NONE",
+		MaxIdleDuration:       30 * time.Second,
+		TTLDuration:           500 * time.Second,
+		MaxKeys:               600000,
+		LRUSamples:            60,
+		EvictionPolicy:        "NONE",
+		KeyspaceNotifications: []KeyspaceEvent{KeyspaceEventPut, KeyspaceEventDel},
+		Indexes:               []Index{{Name: "by-city", Field: "address.city", Format: IndexFormatJSON}},
+	}}
+
+	c.ACL = &ACL{Users: []*User{
+		{
+			Name:     "olric",
+			Password: "secret",
+			Commands: []string{"*"},
+		},
+		{
+			Name:     "reader",
+			Password: "pass",
+			Commands: []string{"dm.get", "dm.scan"},
+			DMaps:    []string{"users.*"},
+		},
 	}}
 
 	c.ServiceDiscovery = make(map[string]interface{})
@@ -233,3 +283,24 @@ func TestConfig_Initialize(t *testing.T) {
 	require.NoError(t, c.Sanitize())
 	require.NoError(t, c.Validate())
 }
+
+func TestConfig_Validate_PubSubRoutingMode(t *testing.T) {
+	c := &Config{}
+	require.NoError(t, c.Sanitize())
+	c.PubSubRoutingMode = 2
+	require.Error(t, c.Validate())
+}
+
+func TestConfig_Validate_EntryProcessors(t *testing.T) {
+	c := &Config{}
+	require.NoError(t, c.Sanitize())
+	c.EntryProcessors = map[string]EntryProcessor{
+		"touch": func(entry MutableEntry, args []byte) ([]byte, error) {
+			return nil, nil
+		},
+	}
+	require.NoError(t, c.Validate())
+
+	c.EntryProcessors["nil"] = nil
+	require.Error(t, c.Validate())
+}
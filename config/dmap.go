@@ -64,6 +64,18 @@ type DMap struct {
 	// EvictionPolicy determines the eviction policy in use. It's NONE by default.
 	// Set as LRU to enable LRU eviction policy.
 	EvictionPolicy EvictionPolicy
+
+	// KeyspaceNotifications denotes the classes of keyspace events to publish.
+	// It's empty by default, no event is published. See KeyspaceEvent.
+	KeyspaceNotifications []KeyspaceEvent
+
+	// Indexes declares the secondary indexes on the values of the DMap. See
+	// Index.
+	Indexes []Index
+
+	// MapStore is the external data store of the DMap. It's nil by default.
+	// See MapStore.
+	MapStore *MapStore
 }
 
 // Sanitize sets default values to empty configuration variables, if it's possible.
@@ -85,6 +97,14 @@ func (dm *DMap) Sanitize() error {
 		dm.Engine = NewEngine()
 	}
 
+	sanitizeIndexes(dm.Indexes)
+
+	if dm.MapStore != nil {
+		if err := dm.MapStore.Sanitize(); err != nil {
+			return fmt.Errorf("failed to sanitize mapstore configuration: %w", err)
+		}
+	}
+
 	if err := dm.Engine.Sanitize(); err != nil {
 		return fmt.Errorf("failed to sanitize storage engine configuration: %w", err)
 	}
@@ -98,6 +118,20 @@ func (dm *DMap) Validate() error {
 		return fmt.Errorf("failed to validate storage engine configuration: %w", err)
 	}
 
+	if err := validateKeyspaceEvents(dm.KeyspaceNotifications); err != nil {
+		return err
+	}
+
+	if err := validateIndexes(dm.Indexes); err != nil {
+		return err
+	}
+
+	if dm.MapStore != nil {
+		if err := dm.MapStore.Validate(); err != nil {
+			return fmt.Errorf("failed to validate mapstore configuration: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -34,3 +34,56 @@ func TestConfig_DMap(t *testing.T) {
 	require.Equal(t, EvictionPolicy("NONE"), d.EvictionPolicy)
 	require.NotNil(t, d.Engine)
 }
+
+func TestConfig_DMap_KeyspaceNotifications(t *testing.T) {
+	d := &DMap{
+		KeyspaceNotifications: []KeyspaceEvent{KeyspaceEventPut, KeyspaceEventEvicted},
+	}
+	require.NoError(t, d.Sanitize())
+	require.NoError(t, d.Validate())
+
+	d.KeyspaceNotifications = append(d.KeyspaceNotifications, "foobar")
+	require.Error(t, d.Validate())
+}
+
+func TestConfig_DMap_Indexes(t *testing.T) {
+	d := &DMap{
+		Indexes: []Index{{Name: "by-city", Field: "address.city"}},
+	}
+	require.NoError(t, d.Sanitize())
+	require.NoError(t, d.Validate())
+	require.Equal(t, IndexFormatJSON, d.Indexes[0].Format)
+
+	d.Indexes = append(d.Indexes, Index{Name: "by-city", Field: "name"})
+	require.Error(t, d.Validate())
+
+	d.Indexes = []Index{{Name: "by-name", Field: "name", Format: "xml"}}
+	require.Error(t, d.Validate())
+
+	d.Indexes = []Index{{Name: "by-name"}}
+	require.Error(t, d.Validate())
+}
+
+type testMapStore struct{}
+
+func (testMapStore) Load(string) ([]byte, error)                 { return nil, nil }
+func (testMapStore) LoadAll([]string) (map[string][]byte, error) { return nil, nil }
+func (testMapStore) Store(string, []byte) error                  { return nil }
+func (testMapStore) Delete(string) error                         { return nil }
+
+func TestConfig_DMap_MapStore(t *testing.T) {
+	d := &DMap{
+		MapStore: &MapStore{Implementation: testMapStore{}},
+	}
+	require.NoError(t, d.Sanitize())
+	require.NoError(t, d.Validate())
+	require.Equal(t, WriteThrough, d.MapStore.WriteMode)
+	require.Equal(t, DefaultWriteBehindDelay, d.MapStore.WriteDelay)
+
+	d.MapStore.WriteMode = "write-around"
+	require.Error(t, d.Validate())
+
+	d.MapStore = &MapStore{}
+	require.NoError(t, d.Sanitize())
+	require.Error(t, d.Validate())
+}
@@ -74,6 +74,21 @@ type DMaps struct {
 	// different values per DMap.
 	TriggerCompactionInterval time.Duration
 
+	// KeyspaceNotifications denotes the classes of keyspace events to publish.
+	// It's empty by default, no event is published. See KeyspaceEvent.
+	KeyspaceNotifications []KeyspaceEvent
+
+	// WAL contains configuration for the write-ahead log. It's disabled by default.
+	// This is a global configuration variable. So you cannot set different values per DMap.
+	WAL *WAL
+
+	// SnapshotDir is the directory of the snapshots that are taken and restored
+	// with the cluster.snapshot and cluster.restore commands. The directory
+	// argument of the commands is resolved relative to it. The commands are
+	// disabled if it's empty. This is a global configuration variable. So you
+	// cannot set different values per DMap.
+	SnapshotDir string
+
 	// Custom is useful to set custom cache config per DMap instance.
 	Custom map[string]DMap
 }
@@ -84,6 +99,13 @@ func (dm *DMaps) Sanitize() error {
 		dm.Engine = NewEngine()
 	}
 
+	if dm.WAL == nil {
+		dm.WAL = NewWAL()
+	}
+	if err := dm.WAL.Sanitize(); err != nil {
+		return err
+	}
+
 	if dm.Custom == nil {
 		dm.Custom = make(map[string]DMap)
 	}
@@ -133,6 +155,25 @@ func (dm *DMaps) Validate() error {
 	if err := dm.Engine.Validate(); err != nil {
 		return fmt.Errorf("failed to validate storage engine configuration: %w", err)
 	}
+	if err := dm.WAL.Validate(); err != nil {
+		return fmt.Errorf("failed to validate write-ahead log configuration: %w", err)
+	}
+	if err := validateKeyspaceEvents(dm.KeyspaceNotifications); err != nil {
+		return err
+	}
+	for name, d := range dm.Custom {
+		if err := validateKeyspaceEvents(d.KeyspaceNotifications); err != nil {
+			return fmt.Errorf("failed to validate DMap: %s: %w", name, err)
+		}
+		if err := validateIndexes(d.Indexes); err != nil {
+			return fmt.Errorf("failed to validate DMap: %s: %w", name, err)
+		}
+		if d.MapStore != nil {
+			if err := d.MapStore.Validate(); err != nil {
+				return fmt.Errorf("failed to validate DMap: %s: %w", name, err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -16,6 +16,8 @@ package config
 
 import (
 	"fmt"
+
+	"github.com/buraksezer/olric/internal/diskstore"
 	"github.com/buraksezer/olric/internal/kvstore"
 	"github.com/buraksezer/olric/pkg/storage"
 )
@@ -72,6 +74,19 @@ func (s *Engine) Sanitize() error {
 				return err
 			}
 			s.Implementation = kv
+		case DiskStorageEngine:
+			cfg := diskstore.DefaultConfig().ToMap()
+			for key, value := range cfg {
+				_, ok := s.Config[key]
+				if !ok {
+					s.Config[key] = value
+				}
+			}
+			ds, err := diskstore.New(storage.NewConfig(s.Config))
+			if err != nil {
+				return err
+			}
+			s.Implementation = ds
 		default:
 			return fmt.Errorf("unknown storage engine: %s", s.Name)
 		}
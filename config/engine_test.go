@@ -31,3 +31,19 @@ func TestEngine_Dont_Overwrite_TableSize(t *testing.T) {
 	require.NoError(t, e.Validate())
 	require.Equal(t, 1235, e.Config["tableSize"])
 }
+
+func TestEngine_DiskStorageEngine(t *testing.T) {
+	e := NewEngine()
+	e.Name = DiskStorageEngine
+	require.Error(t, e.Sanitize())
+
+	e = NewEngine()
+	e.Name = DiskStorageEngine
+	e.Config = map[string]interface{}{
+		"dataDir": "/tmp/olric",
+	}
+	require.NoError(t, e.Sanitize())
+	require.NoError(t, e.Validate())
+	require.Equal(t, DiskStorageEngine, e.Implementation.Name())
+	require.Equal(t, "/tmp/olric", e.Config["dataDir"])
+}
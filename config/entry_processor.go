@@ -0,0 +1,67 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// MutableEntry is an entry of a DMap that is processed by an EntryProcessor.
+// The changes are applied after the processor returns without an error.
+type MutableEntry interface {
+	// Key returns the key of the entry.
+	Key() string
+
+	// Exists returns true if the key exists.
+	Exists() bool
+
+	// Value returns the value of the entry. It returns nil if the key doesn't
+	// exist. Values are stored in the encoded form of Put, []byte and string
+	// values are stored as is.
+	Value() []byte
+
+	// SetValue sets the value of the entry. It creates the key if it doesn't
+	// exist.
+	SetValue(value []byte)
+
+	// TTL returns the remaining time to live. It's zero if the key doesn't
+	// expire.
+	TTL() time.Duration
+
+	// SetTTL sets the time to live of the entry. Zero removes the expiration.
+	SetTTL(ttl time.Duration)
+
+	// Delete deletes the entry.
+	Delete()
+}
+
+// EntryProcessor processes an entry on the partition owner of the key while
+// the partition is locked, so the read and the write are atomic. args are
+// sent by the caller, and the returned value is sent back to it. An entry
+// processor has to be registered with the same name on every member.
+type EntryProcessor func(entry MutableEntry, args []byte) ([]byte, error)
+
+func validateEntryProcessors(processors map[string]EntryProcessor) error {
+	for name, processor := range processors {
+		if name == "" {
+			return fmt.Errorf("entry processor name cannot be empty")
+		}
+		if processor == nil {
+			return fmt.Errorf("entry processor cannot be nil: %s", name)
+		}
+	}
+	return nil
+}
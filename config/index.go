@@ -0,0 +1,75 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "fmt"
+
+// IndexFormat denotes the encoding of the values of an indexed DMap.
+type IndexFormat string
+
+const (
+	// IndexFormatJSON is used for the JSON encoded values.
+	IndexFormatJSON IndexFormat = "json"
+
+	// IndexFormatMsgpack is used for the msgpack encoded values.
+	IndexFormatMsgpack IndexFormat = "msgpack"
+)
+
+// Index declares a secondary index on the values of a DMap. The partition
+// owners extract the field from the values and maintain the index in memory.
+// Values without the field, or with a field that is not a string, number or
+// boolean, are not indexed.
+type Index struct {
+	// Name is used to query the index.
+	Name string
+
+	// Field is the path of the indexed field in the values. Nested fields are
+	// separated by dots, e.g. "address.city".
+	Field string
+
+	// Format is the encoding of the values. It's json by default.
+	Format IndexFormat
+}
+
+func sanitizeIndexes(indexes []Index) {
+	for i := range indexes {
+		if indexes[i].Format == "" {
+			indexes[i].Format = IndexFormatJSON
+		}
+	}
+}
+
+func validateIndexes(indexes []Index) error {
+	names := make(map[string]struct{})
+	for _, index := range indexes {
+		if index.Name == "" {
+			return fmt.Errorf("index name cannot be empty")
+		}
+		if _, ok := names[index.Name]; ok {
+			return fmt.Errorf("duplicate index: %s", index.Name)
+		}
+		names[index.Name] = struct{}{}
+
+		if index.Field == "" {
+			return fmt.Errorf("field of index %s cannot be empty", index.Name)
+		}
+		switch index.Format {
+		case IndexFormatJSON, IndexFormatMsgpack:
+		default:
+			return fmt.Errorf("invalid format of index %s: %s", index.Name, index.Format)
+		}
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ type olricd struct {
 	BindPort                   int     `yaml:"bindPort"`
 	Interface                  string  `yaml:"interface"`
 	ReplicationMode            int     `yaml:"replicationMode"`
+	PubSubRoutingMode          int     `yaml:"pubSubRoutingMode"`
 	PartitionCount             uint64  `yaml:"partitionCount"`
 	LoadFactor                 float64 `yaml:"loadFactor"`
 	KeepAlivePeriod            string  `yaml:"keepAlivePeriod"`
@@ -39,18 +40,42 @@ type olricd struct {
 }
 
 type client struct {
-	DialTimeout     string `yaml:"dialTimeout"`
-	ReadTimeout     string `yaml:"readTimeout"`
-	WriteTimeout    string `yaml:"writeTimeout"`
-	MaxRetries      int    `yaml:"maxRetries"`
-	MinRetryBackoff string `yaml:"minRetryBackoff"`
-	MaxRetryBackoff string `yaml:"maxRetryBackoff"`
-	PoolFIFO        bool   `yaml:"poolFIFO"`
-	PoolSize        int    `yaml:"poolSize"`
-	MinIdleConns    int    `yaml:"minIdleConns"`
-	MaxConnAge      string `yaml:"maxConnAge"`
-	PoolTimeout     string `yaml:"poolTimeout"`
-	IdleTimeout     string `yaml:"idleTimeout"`
+	DialTimeout     string     `yaml:"dialTimeout"`
+	ReadTimeout     string     `yaml:"readTimeout"`
+	WriteTimeout    string     `yaml:"writeTimeout"`
+	MaxRetries      int        `yaml:"maxRetries"`
+	MinRetryBackoff string     `yaml:"minRetryBackoff"`
+	MaxRetryBackoff string     `yaml:"maxRetryBackoff"`
+	PoolFIFO        bool       `yaml:"poolFIFO"`
+	PoolSize        int        `yaml:"poolSize"`
+	MinIdleConns    int        `yaml:"minIdleConns"`
+	MaxConnAge      string     `yaml:"maxConnAge"`
+	PoolTimeout     string     `yaml:"poolTimeout"`
+	IdleTimeout     string     `yaml:"idleTimeout"`
+	Username        string     `yaml:"username"`
+	Password        string     `yaml:"password"`
+	TLS             *clientTLS `yaml:"tls"`
+}
+
+type clientTLS struct {
+	CertFile           string `yaml:"certFile"`
+	KeyFile            string `yaml:"keyFile"`
+	CAFile             string `yaml:"caFile"`
+	ServerName         string `yaml:"serverName"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
+}
+
+type serverTLS struct {
+	CertFile  string `yaml:"certFile"`
+	KeyFile   string `yaml:"keyFile"`
+	CAFile    string `yaml:"caFile"`
+	MutualTLS bool   `yaml:"mutualTLS"`
+}
+
+type metrics struct {
+	BindAddr string `yaml:"bindAddr"`
+	BindPort int    `yaml:"bindPort"`
+	Path     string `yaml:"path"`
 }
 
 // logging contains configuration variables of logging section of config file.
@@ -96,14 +121,30 @@ type engine struct {
 	Config map[string]interface{} `yaml:"config"`
 }
 
+type wal struct {
+	Enabled           bool   `yaml:"enabled"`
+	Path              string `yaml:"path"`
+	FsyncPolicy       string `yaml:"fsyncPolicy"`
+	FsyncInterval     string `yaml:"fsyncInterval"`
+	CompactionMinSize int64  `yaml:"compactionMinSize"`
+}
+
+type index struct {
+	Name   string `yaml:"name"`
+	Field  string `yaml:"field"`
+	Format string `yaml:"format"`
+}
+
 type dmap struct {
-	Engine          *engine `yaml:"engine"`
-	MaxIdleDuration string  `yaml:"maxIdleDuration"`
-	TTLDuration     string  `yaml:"ttlDuration"`
-	MaxKeys         int     `yaml:"maxKeys"`
-	MaxInuse        int     `yaml:"maxInuse"`
-	LRUSamples      int     `yaml:"lruSamples"`
-	EvictionPolicy  string  `yaml:"evictionPolicy"`
+	Engine                *engine  `yaml:"engine"`
+	MaxIdleDuration       string   `yaml:"maxIdleDuration"`
+	TTLDuration           string   `yaml:"ttlDuration"`
+	MaxKeys               int      `yaml:"maxKeys"`
+	MaxInuse              int      `yaml:"maxInuse"`
+	LRUSamples            int      `yaml:"lruSamples"`
+	EvictionPolicy        string   `yaml:"evictionPolicy"`
+	KeyspaceNotifications []string `yaml:"keyspaceNotifications"`
+	Indexes               []index  `yaml:"indexes"`
 }
 
 type dmaps struct {
@@ -117,9 +158,23 @@ type dmaps struct {
 	EvictionPolicy              string          `yaml:"evictionPolicy"`
 	CheckEmptyFragmentsInterval string          `yaml:"checkEmptyFragmentsInterval"`
 	TriggerCompactionInterval   string          `yaml:"triggerCompactionInterval"`
+	WAL                         *wal            `yaml:"wal"`
+	SnapshotDir                 string          `yaml:"snapshotDir"`
+	KeyspaceNotifications       []string        `yaml:"keyspaceNotifications"`
 	Custom                      map[string]dmap `yaml:"custom"`
 }
 
+type user struct {
+	Name     string   `yaml:"name"`
+	Password string   `yaml:"password"`
+	Commands []string `yaml:"commands"`
+	DMaps    []string `yaml:"dmaps"`
+}
+
+type acl struct {
+	Users []user `yaml:"users"`
+}
+
 type serviceDiscovery map[string]interface{}
 
 // Loader is the main configuration struct
@@ -129,6 +184,9 @@ type Loader struct {
 	Olricd           olricd           `yaml:"olricd"`
 	Client           client           `yaml:"client"`
 	DMaps            dmaps            `yaml:"dmaps"`
+	ACL              acl              `yaml:"acl"`
+	TLS              serverTLS        `yaml:"tls"`
+	Metrics          metrics          `yaml:"metrics"`
 	ServiceDiscovery serviceDiscovery `yaml:"serviceDiscovery"`
 }
 
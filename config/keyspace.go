@@ -0,0 +1,52 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "fmt"
+
+// KeyspaceEvent denotes a class of keyspace notifications. Keyspace notifications
+// are published on __keyspace@<dmap>__:<key> channels, the message is the event name.
+// Like Redis, they are fire and forget. A member publishes its events in order, and
+// drops them if they are produced faster than they can be published.
+type KeyspaceEvent string
+
+const (
+	// KeyspaceEventPut is published when a key is set.
+	KeyspaceEventPut KeyspaceEvent = "put"
+
+	// KeyspaceEventExpire is published when the TTL of a key is updated.
+	KeyspaceEventExpire KeyspaceEvent = "expire"
+
+	// KeyspaceEventDel is published when a key is deleted.
+	KeyspaceEventDel KeyspaceEvent = "del"
+
+	// KeyspaceEventExpired is published when an expired or idle key is removed
+	// by the background eviction workers.
+	KeyspaceEventExpired KeyspaceEvent = "expired"
+
+	// KeyspaceEventEvicted is published when a key is evicted by the LRU eviction policy.
+	KeyspaceEventEvicted KeyspaceEvent = "evicted"
+)
+
+func validateKeyspaceEvents(events []KeyspaceEvent) error {
+	for _, event := range events {
+		switch event {
+		case KeyspaceEventPut, KeyspaceEventExpire, KeyspaceEventDel, KeyspaceEventExpired, KeyspaceEventEvicted:
+		default:
+			return fmt.Errorf("invalid keyspace event: %s", event)
+		}
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/buraksezer/olric/config/internal/loader"
 	"github.com/buraksezer/olric/hasher"
+	"github.com/buraksezer/olric/internal/tlsutil"
 	"github.com/hashicorp/memberlist"
 	"github.com/pkg/errors"
 )
@@ -98,6 +99,8 @@ func loadDMapConfig(c *loader.Loader) (*DMaps, error) {
 	res.MaxInuse = c.DMaps.MaxInuse
 	res.EvictionPolicy = EvictionPolicy(c.DMaps.EvictionPolicy)
 	res.LRUSamples = c.DMaps.LRUSamples
+	res.KeyspaceNotifications = loadKeyspaceEvents(c.DMaps.KeyspaceNotifications)
+	res.SnapshotDir = c.DMaps.SnapshotDir
 
 	if c.DMaps.Engine != nil {
 		e := NewEngine()
@@ -106,14 +109,42 @@ func loadDMapConfig(c *loader.Loader) (*DMaps, error) {
 		res.Engine = e
 	}
 
+	if c.DMaps.WAL != nil {
+		w := NewWAL()
+		w.Enabled = c.DMaps.WAL.Enabled
+		w.Path = c.DMaps.WAL.Path
+		if c.DMaps.WAL.FsyncPolicy != "" {
+			w.FsyncPolicy = FsyncPolicy(c.DMaps.WAL.FsyncPolicy)
+		}
+		if c.DMaps.WAL.FsyncInterval != "" {
+			fsyncInterval, err := time.ParseDuration(c.DMaps.WAL.FsyncInterval)
+			if err != nil {
+				return nil, errors.WithMessage(err, "failed to parse dmaps.wal.fsyncInterval")
+			}
+			w.FsyncInterval = fsyncInterval
+		}
+		if c.DMaps.WAL.CompactionMinSize != 0 {
+			w.CompactionMinSize = c.DMaps.WAL.CompactionMinSize
+		}
+		res.WAL = w
+	}
+
 	if c.DMaps.Custom != nil {
 		res.Custom = make(map[string]DMap)
 		for name, dc := range c.DMaps.Custom {
 			cc := DMap{
-				MaxInuse:       dc.MaxInuse,
-				MaxKeys:        dc.MaxKeys,
-				EvictionPolicy: EvictionPolicy(dc.EvictionPolicy),
-				LRUSamples:     dc.LRUSamples,
+				MaxInuse:              dc.MaxInuse,
+				MaxKeys:               dc.MaxKeys,
+				EvictionPolicy:        EvictionPolicy(dc.EvictionPolicy),
+				LRUSamples:            dc.LRUSamples,
+				KeyspaceNotifications: loadKeyspaceEvents(dc.KeyspaceNotifications),
+			}
+			for _, idx := range dc.Indexes {
+				cc.Indexes = append(cc.Indexes, Index{
+					Name:   idx.Name,
+					Field:  idx.Field,
+					Format: IndexFormat(idx.Format),
+				})
 			}
 			if dc.Engine != nil {
 				e := NewEngine()
@@ -141,6 +172,27 @@ func loadDMapConfig(c *loader.Loader) (*DMaps, error) {
 	return res, nil
 }
 
+func loadKeyspaceEvents(events []string) []KeyspaceEvent {
+	var res []KeyspaceEvent
+	for _, event := range events {
+		res = append(res, KeyspaceEvent(event))
+	}
+	return res
+}
+
+func loadACLConfig(c *loader.Loader) *ACL {
+	res := &ACL{}
+	for _, u := range c.ACL.Users {
+		res.Users = append(res.Users, &User{
+			Name:     u.Name,
+			Password: u.Password,
+			Commands: u.Commands,
+			DMaps:    u.DMaps,
+		})
+	}
+	return res
+}
+
 // loadMemberlistConfig creates a new *memberlist.Config by parsing olric.yaml
 func loadMemberlistConfig(c *loader.Loader, mc *memberlist.Config) (*memberlist.Config, error) {
 	var err error
@@ -367,6 +419,15 @@ func Load(filename string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if c.Client.TLS != nil {
+		tlsConfig, err := tlsutil.ClientConfig(c.Client.TLS.CertFile, c.Client.TLS.KeyFile,
+			c.Client.TLS.CAFile, c.Client.TLS.ServerName)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to load client.tls")
+		}
+		tlsConfig.InsecureSkipVerify = c.Client.TLS.InsecureSkipVerify
+		clientConfig.TLSConfig = tlsConfig
+	}
 
 	dmapConfig, err := loadDMapConfig(c)
 	if err != nil {
@@ -393,6 +454,7 @@ func Load(filename string) (*Config, error) {
 		WriteQuorum:                c.Olricd.WriteQuorum,
 		ReadQuorum:                 c.Olricd.ReadQuorum,
 		ReplicationMode:            c.Olricd.ReplicationMode,
+		PubSubRoutingMode:          c.Olricd.PubSubRoutingMode,
 		ReadRepair:                 c.Olricd.ReadRepair,
 		LoadFactor:                 c.Olricd.LoadFactor,
 		MemberCountQuorum:          c.Olricd.MemberCountQuorum,
@@ -405,6 +467,18 @@ func Load(filename string) (*Config, error) {
 		BootstrapTimeout:           bootstrapTimeout,
 		LeaveTimeout:               leaveTimeout,
 		DMaps:                      dmapConfig,
+		ACL:                        loadACLConfig(c),
+		TLS: &TLS{
+			CertFile:  c.TLS.CertFile,
+			KeyFile:   c.TLS.KeyFile,
+			CAFile:    c.TLS.CAFile,
+			MutualTLS: c.TLS.MutualTLS,
+		},
+		Metrics: &Metrics{
+			BindAddr: c.Metrics.BindAddr,
+			BindPort: c.Metrics.BindPort,
+			Path:     c.Metrics.Path,
+		},
 	}
 
 	if err := cfg.Sanitize(); err != nil {
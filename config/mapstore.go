@@ -0,0 +1,83 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/buraksezer/olric/pkg/mapstore"
+)
+
+// MapStoreWriteMode denotes how the writes are propagated to a MapStore.
+type MapStoreWriteMode string
+
+const (
+	// WriteThrough writes the changes to the MapStore synchronously, after
+	// they are applied to the DMap. A write returns the error of the MapStore
+	// if it fails, the change is kept in the DMap and it should be retried.
+	// The typed values, like hashes and lists, are not written to the MapStore.
+	WriteThrough MapStoreWriteMode = "write-through"
+
+	// WriteBehind collects the changes and writes them to the MapStore in
+	// batches. Only the last change of a key in a batch is written. The
+	// pending changes are written when the DMap is destroyed.
+	WriteBehind MapStoreWriteMode = "write-behind"
+)
+
+// DefaultWriteBehindDelay is the default delay between two write-behind
+// batches.
+const DefaultWriteBehindDelay = time.Second
+
+// MapStore configures the external data store of a DMap. The partition owners
+// load the missing keys from the store on Get and MGet, and write the changes
+// of the keys to it.
+type MapStore struct {
+	// Implementation is the MapStore implementation. It's required.
+	Implementation mapstore.MapStore
+
+	// WriteMode is WriteThrough by default.
+	WriteMode MapStoreWriteMode
+
+	// WriteDelay is the delay between two write-behind batches. It's
+	// DefaultWriteBehindDelay by default.
+	WriteDelay time.Duration
+}
+
+// Sanitize sets default values to empty configuration variables, if it's possible.
+func (m *MapStore) Sanitize() error {
+	if m.WriteMode == "" {
+		m.WriteMode = WriteThrough
+	}
+	if m.WriteDelay <= 0 {
+		m.WriteDelay = DefaultWriteBehindDelay
+	}
+	return nil
+}
+
+// Validate finds errors in the current configuration.
+func (m *MapStore) Validate() error {
+	if m.Implementation == nil {
+		return fmt.Errorf("mapstore implementation cannot be nil")
+	}
+	switch m.WriteMode {
+	case WriteThrough, WriteBehind:
+	default:
+		return fmt.Errorf("invalid mapstore write mode: %s", m.WriteMode)
+	}
+	return nil
+}
+
+var _ IConfig = (*MapStore)(nil)
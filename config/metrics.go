@@ -0,0 +1,64 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMetricsPath is the default HTTP path of the metrics endpoint.
+const DefaultMetricsPath = "/metrics"
+
+// Metrics denotes the configuration of the HTTP endpoint that exposes the
+// metrics in Prometheus text format. It's disabled if BindPort is zero.
+type Metrics struct {
+	// BindAddr denotes the address that the metrics endpoint will bind to.
+	// It listens on all interfaces if it's empty.
+	BindAddr string
+
+	// BindPort denotes the port that the metrics endpoint will bind to.
+	BindPort int
+
+	// Path is the HTTP path of the endpoint. Default is /metrics.
+	Path string
+}
+
+// Enabled returns true if the metrics endpoint is enabled.
+func (m *Metrics) Enabled() bool {
+	return m.BindPort != 0
+}
+
+// Sanitize sets default values to empty configuration variables, if it's possible.
+func (m *Metrics) Sanitize() error {
+	if m.Path == "" {
+		m.Path = DefaultMetricsPath
+	}
+	return nil
+}
+
+// Validate finds errors in the current configuration.
+func (m *Metrics) Validate() error {
+	if m.BindPort < 0 || m.BindPort > 65535 {
+		return fmt.Errorf("invalid BindPort: %d", m.BindPort)
+	}
+	if !strings.HasPrefix(m.Path, "/") {
+		return fmt.Errorf("invalid Path: %s, it must start with '/'", m.Path)
+	}
+	return nil
+}
+
+// Interface guard
+var _ IConfig = (*Metrics)(nil)
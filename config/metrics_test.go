@@ -0,0 +1,47 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Metrics(t *testing.T) {
+	c := &Metrics{}
+	require.NoError(t, c.Sanitize())
+	require.NoError(t, c.Validate())
+	require.False(t, c.Enabled())
+	require.Equal(t, DefaultMetricsPath, c.Path)
+}
+
+func TestConfig_Metrics_Validate(t *testing.T) {
+	t.Run("Invalid BindPort", func(t *testing.T) {
+		c := &Metrics{BindPort: 70000, Path: DefaultMetricsPath}
+		require.Error(t, c.Validate())
+	})
+
+	t.Run("Invalid Path", func(t *testing.T) {
+		c := &Metrics{BindPort: 9090, Path: "metrics"}
+		require.Error(t, c.Validate())
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		c := &Metrics{BindPort: 9090, Path: DefaultMetricsPath}
+		require.NoError(t, c.Validate())
+		require.True(t, c.Enabled())
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/buraksezer/olric/internal/tlsutil"
+)
+
+// TLS denotes the TLS configuration of the RESP server. TLS is enabled if
+// CertFile is set. The certificate and the key are reloaded from disk when
+// they are modified, so they can be rotated without restarting the server.
+type TLS struct {
+	// CertFile is the path of the PEM encoded server certificate.
+	CertFile string
+
+	// KeyFile is the path of the PEM encoded private key of the server certificate.
+	KeyFile string
+
+	// CAFile is the path of the PEM encoded CA certificates to verify the client
+	// certificates. It's required if MutualTLS is true.
+	CAFile string
+
+	// MutualTLS requires a verified client certificate to run the node-to-node
+	// commands, such as internal.node.updaterouting and dm.putentry. Set TLSConfig of Client
+	// with a certificate, so that the cluster members can call each other.
+	MutualTLS bool
+}
+
+// Enabled returns true if the server accepts TLS connections.
+func (t *TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// ServerConfig loads the certificates and returns a configuration for the server.
+func (t *TLS) ServerConfig() (*tls.Config, error) {
+	return tlsutil.ServerConfig(t.CertFile, t.KeyFile, t.CAFile)
+}
+
+// Sanitize sets default values to empty configuration variables, if it's possible.
+func (t *TLS) Sanitize() error {
+	return nil
+}
+
+// Validate finds errors in the current configuration.
+func (t *TLS) Validate() error {
+	if t.CertFile != "" && t.KeyFile == "" {
+		return fmt.Errorf("KeyFile cannot be empty")
+	}
+	if t.KeyFile != "" && t.CertFile == "" {
+		return fmt.Errorf("CertFile cannot be empty")
+	}
+	if t.MutualTLS {
+		if !t.Enabled() {
+			return fmt.Errorf("MutualTLS requires CertFile and KeyFile")
+		}
+		if t.CAFile == "" {
+			return fmt.Errorf("MutualTLS requires CAFile")
+		}
+	}
+	return nil
+}
+
+// Interface guard
+var _ IConfig = (*TLS)(nil)
@@ -0,0 +1,46 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_TLS(t *testing.T) {
+	c := &TLS{}
+	require.NoError(t, c.Sanitize())
+	require.NoError(t, c.Validate())
+	require.False(t, c.Enabled())
+}
+
+func TestConfig_TLS_Validate(t *testing.T) {
+	t.Run("Empty KeyFile", func(t *testing.T) {
+		c := &TLS{CertFile: "server.pem"}
+		require.Error(t, c.Validate())
+	})
+
+	t.Run("MutualTLS without CAFile", func(t *testing.T) {
+		c := &TLS{CertFile: "server.pem", KeyFile: "server-key.pem", MutualTLS: true}
+		require.Error(t, c.Validate())
+	})
+
+	t.Run("MutualTLS", func(t *testing.T) {
+		c := &TLS{CertFile: "server.pem", KeyFile: "server-key.pem", CAFile: "ca.pem", MutualTLS: true}
+		require.NoError(t, c.Validate())
+		require.True(t, c.Enabled())
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// FsyncPolicy denotes how often the write-ahead log is flushed to the disk.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways flushes the write-ahead log after every write. It's the safest and the slowest policy.
+	FsyncAlways FsyncPolicy = "always"
+
+	// FsyncInterval flushes the write-ahead log periodically. You may lose the writes
+	// of the last interval in the case of a crash.
+	FsyncInterval FsyncPolicy = "interval"
+
+	// FsyncNever leaves flushing to the operating system.
+	FsyncNever FsyncPolicy = "never"
+)
+
+const (
+	// DefaultFsyncPolicy is the default fsync policy of the write-ahead log.
+	DefaultFsyncPolicy = FsyncInterval
+
+	// DefaultFsyncInterval is the default interval between two sequential flushes
+	// of the write-ahead log.
+	DefaultFsyncInterval = time.Second
+
+	// DefaultWALCompactionMinSize is the default minimum size of the write-ahead
+	// log to compact it at runtime.
+	DefaultWALCompactionMinSize = 64 << 20
+)
+
+// WAL denotes configuration for the write-ahead log of DMaps. If it's enabled, Olric
+// logs put, delete, expire and destroy operations before applying them to the fragments and
+// replays the log when the node restarts. It's a per-node configuration.
+type WAL struct {
+	// Enabled enables the write-ahead log. It's false by default.
+	Enabled bool
+
+	// Path is the path of the log file. It's required if the write-ahead log is enabled.
+	Path string
+
+	// FsyncPolicy determines how often the log is flushed to the disk: always,
+	// interval or never. It's interval by default.
+	FsyncPolicy FsyncPolicy
+
+	// FsyncInterval is the interval between two sequential flushes if FsyncPolicy
+	// is interval. It's one second by default.
+	FsyncInterval time.Duration
+
+	// CompactionMinSize is the minimum size of the log file in bytes to compact it
+	// at runtime. The log is compacted if it's larger than CompactionMinSize and
+	// its size has doubled since the last compaction. It's 64MB by default.
+	CompactionMinSize int64
+}
+
+// NewWAL returns a disabled WAL configuration with sane defaults.
+func NewWAL() *WAL {
+	return &WAL{
+		FsyncPolicy:       DefaultFsyncPolicy,
+		FsyncInterval:     DefaultFsyncInterval,
+		CompactionMinSize: DefaultWALCompactionMinSize,
+	}
+}
+
+// Sanitize sets default values to empty configuration variables, if it's possible.
+func (w *WAL) Sanitize() error {
+	if w.FsyncPolicy == "" {
+		w.FsyncPolicy = DefaultFsyncPolicy
+	}
+	if w.FsyncInterval <= 0 {
+		w.FsyncInterval = DefaultFsyncInterval
+	}
+	if w.CompactionMinSize <= 0 {
+		w.CompactionMinSize = DefaultWALCompactionMinSize
+	}
+	return nil
+}
+
+// Validate finds errors in the current configuration.
+func (w *WAL) Validate() error {
+	switch w.FsyncPolicy {
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return fmt.Errorf("invalid fsync policy: %s", w.FsyncPolicy)
+	}
+	if w.Enabled && w.Path == "" {
+		return fmt.Errorf("path of the write-ahead log cannot be empty")
+	}
+	return nil
+}
+
+// Interface guard
+var _ IConfig = (*WAL)(nil)
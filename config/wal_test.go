@@ -0,0 +1,45 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_WAL(t *testing.T) {
+	w := &WAL{}
+	require.NoError(t, w.Sanitize())
+	require.NoError(t, w.Validate())
+
+	require.Equal(t, DefaultFsyncPolicy, w.FsyncPolicy)
+	require.Equal(t, DefaultFsyncInterval, w.FsyncInterval)
+	require.Equal(t, int64(DefaultWALCompactionMinSize), w.CompactionMinSize)
+}
+
+func TestConfig_WAL_Validate(t *testing.T) {
+	t.Run("Empty path", func(t *testing.T) {
+		w := NewWAL()
+		w.Enabled = true
+		require.Error(t, w.Validate())
+	})
+
+	t.Run("Invalid fsync policy", func(t *testing.T) {
+		w := NewWAL()
+		w.FsyncPolicy = "sometimes"
+		require.Error(t, w.Validate())
+	})
+}
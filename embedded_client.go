@@ -17,34 +17,68 @@ package olric
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/buraksezer/olric/internal/discovery"
 	"github.com/buraksezer/olric/internal/dmap"
+	"github.com/buraksezer/olric/internal/kvstore/entry"
 	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/pubsub"
 	"github.com/buraksezer/olric/internal/util"
 	"github.com/buraksezer/olric/stats"
+	"github.com/redis/go-redis/v9"
 )
 
 // EmbeddedLockContext is returned by Lock and LockWithTimeout methods.
 // It should be stored in a proper way to release the lock.
 type EmbeddedLockContext struct {
-	key   string
-	token []byte
-	dm    *EmbeddedDMap
+	// timeout is accessed atomically, keep it 64-bit aligned.
+	timeout int64
+	key     string
+	owner   string
+	token   uint64
+	// shared is true for the read-write locks and the semaphores.
+	shared bool
+	dm     *EmbeddedDMap
 }
 
 // Unlock releases the lock.
 func (l *EmbeddedLockContext) Unlock(ctx context.Context) error {
-	err := l.dm.dm.Unlock(ctx, l.key, l.token)
+	if l.shared {
+		return convertDMapError(l.dm.dm.SharedRelease(ctx, l.key, l.owner))
+	}
+	err := l.dm.dm.FencedUnlock(ctx, l.key, l.owner)
 	return convertDMapError(err)
 }
 
 // Lease takes the duration to update the expiry for the given Lock.
 func (l *EmbeddedLockContext) Lease(ctx context.Context, duration time.Duration) error {
-	err := l.dm.dm.Lease(ctx, l.key, l.token, duration)
-	return convertDMapError(err)
+	var err error
+	if l.shared {
+		err = l.dm.dm.SharedLease(ctx, l.key, l.owner, duration)
+	} else {
+		err = l.dm.dm.FencedLease(ctx, l.key, l.owner, duration)
+	}
+	if err != nil {
+		return convertDMapError(err)
+	}
+	atomic.StoreInt64(&l.timeout, int64(duration))
+	return nil
+}
+
+// Token returns the fencing token of the lock.
+func (l *EmbeddedLockContext) Token() uint64 {
+	return l.token
+}
+
+// KeepAlive renews the lease of the lock periodically until ctx is cancelled.
+func (l *EmbeddedLockContext) KeepAlive(ctx context.Context) error {
+	return keepLockAlive(ctx, func() time.Duration {
+		return time.Duration(atomic.LoadInt64(&l.timeout))
+	}, l.Lease)
 }
 
 // EmbeddedClient is an Olric client implementation for embedded-member scenario.
@@ -130,6 +164,10 @@ func (e *EmbeddedClient) RefreshMetadata(_ context.Context) error {
 //
 // * Count
 // * Match
+// * Where
+// * WhereTTL
+// * NewerThan
+// * WithValues
 func (dm *EmbeddedDMap) Scan(ctx context.Context, options ...ScanOption) (Iterator, error) {
 	cc, err := NewClusterClient([]string{dm.client.db.rt.This().String()})
 	if err != nil {
@@ -161,18 +199,10 @@ func (dm *EmbeddedDMap) Scan(ctx context.Context, options ...ScanOption) (Iterat
 // It returns immediately if it acquires the lock for the given key. Otherwise,
 // it waits until deadline.
 //
-// You should know that the locks are approximate, and only to be used for
-// non-critical purposes.
-func (dm *EmbeddedDMap) Lock(ctx context.Context, key string, deadline time.Duration) (LockContext, error) {
-	token, err := dm.dm.Lock(ctx, key, 0*time.Second, deadline)
-	if err != nil {
-		return nil, convertDMapError(err)
-	}
-	return &EmbeddedLockContext{
-		key:   key,
-		token: token,
-		dm:    dm,
-	}, nil
+// Every acquisition comes with a fencing token, see LockContext.Token. The
+// lock is reentrant for the same owner id, see LockOwner.
+func (dm *EmbeddedDMap) Lock(ctx context.Context, key string, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	return dm.LockWithTimeout(ctx, key, 0, deadline, options...)
 }
 
 // LockWithTimeout sets a lock for the given key. If the lock is still unreleased
@@ -183,20 +213,164 @@ func (dm *EmbeddedDMap) Lock(ctx context.Context, key string, deadline time.Dura
 // It returns immediately if it acquires the lock for the given key. Otherwise,
 // it waits until deadline.
 //
-// You should know that the locks are approximate, and only to be used for
-// non-critical purposes.
-func (dm *EmbeddedDMap) LockWithTimeout(ctx context.Context, key string, timeout, deadline time.Duration) (LockContext, error) {
-	token, err := dm.dm.Lock(ctx, key, timeout, deadline)
+// Every acquisition comes with a fencing token, see LockContext.Token. The
+// lock is reentrant for the same owner id, see LockOwner.
+func (dm *EmbeddedDMap) LockWithTimeout(ctx context.Context, key string, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	lc, err := newLockConfig(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := dm.dm.FencedLock(ctx, key, lc.Owner, timeout, deadline)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return &EmbeddedLockContext{
+		key:     key,
+		owner:   lc.Owner,
+		token:   token,
+		timeout: int64(timeout),
+		dm:      dm,
+	}, nil
+}
+
+// EmbeddedRWLock is a read-write lock implementation for embedded-member
+// scenario.
+type EmbeddedRWLock struct {
+	key string
+	dm  *EmbeddedDMap
+}
+
+func (l *EmbeddedRWLock) lock(ctx context.Context, write bool, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	lc, err := newLockConfig(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := l.dm.dm.RWLock(ctx, l.key, lc.Owner, write, timeout, deadline)
 	if err != nil {
 		return nil, convertDMapError(err)
 	}
 	return &EmbeddedLockContext{
-		key:   key,
-		token: token,
-		dm:    dm,
+		key:     l.key,
+		owner:   lc.Owner,
+		token:   token,
+		timeout: int64(timeout),
+		shared:  true,
+		dm:      l.dm,
 	}, nil
 }
 
+// RLock acquires the lock for reading. It waits until deadline if a writer
+// holds the lock.
+func (l *EmbeddedRWLock) RLock(ctx context.Context, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	return l.lock(ctx, false, timeout, deadline, options...)
+}
+
+// Lock acquires the lock for writing. It waits until deadline if anybody holds
+// the lock.
+func (l *EmbeddedRWLock) Lock(ctx context.Context, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	return l.lock(ctx, true, timeout, deadline, options...)
+}
+
+// RWLock returns a read-write lock for the given key.
+func (dm *EmbeddedDMap) RWLock(key string) RWLock {
+	return &EmbeddedRWLock{
+		key: key,
+		dm:  dm,
+	}
+}
+
+// EmbeddedSemaphore is a counting semaphore implementation for embedded-member
+// scenario.
+type EmbeddedSemaphore struct {
+	name    string
+	permits int
+	dm      *EmbeddedDMap
+}
+
+// Acquire acquires a permit of the semaphore. It waits until deadline if no
+// permit is available.
+func (s *EmbeddedSemaphore) Acquire(ctx context.Context, timeout, deadline time.Duration, options ...LockOption) (LockContext, error) {
+	lc, err := newLockConfig(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.dm.dm.SemAcquire(ctx, s.name, lc.Owner, s.permits, timeout, deadline)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return &EmbeddedLockContext{
+		key:     s.name,
+		owner:   lc.Owner,
+		token:   token,
+		timeout: int64(timeout),
+		shared:  true,
+		dm:      s.dm,
+	}, nil
+}
+
+// Semaphore returns a counting semaphore with the given name.
+func (dm *EmbeddedDMap) Semaphore(name string, permits int) Semaphore {
+	return &EmbeddedSemaphore{
+		name:    name,
+		permits: permits,
+		dm:      dm,
+	}
+}
+
+// Eval runs the Lua script atomically on the partition owner of the keys.
+// All the keys have to belong to the same partition. See DMap.Eval for the
+// available functions.
+func (dm *EmbeddedDMap) Eval(ctx context.Context, script string, keys []string, args ...string) (interface{}, error) {
+	result, err := dm.dm.Eval(ctx, script, keys, args...)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return result, nil
+}
+
+// Execute runs the entry processor on the partition owner of the key. See
+// DMap.Execute for details.
+func (dm *EmbeddedDMap) Execute(ctx context.Context, key, processor string, args []byte) ([]byte, error) {
+	result, err := dm.dm.Execute(ctx, key, processor, args)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return result, nil
+}
+
+// Aggregate runs the aggregator on the primary partitions of every member,
+// and merges the partial results.
+func (dm *EmbeddedDMap) Aggregate(ctx context.Context, aggregator *Aggregator) (AggregateResult, error) {
+	result, err := dm.dm.Aggregate(ctx, &aggregator.config)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return result, nil
+}
+
+// Query returns the entries whose indexed field equals value. See DMap.Query
+// for details.
+func (dm *EmbeddedDMap) Query(ctx context.Context, index string, value interface{}, options ...QueryOption) (*QueryResult, error) {
+	indexed, err := indexValue(value)
+	if err != nil {
+		return nil, err
+	}
+	cfg := newQueryConfig(options)
+	result, err := dm.dm.Query(ctx, index, indexed, cfg.After, cfg.Count)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+
+	entries := make([]QueryEntry, 0, len(result))
+	for _, entry := range result {
+		entries = append(entries, QueryEntry{Key: entry.Key, Value: entry.Value})
+	}
+	return newQueryResult(entries, cfg), nil
+}
+
 // Destroy flushes the given DMap on the cluster. You should know that there
 // is no global lock on DMaps. So if you call Put/PutEx and Destroy methods
 // concurrently on the cluster, Put call may set new values to the DMap.
@@ -251,6 +425,65 @@ func (dm *EmbeddedDMap) Delete(ctx context.Context, keys ...string) (int, error)
 	return dm.dm.Delete(ctx, keys...)
 }
 
+// MGet gets the values for the given keys. Keys are grouped by their partition
+// owners and the owners are queried in parallel. It returns the values of the
+// found keys and the errors of the failed ones. A missing key is reported
+// with ErrKeyNotFound. The error map is nil if every key is found.
+func (dm *EmbeddedDMap) MGet(ctx context.Context, keys ...string) (map[string]*GetResponse, map[string]error) {
+	entries, errs := dm.dm.MGet(ctx, keys...)
+
+	var failed map[string]error
+	result := make(map[string]*GetResponse)
+	for i, key := range keys {
+		if errs[i] != nil {
+			if failed == nil {
+				failed = make(map[string]error)
+			}
+			failed[key] = convertDMapError(errs[i])
+			continue
+		}
+		result[key] = &GetResponse{
+			entry: entries[i],
+		}
+	}
+	return result, failed
+}
+
+// MPut sets the given key/value pairs. Keys are grouped by their partition
+// owners and the owners are called in parallel. It returns the errors of the
+// failed keys. The error map is nil if every key is set. It is safe to modify
+// the contents of the argument after MPut returns but not before.
+func (dm *EmbeddedDMap) MPut(ctx context.Context, entries map[string]interface{}) map[string]error {
+	keys := make([]string, 0, len(entries))
+	values := make([]interface{}, 0, len(entries))
+	for key, value := range entries {
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+
+	errs, err := dm.dm.MPut(ctx, keys, values)
+	if err != nil {
+		// Keys and values are built from the same map, it's not expected.
+		failed := make(map[string]error)
+		for _, key := range keys {
+			failed[key] = convertDMapError(err)
+		}
+		return failed
+	}
+
+	var failed map[string]error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if failed == nil {
+			failed = make(map[string]error)
+		}
+		failed[keys[i]] = convertDMapError(err)
+	}
+	return failed
+}
+
 // Get gets the value for the given key. It returns ErrKeyNotFound if the DB
 // does not contain the key. It's thread-safe. It is safe to modify the contents
 // of the returned value. See GetResponse for the details.
@@ -265,6 +498,345 @@ func (dm *EmbeddedDMap) Get(ctx context.Context, key string) (*GetResponse, erro
 	}, nil
 }
 
+// CompareAndSwap sets the value for the given key if the timestamp of the
+// current entry equals to expected. See GetResponse.Timestamp. A missing key
+// has version 0. The comparison is done on the partition owner under the
+// fragment lock. It returns false if the versions don't match.
+func (dm *EmbeddedDMap) CompareAndSwap(ctx context.Context, key string, expected int64, value interface{}) (bool, error) {
+	swapped, err := dm.dm.CompareAndSwap(ctx, key, expected, value)
+	if err != nil {
+		return false, convertDMapError(err)
+	}
+	return swapped, nil
+}
+
+// HSet sets the given fields of the hash stored at key. A new hash is created
+// if the key doesn't exist. It returns the number of fields that were added.
+// It returns ErrWrongType if the key holds a value that is not a hash.
+func (dm *EmbeddedDMap) HSet(ctx context.Context, key string, fields map[string]interface{}) (int, error) {
+	added, err := dm.dm.HSet(ctx, key, fields)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return added, nil
+}
+
+// HGet gets the value of field in the hash stored at key. It returns
+// ErrKeyNotFound if the hash or the field doesn't exist. The response
+// carries the TTL and the timestamp of the hash.
+func (dm *EmbeddedDMap) HGet(ctx context.Context, key, field string) (*GetResponse, error) {
+	result, err := dm.dm.HGet(ctx, key, field)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return &GetResponse{
+		entry: result,
+	}, nil
+}
+
+// HDel removes the given fields from the hash stored at key. The key is
+// deleted when the last field is removed. It returns the number of fields
+// that were removed.
+func (dm *EmbeddedDMap) HDel(ctx context.Context, key string, fields ...string) (int, error) {
+	removed, err := dm.dm.HDel(ctx, key, fields...)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return removed, nil
+}
+
+// HGetAll gets all fields of the hash stored at key. It returns an empty map
+// if the key doesn't exist.
+func (dm *EmbeddedDMap) HGetAll(ctx context.Context, key string) (map[string]*GetResponse, error) {
+	fields, err := dm.dm.HGetAll(ctx, key)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+
+	result := make(map[string]*GetResponse, len(fields))
+	for field, value := range fields {
+		e := entry.New()
+		e.SetKey(field)
+		e.SetValue(value)
+		result[field] = &GetResponse{
+			entry: e,
+		}
+	}
+	return result, nil
+}
+
+// HIncrBy atomically increments the integer value of field in the hash
+// stored at key by delta. It returns ErrHashValueNotInteger if the field
+// holds a value that is not an integer.
+func (dm *EmbeddedDMap) HIncrBy(ctx context.Context, key, field string, delta int) (int, error) {
+	value, err := dm.dm.HIncrBy(ctx, key, field, delta)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return value, nil
+}
+
+func toZMembers(members []dmap.ZMember) []ZMember {
+	result := make([]ZMember, 0, len(members))
+	for _, m := range members {
+		result = append(result, ZMember{Member: m.Member, Score: m.Score})
+	}
+	return result
+}
+
+// ZAdd adds the members to the sorted set stored at key. The score is updated
+// if the member already exists. It returns the number of members that were
+// added. It returns ErrWrongType if the key holds a value that is not a
+// sorted set.
+func (dm *EmbeddedDMap) ZAdd(ctx context.Context, key string, members ...ZMember) (int, error) {
+	zmembers := make([]dmap.ZMember, 0, len(members))
+	for _, m := range members {
+		zmembers = append(zmembers, dmap.ZMember{Member: m.Member, Score: m.Score})
+	}
+	added, err := dm.dm.ZAdd(ctx, key, zmembers...)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return added, nil
+}
+
+// ZRange gets the members of the sorted set stored at key between the ranks
+// start and stop, both inclusive, ordered from the lowest to the highest
+// score. Negative ranks are counted from the end, -1 is the last member.
+func (dm *EmbeddedDMap) ZRange(ctx context.Context, key string, start, stop int) ([]ZMember, error) {
+	members, err := dm.dm.ZRange(ctx, key, start, stop)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return toZMembers(members), nil
+}
+
+// ZRangeByScore gets the members of the sorted set stored at key with a
+// score between min and max, both inclusive. Use math.Inf for open ranges.
+func (dm *EmbeddedDMap) ZRangeByScore(ctx context.Context, key string, min, max float64) ([]ZMember, error) {
+	members, err := dm.dm.ZRangeByScore(ctx, key, min, max)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return toZMembers(members), nil
+}
+
+// ZRem removes the members from the sorted set stored at key. The key is
+// deleted when the last member is removed. It returns the number of members
+// that were removed.
+func (dm *EmbeddedDMap) ZRem(ctx context.Context, key string, members ...string) (int, error) {
+	removed, err := dm.dm.ZRem(ctx, key, members...)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return removed, nil
+}
+
+// ZRank gets the rank of member in the sorted set stored at key. The member
+// with the lowest score has rank 0. It returns ErrKeyNotFound if the sorted
+// set or the member doesn't exist.
+func (dm *EmbeddedDMap) ZRank(ctx context.Context, key, member string) (int, error) {
+	rank, err := dm.dm.ZRank(ctx, key, member)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return rank, nil
+}
+
+// ZIncrBy atomically increments the score of member in the sorted set stored
+// at key by delta. It returns the new score.
+func (dm *EmbeddedDMap) ZIncrBy(ctx context.Context, key, member string, delta float64) (float64, error) {
+	score, err := dm.dm.ZIncrBy(ctx, key, member, delta)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return score, nil
+}
+
+// LPush inserts the values at the head of the list stored at key. The last
+// value becomes the head. It returns the length of the list. It returns
+// ErrWrongType if the key holds a value that is not a list.
+func (dm *EmbeddedDMap) LPush(ctx context.Context, key string, values ...interface{}) (int, error) {
+	length, err := dm.dm.LPush(ctx, key, values...)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return length, nil
+}
+
+// RPush inserts the values at the tail of the list stored at key. It returns
+// the length of the list.
+func (dm *EmbeddedDMap) RPush(ctx context.Context, key string, values ...interface{}) (int, error) {
+	length, err := dm.dm.RPush(ctx, key, values...)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return length, nil
+}
+
+// LPop removes and returns the head of the list stored at key. It returns
+// ErrKeyNotFound if the list doesn't exist.
+func (dm *EmbeddedDMap) LPop(ctx context.Context, key string) (*GetResponse, error) {
+	result, err := dm.dm.LPop(ctx, key)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return &GetResponse{
+		entry: result,
+	}, nil
+}
+
+// RPop removes and returns the tail of the list stored at key. It returns
+// ErrKeyNotFound if the list doesn't exist.
+func (dm *EmbeddedDMap) RPop(ctx context.Context, key string) (*GetResponse, error) {
+	result, err := dm.dm.RPop(ctx, key)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return &GetResponse{
+		entry: result,
+	}, nil
+}
+
+// LRange gets the elements of the list stored at key between start and stop,
+// both inclusive. Negative indexes are counted from the tail, -1 is the last
+// element.
+func (dm *EmbeddedDMap) LRange(ctx context.Context, key string, start, stop int) ([]*GetResponse, error) {
+	elements, err := dm.dm.LRange(ctx, key, start, stop)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+
+	result := make([]*GetResponse, 0, len(elements))
+	for _, element := range elements {
+		e := entry.New()
+		e.SetKey(key)
+		e.SetValue(element)
+		result = append(result, &GetResponse{
+			entry: e,
+		})
+	}
+	return result, nil
+}
+
+// LLen returns the length of the list stored at key. It returns zero if the
+// key doesn't exist.
+func (dm *EmbeddedDMap) LLen(ctx context.Context, key string) (int, error) {
+	length, err := dm.dm.LLen(ctx, key)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return length, nil
+}
+
+// BLPop removes and returns the head of the list stored at key. It blocks
+// until an element is pushed if the list is empty. It returns ErrKeyNotFound
+// if the list is still empty after timeout. Zero timeout blocks until the
+// context is done. The blocked callers are not served in order.
+func (dm *EmbeddedDMap) BLPop(ctx context.Context, key string, timeout time.Duration) (*GetResponse, error) {
+	result, err := dm.dm.BLPop(ctx, key, timeout)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return &GetResponse{
+		entry: result,
+	}, nil
+}
+
+func toStreamEntries(entries []dmap.StreamEntry) []StreamEntry {
+	result := make([]StreamEntry, 0, len(entries))
+	for _, se := range entries {
+		var fields map[string]*GetResponse
+		if se.Fields != nil {
+			fields = make(map[string]*GetResponse, len(se.Fields))
+			for field, value := range se.Fields {
+				e := entry.New()
+				e.SetKey(field)
+				e.SetValue(value)
+				fields[field] = &GetResponse{
+					entry: e,
+				}
+			}
+		}
+		result = append(result, StreamEntry{
+			ID:     se.ID,
+			Fields: fields,
+		})
+	}
+	return result
+}
+
+// XAdd appends an entry with the given fields to the stream stored at key
+// and returns its ID. The entries are stored on the partition owner and
+// replicated like the other entries, so they can be read after the
+// consumers reconnect.
+func (dm *EmbeddedDMap) XAdd(ctx context.Context, key string, fields map[string]interface{}, options ...XAddOption) (string, error) {
+	var xc xaddConfig
+	for _, opt := range options {
+		opt(&xc)
+	}
+	id, err := dm.dm.XAdd(ctx, key, fields, xc.maxLen)
+	if err != nil {
+		return "", convertDMapError(err)
+	}
+	return id, nil
+}
+
+// XRead gets the entries of the stream stored at key with an ID greater
+// than id. An empty id or "0" reads from the beginning. Zero count returns
+// all the entries.
+func (dm *EmbeddedDMap) XRead(ctx context.Context, key, id string, count int) ([]StreamEntry, error) {
+	entries, err := dm.dm.XRead(ctx, key, id, count)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return toStreamEntries(entries), nil
+}
+
+// XReadGroup reads the entries of the stream stored at key for a consumer
+// of the group. The group is created on the first call and it starts from
+// the beginning of the stream. If id is StreamNewEntries, it returns the
+// entries that were never delivered to the group. Otherwise, it returns the
+// pending entries of the consumer with an ID greater than id. The returned
+// entries are pending until they are acknowledged with XAck.
+func (dm *EmbeddedDMap) XReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]StreamEntry, error) {
+	entries, err := dm.dm.XReadGroup(ctx, key, group, consumer, id, count)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+	return toStreamEntries(entries), nil
+}
+
+// XAck acknowledges the entries of the group. It returns the number of the
+// acknowledged entries. It returns ErrNoSuchGroup if the group doesn't exist.
+func (dm *EmbeddedDMap) XAck(ctx context.Context, key, group string, ids ...string) (int, error) {
+	acked, err := dm.dm.XAck(ctx, key, group, ids...)
+	if err != nil {
+		return 0, convertDMapError(err)
+	}
+	return acked, nil
+}
+
+// XPending gets the pending entries of the group ordered by their IDs. It
+// returns ErrNoSuchGroup if the group doesn't exist.
+func (dm *EmbeddedDMap) XPending(ctx context.Context, key, group string) ([]PendingEntry, error) {
+	pending, err := dm.dm.XPending(ctx, key, group)
+	if err != nil {
+		return nil, convertDMapError(err)
+	}
+
+	result := make([]PendingEntry, 0, len(pending))
+	for _, p := range pending {
+		result = append(result, PendingEntry{
+			ID:         p.ID,
+			Consumer:   p.Consumer,
+			Idle:       p.Idle,
+			Deliveries: p.Deliveries,
+		})
+	}
+	return result, nil
+}
+
 // Put sets the value for the given key. It overwrites any previous value for
 // that key, and it's thread-safe. The key has to be a string. value type is arbitrary.
 // It is safe to modify the contents of the arguments after Put returns but not before.
@@ -281,6 +853,10 @@ func (dm *EmbeddedDMap) Put(ctx context.Context, key string, value interface{},
 }
 
 func (e *EmbeddedClient) NewDMap(name string, options ...DMapOption) (DMap, error) {
+	if dmap.IsReservedDMap(name) {
+		return nil, fmt.Errorf("%w: %s is reserved for internal use", protocol.ErrInvalidArgument, name)
+	}
+
 	dm, err := e.db.dmap.NewDMap(name)
 	if err != nil {
 		return nil, convertDMapError(err)
@@ -381,9 +957,17 @@ func (e *EmbeddedClient) Members(_ context.Context) ([]Member, error) {
 	return result, nil
 }
 
+func (e *EmbeddedClient) pickChannelOwner(channel string) (*redis.Client, error) {
+	if err := e.db.isOperable(); err != nil {
+		return nil, err
+	}
+	owner := e.db.primary.PartitionByHKey(pubsub.ChannelHKey(channel)).Owner()
+	return e.db.client.Get(owner.String()), nil
+}
+
 // NewPubSub returns a new PubSub client with the given options.
 func (e *EmbeddedClient) NewPubSub(options ...PubSubOption) (*PubSub, error) {
-	return newPubSub(e.db.client, options...)
+	return newPubSub(e.db.client, e.pickChannelOwner, options...)
 }
 
 // NewEmbeddedClient creates and returns a new EmbeddedClient instance.
@@ -17,9 +17,12 @@ package olric
 import (
 	"context"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/protocol"
 	"github.com/buraksezer/olric/internal/testutil"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
@@ -149,6 +152,284 @@ func TestEmbeddedClient_DMap_Put_XX(t *testing.T) {
 	require.ErrorIs(t, err, ErrKeyNotFound)
 }
 
+func TestEmbeddedClient_DMap_Put_IfVersion(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	err = dm.Put(ctx, "mykey", "myvalue", IfVersion(0))
+	require.NoError(t, err)
+
+	gr, err := dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+
+	err = dm.Put(ctx, "mykey", "myvalue-2", IfVersion(gr.Timestamp()+1))
+	require.ErrorIs(t, err, ErrVersionMismatch)
+
+	err = dm.Put(ctx, "mykey", "myvalue-2", IfVersion(gr.Timestamp()))
+	require.NoError(t, err)
+}
+
+func TestEmbeddedClient_DMap_CompareAndSwap(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	err = dm.Put(ctx, "mykey", "myvalue")
+	require.NoError(t, err)
+
+	gr, err := dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+
+	swapped, err := dm.CompareAndSwap(ctx, "mykey", gr.Timestamp(), "myvalue-2")
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	swapped, err = dm.CompareAndSwap(ctx, "mykey", gr.Timestamp(), "myvalue-3")
+	require.NoError(t, err)
+	require.False(t, swapped)
+
+	gr, err = dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+	value, err := gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "myvalue-2", value)
+}
+
+func TestEmbeddedClient_DMap_Hash(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	added, err := dm.HSet(ctx, "myhash", map[string]interface{}{
+		"name":  "olric",
+		"count": 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, added)
+
+	gr, err := dm.HGet(ctx, "myhash", "name")
+	require.NoError(t, err)
+	name, err := gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "olric", name)
+
+	count, err := dm.HIncrBy(ctx, "myhash", "count", 10)
+	require.NoError(t, err)
+	require.Equal(t, 11, count)
+
+	fields, err := dm.HGetAll(ctx, "myhash")
+	require.NoError(t, err)
+	require.Len(t, fields, 2)
+	value, err := fields["count"].Int()
+	require.NoError(t, err)
+	require.Equal(t, 11, value)
+
+	removed, err := dm.HDel(ctx, "myhash", "name", "count", "missing")
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+
+	_, err = dm.HGet(ctx, "myhash", "name")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	err = dm.Put(ctx, "mykey", "myvalue")
+	require.NoError(t, err)
+	_, err = dm.HSet(ctx, "mykey", map[string]interface{}{"name": "olric"})
+	require.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestEmbeddedClient_DMap_SortedSet(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	added, err := dm.ZAdd(ctx, "leaderboard",
+		ZMember{Member: "alice", Score: 30},
+		ZMember{Member: "bob", Score: 10},
+		ZMember{Member: "carol", Score: 20},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 3, added)
+
+	score, err := dm.ZIncrBy(ctx, "leaderboard", "bob", 25.5)
+	require.NoError(t, err)
+	require.Equal(t, 35.5, score)
+
+	members, err := dm.ZRange(ctx, "leaderboard", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []ZMember{
+		{Member: "carol", Score: 20},
+		{Member: "alice", Score: 30},
+		{Member: "bob", Score: 35.5},
+	}, members)
+
+	members, err = dm.ZRangeByScore(ctx, "leaderboard", math.Inf(-1), 30)
+	require.NoError(t, err)
+	require.Equal(t, []ZMember{
+		{Member: "carol", Score: 20},
+		{Member: "alice", Score: 30},
+	}, members)
+
+	rank, err := dm.ZRank(ctx, "leaderboard", "bob")
+	require.NoError(t, err)
+	require.Equal(t, 2, rank)
+
+	_, err = dm.ZRank(ctx, "leaderboard", "missing")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	removed, err := dm.ZRem(ctx, "leaderboard", "alice", "missing")
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+
+	_, err = dm.ZAdd(ctx, "leaderboard", ZMember{Member: "dave", Score: math.NaN()})
+	require.ErrorIs(t, err, ErrScoreNaN)
+
+	err = dm.Put(ctx, "mykey", "myvalue")
+	require.NoError(t, err)
+	_, err = dm.ZAdd(ctx, "mykey", ZMember{Member: "alice", Score: 1})
+	require.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestEmbeddedClient_DMap_List(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	length, err := dm.RPush(ctx, "jobs", "job-2", "job-3")
+	require.NoError(t, err)
+	require.Equal(t, 2, length)
+
+	length, err = dm.LPush(ctx, "jobs", "job-1")
+	require.NoError(t, err)
+	require.Equal(t, 3, length)
+
+	elements, err := dm.LRange(ctx, "jobs", 0, -1)
+	require.NoError(t, err)
+	require.Len(t, elements, 3)
+	for i, element := range elements {
+		value, err := element.String()
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("job-%d", i+1), value)
+	}
+
+	gr, err := dm.RPop(ctx, "jobs")
+	require.NoError(t, err)
+	value, err := gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "job-3", value)
+
+	gr, err = dm.LPop(ctx, "jobs")
+	require.NoError(t, err)
+	value, err = gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "job-1", value)
+
+	length, err = dm.LLen(ctx, "jobs")
+	require.NoError(t, err)
+	require.Equal(t, 1, length)
+
+	gr, err = dm.BLPop(ctx, "jobs", time.Second)
+	require.NoError(t, err)
+	value, err = gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "job-2", value)
+
+	_, err = dm.BLPop(ctx, "jobs", 100*time.Millisecond)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	go func() {
+		<-time.After(100 * time.Millisecond)
+		_, err := dm.RPush(ctx, "jobs", "job-4")
+		require.NoError(t, err)
+	}()
+
+	gr, err = dm.BLPop(ctx, "jobs", 5*time.Second)
+	require.NoError(t, err)
+	value, err = gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "job-4", value)
+}
+
+func TestEmbeddedClient_DMap_Stream(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := dm.XAdd(ctx, "events", map[string]interface{}{"seq": i})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	entries, err := dm.XRead(ctx, "events", ids[0], 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, ids[1], entries[0].ID)
+	seq, err := entries[0].Fields["seq"].Int()
+	require.NoError(t, err)
+	require.Equal(t, 1, seq)
+
+	entries, err = dm.XReadGroup(ctx, "events", "workers", "worker-1", StreamNewEntries, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	acked, err := dm.XAck(ctx, "events", "workers", entries[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, acked)
+
+	pending, err := dm.XPending(ctx, "events", "workers")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, ids[1], pending[0].ID)
+	require.Equal(t, "worker-1", pending[0].Consumer)
+	require.Equal(t, 1, pending[0].Deliveries)
+
+	// The consumer resumes from its pending entries.
+	entries, err = dm.XReadGroup(ctx, "events", "workers", "worker-1", "0", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, ids[1], entries[0].ID)
+
+	_, err = dm.XAck(ctx, "events", "missing", ids[0])
+	require.ErrorIs(t, err, ErrNoSuchGroup)
+
+	_, err = dm.XRead(ctx, "events", "foobar", 0)
+	require.ErrorIs(t, err, ErrInvalidStreamID)
+
+	for i := 0; i < 5; i++ {
+		_, err = dm.XAdd(ctx, "events", map[string]interface{}{"seq": i}, MaxLen(2))
+		require.NoError(t, err)
+	}
+	entries, err = dm.XRead(ctx, "events", "0", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
+
 func TestEmbeddedClient_DMap_Get(t *testing.T) {
 	cluster := newTestOlricCluster(t)
 	db := cluster.addMember(t)
@@ -208,6 +489,34 @@ func TestEmbeddedClient_DMap_Delete_Many_Keys(t *testing.T) {
 	require.Equal(t, 10, count)
 }
 
+func TestEmbeddedClient_DMap_MPut_MGet(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+	cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	entries := make(map[string]interface{})
+	var keys []string
+	for i := 0; i < 100; i++ {
+		entries[testutil.ToKey(i)] = i
+		keys = append(keys, testutil.ToKey(i))
+	}
+	require.Nil(t, dm.MPut(context.Background(), entries))
+
+	result, errs := dm.MGet(context.Background(), append(keys, "missing-key")...)
+	require.Len(t, result, 100)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs["missing-key"], ErrKeyNotFound)
+	for i := 0; i < 100; i++ {
+		value, err := result[testutil.ToKey(i)].Int()
+		require.NoError(t, err)
+		require.Equal(t, i, value)
+	}
+}
+
 func TestEmbeddedClient_DMap_Atomic_Incr(t *testing.T) {
 	cluster := newTestOlricCluster(t)
 	db := cluster.addMember(t)
@@ -533,6 +842,206 @@ func TestEmbeddedClient_DMap_LockWithTimeout_Then_Lease(t *testing.T) {
 	require.ErrorIs(t, err, ErrLockNotAcquired)
 }
 
+func TestEmbeddedClient_DMap_Lock_Fencing(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	key := "lock.key.test"
+
+	lx, err := dm.Lock(ctx, key, time.Second)
+	require.NoError(t, err)
+	first := lx.Token()
+	require.NoError(t, lx.Unlock(ctx))
+
+	lx, err = dm.Lock(ctx, key, time.Second)
+	require.NoError(t, err)
+	require.Greater(t, lx.Token(), first)
+	require.NoError(t, lx.Unlock(ctx))
+}
+
+func TestEmbeddedClient_DMap_Lock_Reentrant(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	key := "lock.key.test"
+
+	outer, err := dm.Lock(ctx, key, time.Second, LockOwner("worker-1"))
+	require.NoError(t, err)
+
+	inner, err := dm.Lock(ctx, key, time.Millisecond, LockOwner("worker-1"))
+	require.NoError(t, err)
+	require.Equal(t, outer.Token(), inner.Token())
+
+	_, err = dm.Lock(ctx, key, time.Millisecond, LockOwner("worker-2"))
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+
+	require.NoError(t, inner.Unlock(ctx))
+
+	// Still held by the outer acquisition.
+	_, err = dm.Lock(ctx, key, time.Millisecond, LockOwner("worker-2"))
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+
+	require.NoError(t, outer.Unlock(ctx))
+
+	lx, err := dm.Lock(ctx, key, time.Second, LockOwner("worker-2"))
+	require.NoError(t, err)
+	require.Greater(t, lx.Token(), outer.Token())
+}
+
+func TestEmbeddedClient_DMap_Lock_Wait(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	key := "lock.key.test"
+
+	lx, err := dm.Lock(ctx, key, time.Second)
+	require.NoError(t, err)
+
+	go func() {
+		<-time.After(100 * time.Millisecond)
+		require.NoError(t, lx.Unlock(ctx))
+	}()
+
+	waiter, err := dm.Lock(ctx, key, 5*time.Second)
+	require.NoError(t, err)
+	require.Greater(t, waiter.Token(), lx.Token())
+}
+
+func TestEmbeddedClient_DMap_Lock_KeepAlive(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	key := "lock.key.test"
+
+	lx, err := dm.LockWithTimeout(ctx, key, 300*time.Millisecond, time.Second)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- lx.KeepAlive(ctx)
+	}()
+
+	<-time.After(time.Second)
+
+	_, err = dm.Lock(context.Background(), key, time.Millisecond)
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.NoError(t, lx.Unlock(context.Background()))
+}
+
+func TestEmbeddedClient_DMap_RWLock(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	rw := dm.RWLock("lock.key.test")
+
+	r1, err := rw.RLock(ctx, 0, time.Second)
+	require.NoError(t, err)
+	r2, err := rw.RLock(ctx, 0, time.Second)
+	require.NoError(t, err)
+
+	_, err = rw.Lock(ctx, 0, time.Millisecond)
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+
+	require.NoError(t, r1.Unlock(ctx))
+	require.NoError(t, r2.Unlock(ctx))
+
+	w, err := rw.Lock(ctx, 0, time.Second)
+	require.NoError(t, err)
+	require.Greater(t, w.Token(), r2.Token())
+
+	_, err = rw.RLock(ctx, 0, time.Millisecond)
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+
+	require.NoError(t, w.Unlock(ctx))
+	require.ErrorIs(t, w.Unlock(ctx), ErrNoSuchLock)
+}
+
+func TestEmbeddedClient_DMap_Semaphore(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	sem := dm.Semaphore("semaphore.test", 2)
+
+	p1, err := sem.Acquire(ctx, 0, time.Second)
+	require.NoError(t, err)
+	_, err = sem.Acquire(ctx, 50*time.Millisecond, time.Second)
+	require.NoError(t, err)
+
+	_, err = sem.Acquire(ctx, 0, time.Millisecond)
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+
+	// Waits until the lease of the second permit expires.
+	p3, err := sem.Acquire(ctx, 0, time.Second)
+	require.NoError(t, err)
+
+	require.NoError(t, p1.Unlock(ctx))
+	require.NoError(t, p3.Unlock(ctx))
+}
+
+func TestEmbeddedClient_DMap_Eval(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = dm.Put(ctx, "mykey", 10)
+	require.NoError(t, err)
+
+	result, err := dm.Eval(ctx, `
+local value = tonumber(olric.get(KEYS[1])) + tonumber(ARGV[1])
+olric.put(KEYS[1], value)
+return {value, "done"}
+`, []string{"mykey"}, "5")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(15), "done"}, result)
+
+	gr, err := dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+	value, err := gr.Int()
+	require.NoError(t, err)
+	require.Equal(t, 15, value)
+
+	_, err = dm.Eval(ctx, `error("boom")`, []string{"mykey"})
+	require.ErrorIs(t, err, ErrScript)
+	require.Contains(t, err.Error(), "boom")
+}
+
 func TestEmbeddedClient_RoutingTable_Standalone(t *testing.T) {
 	cluster := newTestOlricCluster(t)
 	db := cluster.addMember(t)
@@ -614,3 +1123,122 @@ func TestEmbeddedClient_Ping_WithMessage(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, message, response)
 }
+
+func TestEmbeddedClient_DMap_Query(t *testing.T) {
+	c := testutil.NewConfig()
+	c.DMaps.Custom = map[string]config.DMap{
+		"mydmap": {
+			Indexes: []config.Index{{Name: "by-age", Field: "age"}},
+		},
+	}
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMemberWithConfig(t, c)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		value := fmt.Sprintf(`{"age": %d}`, i%2)
+		require.NoError(t, dm.Put(ctx, testutil.ToKey(i), []byte(value)))
+	}
+
+	var keys []string
+	result, err := dm.Query(ctx, "by-age", 1, QueryCount(3))
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 3)
+	require.NotEmpty(t, result.Cursor)
+	for _, entry := range result.Entries {
+		keys = append(keys, entry.Key)
+		require.JSONEq(t, `{"age": 1}`, string(entry.Value))
+	}
+
+	result, err = dm.Query(ctx, "by-age", 1, QueryCount(3), QueryAfter(result.Cursor))
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 2)
+	require.Empty(t, result.Cursor)
+	for _, entry := range result.Entries {
+		keys = append(keys, entry.Key)
+	}
+	require.ElementsMatch(t, []string{
+		testutil.ToKey(1),
+		testutil.ToKey(3),
+		testutil.ToKey(5),
+		testutil.ToKey(7),
+		testutil.ToKey(9),
+	}, keys)
+
+	_, err = dm.Query(ctx, "by-name", "foobar")
+	require.ErrorIs(t, err, ErrNoSuchIndex)
+
+	_, err = dm.Query(ctx, "by-age", []int{1})
+	require.ErrorIs(t, err, protocol.ErrInvalidArgument)
+}
+
+func TestEmbeddedClient_DMap_Aggregate(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+	cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("sessions")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		value := fmt.Sprintf(`{"tenant": "tenant-%d", "duration": %d}`, i%2, i)
+		require.NoError(t, dm.Put(ctx, testutil.ToKey(i), []byte(value)))
+	}
+
+	result, err := dm.Aggregate(ctx, NewCountAggregator().GroupBy("tenant"))
+	require.NoError(t, err)
+	require.Equal(t, AggregateResult{"tenant-0": 10, "tenant-1": 10}, result)
+
+	result, err = dm.Aggregate(ctx, NewMaxAggregator("duration").Where("tenant", OpEqual, "tenant-0"))
+	require.NoError(t, err)
+	require.Equal(t, AggregateResult{"": 18}, result)
+
+	_, err = dm.Aggregate(ctx, NewSumAggregator(""))
+	require.ErrorIs(t, err, protocol.ErrInvalidArgument)
+}
+
+func TestEmbeddedClient_DMap_Execute(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.EntryProcessors = map[string]config.EntryProcessor{
+			"append": func(entry config.MutableEntry, args []byte) ([]byte, error) {
+				value := append(entry.Value(), args...)
+				entry.SetValue(value)
+				return value, nil
+			},
+		}
+		return c
+	}
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMemberWithConfig(t, newConfig())
+	cluster.addMemberWithConfig(t, newConfig())
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+		_, err = dm.Execute(ctx, key, "append", []byte("foo"))
+		require.NoError(t, err)
+		result, err := dm.Execute(ctx, key, "append", []byte("bar"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("foobar"), result)
+
+		gr, err := dm.Get(ctx, key)
+		require.NoError(t, err)
+		value, err := gr.String()
+		require.NoError(t, err)
+		require.Equal(t, "foobar", value)
+	}
+
+	_, err = dm.Execute(ctx, "mykey", "no-such-processor", nil)
+	require.ErrorIs(t, err, ErrNoSuchProcessor)
+}
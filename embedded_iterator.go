@@ -18,7 +18,6 @@ import (
 	"sync"
 
 	"github.com/buraksezer/olric/internal/dmap"
-	"github.com/buraksezer/olric/internal/protocol"
 )
 
 // EmbeddedIterator implements distributed query on DMaps.
@@ -48,19 +47,7 @@ func (e *EmbeddedIterator) scanOnOwners() error {
 			continue
 		}
 
-		// Build a scan command here
-		s := protocol.NewScan(e.clusterIterator.partID, e.clusterIterator.dm.Name(), cursor)
-		if e.clusterIterator.config.HasCount {
-			s.SetCount(e.clusterIterator.config.Count)
-		}
-		if e.clusterIterator.config.HasMatch {
-			s.SetMatch(e.clusterIterator.config.Match)
-		}
-		if e.clusterIterator.config.Replica {
-			s.SetReplica()
-		}
-
-		scanCmd := s.Command(e.clusterIterator.ctx)
+		scanCmd := e.clusterIterator.newScan(cursor).Command(e.clusterIterator.ctx)
 		// Fetch a Redis client for the given owner.
 		rc := e.clusterIterator.clusterClient.client.Get(owner)
 		err := rc.Process(e.clusterIterator.ctx, scanCmd)
@@ -91,6 +78,12 @@ func (e *EmbeddedIterator) Key() string {
 	return e.clusterIterator.Key()
 }
 
+// Value returns the value of the current key. It's only available if the
+// iterator is created with the WithValues option, otherwise it returns nil.
+func (e *EmbeddedIterator) Value() []byte {
+	return e.clusterIterator.Value()
+}
+
 // Close stops the iteration and releases allocated resources.
 func (e *EmbeddedIterator) Close() {
 	e.clusterIterator.Close()
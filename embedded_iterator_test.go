@@ -85,3 +85,34 @@ func TestEmbeddedClient_Scan(t *testing.T) {
 	}
 	require.Equal(t, 100, count)
 }
+
+func TestEmbeddedClient_Scan_WithValues(t *testing.T) {
+	cl := newTestOlricCluster(t)
+	db := cl.addMember(t)
+	cl.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	expected := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		value := fmt.Sprintf(`{"address": {"city": "city-%d"}}`, i%4)
+		err = dm.Put(ctx, testutil.ToKey(i), []byte(value))
+		require.NoError(t, err)
+		if i%4 == 1 {
+			expected[testutil.ToKey(i)] = value
+		}
+	}
+
+	i, err := dm.Scan(ctx, Where("address.city", OpEqual, "city-1"), WithValues())
+	require.NoError(t, err)
+	defer i.Close()
+
+	values := make(map[string]string)
+	for i.Next() {
+		values[i.Key()] = string(i.Value())
+	}
+	require.Equal(t, expected, values)
+}
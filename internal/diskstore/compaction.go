@@ -0,0 +1,129 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diskstore
+
+import (
+	"fmt"
+)
+
+// segmentRange returns the first position of the given segment and the first
+// position of the next one.
+func segmentRange(id uint64) (uint64, uint64) {
+	return newPosition(id, 0), newPosition(id+1, 0)
+}
+
+// liveHKeys returns at most limit hkeys which are stored in the given segment.
+func (d *DiskStore) liveHKeys(s *segment, limit int) []uint64 {
+	start, end := segmentRange(s.id)
+	it := d.offsetIndex.Iterator()
+	it.AdvanceIfNeeded(start)
+
+	var hkeys []uint64
+	for it.HasNext() && len(hkeys) < limit {
+		position := it.Next()
+		if position >= end {
+			break
+		}
+		hkeys = append(hkeys, d.positions[position])
+	}
+	return hkeys
+}
+
+func (d *DiskStore) removeSegment(s *segment) error {
+	for i, item := range d.segments {
+		if item == s {
+			d.segments = append(d.segments[:i], d.segments[i+1:]...)
+			break
+		}
+	}
+	delete(d.segmentsByID, s.id)
+	return s.remove()
+}
+
+// evictSegment moves the live entries of the given segment to the active one. When
+// the segment has no live entries, it copies the required delete records and removes
+// the segment file.
+func (d *DiskStore) evictSegment(s *segment) error {
+	for _, hkey := range d.liveHKeys(s, 1000) {
+		raw, err := d.GetRaw(hkey)
+		if err != nil {
+			return err
+		}
+		err = d.PutRaw(hkey, raw)
+		if err != nil {
+			return fmt.Errorf("put command failed: HKey: %d: %w", hkey, err)
+		}
+	}
+
+	if s.length != 0 {
+		// Continue in the next call
+		return nil
+	}
+
+	// A delete record hides the previous versions of a key in the older segments.
+	// The oldest segment doesn't need to keep them.
+	if d.segments[0] != s {
+		var deleted []uint64
+		_, err := s.replay(func(_ uint64, r *record) error {
+			if r.flags == recordDelete {
+				deleted = append(deleted, r.hkey)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, hkey := range deleted {
+			if d.Check(hkey) {
+				// The key has been inserted again.
+				continue
+			}
+			r := &record{
+				flags: recordDelete,
+				hkey:  hkey,
+			}
+			ns, _, err := d.write(r)
+			if err != nil {
+				return err
+			}
+			ns.inuse += r.size()
+		}
+	}
+
+	return d.removeSegment(s)
+}
+
+func (d *DiskStore) isCompactionOK(s *segment) bool {
+	return float64(s.garbage) >= float64(s.size)*maxGarbageRatio
+}
+
+// Compaction rewrites the segments which mostly contain garbage and removes them.
+func (d *DiskStore) Compaction() (bool, error) {
+	for i, s := range d.segments {
+		if i == len(d.segments)-1 {
+			// Skip the active segment.
+			break
+		}
+		if d.isCompactionOK(s) {
+			err := d.evictSegment(s)
+			if err != nil {
+				return false, err
+			}
+			// Continue scanning
+			return false, nil
+		}
+	}
+	return true, nil
+}
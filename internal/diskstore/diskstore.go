@@ -0,0 +1,683 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package diskstore implements a persistent storage engine. Every instance writes
+entries to append-only segment files and keeps a hash index of the live entries
+in memory. The index is rebuilt from the segment files on Start, so the data
+survives restarts.
+*/
+package diskstore
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/buraksezer/olric/internal/kvstore/entry"
+	"github.com/buraksezer/olric/pkg/storage"
+)
+
+const (
+	maxGarbageRatio = 0.40
+
+	// Key length is encoded as uint8.
+	maxKeyLength = 256
+
+	// 64MB
+	defaultMaxSegmentSize = uint64(1 << 26)
+)
+
+// location points to a live put record in a segment.
+type location struct {
+	// position is segment ID << 32 | offset of the record in the segment.
+	position   uint64
+	length     uint32
+	lastAccess int64
+}
+
+func newPosition(segmentID, offset uint64) uint64 {
+	return segmentID<<32 | offset
+}
+
+func splitPosition(position uint64) (uint64, uint64) {
+	return position >> 32, position & math.MaxUint32
+}
+
+// DiskStore implements a persistent storage engine.
+type DiskStore struct {
+	dataDir        string
+	dir            string
+	maxSegmentSize uint64
+	syncWrites     bool
+
+	segments     []*segment
+	segmentsByID map[uint64]*segment
+	nextID       uint64
+
+	index       map[uint64]*location
+	positions   map[uint64]uint64
+	offsetIndex *roaring64.Bitmap
+
+	config *storage.Config
+	log    *log.Logger
+}
+
+// DefaultConfig returns the default configuration. dataDir has to be set by the user.
+func DefaultConfig() *storage.Config {
+	options := storage.NewConfig(nil)
+	options.Add("dataDir", "")
+	options.Add("maxSegmentSize", defaultMaxSegmentSize)
+	options.Add("syncWrites", false)
+	return options
+}
+
+func New(c *storage.Config) (*DiskStore, error) {
+	if c == nil {
+		c = DefaultConfig()
+	}
+
+	raw, err := c.Get("dataDir")
+	if err != nil {
+		return nil, err
+	}
+	dataDir, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid type for dataDir: %s", reflect.TypeOf(raw))
+	}
+	if dataDir == "" {
+		return nil, errors.New("dataDir cannot be empty")
+	}
+
+	raw, err = c.Get("maxSegmentSize")
+	if err != nil {
+		return nil, err
+	}
+	size, err := prepareSegmentSize(raw)
+	if err != nil {
+		return nil, err
+	}
+	if size <= headerLength || size > math.MaxUint32 {
+		return nil, fmt.Errorf("maxSegmentSize has to be between %d and %d", headerLength, uint64(math.MaxUint32))
+	}
+
+	var syncWrites bool
+	raw, err = c.Get("syncWrites")
+	if err == nil {
+		syncWrites, ok = raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid type for syncWrites: %s", reflect.TypeOf(raw))
+		}
+	}
+
+	return &DiskStore{
+		dataDir:        dataDir,
+		maxSegmentSize: size,
+		syncWrites:     syncWrites,
+		segmentsByID:   make(map[uint64]*segment),
+		nextID:         1,
+		index:          make(map[uint64]*location),
+		positions:      make(map[uint64]uint64),
+		offsetIndex:    roaring64.New(),
+		config:         c,
+	}, nil
+}
+
+func prepareSegmentSize(raw interface{}) (size uint64, err error) {
+	switch raw.(type) {
+	case uint:
+		size = uint64(raw.(uint))
+	case uint8:
+		size = uint64(raw.(uint8))
+	case uint16:
+		size = uint64(raw.(uint16))
+	case uint32:
+		size = uint64(raw.(uint32))
+	case uint64:
+		size = raw.(uint64)
+	case int:
+		size = uint64(raw.(int))
+	case int8:
+		size = uint64(raw.(int8))
+	case int16:
+		size = uint64(raw.(int16))
+	case int32:
+		size = uint64(raw.(int32))
+	case int64:
+		size = uint64(raw.(int64))
+	default:
+		err = fmt.Errorf("invalid type for maxSegmentSize: %s", reflect.TypeOf(raw))
+		return
+	}
+	return
+}
+
+// fragmentDir returns the directory of a fragment. The layout is
+// <dataDir>/<partitionKind>/<partitionID>/<fragmentName>.
+func fragmentDir(dataDir string, c *storage.Config) (string, error) {
+	name, err := c.Get(storage.FragmentNameKey)
+	if err != nil {
+		return "", err
+	}
+	partID, err := c.Get(storage.PartitionIDKey)
+	if err != nil {
+		return "", err
+	}
+	kind, err := c.Get(storage.PartitionKindKey)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(
+		dataDir,
+		fmt.Sprintf("%v", kind),
+		fmt.Sprintf("%v", partID),
+		url.PathEscape(fmt.Sprintf("%v", name)),
+	), nil
+}
+
+func (d *DiskStore) SetConfig(c *storage.Config) {
+	d.config = c
+}
+
+func (d *DiskStore) SetLogger(l *log.Logger) {
+	d.log = l
+}
+
+func (d *DiskStore) logf(format string, v ...interface{}) {
+	if d.log == nil {
+		return
+	}
+	d.log.Printf(format, v...)
+}
+
+// Start opens the segment files of the instance and rebuilds the index.
+func (d *DiskStore) Start() error {
+	if d.config == nil {
+		return errors.New("config cannot be nil")
+	}
+	if d.dir == "" {
+		return errors.New("storage engine instance has not been forked for a fragment")
+	}
+
+	if err := os.MkdirAll(d.dir, 0700); err != nil {
+		return err
+	}
+
+	ids, err := listSegments(d.dir)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		s, err := openSegment(d.dir, id)
+		if err != nil {
+			return err
+		}
+		d.addSegment(s)
+
+		offset, err := s.replay(func(offset uint64, r *record) error {
+			d.apply(s, offset, r)
+			return nil
+		})
+		if errors.Is(err, errCorruptedRecord) && i == len(ids)-1 {
+			// The last write has not been completed. Drop it.
+			d.logf("[WARN] Truncating segment %s at offset %d: %v", s.file.Name(), offset, err)
+			err = s.truncate(offset)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load segment %s: %w", s.file.Name(), err)
+		}
+	}
+	return nil
+}
+
+// apply updates the index for a record that has been read from a segment.
+func (d *DiskStore) apply(s *segment, offset uint64, r *record) {
+	d.unlink(r.hkey)
+	switch r.flags {
+	case recordPut:
+		e := entry.New()
+		e.Decode(r.payload)
+		d.link(r.hkey, s, offset, uint32(len(r.payload)), e.LastAccess())
+	case recordDelete:
+		s.inuse += r.size()
+	}
+}
+
+func (d *DiskStore) addSegment(s *segment) {
+	d.segments = append(d.segments, s)
+	d.segmentsByID[s.id] = s
+	if s.id >= d.nextID {
+		d.nextID = s.id + 1
+	}
+}
+
+// link adds a put record to the index.
+func (d *DiskStore) link(hkey uint64, s *segment, offset uint64, length uint32, lastAccess int64) {
+	position := newPosition(s.id, offset)
+	d.index[hkey] = &location{
+		position:   position,
+		length:     length,
+		lastAccess: lastAccess,
+	}
+	d.positions[position] = hkey
+	d.offsetIndex.Add(position)
+	s.inuse += headerLength + uint64(length)
+	s.length++
+}
+
+// unlink removes the given hkey from the index and marks its record as garbage.
+func (d *DiskStore) unlink(hkey uint64) {
+	loc, ok := d.index[hkey]
+	if !ok {
+		return
+	}
+	delete(d.index, hkey)
+	delete(d.positions, loc.position)
+	d.offsetIndex.Remove(loc.position)
+
+	id, _ := splitPosition(loc.position)
+	s, ok := d.segmentsByID[id]
+	if !ok {
+		return
+	}
+	size := headerLength + uint64(loc.length)
+	s.inuse -= size
+	s.garbage += size
+	s.length--
+}
+
+// write appends a record to the active segment. It creates a new segment if
+// there is not enough space in the active one.
+func (d *DiskStore) write(r *record) (*segment, uint64, error) {
+	var s *segment
+	if len(d.segments) != 0 {
+		s = d.segments[len(d.segments)-1]
+	}
+	if s == nil || s.size+r.size() > d.maxSegmentSize {
+		ns, err := openSegment(d.dir, d.nextID)
+		if err != nil {
+			return nil, 0, err
+		}
+		d.addSegment(ns)
+		s = ns
+	}
+
+	offset, err := s.write(r, d.syncWrites)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s, offset, nil
+}
+
+// Fork creates a new DiskStore instance for a fragment. The configuration has to
+// contain the fragment keys, see storage.FragmentNameKey.
+func (d *DiskStore) Fork(c *storage.Config) (storage.Engine, error) {
+	if c == nil {
+		c = d.config.Copy()
+	}
+
+	child, err := New(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := fragmentDir(child.dataDir, c)
+	if err != nil {
+		return nil, err
+	}
+	child.dir = dir
+	return child, nil
+}
+
+// Persisted returns configurations of the fragments found in dataDir.
+func (d *DiskStore) Persisted() ([]*storage.Config, error) {
+	kinds, err := ioutil.ReadDir(d.dataDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*storage.Config
+	for _, kind := range kinds {
+		if !kind.IsDir() {
+			continue
+		}
+		kindDir := filepath.Join(d.dataDir, kind.Name())
+		parts, err := ioutil.ReadDir(kindDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			partID, err := strconv.ParseUint(part.Name(), 10, 64)
+			if err != nil || !part.IsDir() {
+				continue
+			}
+			partDir := filepath.Join(kindDir, part.Name())
+			fragments, err := ioutil.ReadDir(partDir)
+			if err != nil {
+				return nil, err
+			}
+			for _, fragment := range fragments {
+				if !fragment.IsDir() {
+					continue
+				}
+				name, err := url.PathUnescape(fragment.Name())
+				if err != nil {
+					continue
+				}
+				ids, err := listSegments(filepath.Join(partDir, fragment.Name()))
+				if err != nil {
+					return nil, err
+				}
+				if len(ids) == 0 {
+					continue
+				}
+				c := d.config.Copy()
+				c.Add(storage.FragmentNameKey, name)
+				c.Add(storage.PartitionIDKey, partID)
+				c.Add(storage.PartitionKindKey, kind.Name())
+				result = append(result, c)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (d *DiskStore) Name() string {
+	return "diskstore"
+}
+
+func (d *DiskStore) NewEntry() storage.Entry {
+	return entry.New()
+}
+
+// PutRaw sets the raw value for the given key.
+func (d *DiskStore) PutRaw(hkey uint64, value []byte) error {
+	if uint64(len(value)+headerLength) > d.maxSegmentSize {
+		return storage.ErrEntryTooLarge
+	}
+
+	e := entry.New()
+	e.Decode(value)
+
+	r := &record{
+		flags:   recordPut,
+		hkey:    hkey,
+		payload: value,
+	}
+	s, offset, err := d.write(r)
+	if err != nil {
+		return err
+	}
+
+	d.unlink(hkey)
+	d.link(hkey, s, offset, uint32(len(value)), e.LastAccess())
+	return nil
+}
+
+// Put sets the value for the given key. It overwrites any previous value for that key
+func (d *DiskStore) Put(hkey uint64, value storage.Entry) error {
+	if len(value.Key()) >= maxKeyLength {
+		return storage.ErrKeyTooLarge
+	}
+	return d.PutRaw(hkey, value.Encode())
+}
+
+func (d *DiskStore) read(loc *location) ([]byte, error) {
+	id, offset := splitPosition(loc.position)
+	s, ok := d.segmentsByID[id]
+	if !ok {
+		return nil, fmt.Errorf("segment not found: %d", id)
+	}
+	return s.readPayload(offset, loc.length)
+}
+
+func (d *DiskStore) getEntry(loc *location) (storage.Entry, error) {
+	raw, err := d.read(loc)
+	if err != nil {
+		return nil, err
+	}
+	e := entry.New()
+	e.Decode(raw)
+	e.SetLastAccess(atomic.LoadInt64(&loc.lastAccess))
+	return e, nil
+}
+
+// GetRaw extracts encoded value for the given hkey. This is useful for merging tables.
+func (d *DiskStore) GetRaw(hkey uint64) ([]byte, error) {
+	loc, ok := d.index[hkey]
+	if !ok {
+		return nil, storage.ErrKeyNotFound
+	}
+	return d.read(loc)
+}
+
+// Get gets the value for the given key. It returns storage.ErrKeyNotFound if the DB
+// does not contain the key. Every call updates the last access time of the entry
+// in memory.
+func (d *DiskStore) Get(hkey uint64) (storage.Entry, error) {
+	loc, ok := d.index[hkey]
+	if !ok {
+		return nil, storage.ErrKeyNotFound
+	}
+
+	e, err := d.getEntry(loc)
+	if err != nil {
+		return nil, err
+	}
+	atomic.StoreInt64(&loc.lastAccess, time.Now().UnixNano())
+	return e, nil
+}
+
+// GetTTL gets the timeout for the given key. It returns storage.ErrKeyNotFound if the DB
+// does not contain the key.
+func (d *DiskStore) GetTTL(hkey uint64) (int64, error) {
+	loc, ok := d.index[hkey]
+	if !ok {
+		return 0, storage.ErrKeyNotFound
+	}
+	e, err := d.getEntry(loc)
+	if err != nil {
+		return 0, err
+	}
+	return e.TTL(), nil
+}
+
+func (d *DiskStore) GetLastAccess(hkey uint64) (int64, error) {
+	loc, ok := d.index[hkey]
+	if !ok {
+		return 0, storage.ErrKeyNotFound
+	}
+	return atomic.LoadInt64(&loc.lastAccess), nil
+}
+
+// GetKey gets the key for the given hkey. It returns storage.ErrKeyNotFound if the DB
+// does not contain the key.
+func (d *DiskStore) GetKey(hkey uint64) (string, error) {
+	loc, ok := d.index[hkey]
+	if !ok {
+		return "", storage.ErrKeyNotFound
+	}
+	e, err := d.getEntry(loc)
+	if err != nil {
+		return "", err
+	}
+	return e.Key(), nil
+}
+
+// Delete deletes the value for the given key. Delete will not returns error if key doesn't exist.
+func (d *DiskStore) Delete(hkey uint64) error {
+	if _, ok := d.index[hkey]; !ok {
+		return nil
+	}
+
+	r := &record{
+		flags: recordDelete,
+		hkey:  hkey,
+	}
+	s, _, err := d.write(r)
+	if err != nil {
+		return err
+	}
+	s.inuse += r.size()
+	d.unlink(hkey)
+	return nil
+}
+
+// UpdateTTL updates the expiry for the given key. It appends a new version of
+// the entry to the active segment.
+func (d *DiskStore) UpdateTTL(hkey uint64, data storage.Entry) error {
+	loc, ok := d.index[hkey]
+	if !ok {
+		return storage.ErrKeyNotFound
+	}
+
+	e, err := d.getEntry(loc)
+	if err != nil {
+		return err
+	}
+	e.SetTTL(data.TTL())
+	e.SetTimestamp(data.Timestamp())
+	e.SetLastAccess(time.Now().UnixNano())
+	return d.PutRaw(hkey, e.Encode())
+}
+
+// Stats is a function which provides disk usage and garbage ratio of a storage instance.
+func (d *DiskStore) Stats() storage.Stats {
+	stats := storage.Stats{
+		NumTables: len(d.segments),
+		Length:    len(d.index),
+	}
+	for _, s := range d.segments {
+		stats.Allocated += int(s.size)
+		stats.Inuse += int(s.inuse)
+		stats.Garbage += int(s.garbage)
+	}
+	return stats
+}
+
+// Check checks the key existence.
+func (d *DiskStore) Check(hkey uint64) bool {
+	_, ok := d.index[hkey]
+	return ok
+}
+
+// Range calls f sequentially for each key and value present in the map.
+// If f returns false, range stops the iteration.
+func (d *DiskStore) Range(f func(hkey uint64, e storage.Entry) bool) {
+	for hkey, loc := range d.index {
+		e, err := d.getEntry(loc)
+		if err != nil {
+			d.logf("[ERROR] Failed to read HKey: %d: %v", hkey, err)
+			continue
+		}
+		if !f(hkey, e) {
+			break
+		}
+	}
+}
+
+// RangeHKey calls f sequentially for each key present in the map.
+// If f returns false, range stops the iteration.
+func (d *DiskStore) RangeHKey(f func(hkey uint64) bool) {
+	for hkey := range d.index {
+		if !f(hkey) {
+			break
+		}
+	}
+}
+
+func (d *DiskStore) scanCommon(cursor uint64, r *regexp.Regexp, count int, f func(e storage.Entry) bool) (uint64, error) {
+	it := d.offsetIndex.Iterator()
+	if cursor != 0 {
+		it.AdvanceIfNeeded(cursor)
+	}
+
+	var num int
+	for it.HasNext() && num < count {
+		position := it.Next()
+		loc := d.index[d.positions[position]]
+		e, err := d.getEntry(loc)
+		if err != nil {
+			return 0, err
+		}
+		if r != nil && !r.MatchString(e.Key()) {
+			continue
+		}
+		if !f(e) {
+			break
+		}
+		cursor = position + 1
+		num++
+	}
+
+	if !it.HasNext() {
+		// end of the scan
+		cursor = 0
+	}
+	return cursor, nil
+}
+
+func (d *DiskStore) Scan(cursor uint64, count int, f func(e storage.Entry) bool) (uint64, error) {
+	return d.scanCommon(cursor, nil, count, f)
+}
+
+func (d *DiskStore) ScanRegexMatch(cursor uint64, expr string, count int, f func(e storage.Entry) bool) (uint64, error) {
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return 0, err
+	}
+	return d.scanCommon(cursor, r, count, f)
+}
+
+// Close closes the segment files. The data stays on disk.
+func (d *DiskStore) Close() error {
+	for _, s := range d.segments {
+		err := s.close()
+		if err != nil && !errors.Is(err, os.ErrClosed) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Destroy closes the segment files and removes the data from disk.
+func (d *DiskStore) Destroy() error {
+	if err := d.Close(); err != nil {
+		return err
+	}
+	if d.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(d.dir)
+}
+
+var (
+	_ storage.Engine     = (*DiskStore)(nil)
+	_ storage.Persistent = (*DiskStore)(nil)
+)
@@ -0,0 +1,423 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diskstore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/kvstore/entry"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func bkey(i int) string {
+	return fmt.Sprintf("%09d", i)
+}
+
+func bval(i int) []byte {
+	return []byte(fmt.Sprintf("%025d", i))
+}
+
+func testConfig(t *testing.T) *storage.Config {
+	dir, err := ioutil.TempDir("", "olric-diskstore")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(dir))
+	})
+
+	c := DefaultConfig()
+	c.Add("dataDir", dir)
+	return c
+}
+
+func testFragmentConfig(c *storage.Config, partID uint64) *storage.Config {
+	fc := c.Copy()
+	fc.Add(storage.FragmentNameKey, "dmap.mydmap")
+	fc.Add(storage.PartitionIDKey, partID)
+	fc.Add(storage.PartitionKindKey, "primary")
+	return fc
+}
+
+func testDiskStore(t *testing.T, c *storage.Config) storage.Engine {
+	if c == nil {
+		c = testConfig(t)
+	}
+	ds, err := New(c)
+	require.NoError(t, err)
+
+	child, err := ds.Fork(testFragmentConfig(c, 0))
+	require.NoError(t, err)
+
+	err = child.Start()
+	require.NoError(t, err)
+
+	return child
+}
+
+func putEntries(t *testing.T, s storage.Engine, num int, timestamp int64) {
+	for i := 0; i < num; i++ {
+		e := entry.New()
+		e.SetKey(bkey(i))
+		e.SetTTL(int64(i))
+		e.SetValue(bval(i))
+		e.SetTimestamp(timestamp)
+		hkey := xxhash.Sum64([]byte(e.Key()))
+		err := s.Put(hkey, e)
+		require.NoError(t, err)
+	}
+}
+
+func TestDiskStore_New_DataDir(t *testing.T) {
+	_, err := New(nil)
+	require.Error(t, err)
+}
+
+func TestDiskStore_Put_Get(t *testing.T) {
+	s := testDiskStore(t, nil)
+
+	timestamp := time.Now().UnixNano()
+	putEntries(t, s, 100, timestamp)
+
+	for i := 0; i < 100; i++ {
+		hkey := xxhash.Sum64([]byte(bkey(i)))
+		e, err := s.Get(hkey)
+		require.NoError(t, err)
+		require.Equal(t, bkey(i), e.Key())
+		require.Equal(t, int64(i), e.TTL())
+		require.Equal(t, bval(i), e.Value())
+		require.Equal(t, timestamp, e.Timestamp())
+	}
+}
+
+func TestDiskStore_Put_Overwrite(t *testing.T) {
+	s := testDiskStore(t, nil)
+
+	putEntries(t, s, 100, time.Now().UnixNano())
+	putEntries(t, s, 100, time.Now().UnixNano())
+
+	stats := s.Stats()
+	require.Equal(t, 100, stats.Length)
+	require.Equal(t, stats.Inuse, stats.Garbage)
+}
+
+func TestDiskStore_Delete(t *testing.T) {
+	s := testDiskStore(t, nil)
+
+	putEntries(t, s, 100, time.Now().UnixNano())
+
+	for i := 0; i < 100; i++ {
+		hkey := xxhash.Sum64([]byte(bkey(i)))
+		err := s.Delete(hkey)
+		require.NoError(t, err)
+
+		_, err = s.Get(hkey)
+		require.ErrorIs(t, err, storage.ErrKeyNotFound)
+	}
+	require.Equal(t, 0, s.Stats().Length)
+}
+
+func TestDiskStore_Reload(t *testing.T) {
+	c := testConfig(t)
+	s := testDiskStore(t, c)
+
+	timestamp := time.Now().UnixNano()
+	putEntries(t, s, 100, timestamp)
+
+	for i := 0; i < 10; i++ {
+		hkey := xxhash.Sum64([]byte(bkey(i)))
+		require.NoError(t, s.Delete(hkey))
+	}
+	require.NoError(t, s.Close())
+
+	fresh := testDiskStore(t, c)
+	require.Equal(t, 90, fresh.Stats().Length)
+
+	for i := 0; i < 100; i++ {
+		hkey := xxhash.Sum64([]byte(bkey(i)))
+		e, err := fresh.Get(hkey)
+		if i < 10 {
+			require.ErrorIs(t, err, storage.ErrKeyNotFound)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, bkey(i), e.Key())
+		require.Equal(t, bval(i), e.Value())
+		require.Equal(t, timestamp, e.Timestamp())
+	}
+}
+
+func TestDiskStore_Reload_Truncate(t *testing.T) {
+	c := testConfig(t)
+	s := testDiskStore(t, c)
+
+	putEntries(t, s, 10, time.Now().UnixNano())
+	ds := s.(*DiskStore)
+	name := ds.segments[0].file.Name()
+	require.NoError(t, s.Close())
+
+	// Simulate a partially written record.
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	fresh := testDiskStore(t, c)
+	require.Equal(t, 10, fresh.Stats().Length)
+
+	putEntries(t, fresh, 20, time.Now().UnixNano())
+	require.NoError(t, fresh.Close())
+
+	fresh = testDiskStore(t, c)
+	require.Equal(t, 20, fresh.Stats().Length)
+}
+
+func TestDiskStore_Persisted(t *testing.T) {
+	c := testConfig(t)
+	ds, err := New(c)
+	require.NoError(t, err)
+
+	for partID := uint64(0); partID < 3; partID++ {
+		child, err := ds.Fork(testFragmentConfig(c, partID))
+		require.NoError(t, err)
+		require.NoError(t, child.Start())
+		putEntries(t, child, 10, time.Now().UnixNano())
+		require.NoError(t, child.Close())
+	}
+
+	configs, err := ds.Persisted()
+	require.NoError(t, err)
+	require.Len(t, configs, 3)
+
+	for _, pc := range configs {
+		name, err := pc.Get(storage.FragmentNameKey)
+		require.NoError(t, err)
+		require.Equal(t, "dmap.mydmap", name)
+
+		child, err := ds.Fork(pc)
+		require.NoError(t, err)
+		require.NoError(t, child.Start())
+		require.Equal(t, 10, child.Stats().Length)
+	}
+}
+
+func TestDiskStore_ExportImport(t *testing.T) {
+	c := testConfig(t)
+	c.Add("maxSegmentSize", 4096)
+	timestamp := time.Now().UnixNano()
+	s := testDiskStore(t, c)
+	putEntries(t, s, 1000, timestamp)
+	require.Greater(t, s.Stats().NumTables, 1)
+
+	fresh := testDiskStore(t, nil)
+
+	ti := s.TransferIterator()
+	for ti.Next() {
+		data, index, err := ti.Export()
+		require.NoError(t, err)
+
+		err = fresh.Import(data, func(u uint64, e storage.Entry) error {
+			return fresh.Put(u, e)
+		})
+		require.NoError(t, err)
+
+		err = ti.Drop(index)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 0, s.Stats().Length)
+
+	ids, err := listSegments(s.(*DiskStore).dir)
+	require.NoError(t, err)
+	require.Len(t, ids, 0)
+
+	for i := 0; i < 1000; i++ {
+		hkey := xxhash.Sum64([]byte(bkey(i)))
+		e, err := fresh.Get(hkey)
+		require.NoError(t, err)
+		require.Equal(t, bkey(i), e.Key())
+		require.Equal(t, int64(i), e.TTL())
+		require.Equal(t, bval(i), e.Value())
+		require.Equal(t, timestamp, e.Timestamp())
+	}
+}
+
+func TestDiskStore_Compaction(t *testing.T) {
+	c := testConfig(t)
+	c.Add("maxSegmentSize", 4096)
+	s := testDiskStore(t, c)
+
+	putEntries(t, s, 1000, time.Now().UnixNano())
+	for i := 0; i < 1000; i++ {
+		if i%2 == 0 {
+			continue
+		}
+		hkey := xxhash.Sum64([]byte(bkey(i)))
+		require.NoError(t, s.Delete(hkey))
+	}
+
+	before := s.Stats()
+	for {
+		done, err := s.Compaction()
+		require.NoError(t, err)
+		if done {
+			break
+		}
+	}
+	after := s.Stats()
+	require.Equal(t, 500, after.Length)
+	require.Less(t, after.Allocated, before.Allocated)
+	require.Less(t, after.Garbage, before.Garbage)
+	require.NoError(t, s.Close())
+
+	// Deleted keys must not come back after compaction.
+	fresh := testDiskStore(t, c)
+	require.Equal(t, 500, fresh.Stats().Length)
+	for i := 0; i < 1000; i++ {
+		hkey := xxhash.Sum64([]byte(bkey(i)))
+		_, err := fresh.Get(hkey)
+		if i%2 == 0 {
+			require.NoError(t, err)
+		} else {
+			require.ErrorIs(t, err, storage.ErrKeyNotFound)
+		}
+	}
+}
+
+func TestDiskStore_UpdateTTL(t *testing.T) {
+	c := testConfig(t)
+	s := testDiskStore(t, c)
+	putEntries(t, s, 10, time.Now().UnixNano())
+
+	timestamp := time.Now().UnixNano()
+	for i := 0; i < 10; i++ {
+		e := entry.New()
+		e.SetTTL(10)
+		e.SetTimestamp(timestamp)
+		hkey := xxhash.Sum64([]byte(bkey(i)))
+		require.NoError(t, s.UpdateTTL(hkey, e))
+	}
+	require.NoError(t, s.Close())
+
+	fresh := testDiskStore(t, c)
+	for i := 0; i < 10; i++ {
+		hkey := xxhash.Sum64([]byte(bkey(i)))
+		ttl, err := fresh.GetTTL(hkey)
+		require.NoError(t, err)
+		require.Equal(t, int64(10), ttl)
+
+		e, err := fresh.Get(hkey)
+		require.NoError(t, err)
+		require.Equal(t, timestamp, e.Timestamp())
+		require.Equal(t, bval(i), e.Value())
+	}
+}
+
+func TestDiskStore_GetLastAccess(t *testing.T) {
+	s := testDiskStore(t, nil)
+	putEntries(t, s, 1, time.Now().UnixNano())
+
+	hkey := xxhash.Sum64([]byte(bkey(0)))
+	before, err := s.GetLastAccess(hkey)
+	require.NoError(t, err)
+
+	_, err = s.Get(hkey)
+	require.NoError(t, err)
+
+	after, err := s.GetLastAccess(hkey)
+	require.NoError(t, err)
+	require.Greater(t, after, before)
+}
+
+func TestDiskStore_Scan(t *testing.T) {
+	c := testConfig(t)
+	c.Add("maxSegmentSize", 1<<16)
+	s := testDiskStore(t, c)
+
+	var key string
+	for i := 0; i < 10000; i++ {
+		if i%2 == 0 {
+			key = "even:" + strconv.Itoa(i)
+		} else {
+			key = "odd:" + strconv.Itoa(i)
+		}
+
+		e := entry.New()
+		e.SetKey(key)
+		e.SetValue(bval(i))
+		hkey := xxhash.Sum64([]byte(e.Key()))
+		require.NoError(t, s.Put(hkey, e))
+	}
+
+	var (
+		count  int
+		cursor uint64
+		err    error
+	)
+	for {
+		cursor, err = s.Scan(cursor, 10, func(e storage.Entry) bool {
+			count++
+			return true
+		})
+		require.NoError(t, err)
+		if cursor == 0 {
+			break
+		}
+	}
+	require.Equal(t, 10000, count)
+
+	count = 0
+	for {
+		cursor, err = s.ScanRegexMatch(cursor, "even:", 10, func(e storage.Entry) bool {
+			count++
+			return true
+		})
+		require.NoError(t, err)
+		if cursor == 0 {
+			break
+		}
+	}
+	require.Equal(t, 5000, count)
+}
+
+func TestDiskStore_Put_ErrEntryTooLarge(t *testing.T) {
+	c := testConfig(t)
+	c.Add("maxSegmentSize", 1024)
+	s := testDiskStore(t, c)
+
+	e := entry.New()
+	e.SetKey(bkey(1))
+	e.SetValue(make([]byte, 1024))
+	err := s.Put(xxhash.Sum64([]byte(e.Key())), e)
+	require.ErrorIs(t, err, storage.ErrEntryTooLarge)
+}
+
+func TestDiskStore_Destroy(t *testing.T) {
+	s := testDiskStore(t, nil)
+	putEntries(t, s, 10, time.Now().UnixNano())
+
+	dir := s.(*DiskStore).dir
+	require.NoError(t, s.Close())
+	require.NoError(t, s.Destroy())
+
+	_, err := os.Stat(filepath.Join(dir, segmentFileName(1)))
+	require.True(t, os.IsNotExist(err))
+}
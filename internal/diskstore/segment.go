@@ -0,0 +1,241 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diskstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	recordPut    = uint8(1)
+	recordDelete = uint8(2)
+
+	// CRC32(uint32) | FLAGS(uint8) | HKEY(uint64) | LENGTH(uint32)
+	headerLength = 17
+
+	segmentExt = ".seg"
+)
+
+var errCorruptedRecord = errors.New("corrupted record")
+
+// On-disk layout for a record:
+//
+// CRC32(uint32) | FLAGS(uint8) | HKEY(uint64) | LENGTH(uint32) | PAYLOAD(bytes)
+//
+// CRC32 covers everything after itself. PAYLOAD is an encoded entry for put
+// records and empty for delete records.
+type record struct {
+	flags   uint8
+	hkey    uint64
+	payload []byte
+}
+
+func (r *record) size() uint64 {
+	return uint64(headerLength + len(r.payload))
+}
+
+func (r *record) encode() []byte {
+	buf := make([]byte, r.size())
+	buf[4] = r.flags
+	binary.BigEndian.PutUint64(buf[5:], r.hkey)
+	binary.BigEndian.PutUint32(buf[13:], uint32(len(r.payload)))
+	copy(buf[headerLength:], r.payload)
+	binary.BigEndian.PutUint32(buf, crc32.ChecksumIEEE(buf[4:]))
+	return buf
+}
+
+// readRecord reads the next record from r. It returns io.EOF if there is no
+// record left and errCorruptedRecord for a partially written or broken record.
+func readRecord(r io.Reader) (*record, error) {
+	header := make([]byte, headerLength)
+	_, err := io.ReadFull(r, header)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, errCorruptedRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[13:])
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, errCorruptedRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h := crc32.NewIEEE()
+	_, _ = h.Write(header[4:])
+	_, _ = h.Write(payload)
+	if h.Sum32() != binary.BigEndian.Uint32(header) {
+		return nil, errCorruptedRecord
+	}
+
+	return &record{
+		flags:   header[4],
+		hkey:    binary.BigEndian.Uint64(header[5:]),
+		payload: payload,
+	}, nil
+}
+
+// segment is an append-only file that stores records.
+type segment struct {
+	id   uint64
+	file *os.File
+
+	// size is the current length of the file.
+	size uint64
+
+	// inuse is the total length of live put records and delete records.
+	inuse uint64
+
+	// garbage is the total length of overwritten or deleted put records.
+	garbage uint64
+
+	// length is the number of live put records.
+	length int
+}
+
+func segmentFileName(id uint64) string {
+	return fmt.Sprintf("%020d%s", id, segmentExt)
+}
+
+func openSegment(dir string, id uint64) (*segment, error) {
+	path := filepath.Join(dir, segmentFileName(id))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &segment{
+		id:   id,
+		file: f,
+		size: uint64(info.Size()),
+	}, nil
+}
+
+// listSegments returns IDs of the segment files in dir in ascending order.
+func listSegments(dir string) ([]uint64, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(file.Name(), segmentExt), 10, 64)
+		if err != nil {
+			// Not a segment file created by us.
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// replay reads all records in the segment and calls f for every record with its offset.
+// It returns the offset of the last valid record and errCorruptedRecord, if the segment
+// ends with a broken record.
+func (s *segment) replay(f func(offset uint64, r *record) error) (uint64, error) {
+	_, err := s.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	var offset uint64
+	reader := bufio.NewReader(s.file)
+	for {
+		r, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		if err = f(offset, r); err != nil {
+			return offset, err
+		}
+		offset += r.size()
+	}
+}
+
+func (s *segment) truncate(size uint64) error {
+	if err := s.file.Truncate(int64(size)); err != nil {
+		return err
+	}
+	s.size = size
+	return nil
+}
+
+func (s *segment) write(r *record, sync bool) (uint64, error) {
+	offset := s.size
+	buf := r.encode()
+	_, err := s.file.WriteAt(buf, int64(offset))
+	if err != nil {
+		return 0, err
+	}
+	s.size += uint64(len(buf))
+	if sync {
+		if err = s.file.Sync(); err != nil {
+			return 0, err
+		}
+	}
+	return offset, nil
+}
+
+func (s *segment) readPayload(offset uint64, length uint32) ([]byte, error) {
+	buf := make([]byte, length)
+	_, err := s.file.ReadAt(buf, int64(offset+headerLength))
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (s *segment) close() error {
+	return s.file.Close()
+}
+
+func (s *segment) remove() error {
+	err := s.file.Close()
+	if err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	return os.Remove(s.file.Name())
+}
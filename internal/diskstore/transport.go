@@ -0,0 +1,94 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diskstore
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/buraksezer/olric/internal/kvstore/entry"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// pack is the transfer format of a segment. It only contains the live entries.
+type pack struct {
+	Entries map[uint64][]byte
+}
+
+type transferIterator struct {
+	storage *DiskStore
+}
+
+func (t *transferIterator) Next() bool {
+	return len(t.storage.segments) != 0
+}
+
+// Drop removes a segment with its live entries. The segment file is deleted.
+func (t *transferIterator) Drop(index int) error {
+	if len(t.storage.segments) == 0 {
+		return fmt.Errorf("there is no segment to drop")
+	}
+
+	s := t.storage.segments[index]
+	for _, hkey := range t.storage.liveHKeys(s, math.MaxInt32) {
+		t.storage.unlink(hkey)
+	}
+	return t.storage.removeSegment(s)
+}
+
+// Export encodes the live entries of the oldest segment.
+func (t *transferIterator) Export() ([]byte, int, error) {
+	s := t.storage.segments[0]
+	p := pack{
+		Entries: make(map[uint64][]byte),
+	}
+	for _, hkey := range t.storage.liveHKeys(s, math.MaxInt32) {
+		raw, err := t.storage.GetRaw(hkey)
+		if err != nil {
+			return nil, 0, err
+		}
+		p.Entries[hkey] = raw
+	}
+
+	data, err := msgpack.Marshal(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, 0, nil
+}
+
+func (d *DiskStore) Import(data []byte, f func(uint64, storage.Entry) error) error {
+	p := &pack{}
+	err := msgpack.Unmarshal(data, p)
+	if err != nil {
+		return err
+	}
+
+	for hkey, raw := range p.Entries {
+		e := entry.New()
+		e.Decode(raw)
+		if err = f(hkey, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DiskStore) TransferIterator() storage.TransferIterator {
+	return &transferIterator{
+		storage: d,
+	}
+}
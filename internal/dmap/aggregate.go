@@ -0,0 +1,260 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// AggregateConfig describes an aggregation. Field and GroupBy are dotted paths
+// in JSON values. Field is optional for COUNT; if it's set, only the entries
+// that have the field are counted. The entries that don't have the GroupBy
+// field are skipped.
+type AggregateConfig struct {
+	Op      string
+	Field   string
+	GroupBy string
+	Where   []protocol.ScanPredicate
+}
+
+func (c *AggregateConfig) validate() error {
+	switch c.Op {
+	case protocol.AggregateCount:
+	case protocol.AggregateSum, protocol.AggregateMin, protocol.AggregateMax:
+		if c.Field == "" {
+			return fmt.Errorf("%w: %s needs a field", protocol.ErrInvalidArgument, c.Op)
+		}
+	default:
+		return fmt.Errorf("%w: unknown operation %s", protocol.ErrInvalidArgument, c.Op)
+	}
+	return nil
+}
+
+// aggregatePartial is the partial result of a group on a member.
+type aggregatePartial struct {
+	Count int64
+	Sum   float64
+	Min   float64
+	Max   float64
+}
+
+func newAggregatePartial() *aggregatePartial {
+	return &aggregatePartial{
+		Min: math.Inf(1),
+		Max: math.Inf(-1),
+	}
+}
+
+func (p *aggregatePartial) add(value float64) {
+	p.Count++
+	p.Sum += value
+	p.Min = math.Min(p.Min, value)
+	p.Max = math.Max(p.Max, value)
+}
+
+func (p *aggregatePartial) merge(other *aggregatePartial) {
+	p.Count += other.Count
+	p.Sum += other.Sum
+	p.Min = math.Min(p.Min, other.Min)
+	p.Max = math.Max(p.Max, other.Max)
+}
+
+func (p *aggregatePartial) result(op string) float64 {
+	switch op {
+	case protocol.AggregateSum:
+		return p.Sum
+	case protocol.AggregateMin:
+		return p.Min
+	case protocol.AggregateMax:
+		return p.Max
+	default:
+		return float64(p.Count)
+	}
+}
+
+func formatAggregateValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func (dm *DMap) aggregateFragment(f *fragment, cfg *AggregateConfig, partials map[string]*aggregatePartial) {
+	f.RLock()
+	defer f.RUnlock()
+
+	f.storage.Range(func(_ uint64, e storage.Entry) bool {
+		if isKeyExpired(e.TTL()) {
+			return true
+		}
+		doc := &entryDocument{value: e.Value()}
+		if len(cfg.Where) > 0 && !matchScanPredicates(e, doc, cfg.Where) {
+			return true
+		}
+
+		var group string
+		if cfg.GroupBy != "" {
+			field, ok := doc.field(cfg.GroupBy)
+			if !ok {
+				return true
+			}
+			group, ok = IndexValue(field)
+			if !ok {
+				return true
+			}
+		}
+
+		var value float64
+		if cfg.Field != "" {
+			field, ok := doc.field(cfg.Field)
+			if !ok {
+				return true
+			}
+			number, ok := field.(float64)
+			if !ok && cfg.Op != protocol.AggregateCount {
+				return true
+			}
+			value = number
+		}
+
+		partial, ok := partials[group]
+		if !ok {
+			partial = newAggregatePartial()
+			partials[group] = partial
+		}
+		partial.add(value)
+		return true
+	})
+}
+
+// aggregateLocal runs the aggregation on the primary partitions owned by this
+// member, and returns the partial results of the groups.
+func (dm *DMap) aggregateLocal(cfg *AggregateConfig) (map[string]*aggregatePartial, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	partials := make(map[string]*aggregatePartial)
+	for partID := uint64(0); partID < dm.s.config.PartitionCount; partID++ {
+		part := dm.s.primary.PartitionByID(partID)
+		if !part.Owner().CompareByID(dm.s.rt.This()) {
+			continue
+		}
+		f, err := dm.loadFragment(part)
+		if errors.Is(err, errFragmentNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dm.aggregateFragment(f, cfg, partials)
+	}
+	return partials, nil
+}
+
+// Aggregate runs the aggregation on the primary fragments of all the members
+// and merges the partial results. It returns the result of every group. The
+// group is an empty string if GroupBy is not set.
+func (dm *DMap) Aggregate(ctx context.Context, cfg *AggregateConfig) (map[string]float64, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	var mtx sync.Mutex
+	partials := make(map[string]*aggregatePartial)
+	mergePartials := func(other map[string]*aggregatePartial) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		for group, partial := range other {
+			current, ok := partials[group]
+			if !ok {
+				partials[group] = partial
+				continue
+			}
+			current.merge(partial)
+		}
+	}
+
+	err := dm.fanOut(ctx, func() error {
+		result, err := dm.aggregateLocal(cfg)
+		if err != nil {
+			return err
+		}
+		mergePartials(result)
+		return nil
+	}, func(ctx context.Context, rc *redis.Client) error {
+		a := protocol.NewAggregateInternal(dm.name, cfg.Op).
+			SetField(cfg.Field).
+			SetGroupBy(cfg.GroupBy)
+		for _, p := range cfg.Where {
+			a.AddWhere(p.Field, p.Op, p.Value)
+		}
+		cmd := a.Command(ctx)
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return protocol.ConvertError(err)
+		}
+		raw, err := cmd.Result()
+		if err != nil {
+			return protocol.ConvertError(err)
+		}
+		result, err := parseAggregatePartials(raw)
+		if err != nil {
+			return err
+		}
+		mergePartials(result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64)
+	for group, partial := range partials {
+		result[group] = partial.result(cfg.Op)
+	}
+	return result, nil
+}
+
+// parseAggregatePartials parses the reply of the AggregateInternal command.
+// Every group has five items: group, count, sum, min and max.
+func parseAggregatePartials(raw []string) (map[string]*aggregatePartial, error) {
+	if len(raw)%5 != 0 {
+		return nil, fmt.Errorf("invalid number of items in partial results: %d", len(raw))
+	}
+
+	partials := make(map[string]*aggregatePartial)
+	for i := 0; i < len(raw); i += 5 {
+		count, err := strconv.ParseInt(raw[i+1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		partial := &aggregatePartial{Count: count}
+		for idx, value := range []*float64{&partial.Sum, &partial.Min, &partial.Max} {
+			*value, err = strconv.ParseFloat(raw[i+2+idx], 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		partials[raw[i]] = partial
+	}
+	return partials, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"strconv"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+func newAggregateConfig(aggregateCmd *protocol.Aggregate) *AggregateConfig {
+	return &AggregateConfig{
+		Op:      aggregateCmd.Op,
+		Field:   aggregateCmd.Field,
+		GroupBy: aggregateCmd.GroupBy,
+		Where:   aggregateCmd.Where,
+	}
+}
+
+func (s *Service) aggregateCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	aggregateCmd, err := protocol.ParseAggregateCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(aggregateCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	result, err := dm.Aggregate(server.RequestContext(conn, s.ctx), newAggregateConfig(aggregateCmd))
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	conn.WriteArray(len(result) * 2)
+	for group, value := range result {
+		conn.WriteBulkString(group)
+		conn.WriteBulkString(formatAggregateValue(value))
+	}
+}
+
+func (s *Service) aggregateInternalCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	aggregateCmd, err := protocol.ParseAggregateCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(aggregateCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	partials, err := dm.aggregateLocal(newAggregateConfig(aggregateCmd))
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	conn.WriteArray(len(partials) * 5)
+	for group, partial := range partials {
+		conn.WriteBulkString(group)
+		conn.WriteBulkString(strconv.FormatInt(partial.Count, 10))
+		conn.WriteBulkString(formatAggregateValue(partial.Sum))
+		conn.WriteBulkString(formatAggregateValue(partial.Min))
+		conn.WriteBulkString(formatAggregateValue(partial.Max))
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_Aggregate(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	// 30 sessions, tenant-0, tenant-1 and tenant-2 have 10 sessions each.
+	for i := 0; i < 30; i++ {
+		value := fmt.Sprintf(`{"tenant": "tenant-%d", "duration": %d, "active": %t}`, i%3, i, i%2 == 0)
+		require.NoError(t, dm1.Put(ctx, testutil.ToKey(i), []byte(value), nil))
+	}
+	require.NoError(t, dm1.Put(ctx, "not-json", []byte("foobar"), nil))
+
+	t.Run("Count", func(t *testing.T) {
+		result, err := dm2.Aggregate(ctx, &AggregateConfig{Op: protocol.AggregateCount})
+		require.NoError(t, err)
+		require.Equal(t, map[string]float64{"": 31}, result)
+	})
+
+	t.Run("Count by tenant", func(t *testing.T) {
+		result, err := dm2.Aggregate(ctx, &AggregateConfig{
+			Op:      protocol.AggregateCount,
+			GroupBy: "tenant",
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]float64{"tenant-0": 10, "tenant-1": 10, "tenant-2": 10}, result)
+	})
+
+	t.Run("Sum, min and max by tenant", func(t *testing.T) {
+		expected := map[string]map[string]float64{
+			protocol.AggregateSum: {"tenant-0": 135, "tenant-1": 145, "tenant-2": 155},
+			protocol.AggregateMin: {"tenant-0": 0, "tenant-1": 1, "tenant-2": 2},
+			protocol.AggregateMax: {"tenant-0": 27, "tenant-1": 28, "tenant-2": 29},
+		}
+		for op, values := range expected {
+			result, err := dm1.Aggregate(ctx, &AggregateConfig{
+				Op:      op,
+				Field:   "duration",
+				GroupBy: "tenant",
+			})
+			require.NoError(t, err)
+			require.Equal(t, values, result, op)
+		}
+	})
+
+	t.Run("Where", func(t *testing.T) {
+		result, err := dm1.Aggregate(ctx, &AggregateConfig{
+			Op:    protocol.AggregateCount,
+			Where: []protocol.ScanPredicate{{Field: "active", Op: protocol.ScanOpEqual, Value: "true"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]float64{"": 15}, result)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := dm1.Aggregate(ctx, &AggregateConfig{Op: protocol.AggregateSum})
+		require.ErrorIs(t, err, protocol.ErrInvalidArgument)
+	})
+}
@@ -15,19 +15,21 @@
 package dmap
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
 	"github.com/tidwall/redcon"
 )
 
-func (s *Service) incrDecrCommon(cmd, dmap, key string, delta int) (int, error) {
+func (s *Service) incrDecrCommon(ctx context.Context, cmd, dmap, key string, delta int) (int, error) {
 	dm, err := s.getOrCreateDMap(dmap)
 	if err != nil {
 		return 0, err
 	}
 
-	e := newEnv(s.ctx)
+	e := newEnv(ctx)
 	e.dmap = dm.name
 	e.key = key
 	return dm.atomicIncrDecr(cmd, e, delta)
@@ -39,7 +41,8 @@ func (s *Service) incrCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 		protocol.WriteError(conn, err)
 		return
 	}
-	latest, err := s.incrDecrCommon(protocol.DMap.Incr, incrCmd.DMap, incrCmd.Key, incrCmd.Delta)
+	ctx := server.RequestContext(conn, s.ctx)
+	latest, err := s.incrDecrCommon(ctx, protocol.DMap.Incr, incrCmd.DMap, incrCmd.Key, incrCmd.Delta)
 	if err != nil {
 		protocol.WriteError(conn, err)
 		return
@@ -53,7 +56,8 @@ func (s *Service) decrCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 		protocol.WriteError(conn, err)
 		return
 	}
-	latest, err := s.incrDecrCommon(protocol.DMap.Decr, decrCmd.DMap, decrCmd.Key, decrCmd.Delta)
+	ctx := server.RequestContext(conn, s.ctx)
+	latest, err := s.incrDecrCommon(ctx, protocol.DMap.Decr, decrCmd.DMap, decrCmd.Key, decrCmd.Delta)
 	if err != nil {
 		protocol.WriteError(conn, err)
 		return
@@ -73,7 +77,7 @@ func (s *Service) getPutCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 		return
 	}
 
-	e := newEnv(s.ctx)
+	e := newEnv(server.RequestContext(conn, s.ctx))
 	e.dmap = getPutCmd.DMap
 	e.key = getPutCmd.Key
 	e.value = getPutCmd.Value
@@ -109,7 +113,7 @@ func (s *Service) incrByFloatCommandHandler(conn redcon.Conn, cmd redcon.Command
 		return
 	}
 
-	e := newEnv(s.ctx)
+	e := newEnv(server.RequestContext(conn, s.ctx))
 	e.dmap = dm.name
 	e.key = incrCmd.Key
 	latest, err := dm.atomicIncrByFloat(e, incrCmd.Delta)
@@ -64,6 +64,7 @@ func (dm *DMap) mergeFragments(part *partitions.Partition, fp *fragmentPack) err
 	f.Lock()
 	defer f.Unlock()
 
+	invalidateIndex(f)
 	return f.storage.Import(fp.Payload, func(hkey uint64, entry storage.Entry) error {
 		return dm.fragmentMergeFunction(f, hkey, entry)
 	})
@@ -0,0 +1,239 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/discovery"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/resp"
+	"github.com/buraksezer/olric/pkg/storage"
+)
+
+// groupByOwner groups the indexes of the given keys by the primary owners of their partitions.
+func (dm *DMap) groupByOwner(keys []string) map[discovery.Member][]int {
+	members := make(map[discovery.Member][]int)
+	for i, key := range keys {
+		hkey := partitions.HKey(dm.name, key)
+		member := dm.s.primary.PartitionByHKey(hkey).Owner()
+		members[member] = append(members[member], i)
+	}
+	return members
+}
+
+// setBatchError sets err for every key in the batch. It's used when the whole
+// request to a partition owner fails.
+func setBatchError(errs []error, indexes []int, err error) {
+	for _, i := range indexes {
+		errs[i] = err
+	}
+}
+
+func (dm *DMap) mgetOnOwner(ctx context.Context, owner discovery.Member, keys []string, indexes []int, entries []storage.Entry, errs []error) {
+	cmd := protocol.NewMGet(dm.name)
+	for _, i := range indexes {
+		cmd.Keys = append(cmd.Keys, keys[i])
+	}
+	mgetCmd := cmd.Command(ctx)
+	rc := dm.s.client.Get(owner.String())
+	err := rc.Process(ctx, mgetCmd)
+	if err != nil {
+		setBatchError(errs, indexes, protocol.ConvertError(err))
+		return
+	}
+
+	values, err := mgetCmd.Result()
+	if err != nil {
+		setBatchError(errs, indexes, protocol.ConvertError(err))
+		return
+	}
+	if len(values) != len(indexes) {
+		setBatchError(errs, indexes, fmt.Errorf("invalid response length from %s: %d != %d", owner, len(values), len(indexes)))
+		return
+	}
+
+	for j, i := range indexes {
+		switch value := values[j].(type) {
+		case nil:
+			errs[i] = ErrKeyNotFound
+		case string:
+			entry := dm.engine.NewEntry()
+			entry.Decode([]byte(value))
+			entries[i] = entry
+		case error:
+			errs[i] = protocol.ConvertError(value)
+		default:
+			errs[i] = fmt.Errorf("invalid response type from %s: %T", owner, value)
+		}
+	}
+}
+
+// mgetOnThisNode gets the keys from this node, and loads the missing ones
+// from the MapStore in a single batch.
+func (dm *DMap) mgetOnThisNode(ctx context.Context, keys []string, indexes []int, entries []storage.Entry, errs []error) {
+	var missing []int
+	for _, i := range indexes {
+		entries[i], errs[i] = dm.getOnCluster(ctx, partitions.HKey(dm.name, keys[i]), keys[i])
+		if errors.Is(errs[i], ErrKeyNotFound) {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 {
+		dm.loadAllFromMapStore(ctx, keys, missing, entries, errs)
+	}
+
+	for _, i := range indexes {
+		if errs[i] == nil {
+			GetHits.Increase(1)
+		} else if errors.Is(errs[i], ErrKeyNotFound) {
+			GetMisses.Increase(1)
+		}
+	}
+}
+
+// MGet gets the values for the given keys. Keys are grouped by their partition owners
+// and every owner is queried in parallel. The returned slices are indexed like keys.
+// It sets ErrKeyNotFound for the missing keys.
+func (dm *DMap) MGet(ctx context.Context, keys ...string) ([]storage.Entry, []error) {
+	entries := make([]storage.Entry, len(keys))
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	for member, indexes := range dm.groupByOwner(keys) {
+		wg.Add(1)
+		go func(member discovery.Member, indexes []int) {
+			defer wg.Done()
+
+			if !member.CompareByName(dm.s.rt.This()) {
+				dm.mgetOnOwner(ctx, member, keys, indexes, entries, errs)
+				return
+			}
+			// We are on the partition owner
+			if dm.config.mapStore == nil {
+				for _, i := range indexes {
+					entries[i], errs[i] = dm.Get(ctx, keys[i])
+				}
+				return
+			}
+			dm.mgetOnThisNode(ctx, keys, indexes, entries, errs)
+		}(member, indexes)
+	}
+	wg.Wait()
+
+	return entries, errs
+}
+
+func (dm *DMap) mputOnOwner(ctx context.Context, owner discovery.Member, keys []string, values [][]byte, indexes []int, errs []error) {
+	cmd := protocol.NewMPut(dm.name)
+	for _, i := range indexes {
+		cmd.Add(keys[i], values[i])
+	}
+	mputCmd := cmd.Command(ctx)
+	rc := dm.s.client.Get(owner.String())
+	err := rc.Process(ctx, mputCmd)
+	if err != nil {
+		setBatchError(errs, indexes, protocol.ConvertError(err))
+		return
+	}
+
+	results, err := mputCmd.Result()
+	if err != nil {
+		setBatchError(errs, indexes, protocol.ConvertError(err))
+		return
+	}
+	if len(results) != len(indexes) {
+		setBatchError(errs, indexes, fmt.Errorf("invalid response length from %s: %d != %d", owner, len(results), len(indexes)))
+		return
+	}
+
+	for j, i := range indexes {
+		if err, ok := results[j].(error); ok {
+			errs[i] = protocol.ConvertError(err)
+		}
+	}
+}
+
+// mput sets the given key/value pairs. values are already encoded. The returned
+// slice is indexed like keys.
+func (dm *DMap) mput(ctx context.Context, keys []string, values [][]byte) []error {
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	for member, indexes := range dm.groupByOwner(keys) {
+		wg.Add(1)
+		go func(member discovery.Member, indexes []int) {
+			defer wg.Done()
+
+			if !member.CompareByName(dm.s.rt.This()) {
+				dm.mputOnOwner(ctx, member, keys, values, indexes, errs)
+				return
+			}
+			// We are on the partition owner
+			for _, i := range indexes {
+				e := newEnv(ctx)
+				e.putConfig = &PutConfig{}
+				e.dmap = dm.name
+				e.key = keys[i]
+				e.value = values[i]
+				errs[i] = dm.put(e)
+			}
+		}(member, indexes)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// MPut sets the given key/value pairs. Keys are grouped by their partition owners
+// and every owner is called in parallel. The returned slice is indexed like keys.
+// It returns ErrInvalidArgument if keys and values have different lengths.
+// It is safe to modify the contents of the arguments after MPut returns but not before.
+func (dm *DMap) MPut(ctx context.Context, keys []string, values []interface{}) ([]error, error) {
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("%w: keys and values must have the same length: %d != %d",
+			protocol.ErrInvalidArgument, len(keys), len(values))
+	}
+
+	errs := make([]error, len(keys))
+	var batchKeys []string
+	var batchValues [][]byte
+	var batchIndexes []int
+	for i, value := range values {
+		valueBuf := pool.Get()
+		enc := resp.New(valueBuf)
+		if err := enc.Encode(value); err != nil {
+			errs[i] = err
+			pool.Put(valueBuf)
+			continue
+		}
+		encoded := make([]byte, valueBuf.Len())
+		copy(encoded, valueBuf.Bytes())
+		pool.Put(valueBuf)
+
+		batchKeys = append(batchKeys, keys[i])
+		batchValues = append(batchValues, encoded)
+		batchIndexes = append(batchIndexes, i)
+	}
+
+	for j, err := range dm.mput(ctx, batchKeys, batchValues) {
+		errs[batchIndexes[j]] = err
+	}
+	return errs, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"errors"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+func (s *Service) mgetCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	mgetCmd, err := protocol.ParseMGetCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(mgetCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	entries, errs := dm.MGet(server.RequestContext(conn, s.ctx), mgetCmd.Keys...)
+	conn.WriteArray(len(entries))
+	for i, entry := range entries {
+		switch {
+		case errors.Is(errs[i], ErrKeyNotFound):
+			conn.WriteNull()
+		case errs[i] != nil:
+			protocol.WriteError(conn, errs[i])
+		default:
+			conn.WriteBulk(entry.Encode())
+		}
+	}
+}
+
+func (s *Service) mputCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	mputCmd, err := protocol.ParseMPutCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(mputCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	errs := dm.mput(server.RequestContext(conn, s.ctx), mputCmd.Keys, mputCmd.Values)
+	conn.WriteArray(len(errs))
+	for _, err := range errs {
+		if err != nil {
+			protocol.WriteError(conn, err)
+			continue
+		}
+		conn.WriteString(protocol.StatusOK)
+	}
+}
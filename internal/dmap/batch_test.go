@@ -0,0 +1,95 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_MPut_MGet_Cluster(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	cluster.AddMember(nil)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+
+	var keys []string
+	var values []interface{}
+	for i := 0; i < 100; i++ {
+		keys = append(keys, testutil.ToKey(i))
+		values = append(values, testutil.ToVal(i))
+	}
+	errs, err := dm1.MPut(ctx, keys, values)
+	require.NoError(t, err)
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	entries, errs := dm2.MGet(ctx, append(keys, "missing-key")...)
+	require.Len(t, entries, 101)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, errs[i])
+		require.Equal(t, testutil.ToVal(i), entries[i].Value())
+	}
+	require.ErrorIs(t, errs[100], ErrKeyNotFound)
+	require.Nil(t, entries[100])
+}
+
+func TestDMap_MPut_Encoding_Error(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	cluster.AddMember(nil)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+
+	errs, err := dm.MPut(ctx, []string{"key-1", "key-2"}, []interface{}{"value-1", struct{}{}})
+	require.NoError(t, err)
+	require.NoError(t, errs[0])
+	// resp encoder doesn't support this type.
+	require.Error(t, errs[1])
+
+	entries, errs := dm.MGet(ctx, "key-1", "key-2")
+	require.NoError(t, errs[0])
+	require.Equal(t, []byte("value-1"), entries[0].Value())
+	require.ErrorIs(t, errs[1], ErrKeyNotFound)
+}
+
+func TestDMap_MPut_Length_Mismatch(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	_, err = dm.MPut(context.Background(), []string{"key-1", "key-2"}, []interface{}{"value-1"})
+	require.ErrorIs(t, err, protocol.ErrInvalidArgument)
+}
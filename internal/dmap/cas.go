@@ -0,0 +1,39 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"errors"
+)
+
+// CompareAndSwap sets the value for the given key if the timestamp of the current
+// entry equals to expected. Timestamps are used as versions, a missing key has
+// version 0. The comparison is done under the fragment lock on the partition owner.
+// It returns false if the versions don't match.
+func (dm *DMap) CompareAndSwap(ctx context.Context, key string, expected int64, value interface{}) (bool, error) {
+	pc := &PutConfig{
+		HasIfVersion: true,
+		IfVersion:    expected,
+	}
+	err := dm.Put(ctx, key, value, pc)
+	if errors.Is(err, ErrVersionMismatch) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"errors"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+func (s *Service) compareAndSwapCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	casCmd, err := protocol.ParseCompareAndSwapCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(casCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	e := newEnv(server.RequestContext(conn, s.ctx))
+	e.putConfig = &PutConfig{
+		HasIfVersion: true,
+		IfVersion:    casCmd.Expected,
+	}
+	e.dmap = casCmd.DMap
+	e.key = casCmd.Key
+	e.value = casCmd.Value
+	err = dm.put(e)
+	if errors.Is(err, ErrVersionMismatch) {
+		conn.WriteInt(0)
+		return
+	}
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(1)
+}
@@ -0,0 +1,83 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_CompareAndSwap(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+
+		// A missing key has version 0.
+		swapped, err := dm1.CompareAndSwap(ctx, key, 0, "value-1")
+		require.NoError(t, err)
+		require.True(t, swapped)
+
+		swapped, err = dm2.CompareAndSwap(ctx, key, 0, "value-2")
+		require.NoError(t, err)
+		require.False(t, swapped)
+
+		e, err := dm2.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, []byte("value-1"), e.Value())
+
+		swapped, err = dm2.CompareAndSwap(ctx, key, e.Timestamp(), "value-2")
+		require.NoError(t, err)
+		require.True(t, swapped)
+
+		// The version has been changed by the previous call.
+		swapped, err = dm1.CompareAndSwap(ctx, key, e.Timestamp(), "value-3")
+		require.NoError(t, err)
+		require.False(t, swapped)
+
+		e, err = dm1.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, []byte("value-2"), e.Value())
+	}
+}
+
+func TestDMap_Put_IfVersion(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	err = dm.Put(ctx, "mykey", "myvalue", nil)
+	require.NoError(t, err)
+
+	err = dm.Put(ctx, "mykey", "myvalue-2", &PutConfig{HasIfVersion: true, IfVersion: 1})
+	require.ErrorIs(t, err, ErrVersionMismatch)
+}
@@ -0,0 +1,359 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// chunkSweepInterval is the period of the sweeps that delete the orphaned
+// chunks. A chunk is deleted if it's orphaned in two consecutive sweeps, so the
+// chunks that are moved to this member before their key aren't deleted.
+const chunkSweepInterval = time.Minute
+
+var errChunkNotFound = errors.New("chunk not found")
+
+// chunk is an entry of the chunks DMap. It keeps a part of the value stored at
+// Key of DMap.
+type chunk struct {
+	DMap string
+	Key  string
+	Data []byte
+}
+
+// chunkStore reads and writes the chunks of the value stored at key. The chunks
+// are stored in the partition of the key, so they're replicated and moved to
+// the new owners with the key. The chunks are decoded once and the modified
+// ones are written back by flush. The caller has to hold the lock of the
+// fragment of the key.
+type chunkStore struct {
+	ctx     context.Context
+	dm      *DMap
+	part    *partitions.Partition
+	dmap    string
+	key     string
+	values  map[string]interface{}
+	dirty   map[string]struct{}
+	removed []string
+}
+
+func (dm *DMap) newChunkStore(ctx context.Context, part *partitions.Partition, key string) (*chunkStore, error) {
+	cdm, err := dm.s.getOrCreateDMap(chunksDMapName)
+	if err != nil {
+		return nil, err
+	}
+	return &chunkStore{
+		ctx:    ctx,
+		dm:     cdm,
+		part:   part,
+		dmap:   dm.name,
+		key:    key,
+		values: make(map[string]interface{}),
+		dirty:  make(map[string]struct{}),
+	}, nil
+}
+
+// load returns the decoded value of the chunk.
+func (c *chunkStore) load(key string, decode func(data []byte) (interface{}, error)) (interface{}, error) {
+	if value, ok := c.values[key]; ok {
+		return value, nil
+	}
+	data, err := c.get(key)
+	if err != nil {
+		return nil, err
+	}
+	value, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	c.values[key] = value
+	return value, nil
+}
+
+// set replaces the value of the chunk. The value is encoded with msgpack.
+func (c *chunkStore) set(key string, value interface{}) {
+	c.values[key] = value
+	c.dirty[key] = struct{}{}
+}
+
+// remove deletes the chunk after the value that refers to it is stored. See
+// deleteRemoved.
+func (c *chunkStore) remove(key string) {
+	delete(c.values, key)
+	delete(c.dirty, key)
+	c.removed = append(c.removed, key)
+}
+
+// flush writes the modified chunks.
+func (c *chunkStore) flush() error {
+	for key := range c.dirty {
+		data, err := msgpack.Marshal(c.values[key])
+		if err != nil {
+			return err
+		}
+		if err = c.put(key, data); err != nil {
+			return err
+		}
+	}
+	c.dirty = make(map[string]struct{})
+	return nil
+}
+
+// deleteRemoved deletes the removed chunks. It's called after storing the
+// value, so the stored value never refers to a deleted chunk.
+func (c *chunkStore) deleteRemoved() error {
+	for _, key := range c.removed {
+		if err := c.delete(key); err != nil {
+			return err
+		}
+	}
+	c.removed = nil
+	return nil
+}
+
+// newKey returns a chunk key that starts with prefix and belongs to the
+// partition of the store. The prefix has to be unique.
+func (c *chunkStore) newKey(prefix string) string {
+	return c.dm.s.colocatedKey(chunksDMapName, prefix, c.part.ID())
+}
+
+func (c *chunkStore) get(key string) ([]byte, error) {
+	f, err := c.dm.loadFragment(c.part)
+	if errors.Is(err, errFragmentNotFound) {
+		return nil, errChunkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.RLock()
+	defer f.RUnlock()
+
+	entry, err := f.storage.Get(partitions.HKey(chunksDMapName, key))
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, errChunkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ch chunk
+	if err = msgpack.Unmarshal(entry.Value(), &ch); err != nil {
+		return nil, err
+	}
+	return ch.Data, nil
+}
+
+func (c *chunkStore) put(key string, data []byte) error {
+	value, err := msgpack.Marshal(&chunk{
+		DMap: c.dmap,
+		Key:  c.key,
+		Data: data,
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := c.dm.loadOrCreateFragment(c.part)
+	if err != nil {
+		return err
+	}
+
+	e := newEnv(c.ctx)
+	e.dmap = chunksDMapName
+	e.key = key
+	e.hkey = partitions.HKey(chunksDMapName, key)
+	e.value = value
+	e.fragment = f
+
+	f.Lock()
+	defer f.Unlock()
+
+	return c.dm.putOnLockedFragment(e)
+}
+
+func (c *chunkStore) delete(key string) error {
+	f, err := c.dm.loadFragment(c.part)
+	if errors.Is(err, errFragmentNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	hkey := partitions.HKey(chunksDMapName, key)
+	if !f.storage.Check(hkey) {
+		return nil
+	}
+	return c.dm.deleteOnCluster(hkey, key, f)
+}
+
+// referencesChunk returns true if the value refers to the chunk.
+func referencesChunk(value []byte, key string) bool {
+	switch {
+	case bytes.HasPrefix(value, listPrefix):
+		meta, err := decodeListMeta(value)
+		if err != nil {
+			return false
+		}
+		for _, c := range meta.Chunks {
+			if c.Key == key {
+				return true
+			}
+		}
+	case bytes.HasPrefix(value, streamPrefix):
+		s, err := decodeStream(value)
+		if err != nil {
+			return false
+		}
+		for _, c := range s.Chunks {
+			if c.Key == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type chunkRef struct {
+	hkey uint64
+	key  string
+	dmap string
+	of   string
+}
+
+// isChunkReferenced checks whether the key of the chunk still refers to it.
+// The caller has to hold the lock of the fragment of the key.
+func isChunkReferenced(f *fragment, ref *chunkRef) bool {
+	entry, err := f.storage.Get(partitions.HKey(ref.dmap, ref.of))
+	if err != nil || isKeyExpired(entry.TTL()) {
+		return false
+	}
+	return referencesChunk(entry.Value(), ref.key)
+}
+
+// sweepChunk deletes the chunk if it's orphaned and suspected, and returns true
+// if it's orphaned.
+func (s *Service) sweepChunk(part *partitions.Partition, cdm *DMap, cf *fragment, ref *chunkRef, suspected bool) (bool, error) {
+	// Lock the fragment of the key first, like the writers.
+	if tmp, ok := part.Map().Load(s.fragmentName(ref.dmap)); ok {
+		f := tmp.(*fragment)
+		f.RLock()
+		defer f.RUnlock()
+
+		if isChunkReferenced(f, ref) {
+			return false, nil
+		}
+	}
+	if !suspected {
+		return true, nil
+	}
+
+	cf.Lock()
+	defer cf.Unlock()
+
+	if !cf.storage.Check(ref.hkey) {
+		return true, nil
+	}
+	return true, cdm.deleteOnCluster(ref.hkey, ref.key, cf)
+}
+
+func (s *Service) sweepPartitionChunks(part *partitions.Partition, cdm *DMap, suspects, orphans map[uint64]struct{}) {
+	tmp, ok := part.Map().Load(cdm.fragmentName)
+	if !ok {
+		return
+	}
+	cf := tmp.(*fragment)
+
+	var refs []*chunkRef
+	cf.RLock()
+	cf.storage.Range(func(hkey uint64, e storage.Entry) bool {
+		var ch chunk
+		if err := msgpack.Unmarshal(e.Value(), &ch); err != nil {
+			s.log.V(3).Printf("[ERROR] Failed to decode chunk: %s: %v", e.Key(), err)
+			return true
+		}
+		refs = append(refs, &chunkRef{
+			hkey: hkey,
+			key:  e.Key(),
+			dmap: ch.DMap,
+			of:   ch.Key,
+		})
+		return true
+	})
+	cf.RUnlock()
+
+	for _, ref := range refs {
+		_, suspected := suspects[ref.hkey]
+		orphaned, err := s.sweepChunk(part, cdm, cf, ref, suspected)
+		if err != nil {
+			s.log.V(3).Printf("[ERROR] Failed to delete orphaned chunk: %s: %v", ref.key, err)
+			continue
+		}
+		if orphaned && !suspected {
+			orphans[ref.hkey] = struct{}{}
+		}
+	}
+}
+
+// sweepChunks deletes the suspected chunks that are still orphaned, and
+// returns the newly orphaned ones. The chunks are orphaned when their keys are
+// deleted, overwritten, evicted or expired.
+func (s *Service) sweepChunks(suspects map[uint64]struct{}) map[uint64]struct{} {
+	orphans := make(map[uint64]struct{})
+	cdm, err := s.getDMap(chunksDMapName)
+	if errors.Is(err, ErrDMapNotFound) {
+		return orphans
+	}
+	if err != nil {
+		s.log.V(3).Printf("[ERROR] Failed to load DMap: %s: %v", chunksDMapName, err)
+		return orphans
+	}
+
+	for partID := uint64(0); partID < s.config.PartitionCount; partID++ {
+		part := s.primary.PartitionByID(partID)
+		if !part.Owner().CompareByName(s.rt.This()) {
+			continue
+		}
+		s.sweepPartitionChunks(part, cdm, suspects, orphans)
+	}
+	return orphans
+}
+
+func (s *Service) chunkSweepWorker() {
+	defer s.wg.Done()
+	timer := time.NewTimer(chunkSweepInterval)
+	defer timer.Stop()
+
+	suspects := make(map[uint64]struct{})
+	for {
+		timer.Reset(chunkSweepInterval)
+		select {
+		case <-timer.C:
+			suspects = s.sweepChunks(suspects)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
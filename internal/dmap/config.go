@@ -30,9 +30,18 @@ type dmapConfig struct {
 	maxInuse        int
 	lruSamples      int
 	evictionPolicy  config.EvictionPolicy
+	keyspaceEvents  map[config.KeyspaceEvent]struct{}
+	indexes         []config.Index
+	mapStore        *config.MapStore
 }
 
 func (c *dmapConfig) load(dc *config.DMaps, name string) error {
+	if IsReservedDMap(name) {
+		// Reserved DMaps only inherit the storage engine.
+		c.engine = dc.Engine
+		return nil
+	}
+
 	// Try to set config configuration for this dmap.
 	c.maxIdleDuration = dc.MaxIdleDuration
 	c.ttlDuration = dc.TTLDuration
@@ -41,6 +50,7 @@ func (c *dmapConfig) load(dc *config.DMaps, name string) error {
 	c.lruSamples = dc.LRUSamples
 	c.evictionPolicy = dc.EvictionPolicy
 	c.engine = dc.Engine
+	keyspaceEvents := dc.KeyspaceNotifications
 
 	if dc.Custom != nil {
 		// config.DMap struct can be used for fine-grained control.
@@ -70,6 +80,19 @@ func (c *dmapConfig) load(dc *config.DMaps, name string) error {
 			if c.engine == nil {
 				c.engine = cs.Engine
 			}
+			if cs.KeyspaceNotifications != nil {
+				keyspaceEvents = cs.KeyspaceNotifications
+			}
+			// Indexes and MapStores are declared per DMap.
+			c.indexes = cs.Indexes
+			c.mapStore = cs.MapStore
+		}
+	}
+
+	if len(keyspaceEvents) != 0 {
+		c.keyspaceEvents = make(map[config.KeyspaceEvent]struct{})
+		for _, event := range keyspaceEvents {
+			c.keyspaceEvents[event] = struct{}{}
 		}
 	}
 
@@ -76,3 +76,24 @@ func TestDMap_Config(t *testing.T) {
 		require.Equal(t, c.DMaps.Custom["foobar"].Engine, dcc.engine)
 	})
 }
+
+func TestDMap_Config_KeyspaceNotifications(t *testing.T) {
+	c := config.New("local")
+	c.DMaps.KeyspaceNotifications = []config.KeyspaceEvent{config.KeyspaceEventPut}
+	c.DMaps.Custom = map[string]config.DMap{"foobar": {
+		KeyspaceNotifications: []config.KeyspaceEvent{config.KeyspaceEventDel, config.KeyspaceEventEvicted},
+	}}
+
+	dc := dmapConfig{}
+	require.NoError(t, dc.load(c.DMaps, "mydmap"))
+	require.Equal(t, map[config.KeyspaceEvent]struct{}{
+		config.KeyspaceEventPut: {},
+	}, dc.keyspaceEvents)
+
+	dcc := dmapConfig{}
+	require.NoError(t, dcc.load(c.DMaps, "foobar"))
+	require.Equal(t, map[config.KeyspaceEvent]struct{}{
+		config.KeyspaceEventDel:     {},
+		config.KeyspaceEventEvicted: {},
+	}, dcc.keyspaceEvents)
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/buraksezer/olric/config"
 	"github.com/buraksezer/olric/internal/cluster/partitions"
 	"github.com/buraksezer/olric/internal/discovery"
 	"github.com/buraksezer/olric/internal/protocol"
@@ -48,6 +49,12 @@ func (dm *DMap) deleteFromFragment(key string, kind partitions.Kind) error {
 	f.Lock()
 	defer f.Unlock()
 
+	if kind == partitions.PRIMARY {
+		if err = dm.logDelete(key); err != nil {
+			return err
+		}
+		dm.unindexEntry(f, hkey)
+	}
 	return f.storage.Delete(hkey)
 }
 
@@ -107,10 +114,16 @@ func (dm *DMap) deleteOnCluster(hkey uint64, key string, f *fragment) error {
 		}
 	}
 
+	err = dm.logDelete(key)
+	if err != nil {
+		return err
+	}
+
 	err = f.storage.Delete(hkey)
 	if err != nil {
 		return err
 	}
+	dm.unindexEntry(f, hkey)
 
 	// DeleteHits is the number of deletion reqs resulting in an item being removed.
 	DeleteHits.Increase(1)
@@ -119,6 +132,14 @@ func (dm *DMap) deleteOnCluster(hkey uint64, key string, f *fragment) error {
 }
 
 func (dm *DMap) deleteKey(key string) error {
+	if err := dm.deleteKeyOnFragment(key); err != nil {
+		return err
+	}
+	// The MapStore is written after the fragment lock is released.
+	return dm.flushMapStore(key)
+}
+
+func (dm *DMap) deleteKeyOnFragment(key string) error {
 	hkey := partitions.HKey(dm.name, key)
 	part := dm.getPartitionByHKey(hkey, partitions.PRIMARY)
 	f, err := dm.loadOrCreateFragment(part)
@@ -130,13 +151,22 @@ func (dm *DMap) deleteKey(key string) error {
 	defer f.Unlock()
 
 	// Check the HKey before trying to delete it.
-	if !f.storage.Check(hkey) {
+	if f.storage.Check(hkey) {
+		err = dm.deleteOnCluster(hkey, key, f)
+		if err != nil {
+			return err
+		}
+		dm.notifyKeyspaceEvent(config.KeyspaceEventDel, key)
+	} else {
 		// DeleteMisses is the number of deletions reqs for missing keys
 		DeleteMisses.Increase(1)
-		return nil
 	}
 
-	return dm.deleteOnCluster(hkey, key, f)
+	// The key may only exist on the MapStore. It's deleted from the MapStore
+	// after the cluster, so a failed delete doesn't leave the key only in the
+	// cluster.
+	dm.deleteOnMapStore(key)
+	return nil
 }
 
 func (dm *DMap) deleteKeys(ctx context.Context, keys ...string) (int, error) {
@@ -155,7 +185,7 @@ func (dm *DMap) deleteKeys(ctx context.Context, keys ...string) (int, error) {
 				}
 			}
 		} else {
-			cmd := protocol.NewDel(dm.name, distributedKeys...).Command(dm.s.ctx)
+			cmd := protocol.NewDel(dm.name, distributedKeys...).Command(ctx)
 			rc := dm.s.client.Get(member.String())
 			err := rc.Process(ctx, cmd)
 			if err != nil {
@@ -20,6 +20,7 @@ import (
 	"github.com/buraksezer/olric/config"
 	"github.com/buraksezer/olric/internal/cluster/partitions"
 	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
 	"github.com/tidwall/redcon"
 )
 
@@ -36,6 +37,10 @@ func (dm *DMap) destroyFragmentOnPartition(part *partitions.Partition) error {
 }
 
 func (s *Service) destroyLocalDMap(name string) error {
+	if err := s.logDestroy(name); err != nil {
+		return err
+	}
+
 	// This is very similar with rm -rf. Destroys given dmap on the cluster
 	for partID := uint64(0); partID < s.config.PartitionCount; partID++ {
 		dm, err := s.getDMap(name)
@@ -63,9 +68,15 @@ func (s *Service) destroyLocalDMap(name string) error {
 	}
 
 	s.Lock()
+	dm, ok := s.dmaps[name]
 	delete(s.dmaps, name)
 	s.Unlock()
 
+	if ok && dm.writeBehind != nil {
+		// Stop the write-behind worker. The next instance of the DMap starts
+		// its own worker.
+		dm.writeBehind.close()
+	}
 	return nil
 }
 
@@ -85,7 +96,7 @@ func (s *Service) destroyCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 	if destroyCmd.Local {
 		err = s.destroyLocalDMap(destroyCmd.DMap)
 	} else {
-		err = dm.destroyOnCluster(s.ctx)
+		err = dm.destroyOnCluster(server.RequestContext(conn, s.ctx))
 	}
 
 	if err != nil {
@@ -19,8 +19,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/buraksezer/olric/config"
 	"github.com/buraksezer/olric/internal/cluster/partitions"
 	"github.com/buraksezer/olric/pkg/storage"
+	"golang.org/x/sync/singleflight"
 )
 
 const nilTimeout = 0 * time.Second
@@ -39,6 +41,9 @@ type DMap struct {
 	s            *Service
 	engine       storage.Engine
 	config       *dmapConfig
+	loader       singleflight.Group
+	writeBehind  *mapStoreQueue
+	writeThrough *mapStoreQueue
 }
 
 // Name exposes name of the DMap.
@@ -82,6 +87,12 @@ func (s *Service) NewDMap(name string) (*DMap, error) {
 	s.Lock()
 	defer s.Unlock()
 
+	return s.newDMap(name)
+}
+
+// newDMap creates and returns a new DMap instance without any check. The caller
+// has to hold the lock.
+func (s *Service) newDMap(name string) (*DMap, error) {
 	dm, ok := s.dmaps[name]
 	if ok {
 		return dm, nil
@@ -100,6 +111,17 @@ func (s *Service) NewDMap(name string) (*DMap, error) {
 	// It's a shortcut.
 	dm.engine = dm.config.engine.Implementation
 	s.dmaps[name] = dm
+
+	if dm.config.mapStore != nil {
+		// The write-behind changes are written directly after the DMap is
+		// destroyed, so every DMap with a MapStore needs this queue.
+		dm.writeThrough = newMapStoreQueue()
+	}
+	if dm.config.mapStore != nil && dm.config.mapStore.WriteMode == config.WriteBehind {
+		dm.writeBehind = newMapStoreQueue()
+		s.wg.Add(1)
+		go dm.writeBehindWorker()
+	}
 	return dm, nil
 }
 
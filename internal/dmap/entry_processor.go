@@ -0,0 +1,202 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNoSuchProcessor is returned if the entry processor is not registered on
+// the partition owner.
+var ErrNoSuchProcessor = errors.New("no such entry processor")
+
+// mutableEntry implements config.MutableEntry. It records the changes, they
+// are applied after the processor returns.
+type mutableEntry struct {
+	key     string
+	exists  bool
+	value   []byte
+	ttl     int64 // in milliseconds, absolute
+	changed bool
+	deleted bool
+}
+
+var _ config.MutableEntry = (*mutableEntry)(nil)
+
+func (m *mutableEntry) Key() string {
+	return m.key
+}
+
+func (m *mutableEntry) Exists() bool {
+	return m.exists
+}
+
+func (m *mutableEntry) Value() []byte {
+	return m.value
+}
+
+func (m *mutableEntry) SetValue(value []byte) {
+	m.value = value
+	m.exists = true
+	m.changed = true
+	m.deleted = false
+}
+
+func (m *mutableEntry) TTL() time.Duration {
+	if m.ttl == 0 {
+		return 0
+	}
+	remaining := m.ttl - time.Now().UnixNano()/1000000
+	if remaining <= 0 {
+		// Expires right now
+		return time.Millisecond
+	}
+	return time.Duration(remaining) * time.Millisecond
+}
+
+func (m *mutableEntry) SetTTL(ttl time.Duration) {
+	m.ttl = 0
+	if ttl > 0 {
+		m.ttl = (time.Now().UnixNano() + ttl.Nanoseconds()) / 1000000
+	}
+	m.changed = true
+}
+
+func (m *mutableEntry) Delete() {
+	m.value = nil
+	m.exists = false
+	m.deleted = true
+}
+
+func runEntryProcessor(processor config.EntryProcessor, entry *mutableEntry, args []byte) (result []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("entry processor panicked: %v", r)
+		}
+	}()
+	return processor(entry, args)
+}
+
+// executeOnOwner runs the entry processor under the fragment lock. It has to
+// be called on the partition owner. The changes are replicated like Put and
+// Delete.
+func (dm *DMap) executeOnOwner(ctx context.Context, key string, processor config.EntryProcessor, args []byte) ([]byte, error) {
+	e := newEnv(ctx)
+	e.dmap = dm.name
+	e.key = key
+	e.hkey = partitions.HKey(dm.name, key)
+
+	result, err := dm.executeOnFragment(e, processor, args)
+	if err != nil {
+		return nil, err
+	}
+	// The MapStore is written after the fragment lock is released.
+	if err = dm.flushMapStore(key); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (dm *DMap) executeOnFragment(e *env, processor config.EntryProcessor, args []byte) ([]byte, error) {
+	part := dm.getPartitionByHKey(e.hkey, partitions.PRIMARY)
+	f, err := dm.loadOrCreateFragment(part)
+	if err != nil {
+		return nil, err
+	}
+
+	e.fragment = f
+	f.Lock()
+	defer f.Unlock()
+
+	key := e.key
+	entry := &mutableEntry{key: key}
+	current, err := f.storage.Get(e.hkey)
+	switch {
+	case errors.Is(err, storage.ErrKeyNotFound):
+	case err != nil:
+		return nil, err
+	case isKeyExpired(current.TTL()):
+	default:
+		entry.exists = true
+		entry.value = make([]byte, len(current.Value()))
+		copy(entry.value, current.Value())
+		entry.ttl = current.TTL()
+	}
+	existed := entry.exists
+
+	result, err := runEntryProcessor(processor, entry, args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case entry.deleted:
+		if !existed {
+			return result, nil
+		}
+		if err = dm.deleteEntry(e.hkey, key, f); err != nil {
+			return nil, err
+		}
+		dm.notifyKeyspaceEvent(config.KeyspaceEventDel, key)
+	case entry.changed && entry.exists:
+		e.value = entry.value
+		if e.value == nil {
+			e.value = []byte{}
+		}
+		if entry.ttl != 0 {
+			e.putConfig.HasPXAT = true
+			e.putConfig.PXAT = time.Duration(entry.ttl) * time.Millisecond
+		}
+		if err = dm.putOnLockedFragment(e); err != nil {
+			return nil, err
+		}
+		dm.notifyKeyspaceEvent(config.KeyspaceEventPut, key)
+	}
+	return result, nil
+}
+
+// Execute runs the registered entry processor on the partition owner of the
+// key, and returns its result. The entry processor reads and modifies the
+// entry atomically, so it replaces Lock, Get, Put and Unlock cycles.
+func (dm *DMap) Execute(ctx context.Context, key, processor string, args []byte) ([]byte, error) {
+	member, isThis := dm.keyOwner(key)
+	if !isThis {
+		cmd := protocol.NewExecute(dm.name, key, processor).SetArgs(args).Command(ctx)
+		rc := dm.s.client.Get(member.String())
+		err := rc.Process(ctx, cmd)
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, protocol.ConvertError(err)
+		}
+		return cmd.Bytes()
+	}
+
+	fn, ok := dm.s.config.EntryProcessors[processor]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchProcessor, processor)
+	}
+	return dm.executeOnOwner(ctx, key, fn, args)
+}
@@ -0,0 +1,46 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+func (s *Service) executeCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	executeCmd, err := protocol.ParseExecuteCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(executeCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	result, err := dm.Execute(server.RequestContext(conn, s.ctx), executeCmd.Key, executeCmd.Processor, executeCmd.Args)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	if result == nil {
+		conn.WriteNull()
+		return
+	}
+	conn.WriteBulk(result)
+}
@@ -0,0 +1,143 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newEntryProcessorTestConfig() *config.Config {
+	c := testutil.NewConfig()
+	c.EntryProcessors = map[string]config.EntryProcessor{
+		// incr adds args to the value, and returns the new value.
+		"incr": func(entry config.MutableEntry, args []byte) ([]byte, error) {
+			var current int
+			if entry.Exists() {
+				var err error
+				current, err = strconv.Atoi(string(entry.Value()))
+				if err != nil {
+					return nil, err
+				}
+			}
+			delta, err := strconv.Atoi(string(args))
+			if err != nil {
+				return nil, err
+			}
+			value := []byte(strconv.Itoa(current + delta))
+			entry.SetValue(value)
+			return value, nil
+		},
+		"expire": func(entry config.MutableEntry, args []byte) ([]byte, error) {
+			ttl, err := time.ParseDuration(string(args))
+			if err != nil {
+				return nil, err
+			}
+			entry.SetTTL(ttl)
+			return nil, nil
+		},
+		"delete": func(entry config.MutableEntry, _ []byte) ([]byte, error) {
+			entry.Delete()
+			return nil, nil
+		},
+		"fail": func(config.MutableEntry, []byte) ([]byte, error) {
+			return nil, errors.New("processor failed")
+		},
+		"panic": func(config.MutableEntry, []byte) ([]byte, error) {
+			panic("boom")
+		},
+	}
+	return c
+}
+
+func TestDMap_Execute(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(testcluster.NewEnvironment(newEntryProcessorTestConfig())).(*Service)
+	s2 := cluster.AddMember(testcluster.NewEnvironment(newEntryProcessorTestConfig())).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	t.Run("Read and write", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			key := testutil.ToKey(i)
+			for j := 1; j <= 4; j++ {
+				dm := dm1
+				if j%2 == 0 {
+					dm = dm2
+				}
+				result, err := dm.Execute(ctx, key, "incr", []byte("2"))
+				require.NoError(t, err)
+				require.Equal(t, strconv.Itoa(j*2), string(result))
+			}
+
+			gr, err := dm1.Get(ctx, key)
+			require.NoError(t, err)
+			require.Equal(t, []byte("8"), gr.Value())
+		}
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			key := testutil.ToKey(i)
+			_, err := dm2.Execute(ctx, key, "expire", []byte("1h"))
+			require.NoError(t, err)
+
+			gr, err := dm1.Get(ctx, key)
+			require.NoError(t, err)
+			require.NotZero(t, gr.TTL())
+			require.Equal(t, []byte("8"), gr.Value())
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			key := testutil.ToKey(i)
+			_, err := dm1.Execute(ctx, key, "delete", nil)
+			require.NoError(t, err)
+
+			_, err = dm2.Get(ctx, key)
+			require.ErrorIs(t, err, ErrKeyNotFound)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			key := testutil.ToKey(i)
+			for _, dm := range []*DMap{dm1, dm2} {
+				_, err := dm.Execute(ctx, key, "no-such-processor", nil)
+				require.ErrorIs(t, err, ErrNoSuchProcessor)
+
+				_, err = dm.Execute(ctx, key, "fail", nil)
+				require.EqualError(t, err, "processor failed")
+
+				_, err = dm.Execute(ctx, key, "panic", nil)
+				require.EqualError(t, err, "entry processor panicked: boom")
+			}
+		}
+	})
+}
@@ -32,6 +32,9 @@ type env struct {
 	timeout   time.Duration
 	kind      partitions.Kind
 	fragment  *fragment
+	// loaded is true if the value is loaded from the MapStore, so it's not
+	// written back.
+	loaded bool
 }
 
 func newEnv(ctx context.Context) *env {
@@ -0,0 +1,383 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/redis/go-redis/v9"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// scriptTimeout is the longest period that a script runs. The partition of
+// the script is locked until it returns.
+const scriptTimeout = 5 * time.Second
+
+const (
+	// scriptCallStackSize is the maximum depth of the function calls in a
+	// script.
+	scriptCallStackSize = 200
+
+	// scriptRegistrySize is the initial size of the data stack of a script.
+	// It grows up to scriptRegistryMaxSize.
+	scriptRegistrySize    = 1024
+	scriptRegistryMaxSize = 64 * 1024
+
+	// scriptMaxRepSize is the maximum length of a string that is created by
+	// string.rep.
+	scriptMaxRepSize = 1 << 20
+)
+
+var (
+	// ErrScript is returned if a script cannot be compiled or it fails.
+	ErrScript = errors.New("script error")
+
+	// ErrCrossPartition is returned if the keys of a script belong to
+	// different partitions.
+	ErrCrossPartition = errors.New("keys belong to different partitions")
+)
+
+// typedValuePrefix is the common prefix of the hashes, lists, streams and
+// the other typed values.
+var typedValuePrefix = []byte("\x00olric.")
+
+// ConvertScriptError converts the error returned by the partition owner.
+// Unlike protocol.ConvertError, it keeps the message of the script errors.
+func ConvertScriptError(err error) error {
+	if err == nil {
+		return nil
+	}
+	parsed := strings.SplitN(err.Error(), " ", 2)
+	if len(parsed) == 2 && protocol.GetError(parsed[0]) == ErrScript {
+		return fmt.Errorf("%w%s", ErrScript, strings.TrimPrefix(parsed[1], ErrScript.Error()))
+	}
+	return protocol.ConvertError(err)
+}
+
+// scriptLibs are the Lua libraries available to the scripts. The scripts
+// cannot access the filesystem or the operating system.
+var scriptLibs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// scriptEnv runs a script on a locked fragment.
+type scriptEnv struct {
+	ctx    context.Context
+	dm     *DMap
+	partID uint64
+	f      *fragment
+	// err is the error returned by an olric.* function. It's returned
+	// instead of ErrScript.
+	err error
+	// changed is the keys that are changed by the script. Their changes are
+	// written to the MapStore after the fragment lock is released.
+	changed []string
+}
+
+func (s *scriptEnv) hkey(L *lua.LState, key string) uint64 {
+	hkey := partitions.HKey(s.dm.name, key)
+	if s.dm.s.primary.PartitionIDByHKey(hkey) != s.partID {
+		s.raise(L, ErrCrossPartition)
+	}
+	return hkey
+}
+
+func (s *scriptEnv) raise(L *lua.LState, err error) {
+	s.err = err
+	L.RaiseError("%s", err.Error())
+}
+
+// get implements olric.get(key). It returns nil if the key doesn't exist.
+func (s *scriptEnv) get(L *lua.LState) int {
+	hkey := s.hkey(L, L.CheckString(1))
+	entry, err := s.f.storage.Get(hkey)
+	if errors.Is(err, storage.ErrKeyNotFound) || (err == nil && isKeyExpired(entry.TTL())) {
+		L.Push(lua.LNil)
+		return 1
+	}
+	if err != nil {
+		s.raise(L, err)
+	}
+	if bytes.HasPrefix(entry.Value(), typedValuePrefix) {
+		s.raise(L, ErrWrongType)
+	}
+	L.Push(lua.LString(entry.Value()))
+	return 1
+}
+
+// put implements olric.put(key, value [, px]). px is the timeout of the key
+// in milliseconds.
+func (s *scriptEnv) put(L *lua.LState) int {
+	key := L.CheckString(1)
+	hkey := s.hkey(L, key)
+	value, err := encodeValue(L.CheckString(2))
+	if err != nil {
+		s.raise(L, err)
+	}
+
+	e := newEnv(s.ctx)
+	e.dmap = s.dm.name
+	e.key = key
+	e.hkey = hkey
+	e.fragment = s.f
+	e.value = value
+	if px := L.OptInt64(3, 0); px > 0 {
+		e.putConfig.HasPX = true
+		e.putConfig.PX = time.Duration(px) * time.Millisecond
+	}
+	if err = s.dm.putOnLockedFragment(e); err != nil {
+		s.raise(L, err)
+	}
+	s.changed = append(s.changed, key)
+	s.dm.notifyKeyspaceEvent(config.KeyspaceEventPut, key)
+	return 0
+}
+
+func (s *scriptEnv) exists(hkey uint64) (bool, error) {
+	ttl, err := s.f.storage.GetTTL(hkey)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !isKeyExpired(ttl), nil
+}
+
+// del implements olric.delete(key). It returns 1 if the key is deleted,
+// otherwise 0.
+func (s *scriptEnv) del(L *lua.LState) int {
+	key := L.CheckString(1)
+	hkey := s.hkey(L, key)
+	ok, err := s.exists(hkey)
+	if err != nil {
+		s.raise(L, err)
+	}
+	if !ok {
+		L.Push(lua.LNumber(0))
+		return 1
+	}
+	if err = s.dm.deleteEntry(hkey, key, s.f); err != nil {
+		s.raise(L, err)
+	}
+	s.changed = append(s.changed, key)
+	s.dm.notifyKeyspaceEvent(config.KeyspaceEventDel, key)
+	L.Push(lua.LNumber(1))
+	return 1
+}
+
+// expire implements olric.expire(key, px). px is the new timeout of the key
+// in milliseconds. It returns 1 if the key exists, otherwise 0.
+func (s *scriptEnv) expire(L *lua.LState) int {
+	key := L.CheckString(1)
+	hkey := s.hkey(L, key)
+	px := L.CheckInt64(2)
+	ok, err := s.exists(hkey)
+	if err != nil {
+		s.raise(L, err)
+	}
+	if !ok {
+		L.Push(lua.LNumber(0))
+		return 1
+	}
+
+	e := newEnv(s.ctx)
+	e.dmap = s.dm.name
+	e.key = key
+	e.hkey = hkey
+	e.fragment = s.f
+	e.putConfig.OnlyUpdateTTL = true
+	e.timeout = time.Duration(px) * time.Millisecond
+	if err = s.dm.putOnLockedFragment(e); err != nil {
+		s.raise(L, err)
+	}
+	s.dm.notifyKeyspaceEvent(config.KeyspaceEventExpire, key)
+	L.Push(lua.LNumber(1))
+	return 1
+}
+
+// strRep replaces string.rep. It limits the length of the result, so a script
+// cannot exhaust the memory of the member with a single call.
+func strRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n <= 0 || len(str) == 0 {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	if n > scriptMaxRepSize/len(str) {
+		L.RaiseError("string.rep result is larger than %d bytes", scriptMaxRepSize)
+	}
+	L.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+func (s *scriptEnv) newState(keys, args []string) *lua.LState {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   scriptCallStackSize,
+		RegistrySize:    scriptRegistrySize,
+		RegistryMaxSize: scriptRegistryMaxSize,
+	})
+	for _, lib := range scriptLibs {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	L.GetGlobal(lua.StringLibName).(*lua.LTable).RawSetString("rep", L.NewFunction(strRep))
+	for _, name := range []string{"dofile", "loadfile", "module", "require", "print"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	toTable := func(items []string) *lua.LTable {
+		t := L.CreateTable(len(items), 0)
+		for _, item := range items {
+			t.Append(lua.LString(item))
+		}
+		return t
+	}
+	L.SetGlobal("KEYS", toTable(keys))
+	L.SetGlobal("ARGV", toTable(args))
+	L.SetGlobal("olric", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"get":    s.get,
+		"put":    s.put,
+		"delete": s.del,
+		"expire": s.expire,
+	}))
+	return L
+}
+
+// fromLuaValue converts the return value of a script like Redis does: numbers
+// are truncated to integers, true is 1, false is nil and tables are converted
+// to arrays.
+func fromLuaValue(value lua.LValue) interface{} {
+	switch v := value.(type) {
+	case lua.LNumber:
+		return int64(v)
+	case lua.LString:
+		return string(v)
+	case lua.LBool:
+		if v {
+			return int64(1)
+		}
+		return nil
+	case *lua.LTable:
+		items := make([]interface{}, 0, v.Len())
+		for i := 1; i <= v.Len(); i++ {
+			items = append(items, fromLuaValue(v.RawGetInt(i)))
+		}
+		return items
+	default:
+		return nil
+	}
+}
+
+// Eval runs the Lua script atomically on the partition owner of the keys.
+// The keys have to belong to the same partition. The script accesses the
+// keys with olric.get, olric.put, olric.delete and olric.expire functions.
+// KEYS and ARGV tables hold the keys and the arguments.
+//
+// It returns nil, int64, string or []interface{}.
+func (dm *DMap) Eval(ctx context.Context, script string, keys []string, args ...string) (interface{}, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: at least one key is required", protocol.ErrInvalidArgument)
+	}
+
+	part := dm.getPartitionByHKey(partitions.HKey(dm.name, keys[0]), partitions.PRIMARY)
+	for _, key := range keys[1:] {
+		if dm.s.primary.PartitionIDByHKey(partitions.HKey(dm.name, key)) != part.ID() {
+			return nil, ErrCrossPartition
+		}
+	}
+
+	member := part.Owner()
+	if !member.CompareByName(dm.s.rt.This()) {
+		cmd := protocol.NewEval(dm.name, script, keys, args...).Command(ctx)
+		rc := dm.s.client.Get(member.String())
+		err := rc.Process(ctx, cmd)
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, ConvertScriptError(err)
+		}
+		return cmd.Val(), nil
+	}
+
+	f, err := dm.loadOrCreateFragment(part)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, scriptTimeout)
+	defer cancel()
+
+	s := &scriptEnv{
+		ctx:    ctx,
+		dm:     dm,
+		partID: part.ID(),
+		f:      f,
+	}
+	L := s.newState(keys, args)
+	defer L.Close()
+	L.SetContext(ctx)
+
+	fn, err := L.LoadString(script)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScript, err)
+	}
+
+	err = s.call(L, fn)
+	// The MapStore is written after the fragment lock is released. The
+	// changes of a failed script are kept in the DMap, so they are written
+	// too.
+	if ferr := dm.flushMapStore(s.changed...); ferr != nil && err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromLuaValue(L.Get(-1)), nil
+}
+
+// call runs the script under the fragment lock.
+func (s *scriptEnv) call(L *lua.LState, fn *lua.LFunction) error {
+	s.f.Lock()
+	defer s.f.Unlock()
+
+	L.Push(fn)
+	if err := L.PCall(0, 1, nil); err != nil {
+		if s.err != nil {
+			return s.err
+		}
+		return fmt.Errorf("%w: %v", ErrScript, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+// writeScriptResult writes the return value of a script. See fromLuaValue.
+func writeScriptResult(conn redcon.Conn, value interface{}) {
+	switch v := value.(type) {
+	case int64:
+		conn.WriteInt64(v)
+	case string:
+		conn.WriteBulkString(v)
+	case []interface{}:
+		conn.WriteArray(len(v))
+		for _, item := range v {
+			writeScriptResult(conn, item)
+		}
+	default:
+		conn.WriteNull()
+	}
+}
+
+func (s *Service) evalCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	evalCmd, err := protocol.ParseEvalCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(evalCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	result, err := dm.Eval(server.RequestContext(conn, s.ctx), evalCmd.Script, evalCmd.Keys, evalCmd.Args...)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	writeScriptResult(conn, result)
+}
@@ -0,0 +1,205 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const rateLimiterScript = `
+local current = tonumber(olric.get(KEYS[1]) or "0")
+if current >= tonumber(ARGV[1]) then
+	return 0
+end
+olric.put(KEYS[1], current + 1, tonumber(ARGV[2]))
+return 1
+`
+
+// findKey returns a key that belongs to the partition of the given key if
+// same is true. Otherwise, it returns a key from another partition.
+func findKey(s *Service, dmap, key string, same bool) string {
+	partID := s.primary.PartitionIDByHKey(partitions.HKey(dmap, key))
+	for i := 0; ; i++ {
+		candidate := testutil.ToKey(i)
+		if candidate == key {
+			continue
+		}
+		if (s.primary.PartitionIDByHKey(partitions.HKey(dmap, candidate)) == partID) == same {
+			return candidate
+		}
+	}
+}
+
+func TestDMap_Eval(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+		for j := 0; j < 5; j++ {
+			dm := dm1
+			if j%2 == 0 {
+				dm = dm2
+			}
+			allowed, err := dm.Eval(ctx, rateLimiterScript, []string{key}, "3", "60000")
+			require.NoError(t, err)
+			if j < 3 {
+				require.Equal(t, int64(1), allowed)
+			} else {
+				require.Equal(t, int64(0), allowed)
+			}
+		}
+
+		gr, err := dm1.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, []byte("3"), gr.Value())
+		require.NotZero(t, gr.TTL())
+	}
+}
+
+func TestDMap_Eval_Functions(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue", nil))
+
+	result, err := dm.Eval(ctx, `
+local value = olric.get(KEYS[1])
+local expired = olric.expire(KEYS[1], 60000)
+local missing = olric.expire(KEYS[2], 60000)
+return {value, expired, missing, olric.get(KEYS[2]), 1.5, true}
+`, []string{"mykey", findKey(s, "mymap", "mykey", true)})
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"myvalue", int64(1), int64(0), nil, int64(1), int64(1)}, result)
+
+	gr, err := dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+	require.NotZero(t, gr.TTL())
+
+	result, err = dm.Eval(ctx, `return olric.delete(KEYS[1]) + olric.delete(KEYS[1])`, []string{"mykey"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result)
+
+	_, err = dm.Get(ctx, "mykey")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	result, err = dm.Eval(ctx, `return nil`, []string{"mykey"})
+	require.NoError(t, err)
+	require.Nil(t, result)
+}
+
+func TestDMap_Eval_Errors(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	t.Run("Script error", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			for _, dm := range []*DMap{dm1, dm2} {
+				_, err = dm.Eval(ctx, `error("boom")`, []string{testutil.ToKey(i)})
+				require.ErrorIs(t, err, ErrScript)
+				require.Contains(t, err.Error(), "boom")
+			}
+		}
+	})
+
+	t.Run("Syntax error", func(t *testing.T) {
+		_, err = dm1.Eval(ctx, `return (`, []string{"mykey"})
+		require.ErrorIs(t, err, ErrScript)
+	})
+
+	t.Run("Sandbox", func(t *testing.T) {
+		_, err = dm1.Eval(ctx, `return os.getenv("HOME")`, []string{"mykey"})
+		require.ErrorIs(t, err, ErrScript)
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		// Call stack
+		_, err = dm1.Eval(ctx, `local function f() return 1 + f() end return f()`, []string{"mykey"})
+		require.ErrorIs(t, err, ErrScript)
+
+		// Data stack
+		_, err = dm1.Eval(ctx, `return unpack({}, 1, 1000000)`, []string{"mykey"})
+		require.ErrorIs(t, err, ErrScript)
+
+		_, err = dm1.Eval(ctx, `return string.rep("x", 1024 * 1024 * 1024)`, []string{"mykey"})
+		require.ErrorIs(t, err, ErrScript)
+		require.Contains(t, err.Error(), "string.rep")
+		_, err = dm1.Eval(ctx, `return ("x"):rep(1024 * 1024 * 1024)`, []string{"mykey"})
+		require.ErrorIs(t, err, ErrScript)
+
+		result, err := dm1.Eval(ctx, `return string.rep("ab", 3)`, []string{"mykey"})
+		require.NoError(t, err)
+		require.Equal(t, "ababab", result)
+	})
+
+	t.Run("Cross partition", func(t *testing.T) {
+		other := findKey(s1, "mymap", "key-0", false)
+
+		_, err = dm1.Eval(ctx, `return 1`, []string{"key-0", other})
+		require.ErrorIs(t, err, ErrCrossPartition)
+
+		_, err = dm1.Eval(ctx, `return olric.get(ARGV[1])`, []string{"key-0"}, other)
+		require.ErrorIs(t, err, ErrCrossPartition)
+	})
+
+	t.Run("Wrong type", func(t *testing.T) {
+		_, err = dm1.HSet(ctx, "myhash", map[string]interface{}{"field": "value"})
+		require.NoError(t, err)
+
+		_, err = dm1.Eval(ctx, `return olric.get(KEYS[1])`, []string{"myhash"})
+		require.ErrorIs(t, err, ErrWrongType)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		// Runs on the partition owner, the caller's context isn't involved.
+		dm := dm1
+		if !s1.primary.PartitionByHKey(partitions.HKey("mymap", "mykey")).Owner().CompareByName(s1.rt.This()) {
+			dm = dm2
+		}
+		_, err = dm.Eval(tctx, `while true do end`, []string{"mykey"})
+		require.ErrorIs(t, err, ErrScript)
+	})
+}
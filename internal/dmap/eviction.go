@@ -20,8 +20,10 @@ import (
 	"math/rand"
 	"runtime"
 	"sort"
+	"strings"
 	"time"
 
+	"github.com/buraksezer/olric/config"
 	"github.com/buraksezer/olric/internal/cluster/partitions"
 	"github.com/buraksezer/olric/pkg/storage"
 	"golang.org/x/sync/semaphore"
@@ -102,8 +104,12 @@ func (s *Service) evictKeys() {
 	partID := uint64(rand.Intn(int(s.config.PartitionCount)))
 	part := s.primary.PartitionByID(partID)
 	part.Map().Range(func(name, tmp interface{}) bool {
+		if !strings.HasPrefix(name.(string), "dmap.") {
+			// Continue. This fragment belongs to a different data structure.
+			return true
+		}
 		f := tmp.(*fragment)
-		s.scanFragmentForEviction(partID, name.(string), f)
+		s.scanFragmentForEviction(partID, strings.TrimPrefix(name.(string), "dmap."), f)
 		// this breaks the loop, we only scan one dmap instance per call
 		return false
 	})
@@ -165,6 +171,7 @@ func (s *Service) scanFragmentForEviction(partID uint64, name string, f *fragmen
 
 				// number of valid items removed from cache to free memory for new items.
 				EvictedTotal.Increase(1)
+				dm.notifyKeyspaceEvent(config.KeyspaceEventExpired, key)
 			}
 			return true
 		})
@@ -248,5 +255,6 @@ func (dm *DMap) evictKeyWithLRU(e *env) error {
 
 	// number of valid items removed from cache to free memory for new items.
 	EvictedTotal.Increase(1)
+	dm.notifyKeyspaceEvent(config.KeyspaceEventEvicted, key)
 	return nil
 }
@@ -0,0 +1,298 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// fencedLockInterval is the longest period that FencedLock waits on the
+// partition owner before checking the ownership again. It also keeps the
+// blocking commands shorter than the read timeout of the internal client.
+const fencedLockInterval = time.Second
+
+// fencedLockPrefix marks the state of the fenced locks. The state of the lock
+// is encoded with msgpack after the prefix.
+var fencedLockPrefix = []byte("\x00olric.lock\x00")
+
+// fencedLock is the state of a lock. It's deleted when the lock is released.
+// The fencing token is taken from the counter of the partition, so it keeps
+// increasing for the key. See updateLockState.
+type fencedLock struct {
+	Owner string
+	Count int
+	Token uint64
+	// ExpiresAt is in milliseconds. Zero means that the lock has no lease.
+	ExpiresAt int64
+}
+
+func (l *fencedLock) isHeld(now int64) bool {
+	return l.Owner != "" && (l.ExpiresAt == 0 || now < l.ExpiresAt)
+}
+
+func (l *fencedLock) setLease(now int64, lease time.Duration) {
+	if lease > 0 {
+		l.ExpiresAt = now + lease.Milliseconds()
+		return
+	}
+	l.ExpiresAt = 0
+}
+
+func decodeFencedLock(value []byte) (*fencedLock, error) {
+	if !bytes.HasPrefix(value, fencedLockPrefix) {
+		return nil, ErrWrongType
+	}
+	l := &fencedLock{}
+	if err := msgpack.Unmarshal(value[len(fencedLockPrefix):], l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func encodeFencedLock(l *fencedLock) ([]byte, error) {
+	data, err := msgpack.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 0, len(fencedLockPrefix)+len(data))
+	value = append(value, fencedLockPrefix...)
+	return append(value, data...), nil
+}
+
+func nowInMilliseconds() int64 {
+	return time.Now().UnixNano() / 1000000
+}
+
+// updateFencedLock runs update on the state of the lock under the fragment
+// lock. update returns false if it doesn't modify the state. The state is
+// deleted if the lock has no owner. See updateLockState.
+func (dm *DMap) updateFencedLock(ctx context.Context, key string, update func(l *fencedLock, nextToken func() uint64) (bool, error)) error {
+	return dm.updateLockState(ctx, key, func(txn *lockTxn) (bool, error) {
+		l := &fencedLock{}
+		if txn.value != nil {
+			var err error
+			l, err = decodeFencedLock(txn.value)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		changed, err := update(l, txn.nextToken)
+		if err != nil || !changed {
+			return false, err
+		}
+		if l.Owner == "" {
+			txn.value = nil
+			return true, nil
+		}
+		txn.value, err = encodeFencedLock(l)
+		if err != nil {
+			return false, err
+		}
+		txn.expiresAt = l.ExpiresAt
+		return true, nil
+	})
+}
+
+// tryFencedLock acquires the lock if it's free, expired or already held by
+// the owner. It returns the fencing token if the lock is acquired. Otherwise,
+// it returns the expiry of the current holder's lease, in milliseconds.
+func (dm *DMap) tryFencedLock(ctx context.Context, key, owner string, lease time.Duration) (uint64, int64, bool, error) {
+	var (
+		token     uint64
+		expiresAt int64
+		acquired  bool
+	)
+	err := dm.updateFencedLock(ctx, key, func(l *fencedLock, nextToken func() uint64) (bool, error) {
+		now := nowInMilliseconds()
+		if l.isHeld(now) {
+			if l.Owner != owner {
+				expiresAt = l.ExpiresAt
+				return false, nil
+			}
+			// Reentrant acquisition
+			l.Count++
+		} else {
+			l.Token = nextToken()
+			l.Owner = owner
+			l.Count = 1
+		}
+		l.setLease(now, lease)
+		token, acquired = l.Token, true
+		return true, nil
+	})
+	return token, expiresAt, acquired, err
+}
+
+// lockAttempt tries to acquire a lock on the partition owner. It returns the
+// fencing token if the lock is acquired. Otherwise, it returns the earliest
+// expiry of the leases that block the caller, in milliseconds, or zero.
+type lockAttempt func() (token uint64, expiresAt int64, acquired bool, err error)
+
+// acquireLock calls try until it acquires the lock with the given hkey or
+// deadline passes. The blocked callers are woken up by lockWaiters. If this
+// member isn't the owner of the hkey, forward builds the command that waits on
+// the owner for at most fencedLockInterval.
+func (dm *DMap) acquireLock(ctx context.Context, hkey uint64, deadline time.Duration,
+	forward func(wait time.Duration) *redis.IntCmd, try lockAttempt) (uint64, error) {
+	until := time.Now().Add(deadline)
+
+	for {
+		wait := time.Until(until)
+		if wait < 0 {
+			wait = 0
+		}
+		if wait > fencedLockInterval {
+			wait = fencedLockInterval
+		}
+
+		member, ok := dm.hkeyOwner(hkey)
+		if !ok {
+			cmd := forward(wait)
+			rc := dm.s.client.Get(member.String())
+			err := rc.Process(ctx, cmd)
+			if err == nil {
+				var token int64
+				token, err = cmd.Result()
+				if err == nil {
+					return uint64(token), nil
+				}
+			}
+			err = protocol.ConvertError(err)
+			if !errors.Is(err, ErrLockNotAcquired) || time.Until(until) <= 0 {
+				return 0, err
+			}
+			continue
+		}
+
+		// Register before trying to acquire, so a release between the two
+		// calls isn't missed.
+		notified, done := dm.s.lockWaiters.wait(hkey)
+		token, expiresAt, acquired, err := try()
+		if err != nil {
+			done()
+			return 0, err
+		}
+		if acquired {
+			done()
+			return token, nil
+		}
+		if wait <= 0 {
+			done()
+			return 0, ErrLockNotAcquired
+		}
+
+		if expiresAt != 0 {
+			// Try again when the blocking lease expires.
+			expiry := time.Duration(expiresAt-nowInMilliseconds()+1) * time.Millisecond
+			if expiry < wait {
+				wait = expiry
+			}
+		}
+
+		err = dm.s.awaitNotification(ctx, notified, wait)
+		done()
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// FencedLock acquires the lock for the given key on behalf of owner and
+// returns its fencing token. The token increases every time the lock changes
+// hands, so it can be used to reject the requests of a former holder. The lock
+// is reentrant: the owner can acquire it again, and it's released after the
+// same number of FencedUnlock calls. The lock is released automatically after
+// lease if it's not zero.
+//
+// The blocked callers are woken up when the lock is released. It returns
+// ErrLockNotAcquired if the lock cannot be acquired until deadline.
+func (dm *DMap) FencedLock(ctx context.Context, key, owner string, lease, deadline time.Duration) (uint64, error) {
+	hkey, _, _ := dm.lockOwner(key)
+	return dm.acquireLock(ctx, hkey, deadline, func(wait time.Duration) *redis.IntCmd {
+		return protocol.NewFencedLock(dm.name, key, owner, wait.Seconds()).
+			SetPX(lease.Milliseconds()).
+			Command(ctx)
+	}, func() (uint64, int64, bool, error) {
+		return dm.tryFencedLock(ctx, key, owner, lease)
+	})
+}
+
+// FencedUnlock releases the lock held by owner. A reentrant lock is released
+// after the last call. It returns ErrNoSuchLock if owner doesn't hold the lock.
+func (dm *DMap) FencedUnlock(ctx context.Context, key, owner string) error {
+	hkey, member, ok := dm.lockOwner(key)
+	if !ok {
+		cmd := protocol.NewFencedUnlock(dm.name, key, owner).Command(ctx)
+		rc := dm.s.client.Get(member.String())
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return protocol.ConvertError(err)
+		}
+		return protocol.ConvertError(cmd.Err())
+	}
+
+	var released bool
+	err := dm.updateFencedLock(ctx, key, func(l *fencedLock, _ func() uint64) (bool, error) {
+		if !l.isHeld(nowInMilliseconds()) || l.Owner != owner {
+			return false, ErrNoSuchLock
+		}
+		l.Count--
+		if l.Count == 0 {
+			l.Owner = ""
+			l.ExpiresAt = 0
+			released = true
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if released {
+		dm.s.lockWaiters.notify(hkey)
+	}
+	return nil
+}
+
+// FencedLease sets the lease of the lock held by owner. Zero lease removes
+// the expiry. It returns ErrNoSuchLock if owner doesn't hold the lock.
+func (dm *DMap) FencedLease(ctx context.Context, key, owner string, lease time.Duration) error {
+	_, member, ok := dm.lockOwner(key)
+	if !ok {
+		cmd := protocol.NewFencedLockLease(dm.name, key, owner, lease.Milliseconds()).Command(ctx)
+		rc := dm.s.client.Get(member.String())
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return protocol.ConvertError(err)
+		}
+		return protocol.ConvertError(cmd.Err())
+	}
+
+	return dm.updateFencedLock(ctx, key, func(l *fencedLock, _ func() uint64) (bool, error) {
+		now := nowInMilliseconds()
+		if !l.isHeld(now) || l.Owner != owner {
+			return false, ErrNoSuchLock
+		}
+		l.setLease(now, lease)
+		return true, nil
+	})
+}
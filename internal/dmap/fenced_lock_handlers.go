@@ -0,0 +1,89 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"time"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+func (s *Service) fencedLockCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	lockCmd, err := protocol.ParseFencedLockCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(lockCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	lease := time.Duration(lockCmd.PX) * time.Millisecond
+	deadline := time.Duration(lockCmd.Deadline * float64(time.Second))
+	token, err := dm.FencedLock(server.RequestContext(conn, s.ctx), lockCmd.Key, lockCmd.Owner, lease, deadline)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt64(int64(token))
+}
+
+func (s *Service) fencedUnlockCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	unlockCmd, err := protocol.ParseFencedUnlockCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(unlockCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	err = dm.FencedUnlock(server.RequestContext(conn, s.ctx), unlockCmd.Key, unlockCmd.Owner)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteString(protocol.StatusOK)
+}
+
+func (s *Service) fencedLockLeaseCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	leaseCmd, err := protocol.ParseFencedLockLeaseCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(leaseCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	lease := time.Duration(leaseCmd.PX) * time.Millisecond
+	err = dm.FencedLease(server.RequestContext(conn, s.ctx), leaseCmd.Key, leaseCmd.Owner, lease)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteString(protocol.StatusOK)
+}
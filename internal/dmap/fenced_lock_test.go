@@ -0,0 +1,225 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_FencedLock(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+
+		first, err := dm1.FencedLock(ctx, key, "owner-1", 0, 0)
+		require.NoError(t, err)
+
+		// Reentrant acquisition returns the same token.
+		token, err := dm2.FencedLock(ctx, key, "owner-1", 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, first, token)
+
+		_, err = dm2.FencedLock(ctx, key, "owner-2", 0, 10*time.Millisecond)
+		require.ErrorIs(t, err, ErrLockNotAcquired)
+
+		require.NoError(t, dm1.FencedUnlock(ctx, key, "owner-1"))
+		// Still held by owner-1
+		_, err = dm2.FencedLock(ctx, key, "owner-2", 0, 0)
+		require.ErrorIs(t, err, ErrLockNotAcquired)
+
+		require.NoError(t, dm2.FencedUnlock(ctx, key, "owner-1"))
+		err = dm2.FencedUnlock(ctx, key, "owner-1")
+		require.ErrorIs(t, err, ErrNoSuchLock)
+
+		token, err = dm2.FencedLock(ctx, key, "owner-2", 0, 0)
+		require.NoError(t, err)
+		require.Greater(t, token, first)
+
+		err = dm1.FencedUnlock(ctx, key, "owner-1")
+		require.ErrorIs(t, err, ErrNoSuchLock)
+		require.NoError(t, dm1.FencedUnlock(ctx, key, "owner-2"))
+	}
+}
+
+func TestDMap_FencedLock_Wait(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	key := "mykey"
+	first, err := dm1.FencedLock(ctx, key, "owner-1", 0, 0)
+	require.NoError(t, err)
+
+	type result struct {
+		token uint64
+		err   error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		token, err := dm2.FencedLock(ctx, key, "owner-2", 0, 5*time.Second)
+		resultCh <- result{token: token, err: err}
+	}()
+
+	<-time.After(100 * time.Millisecond)
+	start := time.Now()
+	require.NoError(t, dm1.FencedUnlock(ctx, key, "owner-1"))
+
+	select {
+	case res := <-resultCh:
+		require.NoError(t, res.err)
+		require.Greater(t, res.token, first)
+		// Woken up by the notification, not by polling the owner.
+		require.Less(t, time.Since(start), fencedLockInterval)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "FencedLock is still blocked")
+	}
+}
+
+func TestDMap_FencedLock_Lease(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	key := "mykey"
+	first, err := dm.FencedLock(ctx, key, "owner-1", 100*time.Millisecond, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, dm.FencedLease(ctx, key, "owner-1", 200*time.Millisecond))
+	err = dm.FencedLease(ctx, key, "owner-2", time.Second)
+	require.ErrorIs(t, err, ErrNoSuchLock)
+
+	// Acquired after the lease of owner-1 expires.
+	token, err := dm.FencedLock(ctx, key, "owner-2", 0, time.Second)
+	require.NoError(t, err)
+	require.Greater(t, token, first)
+
+	err = dm.FencedUnlock(ctx, key, "owner-1")
+	require.ErrorIs(t, err, ErrNoSuchLock)
+	err = dm.FencedLease(ctx, key, "owner-1", time.Second)
+	require.ErrorIs(t, err, ErrNoSuchLock)
+}
+
+// countLockStates returns the number of the locks that have a state on the
+// member.
+func countLockStates(s *Service) int {
+	hkeys := make(map[uint64]struct{})
+	for partID := uint64(0); partID < s.config.PartitionCount; partID++ {
+		part := s.primary.PartitionByID(partID)
+		tmp, ok := part.Map().Load(s.fragmentName(locksDMapName))
+		if !ok {
+			continue
+		}
+		f := tmp.(*fragment)
+		f.storage.RangeHKey(func(hkey uint64) bool {
+			key, err := f.storage.GetKey(hkey)
+			if err == nil && !strings.HasPrefix(key, lockTokenPrefix) {
+				hkeys[hkey] = struct{}{}
+			}
+			return true
+		})
+	}
+	return len(hkeys)
+}
+
+func TestDMap_FencedLock_Value(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	// The lock doesn't touch the value of the key.
+	require.NoError(t, dm.Put(ctx, "mykey", "value", nil))
+	first, err := dm.FencedLock(ctx, "mykey", "owner-1", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, countLockStates(s))
+
+	require.NoError(t, dm.Put(ctx, "mykey", "other-value", nil))
+	e, err := dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+	require.Equal(t, []byte("other-value"), e.Value())
+
+	count, err := dm.Delete(ctx, "mykey")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	_, err = dm.FencedLock(ctx, "mykey", "owner-2", 0, 0)
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+
+	// The state is deleted after the lock is released, but the token keeps
+	// increasing.
+	require.NoError(t, dm.FencedUnlock(ctx, "mykey", "owner-1"))
+	require.Equal(t, 0, countLockStates(s))
+
+	token, err := dm.FencedLock(ctx, "mykey", "owner-2", 0, 0)
+	require.NoError(t, err)
+	require.Greater(t, token, first)
+	require.NoError(t, dm.FencedUnlock(ctx, "mykey", "owner-2"))
+	require.Equal(t, 0, countLockStates(s))
+
+	_, err = dm.Get(ctx, "mykey")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestDMap_FencedLock_Lease_Expired(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	first, err := dm.FencedLock(ctx, "mykey", "owner-1", 10*time.Millisecond, 0)
+	require.NoError(t, err)
+	<-time.After(20 * time.Millisecond)
+
+	// The expired state is replaced and the token keeps increasing.
+	token, err := dm.FencedLock(ctx, "mykey", "owner-2", 0, 0)
+	require.NoError(t, err)
+	require.Greater(t, token, first)
+	err = dm.FencedUnlock(ctx, "mykey", "owner-1")
+	require.ErrorIs(t, err, ErrNoSuchLock)
+	require.NoError(t, dm.FencedUnlock(ctx, "mykey", "owner-2"))
+}
@@ -27,6 +27,8 @@ import (
 	"github.com/buraksezer/olric/internal/protocol"
 	"github.com/buraksezer/olric/pkg/storage"
 	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type fragment struct {
@@ -36,6 +38,10 @@ type fragment struct {
 	storage storage.Engine
 	ctx     context.Context
 	cancel  context.CancelFunc
+
+	// index is the secondary index of a primary fragment. It's nil until
+	// the first query. See loadIndex.
+	index *fragmentIndex
 }
 
 func (f *fragment) Stats() storage.Stats {
@@ -97,6 +103,14 @@ func (f *fragment) Move(part *partitions.Partition, name string, owners []discov
 		return err
 	}
 
+	ctx, span := f.service.startSpan(f.service.ctx, "dmap.moveFragment",
+		attribute.String("olric.dmap", fp.Name),
+		attribute.Int64("olric.partition_id", int64(part.ID())),
+		attribute.String("olric.partition_kind", part.Kind().String()),
+		attribute.Int("olric.payload_size", len(value)),
+	)
+	defer span.End()
+
 	for _, owner := range owners {
 		if f.service.config.EnableClusterEventsChannel {
 			e := &events.FragmentMigrationEvent{
@@ -114,13 +128,15 @@ func (f *fragment) Move(part *partitions.Partition, name string, owners []discov
 			go f.service.publishEvent(e)
 		}
 
-		cmd := protocol.NewMoveFragment(value).Command(f.service.ctx)
+		cmd := protocol.NewMoveFragment(value).Command(ctx)
 		rc := f.service.client.Get(owner.String())
-		err = rc.Process(f.service.ctx, cmd)
+		err = rc.Process(ctx, cmd)
 		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
 			return err
 		}
 		if err := cmd.Err(); err != nil {
+			span.SetStatus(codes.Error, err.Error())
 			return err
 		}
 	}
@@ -128,8 +144,12 @@ func (f *fragment) Move(part *partitions.Partition, name string, owners []discov
 	return i.Drop(index)
 }
 
-func (dm *DMap) newFragment() (*fragment, error) {
-	c := storage.NewConfig(dm.config.engine.Config)
+func (dm *DMap) newFragment(part *partitions.Partition) (*fragment, error) {
+	c := storage.NewConfig(dm.config.engine.Config).Copy()
+	// Persistent storage engines use these keys to find the data of the fragment.
+	c.Add(storage.FragmentNameKey, dm.fragmentName)
+	c.Add(storage.PartitionIDKey, part.ID())
+	c.Add(storage.PartitionKindKey, strings.ToLower(part.Kind().String()))
 	engine, err := dm.engine.Fork(c)
 	if err != nil {
 		return nil, err
@@ -161,7 +181,7 @@ func (dm *DMap) loadOrCreateFragment(part *partitions.Partition) (*fragment, err
 		return fg.(*fragment), nil
 	}
 
-	f, err := dm.newFragment()
+	f, err := dm.newFragment(part)
 	if err != nil {
 		return nil, err
 	}
@@ -25,6 +25,8 @@ import (
 	"github.com/buraksezer/olric/internal/protocol"
 	"github.com/buraksezer/olric/internal/stats"
 	"github.com/buraksezer/olric/pkg/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // Entry is a DMap entry with its metadata.
@@ -81,10 +83,10 @@ func (dm *DMap) getOnFragment(e *env) (storage.Entry, error) {
 	return entry, nil
 }
 
-func (dm *DMap) lookupOnPreviousOwner(owner *discovery.Member, key string) (*version, error) {
-	cmd := protocol.NewGetEntry(dm.name, key).Command(dm.s.ctx)
+func (dm *DMap) lookupOnPreviousOwner(ctx context.Context, owner *discovery.Member, key string) (*version, error) {
+	cmd := protocol.NewGetEntry(dm.name, key).Command(ctx)
 	rc := dm.s.client.Get(owner.String())
-	err := rc.Process(dm.s.ctx, cmd)
+	err := rc.Process(ctx, cmd)
 	if err != nil {
 		return nil, protocol.ConvertError(err)
 	}
@@ -141,12 +143,18 @@ func (dm *DMap) lookupOnThisNode(hkey uint64, key string) *version {
 
 // lookupOnOwners collects versions of a key/value pair on the partition owner
 // by including previous partition owners.
-func (dm *DMap) lookupOnOwners(hkey uint64, key string) []*version {
+func (dm *DMap) lookupOnOwners(ctx context.Context, hkey uint64, key string) []*version {
 	owners := dm.s.primary.PartitionOwnersByHKey(hkey)
 	if len(owners) == 0 {
 		panic("partition owners list cannot be empty")
 	}
 
+	ctx, span := dm.s.startSpan(ctx, "dmap.lookupOnOwners",
+		attribute.String("olric.dmap", dm.name),
+		attribute.Int("olric.owners", len(owners)),
+	)
+	defer span.End()
+
 	var versions []*version
 	versions = append(versions, dm.lookupOnThisNode(hkey, key))
 
@@ -154,7 +162,7 @@ func (dm *DMap) lookupOnOwners(hkey uint64, key string) []*version {
 	// Traverse in reverse order. Except from the latest host, this one.
 	for i := len(owners) - 2; i >= 0; i-- {
 		owner := owners[i]
-		v, err := dm.lookupOnPreviousOwner(&owner, key)
+		v, err := dm.lookupOnPreviousOwner(ctx, &owner, key)
 		if err != nil {
 			if dm.s.log.V(6).Ok() {
 				dm.s.log.V(6).Printf("[ERROR] Failed to call get on a previous "+
@@ -193,15 +201,22 @@ func (dm *DMap) sanitizeAndSortVersions(versions []*version) []*version {
 	return dm.sortVersions(sanitized)
 }
 
-func (dm *DMap) lookupOnReplicas(hkey uint64, key string) []*version {
+func (dm *DMap) lookupOnReplicas(ctx context.Context, hkey uint64, key string) []*version {
 	// Check backup.
 	backups := dm.s.backup.PartitionOwnersByHKey(hkey)
+
+	ctx, span := dm.s.startSpan(ctx, "dmap.lookupOnReplicas",
+		attribute.String("olric.dmap", dm.name),
+		attribute.Int("olric.replicas", len(backups)),
+	)
+	defer span.End()
+
 	versions := make([]*version, 0, len(backups))
 	for _, replica := range backups {
 		host := replica
-		cmd := protocol.NewGetEntry(dm.name, key).SetReplica().Command(dm.s.ctx)
+		cmd := protocol.NewGetEntry(dm.name, key).SetReplica().Command(ctx)
 		rc := dm.s.client.Get(host.String())
-		err := rc.Process(dm.s.ctx, cmd)
+		err := rc.Process(ctx, cmd)
 		err = protocol.ConvertError(err)
 		if err != nil {
 			if dm.s.log.V(6).Ok() {
@@ -229,7 +244,10 @@ func (dm *DMap) lookupOnReplicas(hkey uint64, key string) []*version {
 	return versions
 }
 
-func (dm *DMap) readRepair(winner *version, versions []*version) {
+func (dm *DMap) readRepair(ctx context.Context, winner *version, versions []*version) {
+	ctx, span := dm.s.startSpan(ctx, "dmap.readRepair", attribute.String("olric.dmap", dm.name))
+	defer span.End()
+
 	for _, version := range versions {
 		if version.entry != nil && winner.entry.Timestamp() == version.entry.Timestamp() {
 			continue
@@ -258,9 +276,9 @@ func (dm *DMap) readRepair(winner *version, versions []*version) {
 			f.Unlock()
 		} else {
 			// If readRepair is enabled, this function is called by every GET request.
-			cmd := protocol.NewPutEntry(dm.name, winner.entry.Key(), winner.entry.Encode()).Command(dm.s.ctx)
+			cmd := protocol.NewPutEntry(dm.name, winner.entry.Key(), winner.entry.Encode()).Command(ctx)
 			rc := dm.s.client.Get(version.host.String())
-			err := rc.Process(dm.s.ctx, cmd)
+			err := rc.Process(ctx, cmd)
 			if err != nil {
 				dm.s.log.V(3).Printf("[ERROR] Failed to synchronize replica %s: %v", version.host, err)
 				continue
@@ -273,17 +291,25 @@ func (dm *DMap) readRepair(winner *version, versions []*version) {
 	}
 }
 
-func (dm *DMap) getOnCluster(hkey uint64, key string) (storage.Entry, error) {
+func (dm *DMap) getOnCluster(ctx context.Context, hkey uint64, key string) (storage.Entry, error) {
+	ctx, span := dm.s.startSpan(ctx, "dmap.getOnCluster",
+		attribute.String("olric.dmap", dm.name),
+		attribute.Int("olric.read_quorum", dm.s.config.ReadQuorum),
+	)
+	defer span.End()
+
 	// RUnlock should not be called with defer statement here because
 	// readRepair function may call putOnFragment function which needs a write
 	// lock. Please don't forget calling RUnlock before returning here.
-	versions := dm.lookupOnOwners(hkey, key)
+	versions := dm.lookupOnOwners(ctx, hkey, key)
 	if dm.s.config.ReadQuorum >= config.MinimumReplicaCount {
-		v := dm.lookupOnReplicas(hkey, key)
+		v := dm.lookupOnReplicas(ctx, hkey, key)
 		versions = append(versions, v...)
 	}
 
+	span.SetAttributes(attribute.Int("olric.versions", len(versions)))
 	if len(versions) < dm.s.config.ReadQuorum {
+		span.SetStatus(codes.Error, ErrReadQuorum.Error())
 		return nil, ErrReadQuorum
 	}
 
@@ -294,6 +320,7 @@ func (dm *DMap) getOnCluster(hkey uint64, key string) (storage.Entry, error) {
 	}
 
 	if len(sorted) < dm.s.config.ReadQuorum {
+		span.SetStatus(codes.Error, ErrReadQuorum.Error())
 		return nil, ErrReadQuorum
 	}
 
@@ -306,7 +333,7 @@ func (dm *DMap) getOnCluster(hkey uint64, key string) (storage.Entry, error) {
 	if dm.s.config.ReadRepair {
 		// Parallel read operations may propagate different versions of
 		// the same key/value pair. The rule is simple: last write wins.
-		dm.readRepair(winner, versions)
+		dm.readRepair(ctx, winner, versions)
 	}
 	return winner.entry, nil
 }
@@ -319,7 +346,11 @@ func (dm *DMap) Get(ctx context.Context, key string) (storage.Entry, error) {
 	member := dm.s.primary.PartitionByHKey(hkey).Owner()
 	// We are on the partition owner
 	if member.CompareByName(dm.s.rt.This()) {
-		entry, err := dm.getOnCluster(hkey, key)
+		entry, err := dm.getOnCluster(ctx, hkey, key)
+		if errors.Is(err, ErrKeyNotFound) && dm.config.mapStore != nil {
+			// Read-through
+			entry, err = dm.loadFromMapStore(ctx, hkey, key)
+		}
 		if errors.Is(err, ErrKeyNotFound) {
 			GetMisses.Increase(1)
 		}
@@ -334,7 +365,7 @@ func (dm *DMap) Get(ctx context.Context, key string) (storage.Entry, error) {
 	}
 
 	// Redirect to the partition owner
-	cmd := protocol.NewGet(dm.name, key).SetRaw().Command(dm.s.ctx)
+	cmd := protocol.NewGet(dm.name, key).SetRaw().Command(ctx)
 	rc := dm.s.client.Get(member.String())
 	err := rc.Process(ctx, cmd)
 	if err != nil {
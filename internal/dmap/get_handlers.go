@@ -17,6 +17,7 @@ package dmap
 import (
 	"github.com/buraksezer/olric/internal/cluster/partitions"
 	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
 	"github.com/tidwall/redcon"
 )
 
@@ -32,7 +33,7 @@ func (s *Service) getCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 		return
 	}
 
-	raw, err := dm.Get(s.ctx, getCmd.Key)
+	raw, err := dm.Get(server.RequestContext(conn, s.ctx), getCmd.Key)
 	if err != nil {
 		protocol.WriteError(conn, err)
 		return
@@ -62,7 +63,7 @@ func (s *Service) getEntryCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 		kind = partitions.BACKUP
 	}
 
-	e := newEnv(s.ctx)
+	e := newEnv(server.RequestContext(conn, s.ctx))
 	e.dmap = getEntryCmd.DMap
 	e.key = getEntryCmd.Key
 	e.hkey = partitions.HKey(getEntryCmd.DMap, getEntryCmd.Key)
@@ -16,26 +16,75 @@ package dmap
 
 import (
 	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/tidwall/redcon"
 )
 
+// handleFunc registers the handler of a DMap command. The public commands
+// cannot access the reserved DMaps.
+func (s *Service) handleFunc(command string, handler func(conn redcon.Conn, cmd redcon.Command)) {
+	if !protocol.IsInternalCommand(command) {
+		handler = rejectReservedDMaps(command, handler)
+	}
+	s.server.ServeMux().HandleFunc(command, handler)
+}
+
 func (s *Service) RegisterHandlers() {
-	s.server.ServeMux().HandleFunc(protocol.DMap.Put, s.putCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.Get, s.getCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.Del, s.delCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.DelEntry, s.delEntryCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.GetEntry, s.getEntryCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.PutEntry, s.putEntryCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.Expire, s.expireCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.PExpire, s.pexpireCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.Destroy, s.destroyCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.Scan, s.scanCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.Incr, s.incrCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.Decr, s.decrCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.GetPut, s.getPutCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.IncrByFloat, s.incrByFloatCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.Lock, s.lockCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.Unlock, s.unlockCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.LockLease, s.lockLeaseCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.DMap.PLockLease, s.plockLeaseCommandHandler)
-	s.server.ServeMux().HandleFunc(protocol.Internal.MoveFragment, s.moveFragmentCommandHandler)
+	s.handleFunc(protocol.DMap.Put, s.putCommandHandler)
+	s.handleFunc(protocol.DMap.Get, s.getCommandHandler)
+	s.handleFunc(protocol.DMap.Del, s.delCommandHandler)
+	s.handleFunc(protocol.DMap.DelEntry, s.delEntryCommandHandler)
+	s.handleFunc(protocol.DMap.MGet, s.mgetCommandHandler)
+	s.handleFunc(protocol.DMap.MPut, s.mputCommandHandler)
+	s.handleFunc(protocol.DMap.CompareAndSwap, s.compareAndSwapCommandHandler)
+	s.handleFunc(protocol.DMap.HSet, s.hsetCommandHandler)
+	s.handleFunc(protocol.DMap.HGet, s.hgetCommandHandler)
+	s.handleFunc(protocol.DMap.HDel, s.hdelCommandHandler)
+	s.handleFunc(protocol.DMap.HGetAll, s.hgetallCommandHandler)
+	s.handleFunc(protocol.DMap.HIncrBy, s.hincrbyCommandHandler)
+	s.handleFunc(protocol.DMap.ZAdd, s.zaddCommandHandler)
+	s.handleFunc(protocol.DMap.ZRange, s.zrangeCommandHandler)
+	s.handleFunc(protocol.DMap.ZRangeByScore, s.zrangebyscoreCommandHandler)
+	s.handleFunc(protocol.DMap.ZRem, s.zremCommandHandler)
+	s.handleFunc(protocol.DMap.ZRank, s.zrankCommandHandler)
+	s.handleFunc(protocol.DMap.ZIncrBy, s.zincrbyCommandHandler)
+	s.handleFunc(protocol.DMap.LPush, s.lpushCommandHandler)
+	s.handleFunc(protocol.DMap.RPush, s.rpushCommandHandler)
+	s.handleFunc(protocol.DMap.LPop, s.lpopCommandHandler)
+	s.handleFunc(protocol.DMap.RPop, s.rpopCommandHandler)
+	s.handleFunc(protocol.DMap.LRange, s.lrangeCommandHandler)
+	s.handleFunc(protocol.DMap.LLen, s.llenCommandHandler)
+	s.handleFunc(protocol.DMap.BLPop, s.blpopCommandHandler)
+	s.handleFunc(protocol.DMap.XAdd, s.xaddCommandHandler)
+	s.handleFunc(protocol.DMap.XRead, s.xreadCommandHandler)
+	s.handleFunc(protocol.DMap.XReadGroup, s.xreadgroupCommandHandler)
+	s.handleFunc(protocol.DMap.XAck, s.xackCommandHandler)
+	s.handleFunc(protocol.DMap.XPending, s.xpendingCommandHandler)
+	s.handleFunc(protocol.DMap.FencedLock, s.fencedLockCommandHandler)
+	s.handleFunc(protocol.DMap.FencedUnlock, s.fencedUnlockCommandHandler)
+	s.handleFunc(protocol.DMap.FencedLockLease, s.fencedLockLeaseCommandHandler)
+	s.handleFunc(protocol.DMap.SemAcquire, s.semAcquireCommandHandler)
+	s.handleFunc(protocol.DMap.RWLock, s.rwLockCommandHandler)
+	s.handleFunc(protocol.DMap.SharedRelease, s.sharedReleaseCommandHandler)
+	s.handleFunc(protocol.DMap.SharedLease, s.sharedLeaseCommandHandler)
+	s.handleFunc(protocol.DMap.Eval, s.evalCommandHandler)
+	s.handleFunc(protocol.DMap.Query, s.queryCommandHandler)
+	s.handleFunc(protocol.DMap.QueryInternal, s.queryInternalCommandHandler)
+	s.handleFunc(protocol.DMap.Aggregate, s.aggregateCommandHandler)
+	s.handleFunc(protocol.DMap.AggregateInternal, s.aggregateInternalCommandHandler)
+	s.handleFunc(protocol.DMap.Execute, s.executeCommandHandler)
+	s.handleFunc(protocol.DMap.GetEntry, s.getEntryCommandHandler)
+	s.handleFunc(protocol.DMap.PutEntry, s.putEntryCommandHandler)
+	s.handleFunc(protocol.DMap.Expire, s.expireCommandHandler)
+	s.handleFunc(protocol.DMap.PExpire, s.pexpireCommandHandler)
+	s.handleFunc(protocol.DMap.Destroy, s.destroyCommandHandler)
+	s.handleFunc(protocol.DMap.Scan, s.scanCommandHandler)
+	s.handleFunc(protocol.DMap.Incr, s.incrCommandHandler)
+	s.handleFunc(protocol.DMap.Decr, s.decrCommandHandler)
+	s.handleFunc(protocol.DMap.GetPut, s.getPutCommandHandler)
+	s.handleFunc(protocol.DMap.IncrByFloat, s.incrByFloatCommandHandler)
+	s.handleFunc(protocol.DMap.Lock, s.lockCommandHandler)
+	s.handleFunc(protocol.DMap.Unlock, s.unlockCommandHandler)
+	s.handleFunc(protocol.DMap.LockLease, s.lockLeaseCommandHandler)
+	s.handleFunc(protocol.DMap.PLockLease, s.plockLeaseCommandHandler)
+	s.handleFunc(protocol.Internal.MoveFragment, s.moveFragmentCommandHandler)
 }
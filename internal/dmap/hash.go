@@ -0,0 +1,223 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	// ErrHashValueNotInteger means that HIncrBy is called on a field that doesn't hold an integer.
+	ErrHashValueNotInteger = errors.New("hash value is not an integer")
+)
+
+// hashPrefix marks the values of the hash entries. The fields of a hash are
+// encoded with msgpack after the prefix.
+var hashPrefix = []byte("\x00olric.hash\x00")
+
+func decodeHash(value []byte) (map[string][]byte, error) {
+	if !bytes.HasPrefix(value, hashPrefix) {
+		return nil, ErrWrongType
+	}
+	fields := make(map[string][]byte)
+	if err := msgpack.Unmarshal(value[len(hashPrefix):], &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func encodeHash(fields map[string][]byte) ([]byte, error) {
+	data, err := msgpack.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 0, len(hashPrefix)+len(data))
+	value = append(value, hashPrefix...)
+	return append(value, data...), nil
+}
+
+// updateHash runs update on the fields of the hash under the fragment lock.
+// update returns false if it doesn't modify the fields. See updateTypedValue.
+func (dm *DMap) updateHash(ctx context.Context, key string, update func(fields map[string][]byte) (bool, error)) error {
+	return dm.updateTypedValue(ctx, key, func(value []byte) ([]byte, bool, error) {
+		fields := make(map[string][]byte)
+		if value != nil {
+			var err error
+			fields, err = decodeHash(value)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		changed, err := update(fields)
+		if err != nil || !changed {
+			return nil, false, err
+		}
+		if len(fields) == 0 {
+			// Empty hashes are removed.
+			return nil, true, nil
+		}
+		value, err = encodeHash(fields)
+		if err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	})
+}
+
+func (dm *DMap) hset(ctx context.Context, key string, fields []string, values [][]byte) (int, error) {
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		cmd := protocol.NewHSet(dm.name, key)
+		for i, field := range fields {
+			cmd.Add(field, values[i])
+		}
+		return dm.processOnKeyOwner(ctx, owner, cmd.Command(ctx))
+	}
+
+	var added int
+	err := dm.updateHash(ctx, key, func(hash map[string][]byte) (bool, error) {
+		for i, field := range fields {
+			if _, ok := hash[field]; !ok {
+				added++
+			}
+			hash[field] = values[i]
+		}
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// HSet sets the fields of the hash stored at key. The hash is created if it
+// doesn't exist. It returns the number of the fields that were added.
+func (dm *DMap) HSet(ctx context.Context, key string, fields map[string]interface{}) (int, error) {
+	var names []string
+	var values [][]byte
+	for field, value := range fields {
+		encoded, err := encodeValue(value)
+		if err != nil {
+			return 0, err
+		}
+		names = append(names, field)
+		values = append(values, encoded)
+	}
+	return dm.hset(ctx, key, names, values)
+}
+
+// HDel removes the fields from the hash stored at key. It returns the number
+// of the fields that were removed. The hash is deleted if it has no fields left.
+func (dm *DMap) HDel(ctx context.Context, key string, fields ...string) (int, error) {
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		return dm.processOnKeyOwner(ctx, owner, protocol.NewHDel(dm.name, key, fields...).Command(ctx))
+	}
+
+	var removed int
+	err := dm.updateHash(ctx, key, func(hash map[string][]byte) (bool, error) {
+		for _, field := range fields {
+			if _, ok := hash[field]; ok {
+				delete(hash, field)
+				removed++
+			}
+		}
+		return removed > 0, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// HIncrBy atomically increments the integer stored at field of the hash by
+// delta. The field is set to zero before the operation if it doesn't exist.
+// It returns the new value.
+func (dm *DMap) HIncrBy(ctx context.Context, key, field string, delta int) (int, error) {
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		return dm.processOnKeyOwner(ctx, owner, protocol.NewHIncrBy(dm.name, key, field, delta).Command(ctx))
+	}
+
+	var updated int
+	err := dm.updateHash(ctx, key, func(hash map[string][]byte) (bool, error) {
+		var current int64
+		if value, ok := hash[field]; ok {
+			var err error
+			current, err = util.ParseInt(value, 10, 64)
+			if err != nil {
+				return false, ErrHashValueNotInteger
+			}
+		}
+		updated = int(current) + delta
+
+		encoded, err := encodeValue(updated)
+		if err != nil {
+			return false, err
+		}
+		hash[field] = encoded
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// HGetAll returns the fields of the hash stored at key with the encoded values.
+// It returns an empty map if the key doesn't exist.
+func (dm *DMap) HGetAll(ctx context.Context, key string) (map[string][]byte, error) {
+	entry, err := dm.Get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return map[string][]byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeHash(entry.Value())
+}
+
+// HGet returns the value of field in the hash stored at key. The key of the
+// returned entry is the field. It returns ErrKeyNotFound if the hash or the
+// field doesn't exist.
+func (dm *DMap) HGet(ctx context.Context, key, field string) (storage.Entry, error) {
+	entry, err := dm.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := decodeHash(entry.Value())
+	if err != nil {
+		return nil, err
+	}
+	value, ok := fields[field]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	e := dm.engine.NewEntry()
+	e.SetKey(field)
+	e.SetValue(value)
+	e.SetTTL(entry.TTL())
+	e.SetTimestamp(entry.Timestamp())
+	return e, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"sort"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+func (s *Service) hsetCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	hsetCmd, err := protocol.ParseHSetCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(hsetCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	added, err := dm.hset(server.RequestContext(conn, s.ctx), hsetCmd.Key, hsetCmd.Fields, hsetCmd.Values)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(added)
+}
+
+func (s *Service) hgetCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	hgetCmd, err := protocol.ParseHGetCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(hgetCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	entry, err := dm.HGet(server.RequestContext(conn, s.ctx), hgetCmd.Key, hgetCmd.Field)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteBulk(entry.Value())
+}
+
+func (s *Service) hdelCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	hdelCmd, err := protocol.ParseHDelCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(hdelCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	removed, err := dm.HDel(server.RequestContext(conn, s.ctx), hdelCmd.Key, hdelCmd.Fields...)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(removed)
+}
+
+func (s *Service) hgetallCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	hgetallCmd, err := protocol.ParseHGetAllCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(hgetallCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	fields, err := dm.HGetAll(server.RequestContext(conn, s.ctx), hgetallCmd.Key)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	// Field and value pairs, like HGETALL of Redis.
+	conn.WriteArray(len(names) * 2)
+	for _, field := range names {
+		conn.WriteBulkString(field)
+		conn.WriteBulk(fields[field])
+	}
+}
+
+func (s *Service) hincrbyCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	hincrbyCmd, err := protocol.ParseHIncrByCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(hincrbyCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	updated, err := dm.HIncrBy(server.RequestContext(conn, s.ctx), hincrbyCmd.Key, hincrbyCmd.Field, hincrbyCmd.Delta)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(updated)
+}
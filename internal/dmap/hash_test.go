@@ -0,0 +1,186 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_Hash(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+
+		added, err := dm1.HSet(ctx, key, map[string]interface{}{"name": "olric", "version": 6})
+		require.NoError(t, err)
+		require.Equal(t, 2, added)
+
+		added, err = dm2.HSet(ctx, key, map[string]interface{}{"name": "olricd", "year": 2018})
+		require.NoError(t, err)
+		require.Equal(t, 1, added)
+
+		e, err := dm2.HGet(ctx, key, "name")
+		require.NoError(t, err)
+		require.Equal(t, "name", e.Key())
+		require.Equal(t, []byte("olricd"), e.Value())
+
+		_, err = dm1.HGet(ctx, key, "missing")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		updated, err := dm2.HIncrBy(ctx, key, "version", 2)
+		require.NoError(t, err)
+		require.Equal(t, 8, updated)
+
+		_, err = dm1.HIncrBy(ctx, key, "name", 1)
+		require.ErrorIs(t, err, ErrHashValueNotInteger)
+
+		fields, err := dm1.HGetAll(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, map[string][]byte{
+			"name":    []byte("olricd"),
+			"version": []byte("8"),
+			"year":    []byte("2018"),
+		}, fields)
+
+		removed, err := dm2.HDel(ctx, key, "name", "year", "missing")
+		require.NoError(t, err)
+		require.Equal(t, 2, removed)
+
+		removed, err = dm1.HDel(ctx, key, "version")
+		require.NoError(t, err)
+		require.Equal(t, 1, removed)
+
+		// The hash is deleted after removing all the fields.
+		_, err = dm1.Get(ctx, key)
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		fields, err = dm2.HGetAll(ctx, key)
+		require.NoError(t, err)
+		require.Empty(t, fields)
+	}
+}
+
+func TestDMap_Hash_WrongType(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue", nil))
+
+	_, err = dm.HSet(ctx, "mykey", map[string]interface{}{"field": "value"})
+	require.ErrorIs(t, err, ErrWrongType)
+
+	_, err = dm.HGet(ctx, "mykey", "field")
+	require.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestDMap_Hash_HIncrBy_Concurrent(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for _, s := range []*Service{s1, s2} {
+		dm, err := s.NewDMap("mymap")
+		require.NoError(t, err)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(dm *DMap) {
+				defer wg.Done()
+				_, err := dm.HIncrBy(ctx, "mykey", "counter", 1)
+				require.NoError(t, err)
+			}(dm)
+		}
+	}
+	wg.Wait()
+
+	dm, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	e, err := dm.HGet(ctx, "mykey", "counter")
+	require.NoError(t, err)
+	require.Equal(t, []byte("100"), e.Value())
+}
+
+func TestDMap_Hash_Replication(t *testing.T) {
+	cluster := testcluster.New(NewService)
+
+	c1 := testutil.NewConfig()
+	c1.ReplicaCount = 2
+	s1 := cluster.AddMember(testcluster.NewEnvironment(c1)).(*Service)
+
+	c2 := testutil.NewConfig()
+	c2.ReplicaCount = 2
+	s2 := cluster.AddMember(testcluster.NewEnvironment(c2)).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err = dm1.HSet(ctx, testutil.ToKey(i), map[string]interface{}{"field": i})
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+		hkey := partitions.HKey("mymap", key)
+
+		var found bool
+		for _, s := range []*Service{s1, s2} {
+			dm, err := s.NewDMap("mymap")
+			require.NoError(t, err)
+
+			part := dm.getPartitionByHKey(hkey, partitions.BACKUP)
+			f, err := dm.loadFragment(part)
+			if err != nil {
+				continue
+			}
+			e, err := f.storage.Get(hkey)
+			if err != nil {
+				continue
+			}
+			fields, err := decodeHash(e.Value())
+			require.NoError(t, err)
+			require.Equal(t, []byte(fmt.Sprintf("%d", i)), fields["field"])
+			found = true
+		}
+		require.True(t, found, "replica of %s could not be found", key)
+	}
+}
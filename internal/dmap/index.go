@@ -0,0 +1,227 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrNoSuchIndex is returned if the index is not declared for the DMap.
+var ErrNoSuchIndex = errors.New("no such index")
+
+// fragmentIndex maps the values of the indexed fields to the keys of a
+// primary fragment. It's protected by the lock of the fragment.
+type fragmentIndex struct {
+	// values is index name -> field value -> hkeys
+	values map[string]map[string]map[uint64]struct{}
+
+	// keys is hkey -> index name -> field value
+	keys map[uint64]map[string]string
+}
+
+func newFragmentIndex() *fragmentIndex {
+	return &fragmentIndex{
+		values: make(map[string]map[string]map[uint64]struct{}),
+		keys:   make(map[uint64]map[string]string),
+	}
+}
+
+func (i *fragmentIndex) remove(hkey uint64) {
+	for name, value := range i.keys[hkey] {
+		hkeys := i.values[name][value]
+		delete(hkeys, hkey)
+		if len(hkeys) == 0 {
+			delete(i.values[name], value)
+		}
+	}
+	delete(i.keys, hkey)
+}
+
+func (i *fragmentIndex) add(hkey uint64, fields map[string]string) {
+	i.remove(hkey)
+	if len(fields) == 0 {
+		return
+	}
+
+	i.keys[hkey] = fields
+	for name, value := range fields {
+		values, ok := i.values[name]
+		if !ok {
+			values = make(map[string]map[uint64]struct{})
+			i.values[name] = values
+		}
+		hkeys, ok := values[value]
+		if !ok {
+			hkeys = make(map[uint64]struct{})
+			values[value] = hkeys
+		}
+		hkeys[hkey] = struct{}{}
+	}
+}
+
+func (i *fragmentIndex) lookup(name, value string) map[uint64]struct{} {
+	return i.values[name][value]
+}
+
+// IndexValue converts a field value to its indexed form. Strings, numbers and
+// booleans are indexed. Numbers are compared by their value, so 3 and 3.0
+// are the same.
+func IndexValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.FormatInt(int64(v), 10), true
+	case int8:
+		return strconv.FormatInt(int64(v), 10), true
+	case int16:
+		return strconv.FormatInt(int64(v), 10), true
+	case int32:
+		return strconv.FormatInt(int64(v), 10), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// lookupField finds the field in a decoded value. Nested fields are separated
+// by dots.
+func lookupField(value interface{}, field string) (interface{}, bool) {
+	for _, name := range strings.Split(field, ".") {
+		switch m := value.(type) {
+		case map[string]interface{}:
+			v, ok := m[name]
+			if !ok {
+				return nil, false
+			}
+			value = v
+		case map[interface{}]interface{}:
+			v, ok := m[name]
+			if !ok {
+				return nil, false
+			}
+			value = v
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// extractIndexFields returns the indexed values of the fields that exist in
+// value. The value is decoded once for every format.
+func (dm *DMap) extractIndexFields(value []byte) map[string]string {
+	decoded := make(map[config.IndexFormat]interface{})
+	var fields map[string]string
+	for _, index := range dm.config.indexes {
+		doc, ok := decoded[index.Format]
+		if !ok {
+			var err error
+			switch index.Format {
+			case config.IndexFormatMsgpack:
+				err = msgpack.Unmarshal(value, &doc)
+			default:
+				err = json.Unmarshal(value, &doc)
+			}
+			if err != nil {
+				doc = nil
+			}
+			decoded[index.Format] = doc
+		}
+		if doc == nil {
+			continue
+		}
+
+		field, ok := lookupField(doc, index.Field)
+		if !ok {
+			continue
+		}
+		indexed, ok := IndexValue(field)
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[index.Name] = indexed
+	}
+	return fields
+}
+
+// loadIndex returns the index of a primary fragment. It's built from the
+// storage if it doesn't exist or it's invalidated. The caller has to hold
+// the lock of the fragment.
+func (dm *DMap) loadIndex(f *fragment) *fragmentIndex {
+	if f.index != nil {
+		return f.index
+	}
+
+	index := newFragmentIndex()
+	f.storage.Range(func(hkey uint64, entry storage.Entry) bool {
+		index.add(hkey, dm.extractIndexFields(entry.Value()))
+		return true
+	})
+	f.index = index
+	return index
+}
+
+// indexEntry updates the index of a primary fragment after a put. The index
+// is built lazily, so it's skipped if it doesn't exist yet.
+func (dm *DMap) indexEntry(f *fragment, hkey uint64, value []byte) {
+	if f.index == nil || dm.config == nil || len(dm.config.indexes) == 0 {
+		return
+	}
+	f.index.add(hkey, dm.extractIndexFields(value))
+}
+
+// unindexEntry updates the index of a primary fragment after a delete.
+func (dm *DMap) unindexEntry(f *fragment, hkey uint64) {
+	if f.index == nil {
+		return
+	}
+	f.index.remove(hkey)
+}
+
+// invalidateIndex drops the index of a fragment after a bulk change, e.g.
+// merging a moved fragment. It's rebuilt by the next query.
+func invalidateIndex(f *fragment) {
+	f.index = nil
+}
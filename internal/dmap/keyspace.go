@@ -0,0 +1,107 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"fmt"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/stats"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// keyspaceEventQueueSize is the capacity of the queue of keyspace events on
+	// a member. The events are dropped if the queue is full.
+	keyspaceEventQueueSize = 8192
+
+	// keyspaceEventBatchSize is the maximum number of keyspace events that are
+	// published in a single pipeline.
+	keyspaceEventBatchSize = 128
+)
+
+// KeyspaceEventsDropped is the number of keyspace events dropped because the
+// queue was full.
+var KeyspaceEventsDropped = stats.NewInt64Counter()
+
+type keyspaceEvent struct {
+	channel string
+	event   config.KeyspaceEvent
+}
+
+// KeyspaceChannel returns the name of the channel that keyspace notifications
+// are published on for the given key.
+func KeyspaceChannel(dmap, key string) string {
+	return fmt.Sprintf("__keyspace@%s__:%s", dmap, key)
+}
+
+// publishKeyspaceEvents publishes the given events in order.
+func (s *Service) publishKeyspaceEvents(events []keyspaceEvent) {
+	rc := s.client.Get(s.rt.This().String())
+	_, err := rc.Pipelined(s.ctx, func(pipe redis.Pipeliner) error {
+		for _, e := range events {
+			pipe.Publish(s.ctx, e.channel, string(e.event))
+		}
+		return nil
+	})
+	if err != nil {
+		s.log.V(3).Printf("[ERROR] Failed to publish %d keyspace events: %v", len(events), err)
+	}
+}
+
+// keyspaceEventWorker publishes the queued keyspace events in the order they
+// are queued. The events that are queued at the same time are published in a
+// single pipeline.
+func (s *Service) keyspaceEventWorker() {
+	defer s.wg.Done()
+
+	events := make([]keyspaceEvent, 0, keyspaceEventBatchSize)
+	for {
+		select {
+		case e := <-s.keyspaceEvents:
+			events = append(events[:0], e)
+		case <-s.ctx.Done():
+			return
+		}
+
+	drain:
+		for len(events) < keyspaceEventBatchSize {
+			select {
+			case e := <-s.keyspaceEvents:
+				events = append(events, e)
+			default:
+				break drain
+			}
+		}
+		s.publishKeyspaceEvents(events)
+	}
+}
+
+// notifyKeyspaceEvent queues the event, if the event class is enabled for this
+// DMap. It never blocks, the event is dropped if the queue is full.
+func (dm *DMap) notifyKeyspaceEvent(event config.KeyspaceEvent, key string) {
+	if dm.config == nil {
+		return
+	}
+	if _, ok := dm.config.keyspaceEvents[event]; !ok {
+		return
+	}
+
+	select {
+	case dm.s.keyspaceEvents <- keyspaceEvent{channel: KeyspaceChannel(dm.name, key), event: event}:
+	default:
+		KeyspaceEventsDropped.Increase(1)
+	}
+}
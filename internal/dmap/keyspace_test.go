@@ -0,0 +1,49 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"testing"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_KeyspaceEvent_Queue(t *testing.T) {
+	s := &Service{keyspaceEvents: make(chan keyspaceEvent, 2)}
+	dm := &DMap{
+		name: "mydmap",
+		s:    s,
+		config: &dmapConfig{
+			keyspaceEvents: map[config.KeyspaceEvent]struct{}{
+				config.KeyspaceEventPut: {},
+				config.KeyspaceEventDel: {},
+			},
+		},
+	}
+
+	dropped := KeyspaceEventsDropped.Read()
+	dm.notifyKeyspaceEvent(config.KeyspaceEventPut, "mykey")
+	// Not enabled
+	dm.notifyKeyspaceEvent(config.KeyspaceEventExpired, "mykey")
+	dm.notifyKeyspaceEvent(config.KeyspaceEventDel, "mykey")
+	// The queue is full
+	dm.notifyKeyspaceEvent(config.KeyspaceEventPut, "mykey")
+	require.Equal(t, dropped+1, KeyspaceEventsDropped.Read())
+
+	channel := KeyspaceChannel("mydmap", "mykey")
+	require.Equal(t, keyspaceEvent{channel: channel, event: config.KeyspaceEventPut}, <-s.keyspaceEvents)
+	require.Equal(t, keyspaceEvent{channel: channel, event: config.KeyspaceEventDel}, <-s.keyspaceEvents)
+}
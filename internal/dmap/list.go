@@ -0,0 +1,591 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// blockingPopInterval is the longest period that BLPop waits on the partition
+// owner before checking the ownership again. It also keeps the blocking
+// commands shorter than the read timeout of the internal client.
+const blockingPopInterval = time.Second
+
+// listChunkSize is the maximum number of elements in a chunk of a list. Push
+// and pop only rewrite the chunk at the head or the tail, not the whole list.
+const listChunkSize = 128
+
+// listPrefix marks the values of the list entries. A listMeta is encoded with
+// msgpack after the prefix. The elements are stored in chunks, see chunkStore.
+var listPrefix = []byte("\x00olric.list\x00")
+
+type listChunk struct {
+	Key    string
+	Length int
+}
+
+// listMeta is the value of a list entry. The chunks are ordered from the head
+// to the tail. ID and Seq make the chunk keys unique.
+type listMeta struct {
+	ID     int64
+	Seq    uint64
+	Length int
+	Chunks []listChunk
+}
+
+func decodeListMeta(value []byte) (*listMeta, error) {
+	if !bytes.HasPrefix(value, listPrefix) {
+		return nil, ErrWrongType
+	}
+	meta := &listMeta{}
+	if err := msgpack.Unmarshal(value[len(listPrefix):], meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func encodeListMeta(meta *listMeta) ([]byte, error) {
+	data, err := msgpack.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 0, len(listPrefix)+len(data))
+	value = append(value, listPrefix...)
+	return append(value, data...), nil
+}
+
+// list is loaded under the fragment lock of its key.
+type list struct {
+	hkey  uint64
+	meta  *listMeta
+	store *chunkStore
+}
+
+func (dm *DMap) loadList(ctx context.Context, part *partitions.Partition, key string, value []byte) (*list, error) {
+	meta := &listMeta{ID: time.Now().UnixNano()}
+	if value != nil {
+		var err error
+		meta, err = decodeListMeta(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	store, err := dm.newChunkStore(ctx, part, key)
+	if err != nil {
+		return nil, err
+	}
+	return &list{
+		hkey:  partitions.HKey(dm.name, key),
+		meta:  meta,
+		store: store,
+	}, nil
+}
+
+func decodeListChunk(data []byte) (interface{}, error) {
+	var elements [][]byte
+	if err := msgpack.Unmarshal(data, &elements); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+func (l *list) chunk(i int) ([][]byte, error) {
+	elements, err := l.store.load(l.meta.Chunks[i].Key, decodeListChunk)
+	if err != nil {
+		return nil, err
+	}
+	return elements.([][]byte), nil
+}
+
+func (l *list) setChunk(i int, elements [][]byte) {
+	l.store.set(l.meta.Chunks[i].Key, elements)
+	l.meta.Chunks[i].Length = len(elements)
+}
+
+func (l *list) addChunk(head bool) int {
+	prefix := fmt.Sprintf("list.%x.%x.%x.", l.hkey, l.meta.ID, l.meta.Seq)
+	l.meta.Seq++
+	c := listChunk{Key: l.store.newKey(prefix)}
+	l.store.set(c.Key, [][]byte(nil))
+	if head {
+		l.meta.Chunks = append([]listChunk{c}, l.meta.Chunks...)
+		return 0
+	}
+	l.meta.Chunks = append(l.meta.Chunks, c)
+	return len(l.meta.Chunks) - 1
+}
+
+func (l *list) removeChunk(i int) {
+	l.store.remove(l.meta.Chunks[i].Key)
+	l.meta.Chunks = append(l.meta.Chunks[:i], l.meta.Chunks[i+1:]...)
+}
+
+func (l *list) push(head bool, value []byte) error {
+	i := len(l.meta.Chunks) - 1
+	if head {
+		i = 0
+	}
+	if len(l.meta.Chunks) == 0 || l.meta.Chunks[i].Length >= listChunkSize {
+		i = l.addChunk(head)
+	}
+
+	elements, err := l.chunk(i)
+	if err != nil {
+		return err
+	}
+	if head {
+		elements = append([][]byte{value}, elements...)
+	} else {
+		elements = append(elements, value)
+	}
+	l.setChunk(i, elements)
+	l.meta.Length++
+	return nil
+}
+
+func (l *list) pop(head bool) ([]byte, error) {
+	if len(l.meta.Chunks) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	i := len(l.meta.Chunks) - 1
+	if head {
+		i = 0
+	}
+
+	elements, err := l.chunk(i)
+	if err != nil {
+		return nil, err
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("%w: empty list chunk: %s", errChunkNotFound, l.meta.Chunks[i].Key)
+	}
+
+	var value []byte
+	if head {
+		value, elements = elements[0], elements[1:]
+	} else {
+		value, elements = elements[len(elements)-1], elements[:len(elements)-1]
+	}
+	if len(elements) == 0 {
+		l.removeChunk(i)
+	} else {
+		l.setChunk(i, elements)
+	}
+	l.meta.Length--
+	return value, nil
+}
+
+// elements returns the elements between the start and stop indexes, both
+// inclusive. See normalizeRange.
+func (l *list) elements(start, stop int) ([][]byte, error) {
+	start, stop, ok := normalizeRange(start, stop, l.meta.Length)
+	if !ok {
+		return [][]byte{}, nil
+	}
+
+	result := make([][]byte, 0, stop-start)
+	var offset int
+	for i, c := range l.meta.Chunks {
+		if offset >= stop {
+			break
+		}
+		if offset+c.Length > start {
+			elements, err := l.chunk(i)
+			if err != nil {
+				return nil, err
+			}
+			from, to := 0, len(elements)
+			if start > offset {
+				from = start - offset
+			}
+			if stop < offset+len(elements) {
+				to = stop - offset
+			}
+			result = append(result, elements[from:to]...)
+		}
+		offset += c.Length
+	}
+	return result, nil
+}
+
+// keyWaiters wakes up the callers that are blocked on a key of this member,
+// like BLPop calls waiting for a push.
+type keyWaiters struct {
+	mtx     sync.Mutex
+	waiters map[uint64]*keyWaiter
+}
+
+type keyWaiter struct {
+	ch   chan struct{}
+	refs int
+}
+
+func newKeyWaiters() *keyWaiters {
+	return &keyWaiters{
+		waiters: make(map[uint64]*keyWaiter),
+	}
+}
+
+// wait returns a channel that is closed by the next notify call for hkey. The
+// caller has to call done when it stops waiting, so the waiters of the keys
+// that are never notified are removed.
+func (l *keyWaiters) wait(hkey uint64) (notified <-chan struct{}, done func()) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	w, ok := l.waiters[hkey]
+	if !ok {
+		w = &keyWaiter{ch: make(chan struct{})}
+		l.waiters[hkey] = w
+	}
+	w.refs++
+
+	return w.ch, func() {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+
+		w.refs--
+		if w.refs == 0 && l.waiters[hkey] == w {
+			delete(l.waiters, hkey)
+		}
+	}
+}
+
+func (l *keyWaiters) notify(hkey uint64) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if w, ok := l.waiters[hkey]; ok {
+		close(w.ch)
+		delete(l.waiters, hkey)
+	}
+}
+
+func (l *keyWaiters) length() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return len(l.waiters)
+}
+
+// awaitNotification blocks until notified is closed or timeout passes.
+func (s *Service) awaitNotification(ctx context.Context, notified <-chan struct{}, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-notified:
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.ctx.Done():
+		return ErrServerGone
+	}
+	return nil
+}
+
+// updateList runs update on the list under the fragment lock. update returns
+// false if it doesn't modify the list. See updateTypedValue.
+func (dm *DMap) updateList(ctx context.Context, key string, update func(l *list) (bool, error)) error {
+	part := dm.getPartitionByHKey(partitions.HKey(dm.name, key), partitions.PRIMARY)
+
+	var l *list
+	err := dm.updateTypedValue(ctx, key, func(value []byte) ([]byte, bool, error) {
+		var err error
+		l, err = dm.loadList(ctx, part, key, value)
+		if err != nil {
+			return nil, false, err
+		}
+
+		changed, err := update(l)
+		if err != nil || !changed {
+			return nil, false, err
+		}
+		if err = l.store.flush(); err != nil {
+			return nil, false, err
+		}
+		if l.meta.Length == 0 {
+			// Empty lists are removed.
+			return nil, true, nil
+		}
+		value, err = encodeListMeta(l.meta)
+		if err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return l.store.deleteRemoved()
+}
+
+// readList runs read on the list stored at key. It has to be called on the
+// partition owner. The list is empty if the key doesn't exist.
+func (dm *DMap) readList(ctx context.Context, key string, read func(l *list) error) error {
+	return dm.readTypedValue(key, func(part *partitions.Partition, value []byte) error {
+		l, err := dm.loadList(ctx, part, key, value)
+		if err != nil {
+			return err
+		}
+		return read(l)
+	})
+}
+
+func (dm *DMap) push(ctx context.Context, head bool, key string, values [][]byte) (int, error) {
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		if head {
+			return dm.processOnKeyOwner(ctx, owner, protocol.NewLPush(dm.name, key, values...).Command(ctx))
+		}
+		return dm.processOnKeyOwner(ctx, owner, protocol.NewRPush(dm.name, key, values...).Command(ctx))
+	}
+
+	var length int
+	err := dm.updateList(ctx, key, func(l *list) (bool, error) {
+		for _, value := range values {
+			if err := l.push(head, value); err != nil {
+				return false, err
+			}
+		}
+		length = l.meta.Length
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	dm.s.listWaiters.notify(partitions.HKey(dm.name, key))
+	return length, nil
+}
+func (dm *DMap) pushValues(ctx context.Context, head bool, key string, values []interface{}) (int, error) {
+	encoded := make([][]byte, 0, len(values))
+	for _, value := range values {
+		value, err := encodeValue(value)
+		if err != nil {
+			return 0, err
+		}
+		encoded = append(encoded, value)
+	}
+	return dm.push(ctx, head, key, encoded)
+}
+
+// LPush inserts the values at the head of the list stored at key. The values
+// are inserted one after the other, so the last one becomes the head. The list
+// is created if it doesn't exist. It returns the length of the list.
+func (dm *DMap) LPush(ctx context.Context, key string, values ...interface{}) (int, error) {
+	return dm.pushValues(ctx, true, key, values)
+}
+
+// RPush inserts the values at the tail of the list stored at key. The list is
+// created if it doesn't exist. It returns the length of the list.
+func (dm *DMap) RPush(ctx context.Context, key string, values ...interface{}) (int, error) {
+	return dm.pushValues(ctx, false, key, values)
+}
+
+func (dm *DMap) pop(ctx context.Context, head bool, key string) ([]byte, error) {
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		cmd := protocol.NewRPop(dm.name, key).Command(ctx)
+		if head {
+			cmd = protocol.NewLPop(dm.name, key).Command(ctx)
+		}
+		rc := dm.s.client.Get(owner.String())
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return nil, protocol.ConvertError(err)
+		}
+		value, err := cmd.Bytes()
+		if err != nil {
+			return nil, protocol.ConvertError(err)
+		}
+		return value, nil
+	}
+
+	var value []byte
+	err := dm.updateList(ctx, key, func(l *list) (bool, error) {
+		var err error
+		value, err = l.pop(head)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (dm *DMap) newListEntry(key string, value []byte) storage.Entry {
+	e := dm.engine.NewEntry()
+	e.SetKey(key)
+	e.SetValue(value)
+	return e
+}
+
+// LPop removes and returns the head of the list stored at key. It returns
+// ErrKeyNotFound if the list doesn't exist.
+func (dm *DMap) LPop(ctx context.Context, key string) (storage.Entry, error) {
+	value, err := dm.pop(ctx, true, key)
+	if err != nil {
+		return nil, err
+	}
+	return dm.newListEntry(key, value), nil
+}
+
+// RPop removes and returns the tail of the list stored at key. It returns
+// ErrKeyNotFound if the list doesn't exist.
+func (dm *DMap) RPop(ctx context.Context, key string) (storage.Entry, error) {
+	value, err := dm.pop(ctx, false, key)
+	if err != nil {
+		return nil, err
+	}
+	return dm.newListEntry(key, value), nil
+}
+
+func (dm *DMap) blpop(ctx context.Context, key string, timeout time.Duration) ([]byte, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	hkey := partitions.HKey(dm.name, key)
+
+	for {
+		wait := blockingPopInterval
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return nil, ErrKeyNotFound
+			}
+			if remaining < wait {
+				wait = remaining
+			}
+		}
+
+		owner, ok := dm.keyOwner(key)
+		if !ok {
+			cmd := protocol.NewBLPop(dm.name, key, wait.Seconds()).Command(ctx)
+			rc := dm.s.client.Get(owner.String())
+			err := rc.Process(ctx, cmd)
+			if err == nil {
+				var value []byte
+				value, err = cmd.Bytes()
+				if err == nil {
+					return value, nil
+				}
+			}
+			err = protocol.ConvertError(err)
+			if !errors.Is(err, ErrKeyNotFound) {
+				return nil, err
+			}
+			continue
+		}
+
+		// Register before trying to pop, so a push between the two calls
+		// isn't missed.
+		notified, done := dm.s.listWaiters.wait(hkey)
+		value, err := dm.pop(ctx, true, key)
+		if err == nil || !errors.Is(err, ErrKeyNotFound) {
+			done()
+			return value, err
+		}
+
+		err = dm.s.awaitNotification(ctx, notified, wait)
+		done()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// BLPop removes and returns the head of the list stored at key. It blocks
+// until an element is pushed to the list if it's empty. It returns
+// ErrKeyNotFound if the list is still empty after timeout. Zero timeout
+// blocks indefinitely. The blocked callers are not served in order.
+func (dm *DMap) BLPop(ctx context.Context, key string, timeout time.Duration) (storage.Entry, error) {
+	value, err := dm.blpop(ctx, key, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return dm.newListEntry(key, value), nil
+}
+
+// LRange returns the elements of the list stored at key between start and
+// stop, both inclusive. Negative indexes are counted from the tail, -1 is the
+// last element.
+func (dm *DMap) LRange(ctx context.Context, key string, start, stop int) ([][]byte, error) {
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		cmd := protocol.NewLRange(dm.name, key, start, stop).Command(ctx)
+		rc := dm.s.client.Get(owner.String())
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return nil, protocol.ConvertError(err)
+		}
+		res, err := cmd.Result()
+		if err != nil {
+			return nil, protocol.ConvertError(err)
+		}
+		elements := make([][]byte, 0, len(res))
+		for _, item := range res {
+			element, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid element type: %T", item)
+			}
+			elements = append(elements, []byte(element))
+		}
+		return elements, nil
+	}
+
+	var elements [][]byte
+	err := dm.readList(ctx, key, func(l *list) error {
+		var err error
+		elements, err = l.elements(start, stop)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// LLen returns the length of the list stored at key. It returns zero if the
+// key doesn't exist.
+func (dm *DMap) LLen(ctx context.Context, key string) (int, error) {
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		return dm.processOnKeyOwner(ctx, owner, protocol.NewLLen(dm.name, key).Command(ctx))
+	}
+
+	var length int
+	err := dm.readList(ctx, key, func(l *list) error {
+		length = l.meta.Length
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return length, nil
+}
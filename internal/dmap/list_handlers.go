@@ -0,0 +1,167 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"time"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+func (s *Service) lpushCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	lpushCmd, err := protocol.ParseLPushCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(lpushCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	length, err := dm.push(server.RequestContext(conn, s.ctx), true, lpushCmd.Key, lpushCmd.Values)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(length)
+}
+
+func (s *Service) rpushCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	rpushCmd, err := protocol.ParseRPushCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(rpushCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	length, err := dm.push(server.RequestContext(conn, s.ctx), false, rpushCmd.Key, rpushCmd.Values)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(length)
+}
+
+func (s *Service) lpopCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	lpopCmd, err := protocol.ParseLPopCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(lpopCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	value, err := dm.pop(server.RequestContext(conn, s.ctx), true, lpopCmd.Key)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteBulk(value)
+}
+
+func (s *Service) rpopCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	rpopCmd, err := protocol.ParseRPopCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(rpopCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	value, err := dm.pop(server.RequestContext(conn, s.ctx), false, rpopCmd.Key)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteBulk(value)
+}
+
+func (s *Service) lrangeCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	lrangeCmd, err := protocol.ParseLRangeCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(lrangeCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	elements, err := dm.LRange(server.RequestContext(conn, s.ctx), lrangeCmd.Key, lrangeCmd.Start, lrangeCmd.Stop)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteArray(len(elements))
+	for _, element := range elements {
+		conn.WriteBulk(element)
+	}
+}
+
+func (s *Service) llenCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	llenCmd, err := protocol.ParseLLenCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(llenCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	length, err := dm.LLen(server.RequestContext(conn, s.ctx), llenCmd.Key)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(length)
+}
+
+func (s *Service) blpopCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	blpopCmd, err := protocol.ParseBLPopCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(blpopCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	timeout := time.Duration(blpopCmd.Timeout * float64(time.Second))
+	value, err := dm.blpop(server.RequestContext(conn, s.ctx), blpopCmd.Key, timeout)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteBulk(value)
+}
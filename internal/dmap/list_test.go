@@ -0,0 +1,284 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_List(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+
+		length, err := dm1.RPush(ctx, key, "b", "c")
+		require.NoError(t, err)
+		require.Equal(t, 2, length)
+
+		length, err = dm2.LPush(ctx, key, "a", "z")
+		require.NoError(t, err)
+		require.Equal(t, 4, length)
+
+		elements, err := dm2.LRange(ctx, key, 0, -1)
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("z"), []byte("a"), []byte("b"), []byte("c")}, elements)
+
+		elements, err = dm1.LRange(ctx, key, -2, 10)
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("b"), []byte("c")}, elements)
+
+		e, err := dm1.LPop(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, key, e.Key())
+		require.Equal(t, []byte("z"), e.Value())
+
+		e, err = dm2.RPop(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, []byte("c"), e.Value())
+
+		length, err = dm1.LLen(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, 2, length)
+
+		_, err = dm2.LPop(ctx, key)
+		require.NoError(t, err)
+		_, err = dm1.LPop(ctx, key)
+		require.NoError(t, err)
+
+		// The list is deleted after removing all the elements.
+		_, err = dm1.Get(ctx, key)
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		_, err = dm2.RPop(ctx, key)
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		length, err = dm2.LLen(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, 0, length)
+	}
+}
+
+func TestDMap_List_WrongType(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue", nil))
+	_, err = dm.RPush(ctx, "mykey", "value")
+	require.ErrorIs(t, err, ErrWrongType)
+
+	_, err = dm.LLen(ctx, "mykey")
+	require.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestDMap_List_BLPop(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+
+		errCh := make(chan error, 1)
+		valueCh := make(chan []byte, 1)
+		go func() {
+			e, err := dm1.BLPop(ctx, key, 5*time.Second)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			valueCh <- e.Value()
+		}()
+
+		// Give BLPop some time to block.
+		<-time.After(50 * time.Millisecond)
+
+		_, err = dm2.RPush(ctx, key, "job")
+		require.NoError(t, err)
+
+		select {
+		case err := <-errCh:
+			require.NoError(t, err)
+		case value := <-valueCh:
+			require.Equal(t, []byte("job"), value)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "BLPop is still blocked")
+		}
+	}
+}
+
+func TestDMap_List_BLPop_Timeout(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	for _, s := range []*Service{s1, s2} {
+		dm, err := s.NewDMap("mymap")
+		require.NoError(t, err)
+
+		start := time.Now()
+		_, err = dm.BLPop(ctx, "mykey", 100*time.Millisecond)
+		require.ErrorIs(t, err, ErrKeyNotFound)
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	}
+
+	// The waiters are removed after timeout.
+	require.Equal(t, 0, s1.listWaiters.length())
+	require.Equal(t, 0, s2.listWaiters.length())
+}
+
+// countChunks returns the number of the chunks on the primary partitions. The
+// storage engine may keep the stale versions of a chunk until compaction.
+func countChunks(s *Service) int {
+	hkeys := make(map[uint64]struct{})
+	for partID := uint64(0); partID < s.config.PartitionCount; partID++ {
+		part := s.primary.PartitionByID(partID)
+		tmp, ok := part.Map().Load(s.fragmentName(chunksDMapName))
+		if !ok {
+			continue
+		}
+		tmp.(*fragment).storage.RangeHKey(func(hkey uint64) bool {
+			hkeys[hkey] = struct{}{}
+			return true
+		})
+	}
+	return len(hkeys)
+}
+
+func TestDMap_List_Chunks(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	var expected [][]byte
+	for i := 0; i < 3*listChunkSize; i++ {
+		value := []byte(testutil.ToVal(i))
+		_, err = dm1.RPush(ctx, "mykey", value)
+		require.NoError(t, err)
+		expected = append(expected, value)
+	}
+	for i := 0; i < listChunkSize/2; i++ {
+		value := []byte(testutil.ToVal(-i))
+		_, err = dm2.LPush(ctx, "mykey", value)
+		require.NoError(t, err)
+		expected = append([][]byte{value}, expected...)
+	}
+
+	length, err := dm2.LLen(ctx, "mykey")
+	require.NoError(t, err)
+	require.Equal(t, len(expected), length)
+	require.Equal(t, 4, countChunks(s1)+countChunks(s2))
+
+	elements, err := dm1.LRange(ctx, "mykey", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, expected, elements)
+
+	// Spans the chunks.
+	elements, err = dm2.LRange(ctx, "mykey", listChunkSize/2-1, 2*listChunkSize)
+	require.NoError(t, err)
+	require.Equal(t, expected[listChunkSize/2-1:2*listChunkSize+1], elements)
+
+	for len(expected) > 0 {
+		e, err := dm1.LPop(ctx, "mykey")
+		require.NoError(t, err)
+		require.Equal(t, expected[0], e.Value())
+		expected = expected[1:]
+
+		if len(expected) == 0 {
+			break
+		}
+		e, err = dm2.RPop(ctx, "mykey")
+		require.NoError(t, err)
+		require.Equal(t, expected[len(expected)-1], e.Value())
+		expected = expected[:len(expected)-1]
+	}
+
+	// The chunks are deleted with the elements.
+	require.Equal(t, 0, countChunks(s1)+countChunks(s2))
+	_, err = dm1.Get(ctx, "mykey")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestDMap_List_Orphaned_Chunks(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 2*listChunkSize; j++ {
+			_, err = dm.RPush(ctx, testutil.ToKey(i), testutil.ToVal(j))
+			require.NoError(t, err)
+		}
+	}
+	require.Equal(t, 20, countChunks(s))
+
+	// Delete the lists like any other key, the chunks are orphaned.
+	for i := 0; i < 5; i++ {
+		_, err = dm.Delete(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+	}
+
+	suspects := s.sweepChunks(map[uint64]struct{}{})
+	require.Len(t, suspects, 10)
+	require.Equal(t, 20, countChunks(s))
+
+	suspects = s.sweepChunks(suspects)
+	require.Len(t, suspects, 0)
+	require.Equal(t, 10, countChunks(s))
+
+	length, err := dm.LLen(ctx, testutil.ToKey(9))
+	require.NoError(t, err)
+	require.Equal(t, 2*listChunkSize, length)
+}
@@ -0,0 +1,159 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/discovery"
+	"github.com/buraksezer/olric/pkg/storage"
+)
+
+// lockTokenPrefix is the key prefix of the token counters in the locks DMap.
+// Every partition has a counter, so the fencing tokens of a key never go
+// backwards, even after the state of its lock is deleted.
+const lockTokenPrefix = "token."
+
+// lockStateKey returns the key of the state of the lock in the locks DMap.
+func lockStateKey(dmap, key string) string {
+	return strconv.Itoa(len(dmap)) + ":" + dmap + key
+}
+
+// lockOwner returns the hkey of the state of the lock in the locks DMap, its
+// primary owner and true if this member is the owner.
+func (dm *DMap) lockOwner(key string) (uint64, discovery.Member, bool) {
+	hkey := partitions.HKey(locksDMapName, lockStateKey(dm.name, key))
+	member, ok := dm.hkeyOwner(hkey)
+	return hkey, member, ok
+}
+
+// lockTxn is an update on the state of a lock. See updateLockState.
+type lockTxn struct {
+	// value is the encoded state of the lock. It's nil if the lock doesn't
+	// exist. Setting it to nil deletes the state.
+	value []byte
+	// expiresAt is the expiry of the state in milliseconds. Zero means that
+	// the state doesn't expire.
+	expiresAt int64
+	token     uint64
+	tokenUsed bool
+}
+
+// nextToken returns a new fencing token from the counter of the partition.
+func (t *lockTxn) nextToken() uint64 {
+	t.token++
+	t.tokenUsed = true
+	return t.token
+}
+
+// getLiveValue returns the value of the entry or nil if it doesn't exist or
+// has expired. The caller has to hold the lock of the fragment.
+func getLiveValue(f *fragment, hkey uint64) ([]byte, error) {
+	entry, err := f.storage.Get(hkey)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isKeyExpired(entry.TTL()) {
+		return nil, nil
+	}
+	return entry.Value(), nil
+}
+
+// putLockEntry stores an entry of the locks DMap and replicates it. The caller
+// has to hold the lock of the fragment.
+func (dm *DMap) putLockEntry(ctx context.Context, f *fragment, key string, value []byte, expiresAt int64) error {
+	e := newEnv(ctx)
+	e.dmap = locksDMapName
+	e.key = key
+	e.hkey = partitions.HKey(locksDMapName, key)
+	e.value = value
+	e.fragment = f
+	if expiresAt != 0 {
+		e.putConfig.HasPXAT = true
+		e.putConfig.PXAT = time.Duration(expiresAt) * time.Millisecond
+	}
+	return dm.putOnLockedFragment(e)
+}
+
+// updateLockState runs update on the state of the lock for the key under the
+// fragment lock. It has to be called on the owner of the lock, see lockOwner.
+// update returns false if it doesn't modify the state.
+//
+// The state is kept in the locks DMap, so it's invisible to the commands on
+// the DMap of the key and it's exempt from eviction and MapStores. It expires
+// with the leases of the holders.
+func (dm *DMap) updateLockState(ctx context.Context, key string, update func(txn *lockTxn) (bool, error)) error {
+	ldm, err := dm.s.getOrCreateDMap(locksDMapName)
+	if err != nil {
+		return err
+	}
+
+	skey := lockStateKey(dm.name, key)
+	hkey := partitions.HKey(locksDMapName, skey)
+	part := ldm.getPartitionByHKey(hkey, partitions.PRIMARY)
+	f, err := ldm.loadOrCreateFragment(part)
+	if err != nil {
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	txn := &lockTxn{}
+	txn.value, err = getLiveValue(f, hkey)
+	if err != nil {
+		return err
+	}
+
+	tkey := dm.s.colocatedKey(locksDMapName, lockTokenPrefix, part.ID())
+	counter, err := getLiveValue(f, partitions.HKey(locksDMapName, tkey))
+	if err != nil {
+		return err
+	}
+	if len(counter) == 8 {
+		txn.token = binary.BigEndian.Uint64(counter)
+	}
+
+	changed, err := update(txn)
+	if err != nil || !changed {
+		return err
+	}
+
+	// The counter is stored before the state, so the stored state never holds
+	// a token that is greater than the counter.
+	if txn.tokenUsed {
+		counter = make([]byte, 8)
+		binary.BigEndian.PutUint64(counter, txn.token)
+		if err = ldm.putLockEntry(ctx, f, tkey, counter, 0); err != nil {
+			return err
+		}
+	}
+
+	if txn.value == nil {
+		if !f.storage.Check(hkey) {
+			return nil
+		}
+		return ldm.deleteOnCluster(hkey, skey, f)
+	}
+	return ldm.putLockEntry(ctx, f, skey, txn.value, txn.expiresAt)
+}
@@ -0,0 +1,305 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/pkg/mapstore"
+	"github.com/buraksezer/olric/pkg/storage"
+)
+
+// mapStoreOp is the last change of a key that waits to be written to the
+// MapStore.
+type mapStoreOp struct {
+	value   []byte
+	deleted bool
+}
+
+// mapStoreQueue collects the changes of the keys that are applied to the DMap
+// but not written to the MapStore yet. Only the last change of a key is kept.
+type mapStoreQueue struct {
+	mtx    sync.Mutex
+	ops    map[string]*mapStoreOp
+	closed bool
+	done   chan struct{}
+}
+
+func newMapStoreQueue() *mapStoreQueue {
+	return &mapStoreQueue{
+		ops:  make(map[string]*mapStoreOp),
+		done: make(chan struct{}),
+	}
+}
+
+// add queues the change. It returns false if the queue is closed, the change
+// has to be written directly then.
+func (q *mapStoreQueue) add(key string, op *mapStoreOp) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.closed {
+		return false
+	}
+	q.ops[key] = op
+	return true
+}
+
+// take removes the queued change of the key and returns it.
+func (q *mapStoreQueue) take(key string) (*mapStoreOp, bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	op, ok := q.ops[key]
+	if ok {
+		delete(q.ops, key)
+	}
+	return op, ok
+}
+
+// close stops the worker of the queue after it writes the pending changes.
+func (q *mapStoreQueue) close() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.done)
+}
+
+// requeue adds the failed change back, unless the key has a newer change.
+func (q *mapStoreQueue) requeue(key string, op *mapStoreOp) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if _, ok := q.ops[key]; !ok {
+		q.ops[key] = op
+	}
+}
+
+func (q *mapStoreQueue) swap() map[string]*mapStoreOp {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	ops := q.ops
+	q.ops = make(map[string]*mapStoreOp)
+	return ops
+}
+
+func (dm *DMap) flushWriteBehind() {
+	for key, op := range dm.writeBehind.swap() {
+		if err := dm.writeOnMapStore(key, op); err != nil {
+			dm.s.log.V(3).Printf("[ERROR] Failed to write %s to the mapstore of %s: %v", key, dm.name, err)
+			dm.writeBehind.requeue(key, op)
+		}
+	}
+}
+
+func (dm *DMap) writeBehindWorker() {
+	defer dm.s.wg.Done()
+
+	delay := dm.config.mapStore.WriteDelay
+	if delay <= 0 {
+		delay = config.DefaultWriteBehindDelay
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		timer.Reset(delay)
+		select {
+		case <-timer.C:
+			dm.flushWriteBehind()
+		case <-dm.writeBehind.done:
+			// The DMap is destroyed. Write the pending changes before leaving.
+			dm.flushWriteBehind()
+			return
+		case <-dm.s.ctx.Done():
+			// Write the pending changes before leaving.
+			dm.flushWriteBehind()
+			return
+		}
+	}
+}
+
+// writeOnMapStore writes the change of the key to the MapStore.
+func (dm *DMap) writeOnMapStore(key string, op *mapStoreOp) error {
+	if op.deleted {
+		return dm.config.mapStore.Implementation.Delete(key)
+	}
+	return dm.config.mapStore.Implementation.Store(key, op.value)
+}
+
+// queueOnMapStore queues the change of the key. The write-behind worker writes
+// it in the next batch, otherwise flushMapStore writes it.
+func (dm *DMap) queueOnMapStore(key string, op *mapStoreOp) {
+	if dm.config == nil || dm.config.mapStore == nil {
+		return
+	}
+	if dm.writeBehind != nil && dm.writeBehind.add(key, op) {
+		return
+	}
+	dm.writeThrough.add(key, op)
+}
+
+// storeOnMapStore queues the value of the key to be written to the MapStore,
+// if the DMap has one. The typed values, like hashes and lists, are not
+// written because their encoding is internal to Olric. The caller holds the
+// lock of the fragment, so the changes are queued in the order they are
+// applied to the DMap.
+func (dm *DMap) storeOnMapStore(key string, value []byte) {
+	if bytes.HasPrefix(value, typedValuePrefix) {
+		return
+	}
+	dm.queueOnMapStore(key, &mapStoreOp{value: value})
+}
+
+// deleteOnMapStore queues the deletion of the key on the MapStore, if the DMap
+// has one.
+func (dm *DMap) deleteOnMapStore(key string) {
+	dm.queueOnMapStore(key, &mapStoreOp{deleted: true})
+}
+
+// flushMapStore writes the queued changes of the keys to the MapStore. It's
+// called after the fragment lock is released, so a slow MapStore doesn't block
+// the other keys of the partition. The writes of a key are serialized and the
+// first writer takes the last change of the key, so the MapStore never ends up
+// with an older value. It returns the first error.
+func (dm *DMap) flushMapStore(keys ...string) error {
+	if dm.writeThrough == nil {
+		return nil
+	}
+
+	var result error
+	for _, key := range keys {
+		if err := dm.flushKeyToMapStore(key); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func (dm *DMap) flushKeyToMapStore(key string) error {
+	lkey := "mapstore." + dm.name + key
+	dm.s.locker.Lock(lkey)
+	defer func() {
+		err := dm.s.locker.Unlock(lkey)
+		if err != nil {
+			dm.s.log.V(3).Printf("[ERROR] Failed to release the fine grained lock for key: %s on DMap: %s: %v", key, dm.name, err)
+		}
+	}()
+
+	op, ok := dm.writeThrough.take(key)
+	if !ok {
+		// There is no change or a concurrent call has written it.
+		return nil
+	}
+	return dm.writeOnMapStore(key, op)
+}
+
+// deleteEntry deletes the key from the cluster and queues its deletion on the
+// MapStore. Unlike deleteOnCluster, it's used to delete the keys on behalf of
+// the users, the evicted keys are not deleted from the MapStore. The caller
+// has to hold the lock of the fragment and call flushMapStore after releasing
+// it.
+func (dm *DMap) deleteEntry(hkey uint64, key string, f *fragment) error {
+	if err := dm.deleteOnCluster(hkey, key, f); err != nil {
+		return err
+	}
+	dm.deleteOnMapStore(key)
+	return nil
+}
+
+// cacheLoadedValue puts a value that is loaded from the MapStore. It doesn't
+// overwrite the key if it's set in the meantime.
+func (dm *DMap) cacheLoadedValue(ctx context.Context, hkey uint64, key string, value []byte) (storage.Entry, error) {
+	e := newEnv(ctx)
+	e.dmap = dm.name
+	e.key = key
+	e.hkey = hkey
+	e.value = value
+	e.loaded = true
+	e.putConfig.HasNX = true
+
+	err := dm.putOnCluster(e)
+	if errors.Is(err, ErrKeyFound) {
+		// A concurrent write wins.
+		return dm.getOnCluster(ctx, hkey, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dm.prepareEntry(e), nil
+}
+
+// loadFromMapStore loads the key from the MapStore and caches it. Concurrent
+// loads of the same key are merged.
+func (dm *DMap) loadFromMapStore(ctx context.Context, hkey uint64, key string) (storage.Entry, error) {
+	v, err, shared := dm.loader.Do(key, func() (interface{}, error) {
+		value, err := dm.config.mapStore.Implementation.Load(key)
+		if errors.Is(err, mapstore.ErrKeyNotFound) {
+			return nil, ErrKeyNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		// The load is shared by the concurrent callers, so it isn't bound to
+		// the context of the first caller.
+		return dm.cacheLoadedValue(dm.s.ctx, hkey, key, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry := v.(storage.Entry)
+	if shared {
+		// Every caller gets its own copy.
+		tmp := dm.engine.NewEntry()
+		tmp.Decode(entry.Encode())
+		entry = tmp
+	}
+	return entry, nil
+}
+
+// loadAllFromMapStore loads the missing keys of an MGet call from the
+// MapStore with a single LoadAll call, and caches them.
+func (dm *DMap) loadAllFromMapStore(ctx context.Context, keys []string, indexes []int, entries []storage.Entry, errs []error) {
+	missing := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		missing = append(missing, keys[i])
+	}
+
+	values, err := dm.config.mapStore.Implementation.LoadAll(missing)
+	if err != nil {
+		setBatchError(errs, indexes, err)
+		return
+	}
+
+	for _, i := range indexes {
+		value, ok := values[keys[i]]
+		if !ok {
+			continue
+		}
+		entries[i], errs[i] = dm.cacheLoadedValue(ctx, partitions.HKey(dm.name, keys[i]), keys[i], value)
+	}
+}
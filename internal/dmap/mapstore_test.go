@@ -0,0 +1,379 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/buraksezer/olric/pkg/mapstore"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+type testMapStore struct {
+	mtx     sync.RWMutex
+	data    map[string][]byte
+	loads   int64
+	stores  int64
+	failing bool
+	// Store waits on blocked if it's set.
+	blocked  chan struct{}
+	blocking int64
+}
+
+func newTestMapStore() *testMapStore {
+	return &testMapStore{data: make(map[string][]byte)}
+}
+
+func (m *testMapStore) Load(key string) ([]byte, error) {
+	atomic.AddInt64(&m.loads, 1)
+	// Make the concurrent loads overlap.
+	<-time.After(50 * time.Millisecond)
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	value, ok := m.data[key]
+	if !ok {
+		return nil, mapstore.ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (m *testMapStore) LoadAll(keys []string) (map[string][]byte, error) {
+	atomic.AddInt64(&m.loads, 1)
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	result := make(map[string][]byte)
+	for _, key := range keys {
+		if value, ok := m.data[key]; ok {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+func (m *testMapStore) Store(key string, value []byte) error {
+	if m.blocked != nil {
+		atomic.AddInt64(&m.blocking, 1)
+		<-m.blocked
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.failing {
+		return errors.New("mapstore is down")
+	}
+	atomic.AddInt64(&m.stores, 1)
+	m.data[key] = value
+	return nil
+}
+
+func (m *testMapStore) Delete(key string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.failing {
+		return errors.New("mapstore is down")
+	}
+	delete(m.data, key)
+	return nil
+}
+
+func (m *testMapStore) get(key string) ([]byte, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	value, ok := m.data[key]
+	return value, ok
+}
+
+func (m *testMapStore) set(key string, value []byte) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.data[key] = value
+}
+
+func newMapStoreTestCluster(t *testing.T, ms *config.MapStore) (*testcluster.TestCluster, *DMap, *DMap) {
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.DMaps.Custom = map[string]config.DMap{"mymap": {MapStore: ms}}
+		return c
+	}
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(testcluster.NewEnvironment(newConfig())).(*Service)
+	s2 := cluster.AddMember(testcluster.NewEnvironment(newConfig())).(*Service)
+
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+	return cluster, dm1, dm2
+}
+
+func TestDMap_MapStore_ReadThrough(t *testing.T) {
+	store := newTestMapStore()
+	cluster, dm1, dm2 := newMapStoreTestCluster(t, &config.MapStore{Implementation: store})
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		store.set(testutil.ToKey(i), testutil.ToVal(i))
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			for _, dm := range []*DMap{dm1, dm2} {
+				entry, err := dm.Get(ctx, testutil.ToKey(i))
+				require.NoError(t, err)
+				require.Equal(t, testutil.ToVal(i), entry.Value())
+			}
+		}
+		// Loaded once and cached.
+		require.Equal(t, int64(10), atomic.LoadInt64(&store.loads))
+		// Loaded values are not written back.
+		require.Equal(t, int64(0), atomic.LoadInt64(&store.stores))
+
+		_, err := dm1.Get(ctx, "missing-key")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("Single flight", func(t *testing.T) {
+		store.set("concurrent", []byte("value"))
+		loads := atomic.LoadInt64(&store.loads)
+
+		var errGr errgroup.Group
+		for i := 0; i < 10; i++ {
+			errGr.Go(func() error {
+				entry, err := dm1.Get(ctx, "concurrent")
+				if err != nil {
+					return err
+				}
+				if string(entry.Value()) != "value" {
+					return errors.New("invalid value")
+				}
+				return nil
+			})
+		}
+		require.NoError(t, errGr.Wait())
+		require.Less(t, atomic.LoadInt64(&store.loads)-loads, int64(10))
+	})
+
+	t.Run("MGet", func(t *testing.T) {
+		for i := 10; i < 20; i++ {
+			store.set(testutil.ToKey(i), testutil.ToVal(i))
+		}
+		keys := []string{"missing-key"}
+		for i := 10; i < 20; i++ {
+			keys = append(keys, testutil.ToKey(i))
+		}
+
+		entries, errs := dm2.MGet(ctx, keys...)
+		require.ErrorIs(t, errs[0], ErrKeyNotFound)
+		for i := 1; i < len(keys); i++ {
+			require.NoError(t, errs[i])
+			require.Equal(t, testutil.ToVal(i+9), entries[i].Value())
+		}
+	})
+}
+
+func TestDMap_MapStore_WriteThrough(t *testing.T) {
+	store := newTestMapStore()
+	cluster, dm1, dm2 := newMapStoreTestCluster(t, &config.MapStore{Implementation: store})
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, dm1.Put(ctx, testutil.ToKey(i), testutil.ToVal(i), nil))
+		value, ok := store.get(testutil.ToKey(i))
+		require.True(t, ok)
+		require.Equal(t, testutil.ToVal(i), value)
+	}
+
+	for i := 0; i < 10; i++ {
+		_, err := dm2.Delete(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+		_, ok := store.get(testutil.ToKey(i))
+		require.False(t, ok)
+	}
+
+	// The key only exists on the MapStore.
+	store.set("mykey", []byte("myvalue"))
+	_, err := dm1.Delete(ctx, "mykey")
+	require.NoError(t, err)
+	_, ok := store.get("mykey")
+	require.False(t, ok)
+
+	t.Run("MapStore fails", func(t *testing.T) {
+		store.mtx.Lock()
+		store.failing = true
+		store.mtx.Unlock()
+
+		err := dm2.Put(ctx, "mykey", "myvalue", nil)
+		require.Error(t, err)
+
+		store.mtx.Lock()
+		store.failing = false
+		store.mtx.Unlock()
+
+		// The value is stored in the cluster before the MapStore.
+		e, err := dm2.Get(ctx, "mykey")
+		require.NoError(t, err)
+		require.Equal(t, []byte("myvalue"), e.Value())
+		_, ok := store.get("mykey")
+		require.False(t, ok)
+
+		require.NoError(t, dm2.Put(ctx, "mykey", "myvalue", nil))
+		_, ok = store.get("mykey")
+		require.True(t, ok)
+	})
+}
+
+func TestDMap_MapStore_WriteThrough_Fragment_Unlocked(t *testing.T) {
+	store := newTestMapStore()
+	store.blocked = make(chan struct{})
+	cluster, dm1, _ := newMapStoreTestCluster(t, &config.MapStore{Implementation: store})
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	var errGr errgroup.Group
+	errGr.Go(func() error {
+		return dm1.Put(ctx, "mykey", "myvalue", nil)
+	})
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&store.blocking) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The fragment isn't locked while the MapStore is written.
+	got := make(chan []byte, 1)
+	go func() {
+		entry, err := dm1.Get(ctx, "mykey")
+		if err == nil {
+			got <- entry.Value()
+		}
+		close(got)
+	}()
+	select {
+	case value := <-got:
+		require.Equal(t, []byte("myvalue"), value)
+	case <-time.After(5 * time.Second):
+		close(store.blocked)
+		require.Fail(t, "the fragment is locked while the MapStore is written")
+	}
+
+	close(store.blocked)
+	require.NoError(t, errGr.Wait())
+	_, ok := store.get("mykey")
+	require.True(t, ok)
+}
+
+func TestDMap_MapStore_Typed_Values(t *testing.T) {
+	store := newTestMapStore()
+	cluster, dm1, _ := newMapStoreTestCluster(t, &config.MapStore{Implementation: store})
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	_, err := dm1.HSet(ctx, "myhash", map[string]interface{}{"field": "value"})
+	require.NoError(t, err)
+	_, err = dm1.LPush(ctx, "mylist", "value")
+	require.NoError(t, err)
+	_, err = dm1.FencedLock(ctx, "mylock", "owner", 0, 0)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(0), atomic.LoadInt64(&store.stores))
+}
+
+func TestDMap_MapStore_WriteBehind_Destroy(t *testing.T) {
+	store := newTestMapStore()
+	cluster, dm1, _ := newMapStoreTestCluster(t, &config.MapStore{
+		Implementation: store,
+		WriteMode:      config.WriteBehind,
+		WriteDelay:     time.Hour,
+	})
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	require.NoError(t, dm1.Put(ctx, "mykey", "myvalue", nil))
+	_, ok := store.get("mykey")
+	require.False(t, ok)
+
+	// The worker writes the pending changes and stops.
+	queue := dm1.writeBehind
+	require.NoError(t, dm1.Destroy(ctx))
+	require.Eventually(t, func() bool {
+		_, ok := store.get("mykey")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	select {
+	case <-queue.done:
+	default:
+		require.Fail(t, "write-behind queue is still open")
+	}
+	require.False(t, queue.add("mykey", &mapStoreOp{deleted: true}))
+}
+
+func TestDMap_MapStore_WriteBehind(t *testing.T) {
+	store := newTestMapStore()
+	cluster, dm1, dm2 := newMapStoreTestCluster(t, &config.MapStore{
+		Implementation: store,
+		WriteMode:      config.WriteBehind,
+		WriteDelay:     10 * time.Millisecond,
+	})
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 3; j++ {
+			require.NoError(t, dm1.Put(ctx, testutil.ToKey(i), testutil.ToVal(i+j), nil))
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		for i := 0; i < 10; i++ {
+			value, ok := store.get(testutil.ToKey(i))
+			if !ok || string(value) != string(testutil.ToVal(i+2)) {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		_, err := dm2.Delete(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		for i := 0; i < 10; i++ {
+			if _, ok := store.get(testutil.ToKey(i)); ok {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,115 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/pkg/storage"
+)
+
+// persistentEngines returns the storage engines which keep their data on disk.
+func (s *Service) persistentEngines() []storage.Persistent {
+	var result []storage.Persistent
+	seen := make(map[storage.Engine]struct{})
+	add := func(engine storage.Engine) {
+		if engine == nil {
+			return
+		}
+		if _, ok := seen[engine]; ok {
+			return
+		}
+		seen[engine] = struct{}{}
+		if p, ok := engine.(storage.Persistent); ok {
+			result = append(result, p)
+		}
+	}
+
+	add(s.config.DMaps.Engine.Implementation)
+	for _, dc := range s.config.DMaps.Custom {
+		if dc.Engine != nil {
+			add(dc.Engine.Implementation)
+		}
+	}
+	return result
+}
+
+func (s *Service) loadPersistedFragment(c *storage.Config) error {
+	rawName, err := c.Get(storage.FragmentNameKey)
+	if err != nil {
+		return err
+	}
+	rawPartID, err := c.Get(storage.PartitionIDKey)
+	if err != nil {
+		return err
+	}
+	rawKind, err := c.Get(storage.PartitionKindKey)
+	if err != nil {
+		return err
+	}
+
+	fragmentName, _ := rawName.(string)
+	if !strings.HasPrefix(fragmentName, "dmap.") {
+		// This fragment belongs to a different data structure.
+		return nil
+	}
+
+	partID, _ := rawPartID.(uint64)
+	if partID >= s.config.PartitionCount {
+		return fmt.Errorf("invalid partition ID: %d, partition count: %d", partID, s.config.PartitionCount)
+	}
+
+	var part *partitions.Partition
+	switch kind, _ := rawKind.(string); {
+	case strings.EqualFold(kind, partitions.PRIMARY.String()):
+		part = s.primary.PartitionByID(partID)
+	case strings.EqualFold(kind, partitions.BACKUP.String()):
+		part = s.backup.PartitionByID(partID)
+	default:
+		return fmt.Errorf("unknown partition kind: %v", rawKind)
+	}
+
+	s.Lock()
+	dm, err := s.newDMap(strings.TrimPrefix(fragmentName, "dmap."))
+	s.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Forking the storage engine with the same configuration reloads the data.
+	_, err = dm.loadOrCreateFragment(part)
+	return err
+}
+
+// loadPersistedFragments reopens the DMap fragments which are left on disk by a
+// previous run. The balancer moves them to the current partition owners.
+func (s *Service) loadPersistedFragments() error {
+	for _, engine := range s.persistentEngines() {
+		configs, err := engine.Persisted()
+		if err != nil {
+			return err
+		}
+		for _, c := range configs {
+			err = s.loadPersistedFragment(c)
+			if err != nil {
+				s.log.V(3).Printf("[ERROR] Failed to load persisted DMap fragment: %v", err)
+				continue
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_PersistedFragments(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "olric-dmap")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dataDir))
+	}()
+
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.DMaps.Engine = config.NewEngine()
+		c.DMaps.Engine.Name = config.DiskStorageEngine
+		c.DMaps.Engine.Config["dataDir"] = dataDir
+		require.NoError(t, c.DMaps.Engine.Sanitize())
+		return c
+	}
+
+	ctx := context.Background()
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(testcluster.NewEnvironment(newConfig())).(*Service)
+
+	dm, err := s.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		err = dm.Put(ctx, testutil.ToKey(i), testutil.ToVal(i), nil)
+		require.NoError(t, err)
+	}
+	cluster.Shutdown()
+
+	cluster = testcluster.New(NewService)
+	s = cluster.AddMember(testcluster.NewEnvironment(newConfig())).(*Service)
+	defer cluster.Shutdown()
+
+	dm, err = s.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	// The service loads the persisted fragments in the background.
+	err = testutil.TryWithInterval(50, 10*time.Millisecond, func() error {
+		for i := 0; i < 100; i++ {
+			if _, err := dm.Get(ctx, testutil.ToKey(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		gr, err := dm.Get(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+		require.Equal(t, testutil.ToVal(i), gr.Value())
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/buraksezer/olric/internal/stats"
 	"github.com/buraksezer/olric/pkg/storage"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var pool = bufpool.New()
@@ -42,6 +43,10 @@ var (
 	ErrWriteQuorum   = errors.New("write quorum cannot be reached")
 	ErrKeyTooLarge   = errors.New("key too large")
 	ErrEntryTooLarge = errors.New("entry too large for the configured table size")
+
+	// ErrVersionMismatch means that the timestamp of the current entry doesn't
+	// match the expected one.
+	ErrVersionMismatch = errors.New("version mismatch")
 )
 
 func prepareTTL(e *env) int64 {
@@ -86,6 +91,7 @@ func (dm *DMap) putEntryOnFragment(e *env, nt storage.Entry) error {
 	if err != nil {
 		return err
 	}
+	dm.indexEntry(e.fragment, e.hkey, e.value)
 
 	// total number of entries stored during the life of this instance.
 	EntriesTotal.Increase(1)
@@ -133,9 +139,15 @@ func (dm *DMap) putOnReplicaFragment(e *env) error {
 func (dm *DMap) asyncPutOnBackup(e *env, data []byte, owner discovery.Member) {
 	defer dm.s.wg.Done()
 
+	ctx, span := dm.s.startSpan(e.ctx, "dmap.asyncPutOnBackup",
+		attribute.String("olric.dmap", e.dmap),
+		attribute.String("olric.replica", owner.String()),
+	)
+	defer span.End()
+
 	rc := dm.s.client.Get(owner.String())
-	cmd := protocol.NewPutEntry(e.dmap, e.key, data).Command(dm.s.ctx)
-	err := rc.Process(dm.s.ctx, cmd)
+	cmd := protocol.NewPutEntry(e.dmap, e.key, data).Command(ctx)
+	err := rc.Process(ctx, cmd)
 	if err != nil {
 		if dm.s.log.V(3).Ok() {
 			dm.s.log.V(3).Printf("[ERROR] Failed to create replica in async mode: %v", err)
@@ -178,10 +190,18 @@ func (dm *DMap) syncPutOnCluster(e *env, nt storage.Entry) error {
 	encodedEntry := nt.Encode()
 
 	owners := dm.s.backup.PartitionOwnersByHKey(e.hkey)
+
+	ctx, span := dm.s.startSpan(e.ctx, "dmap.syncPutOnCluster",
+		attribute.String("olric.dmap", e.dmap),
+		attribute.Int("olric.replicas", len(owners)),
+		attribute.Int("olric.write_quorum", dm.s.config.WriteQuorum),
+	)
+	defer span.End()
+
 	for _, owner := range owners {
 		rc := dm.s.client.Get(owner.String())
-		cmd := protocol.NewPutEntry(dm.name, e.key, encodedEntry).Command(dm.s.ctx)
-		err := rc.Process(dm.s.ctx, cmd)
+		cmd := protocol.NewPutEntry(dm.name, e.key, encodedEntry).Command(ctx)
+		err := rc.Process(ctx, cmd)
 		if err != nil {
 			return protocol.ConvertError(err)
 		}
@@ -286,10 +306,47 @@ func (dm *DMap) checkPutConditions(e *env) error {
 			return err
 		}
 	}
+
+	// Only set the key if the timestamp of the current entry matches.
+	if e.putConfig.HasIfVersion {
+		return dm.checkVersion(e)
+	}
+	return nil
+}
+
+// checkVersion compares the timestamp of the current entry with the expected one.
+// A missing or expired key has version 0.
+func (dm *DMap) checkVersion(e *env) error {
+	var version int64
+	entry, err := e.fragment.storage.Get(e.hkey)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if entry != nil && !isKeyExpired(entry.TTL()) {
+		version = entry.Timestamp()
+	}
+
+	if version != e.putConfig.IfVersion {
+		return ErrVersionMismatch
+	}
 	return nil
 }
 
 func (dm *DMap) putOnCluster(e *env) error {
+	if err := dm.putOnFragment(e); err != nil {
+		return err
+	}
+	if e.loaded {
+		return nil
+	}
+	// The MapStore is written after the fragment lock is released.
+	return dm.flushMapStore(e.key)
+}
+
+func (dm *DMap) putOnFragment(e *env) error {
 	part := dm.getPartitionByHKey(e.hkey, partitions.PRIMARY)
 	f, err := dm.loadOrCreateFragment(part)
 	if err != nil {
@@ -304,18 +361,42 @@ func (dm *DMap) putOnCluster(e *env) error {
 		return err
 	}
 
+	return dm.putOnLockedFragment(e)
+}
+
+// putOnLockedFragment stores the entry on e.fragment and replicates it. The
+// value is queued to be written to the MapStore after it's stored in the
+// cluster. The caller has to hold the lock of the fragment and call
+// flushMapStore after releasing it.
+func (dm *DMap) putOnLockedFragment(e *env) error {
+	if err := dm.putAndReplicate(e); err != nil {
+		return err
+	}
+	if e.loaded || e.putConfig.OnlyUpdateTTL {
+		return nil
+	}
+	dm.storeOnMapStore(e.key, e.value)
+	return nil
+}
+
+func (dm *DMap) putAndReplicate(e *env) error {
 	if dm.config != nil {
 		if dm.config.ttlDuration.Seconds() != 0 && e.timeout.Seconds() == 0 {
 			e.timeout = dm.config.ttlDuration
 		}
 		if dm.config.evictionPolicy == config.LRUEviction {
-			if err = dm.setLRUEvictionStats(e); err != nil {
+			if err := dm.setLRUEvictionStats(e); err != nil {
 				return err
 			}
 		}
 	}
 
 	nt := dm.prepareEntry(e)
+	// Only the mutations on the partition owner are logged. Replicas and read
+	// repairs are not.
+	if err := dm.logPut(e, nt); err != nil {
+		return err
+	}
 	if dm.s.config.ReplicaCount > config.MinimumReplicaCount {
 		switch dm.s.config.ReplicationMode {
 		case config.AsyncReplicationMode:
@@ -354,7 +435,11 @@ func (dm *DMap) writePutCommand(e *env) (*redis.StatusCmd, error) {
 		cmd.SetXX()
 	}
 
-	return cmd.Command(dm.s.ctx), nil
+	if e.putConfig.HasIfVersion {
+		cmd.SetIfVersion(e.putConfig.IfVersion)
+	}
+
+	return cmd.Command(e.ctx), nil
 }
 
 // put controls every write operation in Olric. It redirects the requests to its owner,
@@ -364,7 +449,16 @@ func (dm *DMap) put(e *env) error {
 	member := dm.s.primary.PartitionByHKey(e.hkey).Owner()
 	if member.CompareByName(dm.s.rt.This()) {
 		// We are on the partition owner.
-		return dm.putOnCluster(e)
+		err := dm.putOnCluster(e)
+		if err != nil {
+			return err
+		}
+		if e.putConfig.OnlyUpdateTTL {
+			dm.notifyKeyspaceEvent(config.KeyspaceEventExpire, e.key)
+		} else {
+			dm.notifyKeyspaceEvent(config.KeyspaceEventPut, e.key)
+		}
+		return nil
 	}
 
 	// Redirect to the partition owner.
@@ -391,6 +485,8 @@ type PutConfig struct {
 	PXAT          time.Duration
 	HasNX         bool
 	HasXX         bool
+	HasIfVersion  bool
+	IfVersion     int64
 	OnlyUpdateTTL bool
 }
 
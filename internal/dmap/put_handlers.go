@@ -19,6 +19,7 @@ import (
 
 	"github.com/buraksezer/olric/internal/cluster/partitions"
 	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
 	"github.com/tidwall/redcon"
 )
 
@@ -54,7 +55,12 @@ func (s *Service) putCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 		pc.PXAT = time.Duration(putCmd.PXAT * int64(time.Millisecond))
 	}
 
-	e := newEnv(s.ctx)
+	if putCmd.HasIfVersion {
+		pc.HasIfVersion = true
+		pc.IfVersion = putCmd.IfVersion
+	}
+
+	e := newEnv(server.RequestContext(conn, s.ctx))
 	e.putConfig = &pc
 	e.dmap = putCmd.DMap
 	e.key = putCmd.Key
@@ -80,7 +86,7 @@ func (s *Service) putEntryCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 		return
 	}
 
-	e := newEnv(s.ctx)
+	e := newEnv(server.RequestContext(conn, s.ctx))
 	e.hkey = partitions.HKey(putEntryCmd.DMap, putEntryCmd.Key)
 	e.dmap = putEntryCmd.DMap
 	e.key = putEntryCmd.Key
@@ -0,0 +1,185 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultQueryCount is the default number of entries in a page of Query.
+const DefaultQueryCount = 100
+
+// QueryConfig is the configuration of a Query call.
+type QueryConfig struct {
+	Count int
+	After string
+}
+
+// QueryEntry is an entry returned by Query.
+type QueryEntry struct {
+	Key   string
+	Value []byte
+}
+
+func (dm *DMap) hasIndex(name string) bool {
+	if dm.config == nil {
+		return false
+	}
+	for _, index := range dm.config.indexes {
+		if index.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sortQueryEntries sorts the entries by key, removes the duplicates and keeps
+// the first count entries.
+func sortQueryEntries(entries []QueryEntry, count int) []QueryEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	result := entries[:0]
+	for i, entry := range entries {
+		if i > 0 && entry.Key == entries[i-1].Key {
+			continue
+		}
+		if len(result) == count {
+			break
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func (dm *DMap) queryFragment(f *fragment, index, value, after string, entries []QueryEntry) []QueryEntry {
+	f.Lock()
+	defer f.Unlock()
+
+	for hkey := range dm.loadIndex(f).lookup(index, value) {
+		entry, err := f.storage.Get(hkey)
+		if err != nil || isKeyExpired(entry.TTL()) {
+			continue
+		}
+		if entry.Key() <= after {
+			continue
+		}
+		data := make([]byte, len(entry.Value()))
+		copy(data, entry.Value())
+		entries = append(entries, QueryEntry{Key: entry.Key(), Value: data})
+	}
+	return entries
+}
+
+// queryLocal runs the query on the primary partitions owned by this member.
+func (dm *DMap) queryLocal(index, value, after string, count int) ([]QueryEntry, error) {
+	if !dm.hasIndex(index) {
+		return nil, ErrNoSuchIndex
+	}
+
+	var entries []QueryEntry
+	for partID := uint64(0); partID < dm.s.config.PartitionCount; partID++ {
+		part := dm.s.primary.PartitionByID(partID)
+		if !part.Owner().CompareByID(dm.s.rt.This()) {
+			continue
+		}
+		f, err := dm.loadFragment(part)
+		if errors.Is(err, errFragmentNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = dm.queryFragment(f, index, value, after, entries)
+	}
+	return sortQueryEntries(entries, count), nil
+}
+
+// fanOut calls local for this member and remote for the other members of the
+// cluster concurrently. It returns the first error.
+func (dm *DMap) fanOut(ctx context.Context, local func() error, remote func(context.Context, *redis.Client) error) error {
+	errGr, ctx := errgroup.WithContext(ctx)
+	for _, member := range dm.s.rt.Discovery().GetMembers() {
+		member := member
+		errGr.Go(func() error {
+			if member.CompareByID(dm.s.rt.This()) {
+				return local()
+			}
+			return remote(ctx, dm.s.client.Get(member.String()))
+		})
+	}
+	return errGr.Wait()
+}
+
+// Query returns the entries whose indexed field equals value. The entries are
+// sorted by key, and the ones with a key smaller than or equal to after are
+// skipped, so the key of the last entry of a page is the cursor of the next
+// page. It returns at most count entries. It fans out to all the members of
+// the cluster and merges the results.
+func (dm *DMap) Query(ctx context.Context, index, value, after string, count int) ([]QueryEntry, error) {
+	if !dm.hasIndex(index) {
+		return nil, ErrNoSuchIndex
+	}
+	if count <= 0 {
+		count = DefaultQueryCount
+	}
+
+	var (
+		mtx     sync.Mutex
+		entries []QueryEntry
+	)
+	err := dm.fanOut(ctx, func() error {
+		result, err := dm.queryLocal(index, value, after, count)
+		if err != nil {
+			return err
+		}
+		mtx.Lock()
+		entries = append(entries, result...)
+		mtx.Unlock()
+		return nil
+	}, func(ctx context.Context, rc *redis.Client) error {
+		cmd := protocol.NewQueryInternal(dm.name, index, value).
+			SetCount(count).
+			SetAfter(after).
+			Command(ctx)
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return protocol.ConvertError(err)
+		}
+		pairs, err := cmd.Result()
+		if err != nil {
+			return protocol.ConvertError(err)
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		for i := 0; i+1 < len(pairs); i += 2 {
+			entries = append(entries, QueryEntry{Key: pairs[i], Value: []byte(pairs[i+1])})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sortQueryEntries(entries, count), nil
+}
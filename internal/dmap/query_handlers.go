@@ -0,0 +1,71 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+func writeQueryEntries(conn redcon.Conn, entries []QueryEntry) {
+	conn.WriteArray(len(entries) * 2)
+	for _, entry := range entries {
+		conn.WriteBulkString(entry.Key)
+		conn.WriteBulk(entry.Value)
+	}
+}
+
+func (s *Service) queryCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	queryCmd, err := protocol.ParseQueryCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(queryCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	entries, err := dm.Query(server.RequestContext(conn, s.ctx), queryCmd.Index, queryCmd.Value, queryCmd.After, queryCmd.Count)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	writeQueryEntries(conn, entries)
+}
+
+func (s *Service) queryInternalCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	queryCmd, err := protocol.ParseQueryCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(queryCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	entries, err := dm.queryLocal(queryCmd.Index, queryCmd.Value, queryCmd.After, queryCmd.Count)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	writeQueryEntries(conn, entries)
+}
@@ -0,0 +1,152 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func newQueryTestConfig() *config.Config {
+	c := testutil.NewConfig()
+	c.DMaps.Custom = map[string]config.DMap{
+		"mymap": {
+			Indexes: []config.Index{
+				{Name: "by-city", Field: "address.city", Format: config.IndexFormatJSON},
+				{Name: "by-age", Field: "age", Format: config.IndexFormatJSON},
+			},
+		},
+		"packed": {
+			Indexes: []config.Index{
+				{Name: "by-city", Field: "city", Format: config.IndexFormatMsgpack},
+			},
+		},
+	}
+	return c
+}
+
+func queryKeys(entries []QueryEntry) []string {
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func TestDMap_Query(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(testcluster.NewEnvironment(newQueryTestConfig())).(*Service)
+	s2 := cluster.AddMember(testcluster.NewEnvironment(newQueryTestConfig())).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	var istanbul []string
+	for i := 0; i < 20; i++ {
+		city := "ankara"
+		if i%2 == 0 {
+			city = "istanbul"
+			istanbul = append(istanbul, testutil.ToKey(i))
+		}
+		value := fmt.Sprintf(`{"age": %d, "address": {"city": %q}}`, i%3, city)
+		require.NoError(t, dm1.Put(ctx, testutil.ToKey(i), []byte(value), nil))
+	}
+	// Not indexed
+	require.NoError(t, dm1.Put(ctx, "not-json", []byte("istanbul"), nil))
+
+	t.Run("Query", func(t *testing.T) {
+		entries, err := dm2.Query(ctx, "by-city", "istanbul", "", 0)
+		require.NoError(t, err)
+		require.ElementsMatch(t, istanbul, queryKeys(entries))
+		for i := 1; i < len(entries); i++ {
+			require.Less(t, entries[i-1].Key, entries[i].Key)
+		}
+	})
+
+	t.Run("Query by number", func(t *testing.T) {
+		entries, err := dm1.Query(ctx, "by-age", "2", "", 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 6)
+		value, err := dm1.Get(ctx, entries[0].Key)
+		require.NoError(t, err)
+		require.Equal(t, value.Value(), entries[0].Value)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		var keys []string
+		after := ""
+		for {
+			entries, err := dm1.Query(ctx, "by-city", "istanbul", after, 3)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(entries), 3)
+			keys = append(keys, queryKeys(entries)...)
+			if len(entries) < 3 {
+				break
+			}
+			after = entries[len(entries)-1].Key
+		}
+		require.ElementsMatch(t, istanbul, keys)
+	})
+
+	t.Run("Update and delete", func(t *testing.T) {
+		require.NoError(t, dm2.Put(ctx, istanbul[0], []byte(`{"address": {"city": "izmir"}}`), nil))
+		_, err := dm2.Delete(ctx, istanbul[1])
+		require.NoError(t, err)
+
+		entries, err := dm1.Query(ctx, "by-city", "istanbul", "", 0)
+		require.NoError(t, err)
+		require.ElementsMatch(t, istanbul[2:], queryKeys(entries))
+
+		entries, err = dm1.Query(ctx, "by-city", "izmir", "", 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{istanbul[0]}, queryKeys(entries))
+	})
+
+	t.Run("No such index", func(t *testing.T) {
+		_, err := dm1.Query(ctx, "by-name", "foobar", "", 0)
+		require.ErrorIs(t, err, ErrNoSuchIndex)
+	})
+}
+
+func TestDMap_Query_Msgpack(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(testcluster.NewEnvironment(newQueryTestConfig())).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("packed")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		value, err := msgpack.Marshal(map[string]interface{}{"city": i % 2})
+		require.NoError(t, err)
+		require.NoError(t, dm.Put(ctx, testutil.ToKey(i), value, nil))
+	}
+
+	entries, err := dm.Query(ctx, "by-city", "1", "", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+}
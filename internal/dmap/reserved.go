@@ -0,0 +1,68 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/tidwall/redcon"
+)
+
+// reservedDMapPrefix is the name prefix of the DMaps that keep the data of
+// Olric itself, like the chunks of the lists. They are exempt from eviction,
+// TTL, MapStores, indexes and keyspace notifications, whatever the
+// configuration says.
+const reservedDMapPrefix = "olric.internal."
+
+// chunksDMapName is the reserved DMap that stores the chunks. See chunkStore.
+const chunksDMapName = reservedDMapPrefix + "chunks"
+
+// locksDMapName is the reserved DMap that stores the state of the fenced locks,
+// the read-write locks and the semaphores. See updateLockState.
+const locksDMapName = reservedDMapPrefix + "locks"
+
+// IsReservedDMap returns true if the DMap is reserved for internal use.
+func IsReservedDMap(name string) bool {
+	return strings.HasPrefix(name, reservedDMapPrefix)
+}
+
+// colocatedKey returns a key of the reserved DMap that starts with prefix and
+// belongs to the partition.
+func (s *Service) colocatedKey(dmap, prefix string, partID uint64) string {
+	for i := 0; ; i++ {
+		key := prefix + strconv.Itoa(i)
+		if s.primary.PartitionIDByHKey(partitions.HKey(dmap, key)) == partID {
+			return key
+		}
+	}
+}
+
+// rejectReservedDMaps wraps the handler of a public command. The reserved DMaps
+// are only accessed by the members through the internal commands, so the
+// clients cannot read or modify the chunks and the state of the locks.
+func rejectReservedDMaps(command string, handler func(conn redcon.Conn, cmd redcon.Command)) func(conn redcon.Conn, cmd redcon.Command) {
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		name, ok := protocol.DMapName(command, cmd.Args)
+		if ok && IsReservedDMap(name) {
+			protocol.WriteError(conn, fmt.Errorf("%w: %s is reserved for internal use", protocol.ErrInvalidArgument, name))
+			return
+		}
+		handler(conn, cmd)
+	}
+}
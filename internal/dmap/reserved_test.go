@@ -0,0 +1,145 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// reservedKeys returns the keys of the reserved DMap on the member.
+func reservedKeys(s *Service, name string) []string {
+	var keys []string
+	for partID := uint64(0); partID < s.config.PartitionCount; partID++ {
+		part := s.primary.PartitionByID(partID)
+		tmp, ok := part.Map().Load(s.fragmentName(name))
+		if !ok {
+			continue
+		}
+		f := tmp.(*fragment)
+		f.storage.RangeHKey(func(hkey uint64) bool {
+			key, err := f.storage.GetKey(hkey)
+			if err == nil {
+				keys = append(keys, key)
+			}
+			return true
+		})
+	}
+	return keys
+}
+
+// processReserved runs the command on the member and requires that it's
+// rejected.
+func processReserved(t *testing.T, s *Service, cmd redis.Cmder) {
+	ctx := context.Background()
+	rc := s.client.Get(s.rt.This().String())
+	err := rc.Process(ctx, cmd)
+	require.ErrorIs(t, protocol.ConvertError(err), protocol.ErrInvalidArgument, cmd.String())
+}
+
+func TestDMap_Reserved_Chunks(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 2*listChunkSize; i++ {
+		_, err = dm.RPush(ctx, "mylist", i)
+		require.NoError(t, err)
+	}
+	keys := reservedKeys(s, chunksDMapName)
+	require.NotEmpty(t, keys)
+
+	processReserved(t, s, protocol.NewGet(chunksDMapName, keys[0]).Command(ctx))
+	processReserved(t, s, protocol.NewPut(chunksDMapName, keys[0], []byte("value")).Command(ctx))
+	processReserved(t, s, protocol.NewDel(chunksDMapName, keys...).Command(ctx))
+	processReserved(t, s, protocol.NewScan(0, chunksDMapName, 0).Command(ctx))
+	processReserved(t, s, protocol.NewDestroy(chunksDMapName).Command(ctx))
+	processReserved(t, s, protocol.NewDestroy(chunksDMapName).SetLocal().Command(ctx))
+
+	require.ElementsMatch(t, keys, reservedKeys(s, chunksDMapName))
+	length, err := dm.LLen(ctx, "mylist")
+	require.NoError(t, err)
+	require.Equal(t, 2*listChunkSize, length)
+}
+
+func TestDMap_Reserved_Locks_Token(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	first, err := dm.FencedLock(ctx, "mykey", "owner-1", 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, dm.FencedUnlock(ctx, "mykey", "owner-1"))
+
+	var counters []string
+	for _, key := range reservedKeys(s, locksDMapName) {
+		if strings.HasPrefix(key, lockTokenPrefix) {
+			counters = append(counters, key)
+		}
+	}
+	require.NotEmpty(t, counters)
+
+	processReserved(t, s, protocol.NewGet(locksDMapName, counters[0]).Command(ctx))
+	processReserved(t, s, protocol.NewPut(locksDMapName, counters[0], []byte{0}).Command(ctx))
+	processReserved(t, s, protocol.NewDel(locksDMapName, counters...).Command(ctx))
+	processReserved(t, s, protocol.NewScan(0, locksDMapName, 0).Command(ctx))
+	processReserved(t, s, protocol.NewDestroy(locksDMapName).Command(ctx))
+	processReserved(t, s, protocol.NewDestroy(locksDMapName).SetLocal().Command(ctx))
+
+	require.ElementsMatch(t, counters, reservedKeys(s, locksDMapName))
+	token, err := dm.FencedLock(ctx, "mykey", "owner-2", 0, 0)
+	require.NoError(t, err)
+	require.Greater(t, token, first)
+}
+
+func TestDMap_Reserved_Locks_Shared(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	_, err = dm.RWLock(ctx, "rwlock", "writer-1", true, 0, 0)
+	require.NoError(t, err)
+	_, err = dm.SemAcquire(ctx, "semaphore", "owner-1", 1, 0, 0)
+	require.NoError(t, err)
+
+	processReserved(t, s, protocol.NewDel(locksDMapName, lockStateKey("mymap", "rwlock")).Command(ctx))
+	processReserved(t, s, protocol.NewDel(locksDMapName, lockStateKey("mymap", "semaphore")).Command(ctx))
+
+	_, err = dm.RWLock(ctx, "rwlock", "writer-2", true, 0, 0)
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+	_, err = dm.SemAcquire(ctx, "semaphore", "owner-2", 1, 0, 0)
+	require.ErrorIs(t, err, ErrLockNotAcquired)
+
+	require.NoError(t, dm.SharedRelease(ctx, "rwlock", "writer-1"))
+	require.NoError(t, dm.SharedRelease(ctx, "semaphore", "owner-1"))
+}
@@ -0,0 +1,124 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/pkg/storage"
+)
+
+// compareScanValues compares a field with the value of a predicate. Numbers
+// are compared numerically, the rest is compared by its indexed form.
+func compareScanValues(field interface{}, value string) (int, bool) {
+	var number float64
+	switch v := field.(type) {
+	case float64:
+		number = v
+	case int64:
+		number = float64(v)
+	default:
+		indexed, ok := IndexValue(field)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(indexed, value), true
+	}
+
+	other, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case number < other:
+		return -1, true
+	case number > other:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func evalScanPredicate(field interface{}, p protocol.ScanPredicate) bool {
+	res, ok := compareScanValues(field, p.Value)
+	if !ok {
+		// Not comparable, only NE is satisfied.
+		return p.Op == protocol.ScanOpNotEqual
+	}
+	switch p.Op {
+	case protocol.ScanOpEqual:
+		return res == 0
+	case protocol.ScanOpNotEqual:
+		return res != 0
+	case protocol.ScanOpLess:
+		return res < 0
+	case protocol.ScanOpLessOrEqual:
+		return res <= 0
+	case protocol.ScanOpGreater:
+		return res > 0
+	case protocol.ScanOpGreaterOrEqual:
+		return res >= 0
+	default:
+		return false
+	}
+}
+
+// entryDocument decodes the JSON value of an entry on demand, only once.
+type entryDocument struct {
+	value   []byte
+	doc     interface{}
+	decoded bool
+}
+
+func (d *entryDocument) field(path string) (interface{}, bool) {
+	if !d.decoded {
+		if err := json.Unmarshal(d.value, &d.doc); err != nil {
+			d.doc = nil
+		}
+		d.decoded = true
+	}
+	return lookupField(d.doc, path)
+}
+
+// matchScanPredicates returns true if the entry satisfies all the predicates.
+// Entries without a TTL or a field never satisfy a predicate on it.
+func matchScanPredicates(e storage.Entry, doc *entryDocument, predicates []protocol.ScanPredicate) bool {
+	for _, p := range predicates {
+		var field interface{}
+		switch p.Field {
+		case protocol.ScanFieldTTL:
+			if e.TTL() == 0 {
+				return false
+			}
+			field = e.TTL() - time.Now().UnixNano()/1000000
+		case protocol.ScanFieldTimestamp:
+			field = e.Timestamp() / 1000000
+		default:
+			var ok bool
+			field, ok = doc.field(p.Field)
+			if !ok {
+				return false
+			}
+		}
+		if !evalScanPredicate(field, p) {
+			return false
+		}
+	}
+	return true
+}
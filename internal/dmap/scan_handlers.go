@@ -29,21 +29,26 @@ func (dm *DMap) scanOnFragment(f *fragment, cursor uint64, sc *ScanConfig) ([]st
 	var items []string
 	var err error
 
-	if sc.HasMatch {
-		cursor, err = f.storage.ScanRegexMatch(cursor, sc.Match, sc.Count, func(e storage.Entry) bool {
-			items = append(items, e.Key())
+	collect := func(e storage.Entry) bool {
+		if len(sc.Where) > 0 && !matchScanPredicates(e, &entryDocument{value: e.Value()}, sc.Where) {
 			return true
-		})
+		}
+		items = append(items, e.Key())
+		if sc.WithValues {
+			items = append(items, string(e.Value()))
+		}
+		return true
+	}
+
+	if sc.HasMatch {
+		cursor, err = f.storage.ScanRegexMatch(cursor, sc.Match, sc.Count, collect)
 		if err != nil {
 			return nil, 0, err
 		}
 		return items, cursor, nil
 	}
 
-	cursor, err = f.storage.Scan(cursor, sc.Count, func(e storage.Entry) bool {
-		items = append(items, e.Key())
-		return true
-	})
+	cursor, err = f.storage.Scan(cursor, sc.Count, collect)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -68,11 +73,13 @@ func (dm *DMap) Scan(partID, cursor uint64, sc *ScanConfig) ([]string, uint64, e
 }
 
 type ScanConfig struct {
-	HasCount bool
-	Count    int
-	HasMatch bool
-	Match    string
-	Replica  bool
+	HasCount   bool
+	Count      int
+	HasMatch   bool
+	Match      string
+	Where      []protocol.ScanPredicate
+	WithValues bool
+	Replica    bool
 }
 
 type ScanOption func(*ScanConfig)
@@ -91,6 +98,18 @@ func Match(s string) ScanOption {
 	}
 }
 
+func Where(field, op, value string) ScanOption {
+	return func(cfg *ScanConfig) {
+		cfg.Where = append(cfg.Where, protocol.ScanPredicate{Field: field, Op: op, Value: value})
+	}
+}
+
+func WithValues() ScanOption {
+	return func(cfg *ScanConfig) {
+		cfg.WithValues = true
+	}
+}
+
 func (s *Service) scanCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 	scanCmd, err := protocol.ParseScanCommand(cmd)
 	if err != nil {
@@ -112,6 +131,14 @@ func (s *Service) scanCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 		options = append(options, Match(scanCmd.Match))
 	}
 
+	for _, p := range scanCmd.Where {
+		options = append(options, Where(p.Field, p.Op, p.Value))
+	}
+
+	if scanCmd.WithValues {
+		options = append(options, WithValues())
+	}
+
 	for _, opt := range options {
 		opt(&sc)
 	}
@@ -17,7 +17,9 @@ package dmap
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/buraksezer/olric/internal/protocol"
 	"github.com/buraksezer/olric/internal/testcluster"
@@ -205,3 +207,85 @@ func TestDMap_scanCommandHandler_count(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, keys, 5)
 }
+
+func TestDMap_scanCommandHandler_Where(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	dm, err := s.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		value := fmt.Sprintf(`{"age": %d, "address": {"city": "city-%d"}}`, i, i%2)
+		pc := &PutConfig{}
+		if i%10 == 0 {
+			pc.HasPX = true
+			pc.PX = time.Hour
+		}
+		err = dm.Put(ctx, testutil.ToKey(i), []byte(value), pc)
+		require.NoError(t, err)
+	}
+
+	scan := func(t *testing.T, r *protocol.Scan) []string {
+		rc := s.client.Get(s.rt.This().String())
+		var items []string
+		for partID := uint64(0); partID < s.config.PartitionCount; partID++ {
+			var cursor uint64
+			for {
+				r.PartID = partID
+				r.Cursor = cursor
+				cmd := r.Command(ctx)
+				require.NoError(t, rc.Process(ctx, cmd))
+				var page []string
+				page, cursor, err = cmd.Result()
+				require.NoError(t, err)
+				items = append(items, page...)
+				if cursor == 0 {
+					break
+				}
+			}
+		}
+		return items
+	}
+
+	t.Run("JSON path", func(t *testing.T) {
+		r := protocol.NewScan(0, "mydmap", 0).
+			AddWhere("address.city", protocol.ScanOpEqual, "city-1").
+			AddWhere("age", protocol.ScanOpGreaterOrEqual, "50").
+			AddWhere("age", protocol.ScanOpLess, "60")
+		var expected []string
+		for i := 51; i < 60; i += 2 {
+			expected = append(expected, testutil.ToKey(i))
+		}
+		require.ElementsMatch(t, expected, scan(t, r))
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		r := protocol.NewScan(0, "mydmap", 0).
+			AddWhere(protocol.ScanFieldTTL, protocol.ScanOpGreater, "60000")
+		require.Len(t, scan(t, r), 10)
+	})
+
+	t.Run("Timestamp", func(t *testing.T) {
+		now := strconv.FormatInt(time.Now().UnixNano()/1000000, 10)
+		r := protocol.NewScan(0, "mydmap", 0).
+			AddWhere(protocol.ScanFieldTimestamp, protocol.ScanOpGreater, now)
+		require.Empty(t, scan(t, r))
+
+		r = protocol.NewScan(0, "mydmap", 0).
+			AddWhere(protocol.ScanFieldTimestamp, protocol.ScanOpLessOrEqual, now)
+		require.Len(t, scan(t, r), 100)
+	})
+
+	t.Run("With values", func(t *testing.T) {
+		r := protocol.NewScan(0, "mydmap", 0).
+			AddWhere("age", protocol.ScanOpEqual, "7").
+			SetWithValues()
+		require.Equal(t, []string{
+			testutil.ToKey(7),
+			`{"age": 7, "address": {"city": "city-1"}}`,
+		}, scan(t, r))
+	})
+}
@@ -29,8 +29,11 @@ import (
 	"github.com/buraksezer/olric/internal/protocol"
 	"github.com/buraksezer/olric/internal/server"
 	"github.com/buraksezer/olric/internal/service"
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/wal"
 	"github.com/buraksezer/olric/pkg/flog"
 	"github.com/buraksezer/olric/pkg/storage"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var errFragmentNotFound = errors.New("fragment not found")
@@ -53,9 +56,20 @@ type Service struct {
 	locker  *locker.Locker
 	dmaps   map[string]*DMap
 	storage *storageMap
+	wal     *wal.WAL
+	tracer  trace.Tracer
 	wg      sync.WaitGroup
 	ctx     context.Context
 	cancel  context.CancelFunc
+
+	// listWaiters wakes up the blocked BLPop calls on this member.
+	listWaiters *keyWaiters
+
+	// lockWaiters wakes up the blocked FencedLock calls on this member.
+	lockWaiters *keyWaiters
+
+	// keyspaceEvents is the queue of the keyspace events to publish.
+	keyspaceEvents chan keyspaceEvent
 }
 
 func registerErrors() {
@@ -68,6 +82,17 @@ func registerErrors() {
 	protocol.SetError("ENTRYTOOLARGE", ErrEntryTooLarge)
 	protocol.SetError("KEYNOTFOUND", ErrKeyNotFound)
 	protocol.SetError("KEYFOUND", ErrKeyFound)
+	protocol.SetError("VERSIONMISMATCH", ErrVersionMismatch)
+	protocol.SetError("WRONGTYPE", ErrWrongType)
+	protocol.SetError("NOTINTEGER", ErrHashValueNotInteger)
+	protocol.SetError("SCORENAN", ErrScoreNaN)
+	protocol.SetError("INVALIDSTREAMID", ErrInvalidStreamID)
+	protocol.SetError("NOGROUP", ErrNoSuchGroup)
+	protocol.SetError("INVALIDSNAPSHOT", ErrInvalidSnapshot)
+	protocol.SetError("SCRIPT", ErrScript)
+	protocol.SetError("CROSSPARTITION", ErrCrossPartition)
+	protocol.SetError("NOSUCHINDEX", ErrNoSuchIndex)
+	protocol.SetError("NOSUCHPROCESSOR", ErrNoSuchProcessor)
 }
 
 func NewService(e *environment.Environment) (service.Service, error) {
@@ -85,9 +110,17 @@ func NewService(e *environment.Environment) (service.Service, error) {
 			engines: make(map[string]storage.Engine),
 			configs: make(map[string]map[string]interface{}),
 		},
-		dmaps:  make(map[string]*DMap),
-		ctx:    ctx,
-		cancel: cancel,
+		dmaps:          make(map[string]*DMap),
+		keyspaceEvents: make(chan keyspaceEvent, keyspaceEventQueueSize),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	s.tracer = tracing.Tracer(s.config.TracerProvider)
+	s.listWaiters = newKeyWaiters()
+	s.lockWaiters = newKeyWaiters()
+	if err := s.openWAL(); err != nil {
+		cancel()
+		return nil, err
 	}
 	registerErrors()
 	s.RegisterHandlers()
@@ -130,6 +163,23 @@ func (s *Service) publishEvent(e events.Event) {
 
 // Start starts the distributed map service.
 func (s *Service) Start() error {
+	if err := s.loadPersistedFragments(); err != nil {
+		return err
+	}
+
+	if err := s.replayWAL(); err != nil {
+		return err
+	}
+	if s.wal != nil {
+		s.wg.Add(1)
+		go s.walCompactionWorker()
+
+		if s.config.DMaps.WAL.FsyncPolicy == config.FsyncInterval {
+			s.wg.Add(1)
+			go s.walSyncWorker()
+		}
+	}
+
 	s.wg.Add(1)
 	go s.janitorWorker()
 
@@ -139,6 +189,12 @@ func (s *Service) Start() error {
 	s.wg.Add(1)
 	go s.evictKeysAtBackground()
 
+	s.wg.Add(1)
+	go s.keyspaceEventWorker()
+
+	s.wg.Add(1)
+	go s.chunkSweepWorker()
+
 	return nil
 }
 
@@ -159,6 +215,10 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 	case <-done:
 	}
+
+	if s.wal != nil {
+		return s.wal.Close()
+	}
 	return nil
 }
 
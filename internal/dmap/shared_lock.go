@@ -0,0 +1,306 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	// semaphorePrefix marks the values of the semaphores.
+	semaphorePrefix = []byte("\x00olric.semaphore\x00")
+
+	// rwLockPrefix marks the values of the read-write locks.
+	rwLockPrefix = []byte("\x00olric.rwlock\x00")
+)
+
+type lockHolder struct {
+	Token uint64
+	Count int
+	// ExpiresAt is in milliseconds. Zero means that the holder has no lease.
+	ExpiresAt int64
+}
+
+func (h *lockHolder) setLease(now int64, lease time.Duration) {
+	if lease > 0 {
+		h.ExpiresAt = now + lease.Milliseconds()
+		return
+	}
+	h.ExpiresAt = 0
+}
+
+// sharedLock is the state of a semaphore or a read-write lock. Like
+// fencedLock, it's deleted when the last holder is gone and the fencing
+// tokens are taken from the counter of the partition.
+type sharedLock struct {
+	// Exclusive is true if the only holder of a read-write lock is a writer.
+	Exclusive bool
+	Holders   map[string]*lockHolder
+}
+
+// expire removes the holders whose lease has expired.
+func (l *sharedLock) expire(now int64) {
+	for owner, h := range l.Holders {
+		if h.ExpiresAt != 0 && now >= h.ExpiresAt {
+			delete(l.Holders, owner)
+		}
+	}
+	if len(l.Holders) == 0 {
+		l.Exclusive = false
+	}
+}
+
+// nextExpiry returns the earliest expiry of the holders' leases or zero.
+func (l *sharedLock) nextExpiry() int64 {
+	var expiresAt int64
+	for _, h := range l.Holders {
+		if h.ExpiresAt != 0 && (expiresAt == 0 || h.ExpiresAt < expiresAt) {
+			expiresAt = h.ExpiresAt
+		}
+	}
+	return expiresAt
+}
+
+// lastExpiry returns the latest expiry of the holders' leases or zero if a
+// holder has no lease.
+func (l *sharedLock) lastExpiry() int64 {
+	var expiresAt int64
+	for _, h := range l.Holders {
+		if h.ExpiresAt == 0 {
+			return 0
+		}
+		if h.ExpiresAt > expiresAt {
+			expiresAt = h.ExpiresAt
+		}
+	}
+	return expiresAt
+}
+
+// acquire adds owner to the holders, or increases its count if owner is
+// already a holder. It returns the fencing token of the holder.
+func (l *sharedLock) acquire(owner string, now int64, lease time.Duration, nextToken func() uint64) uint64 {
+	h, ok := l.Holders[owner]
+	if ok {
+		h.Count++
+	} else {
+		h = &lockHolder{Token: nextToken(), Count: 1}
+		l.Holders[owner] = h
+	}
+	h.setLease(now, lease)
+	return h.Token
+}
+
+// updateSharedLock runs update on the state of the lock under the fragment
+// lock. A new lock is created with prefix if it doesn't exist. If prefix is
+// nil, the state is accepted as a semaphore or a read-write lock. The state is
+// deleted if the lock has no holders. See updateLockState.
+func (dm *DMap) updateSharedLock(ctx context.Context, key string, prefix []byte, update func(l *sharedLock, nextToken func() uint64) (bool, error)) error {
+	return dm.updateLockState(ctx, key, func(txn *lockTxn) (bool, error) {
+		l := &sharedLock{}
+		if txn.value != nil {
+			switch {
+			case prefix == nil && bytes.HasPrefix(txn.value, semaphorePrefix):
+				prefix = semaphorePrefix
+			case prefix == nil && bytes.HasPrefix(txn.value, rwLockPrefix):
+				prefix = rwLockPrefix
+			case prefix == nil || !bytes.HasPrefix(txn.value, prefix):
+				return false, ErrWrongType
+			}
+			if err := msgpack.Unmarshal(txn.value[len(prefix):], l); err != nil {
+				return false, err
+			}
+		}
+		if l.Holders == nil {
+			l.Holders = make(map[string]*lockHolder)
+		}
+
+		changed, err := update(l, txn.nextToken)
+		if err != nil || !changed {
+			return false, err
+		}
+		if len(l.Holders) == 0 {
+			txn.value = nil
+			return true, nil
+		}
+		data, err := msgpack.Marshal(l)
+		if err != nil {
+			return false, err
+		}
+		txn.value = make([]byte, 0, len(prefix)+len(data))
+		txn.value = append(txn.value, prefix...)
+		txn.value = append(txn.value, data...)
+		txn.expiresAt = l.lastExpiry()
+		return true, nil
+	})
+}
+
+// SemAcquire acquires a permit of the semaphore on behalf of owner and
+// returns its fencing token. At most permits owners hold the semaphore at
+// the same time. The owner can acquire it again without taking another
+// permit, and the permit is released after the same number of SharedRelease
+// calls. The permit is released automatically after lease if it's not zero.
+//
+// It returns ErrLockNotAcquired if a permit cannot be acquired until deadline.
+func (dm *DMap) SemAcquire(ctx context.Context, key, owner string, permits int, lease, deadline time.Duration) (uint64, error) {
+	if permits <= 0 {
+		return 0, fmt.Errorf("%w: permits must be a positive number", protocol.ErrInvalidArgument)
+	}
+
+	hkey, _, _ := dm.lockOwner(key)
+	return dm.acquireLock(ctx, hkey, deadline, func(wait time.Duration) *redis.IntCmd {
+		return protocol.NewSemAcquire(dm.name, key, owner, permits, wait.Seconds()).
+			SetPX(lease.Milliseconds()).
+			Command(ctx)
+	}, func() (uint64, int64, bool, error) {
+		var (
+			token     uint64
+			expiresAt int64
+			acquired  bool
+		)
+		err := dm.updateSharedLock(ctx, key, semaphorePrefix, func(l *sharedLock, nextToken func() uint64) (bool, error) {
+			now := nowInMilliseconds()
+			l.expire(now)
+			if _, ok := l.Holders[owner]; !ok && len(l.Holders) >= permits {
+				expiresAt = l.nextExpiry()
+				return false, nil
+			}
+			token, acquired = l.acquire(owner, now, lease, nextToken), true
+			return true, nil
+		})
+		return token, expiresAt, acquired, err
+	})
+}
+
+// RWLock acquires the read-write lock for the given key on behalf of owner
+// and returns its fencing token. Many readers or a single writer hold the
+// lock at the same time. The owner can acquire the lock again in the same
+// mode, and it's released after the same number of SharedRelease calls. A
+// lock is not upgraded or downgraded. The lock is released automatically
+// after lease if it's not zero.
+//
+// It returns ErrLockNotAcquired if the lock cannot be acquired until deadline.
+func (dm *DMap) RWLock(ctx context.Context, key, owner string, write bool, lease, deadline time.Duration) (uint64, error) {
+	mode := protocol.RWLockModeRead
+	if write {
+		mode = protocol.RWLockModeWrite
+	}
+
+	hkey, _, _ := dm.lockOwner(key)
+	return dm.acquireLock(ctx, hkey, deadline, func(wait time.Duration) *redis.IntCmd {
+		return protocol.NewRWLock(dm.name, key, owner, mode, wait.Seconds()).
+			SetPX(lease.Milliseconds()).
+			Command(ctx)
+	}, func() (uint64, int64, bool, error) {
+		var (
+			token     uint64
+			expiresAt int64
+			acquired  bool
+		)
+		err := dm.updateSharedLock(ctx, key, rwLockPrefix, func(l *sharedLock, nextToken func() uint64) (bool, error) {
+			now := nowInMilliseconds()
+			l.expire(now)
+			_, ok := l.Holders[owner]
+			free := len(l.Holders) == 0
+			if write {
+				// Reentrant acquisition by the writer or the first writer.
+				if !free && !(l.Exclusive && ok) {
+					expiresAt = l.nextExpiry()
+					return false, nil
+				}
+				l.Exclusive = true
+			} else if l.Exclusive {
+				expiresAt = l.nextExpiry()
+				return false, nil
+			}
+			token, acquired = l.acquire(owner, now, lease, nextToken), true
+			return true, nil
+		})
+		return token, expiresAt, acquired, err
+	})
+}
+
+// SharedRelease releases the semaphore permit or the read-write lock held by
+// owner. It returns ErrNoSuchLock if owner doesn't hold it.
+func (dm *DMap) SharedRelease(ctx context.Context, key, owner string) error {
+	hkey, member, ok := dm.lockOwner(key)
+	if !ok {
+		cmd := protocol.NewSharedRelease(dm.name, key, owner).Command(ctx)
+		rc := dm.s.client.Get(member.String())
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return protocol.ConvertError(err)
+		}
+		return protocol.ConvertError(cmd.Err())
+	}
+
+	var released bool
+	err := dm.updateSharedLock(ctx, key, nil, func(l *sharedLock, _ func() uint64) (bool, error) {
+		l.expire(nowInMilliseconds())
+		h, ok := l.Holders[owner]
+		if !ok {
+			return false, ErrNoSuchLock
+		}
+		h.Count--
+		if h.Count == 0 {
+			delete(l.Holders, owner)
+			if len(l.Holders) == 0 {
+				l.Exclusive = false
+			}
+			released = true
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if released {
+		dm.s.lockWaiters.notify(hkey)
+	}
+	return nil
+}
+
+// SharedLease sets the lease of the semaphore permit or the read-write lock
+// held by owner. Zero lease removes the expiry. It returns ErrNoSuchLock if
+// owner doesn't hold it.
+func (dm *DMap) SharedLease(ctx context.Context, key, owner string, lease time.Duration) error {
+	_, member, ok := dm.lockOwner(key)
+	if !ok {
+		cmd := protocol.NewSharedLease(dm.name, key, owner, lease.Milliseconds()).Command(ctx)
+		rc := dm.s.client.Get(member.String())
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return protocol.ConvertError(err)
+		}
+		return protocol.ConvertError(cmd.Err())
+	}
+
+	return dm.updateSharedLock(ctx, key, nil, func(l *sharedLock, _ func() uint64) (bool, error) {
+		now := nowInMilliseconds()
+		l.expire(now)
+		h, ok := l.Holders[owner]
+		if !ok {
+			return false, ErrNoSuchLock
+		}
+		h.setLease(now, lease)
+		return true, nil
+	})
+}
@@ -0,0 +1,113 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"time"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+func (s *Service) semAcquireCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	acquireCmd, err := protocol.ParseSemAcquireCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(acquireCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	lease := time.Duration(acquireCmd.PX) * time.Millisecond
+	deadline := time.Duration(acquireCmd.Deadline * float64(time.Second))
+	token, err := dm.SemAcquire(server.RequestContext(conn, s.ctx), acquireCmd.Key, acquireCmd.Owner, acquireCmd.Permits, lease, deadline)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt64(int64(token))
+}
+
+func (s *Service) rwLockCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	lockCmd, err := protocol.ParseRWLockCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(lockCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	write := lockCmd.Mode == protocol.RWLockModeWrite
+	lease := time.Duration(lockCmd.PX) * time.Millisecond
+	deadline := time.Duration(lockCmd.Deadline * float64(time.Second))
+	token, err := dm.RWLock(server.RequestContext(conn, s.ctx), lockCmd.Key, lockCmd.Owner, write, lease, deadline)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt64(int64(token))
+}
+
+func (s *Service) sharedReleaseCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	releaseCmd, err := protocol.ParseSharedReleaseCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(releaseCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	err = dm.SharedRelease(server.RequestContext(conn, s.ctx), releaseCmd.Key, releaseCmd.Owner)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteString(protocol.StatusOK)
+}
+
+func (s *Service) sharedLeaseCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	leaseCmd, err := protocol.ParseSharedLeaseCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dm, err := s.getOrCreateDMap(leaseCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	lease := time.Duration(leaseCmd.PX) * time.Millisecond
+	err = dm.SharedLease(server.RequestContext(conn, s.ctx), leaseCmd.Key, leaseCmd.Owner, lease)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteString(protocol.StatusOK)
+}
@@ -0,0 +1,247 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_SemAcquire(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+
+		first, err := dm1.SemAcquire(ctx, key, "owner-1", 2, 0, 0)
+		require.NoError(t, err)
+
+		// Reentrant acquisition doesn't take another permit.
+		token, err := dm2.SemAcquire(ctx, key, "owner-1", 2, 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, first, token)
+
+		second, err := dm2.SemAcquire(ctx, key, "owner-2", 2, 0, 0)
+		require.NoError(t, err)
+		require.Greater(t, second, first)
+
+		_, err = dm1.SemAcquire(ctx, key, "owner-3", 2, 0, 10*time.Millisecond)
+		require.ErrorIs(t, err, ErrLockNotAcquired)
+
+		require.NoError(t, dm1.SharedRelease(ctx, key, "owner-2"))
+		token, err = dm1.SemAcquire(ctx, key, "owner-3", 2, 0, 0)
+		require.NoError(t, err)
+		require.Greater(t, token, second)
+
+		err = dm2.SharedRelease(ctx, key, "owner-2")
+		require.ErrorIs(t, err, ErrNoSuchLock)
+	}
+}
+
+func TestDMap_SemAcquire_Lease(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	_, err = dm.SemAcquire(ctx, "mykey", "owner-1", 1, 50*time.Millisecond, 0)
+	require.NoError(t, err)
+
+	// Waits until the lease of owner-1 expires.
+	_, err = dm.SemAcquire(ctx, "mykey", "owner-2", 1, 0, time.Second)
+	require.NoError(t, err)
+
+	err = dm.SharedLease(ctx, "mykey", "owner-1", time.Second)
+	require.ErrorIs(t, err, ErrNoSuchLock)
+	require.NoError(t, dm.SharedLease(ctx, "mykey", "owner-2", time.Second))
+
+	_, err = dm.SemAcquire(ctx, "mykey", "owner-1", 0, 0, 0)
+	require.ErrorIs(t, err, protocol.ErrInvalidArgument)
+}
+
+func TestDMap_SemAcquire_Migration(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err = dm1.SemAcquire(ctx, testutil.ToKey(i), "owner-1", 1, 0, 0)
+		require.NoError(t, err)
+	}
+
+	// Moves some of the partitions to the new member.
+	s2 := cluster.AddMember(nil).(*Service)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+		_, err = dm2.SemAcquire(ctx, key, "owner-2", 1, 0, 0)
+		require.ErrorIs(t, err, ErrLockNotAcquired)
+		require.NoError(t, dm2.SharedRelease(ctx, key, "owner-1"))
+	}
+}
+
+func TestDMap_RWLock(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+
+		_, err = dm1.RWLock(ctx, key, "reader-1", false, 0, 0)
+		require.NoError(t, err)
+		_, err = dm2.RWLock(ctx, key, "reader-2", false, 0, 0)
+		require.NoError(t, err)
+
+		_, err = dm1.RWLock(ctx, key, "writer", true, 0, 0)
+		require.ErrorIs(t, err, ErrLockNotAcquired)
+
+		require.NoError(t, dm1.SharedRelease(ctx, key, "reader-1"))
+		require.NoError(t, dm2.SharedRelease(ctx, key, "reader-2"))
+
+		_, err = dm2.RWLock(ctx, key, "writer", true, 0, 0)
+		require.NoError(t, err)
+		// Reentrant acquisition by the writer
+		_, err = dm1.RWLock(ctx, key, "writer", true, 0, 0)
+		require.NoError(t, err)
+
+		_, err = dm1.RWLock(ctx, key, "reader-1", false, 0, 0)
+		require.ErrorIs(t, err, ErrLockNotAcquired)
+		_, err = dm1.RWLock(ctx, key, "writer-2", true, 0, 0)
+		require.ErrorIs(t, err, ErrLockNotAcquired)
+
+		require.NoError(t, dm1.SharedRelease(ctx, key, "writer"))
+		require.NoError(t, dm1.SharedRelease(ctx, key, "writer"))
+
+		_, err = dm1.RWLock(ctx, key, "reader-1", false, 0, 0)
+		require.NoError(t, err)
+		require.NoError(t, dm2.SharedRelease(ctx, key, "reader-1"))
+	}
+}
+
+func TestDMap_RWLock_Wait(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	token, err := dm1.RWLock(ctx, "mykey", "reader", false, 0, 0)
+	require.NoError(t, err)
+
+	go func() {
+		<-time.After(100 * time.Millisecond)
+		require.NoError(t, dm1.SharedRelease(ctx, "mykey", "reader"))
+	}()
+
+	writerToken, err := dm2.RWLock(ctx, "mykey", "writer", true, 0, 5*time.Second)
+	require.NoError(t, err)
+	require.Greater(t, writerToken, token)
+}
+
+func TestDMap_SharedLock_WrongType(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	_, err = dm.SemAcquire(ctx, "mykey", "owner", 1, 0, 0)
+	require.NoError(t, err)
+
+	_, err = dm.RWLock(ctx, "mykey", "owner", true, 0, 0)
+	require.ErrorIs(t, err, ErrWrongType)
+	_, err = dm.FencedLock(ctx, "mykey", "owner", 0, 0)
+	require.ErrorIs(t, err, ErrWrongType)
+
+	require.NoError(t, dm.SharedRelease(ctx, "mykey", "owner"))
+}
+
+func TestDMap_SharedLock_State(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	// The locks don't touch the value of the key.
+	require.NoError(t, dm.Put(ctx, "mykey", "value", nil))
+
+	first, err := dm.RWLock(ctx, "mykey", "reader-1", false, 0, 0)
+	require.NoError(t, err)
+	_, err = dm.RWLock(ctx, "mykey", "reader-2", false, 0, 0)
+	require.NoError(t, err)
+	_, err = dm.SemAcquire(ctx, "other-key", "owner", 1, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, countLockStates(s))
+
+	e, err := dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), e.Value())
+
+	// The state is deleted after the last holder releases the lock, but the
+	// token keeps increasing.
+	require.NoError(t, dm.SharedRelease(ctx, "mykey", "reader-1"))
+	require.Equal(t, 2, countLockStates(s))
+	require.NoError(t, dm.SharedRelease(ctx, "mykey", "reader-2"))
+	require.NoError(t, dm.SharedRelease(ctx, "other-key", "owner"))
+	require.Equal(t, 0, countLockStates(s))
+
+	token, err := dm.RWLock(ctx, "mykey", "writer", true, 0, 0)
+	require.NoError(t, err)
+	require.Greater(t, token, first)
+	require.NoError(t, dm.SharedRelease(ctx, "mykey", "writer"))
+	require.Equal(t, 0, countLockStates(s))
+}
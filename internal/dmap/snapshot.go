@@ -0,0 +1,315 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/discovery"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// SnapshotVersion is the version of the snapshot file format.
+const SnapshotVersion = 1
+
+const snapshotFileExt = ".snapshot"
+
+// ErrInvalidSnapshot is returned when a snapshot file cannot be restored on this cluster.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// snapshotFile is the on-disk format of a primary partition.
+type snapshotFile struct {
+	Version        int
+	PartitionCount uint64
+	PartID         uint64
+	Timestamp      int64
+	Fragments      []snapshotFragment
+}
+
+// snapshotFragment is a DMap fragment in a snapshot file. Payloads are exported
+// by the storage engine of the DMap, so they can only be imported by the same engine.
+type snapshotFragment struct {
+	Name     string
+	Engine   string
+	Payloads [][]byte
+}
+
+func snapshotFileName(partID uint64) string {
+	return fmt.Sprintf("partition-%05d%s", partID, snapshotFileExt)
+}
+
+// exportFragment copies the fragment to a temporary storage engine instance and
+// exports it with a TransferIterator. The fragment itself is not modified.
+func (dm *DMap) exportFragment(part *partitions.Partition, f *fragment) ([][]byte, error) {
+	c := storage.NewConfig(dm.config.engine.Config).Copy()
+	c.Add(storage.FragmentNameKey, "snapshot."+dm.fragmentName)
+	c.Add(storage.PartitionIDKey, part.ID())
+	c.Add(storage.PartitionKindKey, strings.ToLower(part.Kind().String()))
+	tmp, err := dm.engine.Fork(c)
+	if err != nil {
+		return nil, err
+	}
+	if err = tmp.Start(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tmp.Close(); err != nil {
+			dm.s.log.V(3).Printf("[ERROR] Failed to close temporary storage engine: %v", err)
+		}
+		if err := tmp.Destroy(); err != nil {
+			dm.s.log.V(3).Printf("[ERROR] Failed to destroy temporary storage engine: %v", err)
+		}
+	}()
+
+	f.RLock()
+	f.storage.Range(func(hkey uint64, e storage.Entry) bool {
+		err = tmp.Put(hkey, e)
+		return err == nil
+	})
+	f.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var payloads [][]byte
+	i := tmp.TransferIterator()
+	for i.Next() {
+		payload, index, err := i.Export()
+		if err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, payload)
+		if err = i.Drop(index); err != nil {
+			return nil, err
+		}
+	}
+	return payloads, nil
+}
+
+func (s *Service) snapshotPartition(part *partitions.Partition) (*snapshotFile, error) {
+	sf := &snapshotFile{
+		Version:        SnapshotVersion,
+		PartitionCount: s.config.PartitionCount,
+		PartID:         part.ID(),
+		Timestamp:      time.Now().UnixNano(),
+	}
+
+	var names []string
+	part.Map().Range(func(name, _ interface{}) bool {
+		if strings.HasPrefix(name.(string), "dmap.") {
+			names = append(names, name.(string))
+		}
+		return true
+	})
+	sort.Strings(names)
+
+	for _, name := range names {
+		tmp, ok := part.Map().Load(name)
+		if !ok {
+			// Deleted by the janitor
+			continue
+		}
+		dm, err := s.getOrCreateDMap(strings.TrimPrefix(name, "dmap."))
+		if err != nil {
+			return nil, err
+		}
+		payloads, err := dm.exportFragment(part, tmp.(*fragment))
+		if err != nil {
+			return nil, fmt.Errorf("failed to export DMap: %s on PartID: %d: %w", dm.name, part.ID(), err)
+		}
+		sf.Fragments = append(sf.Fragments, snapshotFragment{
+			Name:     dm.name,
+			Engine:   dm.config.engine.Name,
+			Payloads: payloads,
+		})
+	}
+	return sf, nil
+}
+
+func writeSnapshotFile(dir string, sf *snapshotFile) error {
+	data, err := msgpack.Marshal(sf)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, snapshotFileName(sf.PartID))
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	// Rename is atomic, a reader never sees a partially written snapshot file.
+	return os.Rename(tmp, path)
+}
+
+// Snapshot writes the primary partitions owned by this node to dir. Every partition
+// is stored in a separate file.
+func (s *Service) Snapshot(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	for partID := uint64(0); partID < s.config.PartitionCount; partID++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		part := s.primary.PartitionByID(partID)
+		if part.OwnerCount() == 0 || !part.Owner().CompareByName(s.rt.This()) {
+			continue
+		}
+		if part.Length() == 0 {
+			continue
+		}
+
+		sf, err := s.snapshotPartition(part)
+		if err != nil {
+			return err
+		}
+		if err = writeSnapshotFile(dir, sf); err != nil {
+			return err
+		}
+		s.log.V(3).Printf("[INFO] Snapshot of PartID: %d has been written to %s", partID, dir)
+	}
+	return nil
+}
+
+func (s *Service) readSnapshotFile(path string) (*snapshotFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sf := &snapshotFile{}
+	if err = msgpack.Unmarshal(data, sf); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSnapshot, path, err)
+	}
+	if sf.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: %s: unsupported version: %d", ErrInvalidSnapshot, path, sf.Version)
+	}
+	if sf.PartitionCount != s.config.PartitionCount || sf.PartID >= s.config.PartitionCount {
+		return nil, fmt.Errorf("%w: %s: partition count mismatch: %d != %d",
+			ErrInvalidSnapshot, path, sf.PartitionCount, s.config.PartitionCount)
+	}
+	return sf, nil
+}
+
+func (s *Service) sendFragmentPack(ctx context.Context, owner discovery.Member, fp *fragmentPack) error {
+	value, err := msgpack.Marshal(fp)
+	if err != nil {
+		return err
+	}
+
+	cmd := protocol.NewMoveFragment(value).Command(ctx)
+	rc := s.client.Get(owner.String())
+	err = rc.Process(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return cmd.Err()
+}
+
+// checkSnapshotEngines returns ErrInvalidSnapshot if a fragment in the snapshot
+// file is exported by a storage engine other than the engine of its DMap.
+func (s *Service) checkSnapshotEngines(sf *snapshotFile) error {
+	for _, fr := range sf.Fragments {
+		dm, err := s.getOrCreateDMap(fr.Name)
+		if err != nil {
+			return err
+		}
+		if fr.Engine != dm.config.engine.Name {
+			return fmt.Errorf("%w: PartID: %d: storage engine mismatch for DMap: %s: %s != %s",
+				ErrInvalidSnapshot, sf.PartID, fr.Name, fr.Engine, dm.config.engine.Name)
+		}
+	}
+	return nil
+}
+
+func (s *Service) restorePartition(ctx context.Context, sf *snapshotFile) error {
+	primary := s.primary.PartitionByID(sf.PartID)
+	backup := s.backup.PartitionByID(sf.PartID)
+	if primary.OwnerCount() == 0 {
+		return fmt.Errorf("PartID: %d has no owner", sf.PartID)
+	}
+	if err := s.checkSnapshotEngines(sf); err != nil {
+		return err
+	}
+
+	for _, fr := range sf.Fragments {
+		for _, payload := range fr.Payloads {
+			fp := &fragmentPack{
+				PartID:  sf.PartID,
+				Kind:    partitions.PRIMARY,
+				Name:    fr.Name,
+				Payload: payload,
+			}
+			// The receiver merges the fragment by calling Import.
+			if err := s.sendFragmentPack(ctx, primary.Owner(), fp); err != nil {
+				return err
+			}
+
+			fp.Kind = partitions.BACKUP
+			for _, owner := range backup.Owners() {
+				if err := s.sendFragmentPack(ctx, owner, fp); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Restore reads the snapshot files in dir and sends the fragments to the current
+// partition owners. Existing keys are merged by timestamp.
+func (s *Service) Restore(ctx context.Context, dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != snapshotFileExt {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		sf, err := s.readSnapshotFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		if err = s.restorePartition(ctx, sf); err != nil {
+			return fmt.Errorf("failed to restore PartID: %d: %w", sf.PartID, err)
+		}
+		s.log.V(3).Printf("[INFO] PartID: %d has been restored from %s", sf.PartID, dir)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestDMap_Snapshot_Restore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "olric-snapshot")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+
+	dm, err := s1.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		err = dm.Put(ctx, testutil.ToKey(i), testutil.ToVal(i), nil)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, s1.Snapshot(ctx, dir))
+	require.NoError(t, s2.Snapshot(ctx, dir))
+
+	// The snapshot is a copy. Fragments are not modified.
+	for i := 0; i < 100; i++ {
+		_, err := dm.Get(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+	}
+	cluster.Shutdown()
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	cluster = testcluster.New(NewService)
+	fresh := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	require.NoError(t, fresh.Restore(ctx, dir))
+
+	dm, err = fresh.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		gr, err := dm.Get(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+		require.Equal(t, testutil.ToVal(i), gr.Value())
+	}
+}
+
+func TestDMap_Restore_Unsupported_Version(t *testing.T) {
+	dir, err := ioutil.TempDir("", "olric-snapshot")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	data, err := msgpack.Marshal(&snapshotFile{
+		Version:        SnapshotVersion + 1,
+		PartitionCount: s.config.PartitionCount,
+	})
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, snapshotFileName(0)), data, 0600)
+	require.NoError(t, err)
+
+	err = s.Restore(context.Background(), dir)
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+}
+
+func TestDMap_Restore_Engine_Mismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "olric-snapshot")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	data, err := msgpack.Marshal(&snapshotFile{
+		Version:        SnapshotVersion,
+		PartitionCount: s.config.PartitionCount,
+		Fragments: []snapshotFragment{{
+			Name:     "mydmap",
+			Engine:   "foobar",
+			Payloads: [][]byte{[]byte("foobar")},
+		}},
+	})
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, snapshotFileName(0)), data, 0600)
+	require.NoError(t, err)
+
+	err = s.Restore(context.Background(), dir)
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+}
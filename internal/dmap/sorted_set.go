@@ -0,0 +1,283 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrScoreNaN means that the score of a sorted set member is not a number.
+var ErrScoreNaN = errors.New("score is not a number")
+
+// ZMember is a member of a sorted set with its score.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// sortedSetPrefix marks the values of the sorted set entries. The members are
+// encoded with msgpack after the prefix, ordered by score and member. The value
+// is a regular entry, so it's replicated and moved between the members like
+// the other entries.
+var sortedSetPrefix = []byte("\x00olric.zset\x00")
+
+func decodeSortedSet(value []byte) ([]ZMember, error) {
+	if !bytes.HasPrefix(value, sortedSetPrefix) {
+		return nil, ErrWrongType
+	}
+	var members []ZMember
+	if err := msgpack.Unmarshal(value[len(sortedSetPrefix):], &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func encodeSortedSet(members []ZMember) ([]byte, error) {
+	data, err := msgpack.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 0, len(sortedSetPrefix)+len(data))
+	value = append(value, sortedSetPrefix...)
+	return append(value, data...), nil
+}
+
+func sortMembers(members []ZMember) {
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].Member < members[j].Member
+	})
+}
+
+func memberIndex(members []ZMember, member string) int {
+	for i, m := range members {
+		if m.Member == member {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateSortedSet runs update on the members of the sorted set under the
+// fragment lock. The members are sorted again after the update. update returns
+// false if it doesn't modify the members. See updateTypedValue.
+func (dm *DMap) updateSortedSet(ctx context.Context, key string, update func(members []ZMember) ([]ZMember, bool, error)) error {
+	return dm.updateTypedValue(ctx, key, func(value []byte) ([]byte, bool, error) {
+		var members []ZMember
+		if value != nil {
+			var err error
+			members, err = decodeSortedSet(value)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		members, changed, err := update(members)
+		if err != nil || !changed {
+			return nil, false, err
+		}
+		if len(members) == 0 {
+			// Empty sorted sets are removed.
+			return nil, true, nil
+		}
+		sortMembers(members)
+		value, err = encodeSortedSet(members)
+		if err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	})
+}
+
+// ZAdd adds the members to the sorted set stored at key. The score is updated
+// if the member already exists. The sorted set is created if it doesn't exist.
+// It returns the number of the members that were added.
+func (dm *DMap) ZAdd(ctx context.Context, key string, members ...ZMember) (int, error) {
+	for _, m := range members {
+		if math.IsNaN(m.Score) {
+			return 0, ErrScoreNaN
+		}
+	}
+
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		cmd := protocol.NewZAdd(dm.name, key)
+		for _, m := range members {
+			cmd.Add(m.Score, m.Member)
+		}
+		return dm.processOnKeyOwner(ctx, owner, cmd.Command(ctx))
+	}
+
+	var added int
+	err := dm.updateSortedSet(ctx, key, func(current []ZMember) ([]ZMember, bool, error) {
+		for _, m := range members {
+			if i := memberIndex(current, m.Member); i >= 0 {
+				current[i].Score = m.Score
+				continue
+			}
+			current = append(current, m)
+			added++
+		}
+		return current, true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// ZIncrBy increments the score of member in the sorted set stored at key by
+// delta. The member is added with delta as its score if it doesn't exist. It
+// returns the new score.
+func (dm *DMap) ZIncrBy(ctx context.Context, key, member string, delta float64) (float64, error) {
+	if math.IsNaN(delta) {
+		return 0, ErrScoreNaN
+	}
+
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		cmd := protocol.NewZIncrBy(dm.name, key, delta, member).Command(ctx)
+		rc := dm.s.client.Get(owner.String())
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return 0, protocol.ConvertError(err)
+		}
+		score, err := cmd.Result()
+		if err != nil {
+			return 0, protocol.ConvertError(err)
+		}
+		return score, nil
+	}
+
+	var score float64
+	err := dm.updateSortedSet(ctx, key, func(current []ZMember) ([]ZMember, bool, error) {
+		i := memberIndex(current, member)
+		if i < 0 {
+			current = append(current, ZMember{Member: member})
+			i = len(current) - 1
+		}
+		score = current[i].Score + delta
+		if math.IsNaN(score) {
+			// -inf and +inf cancel out each other.
+			return nil, false, ErrScoreNaN
+		}
+		current[i].Score = score
+		return current, true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return score, nil
+}
+
+// ZRem removes the members from the sorted set stored at key. It returns the
+// number of the members that were removed. The sorted set is deleted if it has
+// no members left.
+func (dm *DMap) ZRem(ctx context.Context, key string, members ...string) (int, error) {
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		return dm.processOnKeyOwner(ctx, owner, protocol.NewZRem(dm.name, key, members...).Command(ctx))
+	}
+
+	var removed int
+	err := dm.updateSortedSet(ctx, key, func(current []ZMember) ([]ZMember, bool, error) {
+		for _, member := range members {
+			if i := memberIndex(current, member); i >= 0 {
+				current = append(current[:i], current[i+1:]...)
+				removed++
+			}
+		}
+		return current, removed > 0, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// sortedSetMembers returns the ordered members of the sorted set stored at key.
+// It returns nil if the key doesn't exist.
+func (dm *DMap) sortedSetMembers(ctx context.Context, key string) ([]ZMember, error) {
+	entry, err := dm.Get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeSortedSet(entry.Value())
+}
+
+// ZRange returns the members of the sorted set stored at key between the
+// ranks start and stop, both inclusive. The members are ordered from the
+// lowest to the highest score. Negative ranks are counted from the end of the
+// sorted set, -1 is the member with the highest score.
+func (dm *DMap) ZRange(ctx context.Context, key string, start, stop int) ([]ZMember, error) {
+	members, err := dm.sortedSetMembers(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	start, stop, ok := normalizeRange(start, stop, len(members))
+	if !ok {
+		return []ZMember{}, nil
+	}
+	return members[start:stop], nil
+}
+
+// ZRangeByScore returns the members of the sorted set stored at key with a
+// score between min and max, both inclusive. The members are ordered from the
+// lowest to the highest score.
+func (dm *DMap) ZRangeByScore(ctx context.Context, key string, min, max float64) ([]ZMember, error) {
+	members, err := dm.sortedSetMembers(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	start := sort.Search(len(members), func(i int) bool {
+		return members[i].Score >= min
+	})
+	stop := sort.Search(len(members), func(i int) bool {
+		return members[i].Score > max
+	})
+	if start >= stop {
+		return []ZMember{}, nil
+	}
+	return members[start:stop], nil
+}
+
+// ZRank returns the rank of member in the sorted set stored at key. The rank
+// of the member with the lowest score is 0. It returns ErrKeyNotFound if the
+// sorted set or the member doesn't exist.
+func (dm *DMap) ZRank(ctx context.Context, key, member string) (int, error) {
+	members, err := dm.sortedSetMembers(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	i := memberIndex(members, member)
+	if i < 0 {
+		return 0, ErrKeyNotFound
+	}
+	return i, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"strconv"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+// writeZMembers writes the members and their scores as a flat array, like
+// ZRANGE WITHSCORES of Redis.
+func writeZMembers(conn redcon.Conn, members []ZMember) {
+	conn.WriteArray(len(members) * 2)
+	for _, m := range members {
+		conn.WriteBulkString(m.Member)
+		conn.WriteBulkString(strconv.FormatFloat(m.Score, 'f', -1, 64))
+	}
+}
+
+func (s *Service) zaddCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	zaddCmd, err := protocol.ParseZAddCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(zaddCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	members := make([]ZMember, 0, len(zaddCmd.Members))
+	for i, member := range zaddCmd.Members {
+		members = append(members, ZMember{Member: member, Score: zaddCmd.Scores[i]})
+	}
+	added, err := dm.ZAdd(server.RequestContext(conn, s.ctx), zaddCmd.Key, members...)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(added)
+}
+
+func (s *Service) zrangeCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	zrangeCmd, err := protocol.ParseZRangeCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(zrangeCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	members, err := dm.ZRange(server.RequestContext(conn, s.ctx), zrangeCmd.Key, zrangeCmd.Start, zrangeCmd.Stop)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	writeZMembers(conn, members)
+}
+
+func (s *Service) zrangebyscoreCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	zrangeCmd, err := protocol.ParseZRangeByScoreCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(zrangeCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	members, err := dm.ZRangeByScore(server.RequestContext(conn, s.ctx), zrangeCmd.Key, zrangeCmd.Min, zrangeCmd.Max)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	writeZMembers(conn, members)
+}
+
+func (s *Service) zremCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	zremCmd, err := protocol.ParseZRemCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(zremCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	removed, err := dm.ZRem(server.RequestContext(conn, s.ctx), zremCmd.Key, zremCmd.Members...)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(removed)
+}
+
+func (s *Service) zrankCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	zrankCmd, err := protocol.ParseZRankCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(zrankCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	rank, err := dm.ZRank(server.RequestContext(conn, s.ctx), zrankCmd.Key, zrankCmd.Member)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(rank)
+}
+
+func (s *Service) zincrbyCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	zincrbyCmd, err := protocol.ParseZIncrByCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(zincrbyCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	score, err := dm.ZIncrBy(server.RequestContext(conn, s.ctx), zincrbyCmd.Key, zincrbyCmd.Member, zincrbyCmd.Delta)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteBulkString(strconv.FormatFloat(score, 'f', -1, 64))
+}
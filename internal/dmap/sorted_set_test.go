@@ -0,0 +1,178 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_SortedSet(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+
+		added, err := dm1.ZAdd(ctx, key,
+			ZMember{Member: "alice", Score: 30},
+			ZMember{Member: "bob", Score: 10},
+			ZMember{Member: "carol", Score: 20},
+		)
+		require.NoError(t, err)
+		require.Equal(t, 3, added)
+
+		added, err = dm2.ZAdd(ctx, key,
+			ZMember{Member: "bob", Score: 40},
+			ZMember{Member: "dave", Score: 20},
+		)
+		require.NoError(t, err)
+		require.Equal(t, 1, added)
+
+		members, err := dm2.ZRange(ctx, key, 0, -1)
+		require.NoError(t, err)
+		require.Equal(t, []ZMember{
+			{Member: "carol", Score: 20},
+			{Member: "dave", Score: 20},
+			{Member: "alice", Score: 30},
+			{Member: "bob", Score: 40},
+		}, members)
+
+		members, err = dm1.ZRange(ctx, key, -2, 100)
+		require.NoError(t, err)
+		require.Equal(t, []ZMember{
+			{Member: "alice", Score: 30},
+			{Member: "bob", Score: 40},
+		}, members)
+
+		members, err = dm1.ZRangeByScore(ctx, key, 20, 30)
+		require.NoError(t, err)
+		require.Equal(t, []ZMember{
+			{Member: "carol", Score: 20},
+			{Member: "dave", Score: 20},
+			{Member: "alice", Score: 30},
+		}, members)
+
+		score, err := dm2.ZIncrBy(ctx, key, "carol", 25.5)
+		require.NoError(t, err)
+		require.Equal(t, 45.5, score)
+
+		rank, err := dm1.ZRank(ctx, key, "carol")
+		require.NoError(t, err)
+		require.Equal(t, 3, rank)
+
+		_, err = dm1.ZRank(ctx, key, "missing")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		removed, err := dm2.ZRem(ctx, key, "alice", "bob", "missing")
+		require.NoError(t, err)
+		require.Equal(t, 2, removed)
+
+		removed, err = dm1.ZRem(ctx, key, "carol", "dave")
+		require.NoError(t, err)
+		require.Equal(t, 2, removed)
+
+		// The sorted set is deleted after removing all the members.
+		_, err = dm1.Get(ctx, key)
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		members, err = dm2.ZRange(ctx, key, 0, -1)
+		require.NoError(t, err)
+		require.Empty(t, members)
+	}
+}
+
+func TestDMap_SortedSet_WrongType(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue", nil))
+	_, err = dm.ZAdd(ctx, "mykey", ZMember{Member: "alice", Score: 1})
+	require.ErrorIs(t, err, ErrWrongType)
+
+	_, err = dm.HSet(ctx, "myhash", map[string]interface{}{"field": "value"})
+	require.NoError(t, err)
+	_, err = dm.ZRange(ctx, "myhash", 0, -1)
+	require.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestDMap_SortedSet_ScoreNaN(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	_, err = dm.ZAdd(ctx, "mykey", ZMember{Member: "alice", Score: math.NaN()})
+	require.ErrorIs(t, err, ErrScoreNaN)
+
+	_, err = dm.ZAdd(ctx, "mykey", ZMember{Member: "alice", Score: math.Inf(1)})
+	require.NoError(t, err)
+	_, err = dm.ZIncrBy(ctx, "mykey", "alice", math.Inf(-1))
+	require.ErrorIs(t, err, ErrScoreNaN)
+}
+
+func TestDMap_SortedSet_Balancer_JoinNewNode(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 1; i <= 100; i++ {
+		_, err = dm1.ZAdd(ctx, "leaderboard."+strconv.Itoa(i),
+			ZMember{Member: "alice", Score: float64(i)},
+			ZMember{Member: "bob", Score: float64(-i)},
+		)
+		require.NoError(t, err)
+	}
+
+	// This automatically syncs the cluster and moves some fragments to the new member.
+	s2 := cluster.AddMember(nil).(*Service)
+
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+	for i := 1; i <= 100; i++ {
+		members, err := dm2.ZRange(ctx, "leaderboard."+strconv.Itoa(i), 0, -1)
+		require.NoError(t, err)
+		require.Equal(t, []ZMember{
+			{Member: "bob", Score: float64(-i)},
+			{Member: "alice", Score: float64(i)},
+		}, members)
+	}
+}
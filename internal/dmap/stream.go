@@ -0,0 +1,699 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	// ErrInvalidStreamID means that the given ID is not a valid stream entry ID.
+	ErrInvalidStreamID = errors.New("invalid stream entry ID")
+
+	// ErrNoSuchGroup means that the consumer group doesn't exist on the stream.
+	ErrNoSuchGroup = errors.New("no such consumer group")
+)
+
+// StreamNewEntries is the ID to read the entries that were never delivered
+// to the consumer group.
+const StreamNewEntries = ">"
+
+// StreamEntry is an entry of a stream.
+type StreamEntry struct {
+	ID     string
+	Fields map[string][]byte
+}
+
+// PendingEntry is an entry that is delivered to a consumer of a group but not
+// acknowledged yet.
+type PendingEntry struct {
+	ID         string
+	Consumer   string
+	Idle       time.Duration
+	Deliveries int
+}
+
+// streamID is the ID of a stream entry. It consists of a timestamp in
+// milliseconds and a sequence number for the entries added in the same
+// millisecond.
+type streamID struct {
+	ms  uint64
+	seq uint64
+}
+
+func parseStreamID(raw string) (streamID, error) {
+	if raw == "" {
+		return streamID{}, nil
+	}
+	rawMs, rawSeq := raw, "0"
+	if i := strings.IndexByte(raw, '-'); i >= 0 {
+		rawMs, rawSeq = raw[:i], raw[i+1:]
+	}
+	ms, err := strconv.ParseUint(rawMs, 10, 64)
+	if err != nil {
+		return streamID{}, ErrInvalidStreamID
+	}
+	seq, err := strconv.ParseUint(rawSeq, 10, 64)
+	if err != nil {
+		return streamID{}, ErrInvalidStreamID
+	}
+	return streamID{ms: ms, seq: seq}, nil
+}
+
+func (id streamID) String() string {
+	return fmt.Sprintf("%d-%d", id.ms, id.seq)
+}
+
+func (id streamID) less(other streamID) bool {
+	if id.ms != other.ms {
+		return id.ms < other.ms
+	}
+	return id.seq < other.seq
+}
+
+// streamPending is a pending entry of a consumer group.
+type streamPending struct {
+	Consumer    string
+	DeliveredAt int64
+	Deliveries  int
+}
+
+// streamGroup is the state of a consumer group.
+type streamGroup struct {
+	// LastID is the ID of the last entry delivered to the group.
+	LastID  string
+	Pending map[string]*streamPending
+}
+
+// streamChunkSize is the maximum number of entries in a chunk of a stream.
+// XAdd only rewrites the last chunk, and trimming drops the oldest chunks.
+const streamChunkSize = 128
+
+type streamChunk struct {
+	Key    string
+	LastID string
+	Length int
+}
+
+// stream is the value of a stream entry. The entries are stored in chunks,
+// see chunkStore. The chunks are ordered by the IDs of their entries. ID and
+// Seq make the chunk keys unique.
+type stream struct {
+	ID     int64
+	Seq    uint64
+	LastID string
+	Length int
+	Chunks []streamChunk
+	Groups map[string]*streamGroup
+
+	hkey  uint64
+	store *chunkStore
+}
+
+// streamPrefix marks the values of the stream entries. The stream is encoded
+// with msgpack after the prefix.
+var streamPrefix = []byte("\x00olric.stream\x00")
+
+func decodeStream(value []byte) (*stream, error) {
+	if !bytes.HasPrefix(value, streamPrefix) {
+		return nil, ErrWrongType
+	}
+	s := &stream{}
+	if err := msgpack.Unmarshal(value[len(streamPrefix):], s); err != nil {
+		return nil, err
+	}
+	if s.Groups == nil {
+		s.Groups = make(map[string]*streamGroup)
+	}
+	return s, nil
+}
+
+func encodeStream(s *stream) ([]byte, error) {
+	data, err := msgpack.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 0, len(streamPrefix)+len(data))
+	value = append(value, streamPrefix...)
+	return append(value, data...), nil
+}
+
+func decodeStreamChunk(data []byte) (interface{}, error) {
+	var entries []StreamEntry
+	if err := msgpack.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// newStream decodes the stream, or creates a new one if value is nil. It's
+// used under the fragment lock of the key.
+func (dm *DMap) newStream(ctx context.Context, part *partitions.Partition, key string, value []byte) (*stream, error) {
+	s := &stream{
+		ID:     time.Now().UnixNano(),
+		Groups: make(map[string]*streamGroup),
+	}
+	if value != nil {
+		var err error
+		s, err = decodeStream(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	store, err := dm.newChunkStore(ctx, part, key)
+	if err != nil {
+		return nil, err
+	}
+	s.hkey = partitions.HKey(dm.name, key)
+	s.store = store
+	return s, nil
+}
+
+func (s *stream) chunk(i int) ([]StreamEntry, error) {
+	entries, err := s.store.load(s.Chunks[i].Key, decodeStreamChunk)
+	if err != nil {
+		return nil, err
+	}
+	return entries.([]StreamEntry), nil
+}
+
+func (s *stream) setChunk(i int, entries []StreamEntry) {
+	s.store.set(s.Chunks[i].Key, entries)
+	s.Chunks[i].Length = len(entries)
+	s.Chunks[i].LastID = entries[len(entries)-1].ID
+}
+
+// add appends the entry to the stream.
+func (s *stream) add(entry StreamEntry) error {
+	if len(s.Chunks) == 0 || s.Chunks[len(s.Chunks)-1].Length >= streamChunkSize {
+		prefix := fmt.Sprintf("stream.%x.%x.%x.", s.hkey, s.ID, s.Seq)
+		s.Seq++
+		c := streamChunk{Key: s.store.newKey(prefix)}
+		s.store.set(c.Key, []StreamEntry(nil))
+		s.Chunks = append(s.Chunks, c)
+	}
+
+	i := len(s.Chunks) - 1
+	entries, err := s.chunk(i)
+	if err != nil {
+		return err
+	}
+	s.setChunk(i, append(entries, entry))
+	s.Length++
+	s.LastID = entry.ID
+	return nil
+}
+
+// trim removes the oldest entries to keep the length of the stream at most
+// maxLen.
+func (s *stream) trim(maxLen int) error {
+	for s.Length > maxLen {
+		excess := s.Length - maxLen
+		c := s.Chunks[0]
+		if c.Length <= excess {
+			s.store.remove(c.Key)
+			s.Chunks = s.Chunks[1:]
+			s.Length -= c.Length
+			continue
+		}
+
+		entries, err := s.chunk(0)
+		if err != nil {
+			return err
+		}
+		s.setChunk(0, entries[excess:])
+		s.Length -= excess
+	}
+	return nil
+}
+
+// searchEntries returns the index of the first entry with an ID not less than
+// id if inclusive is true, or greater than id otherwise.
+func searchEntries(entries []StreamEntry, id streamID, inclusive bool) int {
+	return sort.Search(len(entries), func(i int) bool {
+		current, _ := parseStreamID(entries[i].ID)
+		if inclusive {
+			return !current.less(id)
+		}
+		return id.less(current)
+	})
+}
+
+// searchChunks returns the index of the first chunk that has an entry with an
+// ID not less than id if inclusive is true, or greater than id otherwise.
+func (s *stream) searchChunks(id streamID, inclusive bool) int {
+	return sort.Search(len(s.Chunks), func(i int) bool {
+		last, _ := parseStreamID(s.Chunks[i].LastID)
+		if inclusive {
+			return !last.less(id)
+		}
+		return id.less(last)
+	})
+}
+
+// entriesAfter returns the entries with an ID greater than id. Zero count
+// returns all of them.
+func (s *stream) entriesAfter(id streamID, count int) ([]StreamEntry, error) {
+	result := []StreamEntry{}
+	for i := s.searchChunks(id, false); i < len(s.Chunks); i++ {
+		entries, err := s.chunk(i)
+		if err != nil {
+			return nil, err
+		}
+		entries = entries[searchEntries(entries, id, false):]
+		if count > 0 && len(result)+len(entries) >= count {
+			return append(result, entries[:count-len(result)]...), nil
+		}
+		result = append(result, entries...)
+	}
+	return result, nil
+}
+
+// entry returns the entry with the given ID and true if it's still in the
+// stream.
+func (s *stream) entry(id string) (StreamEntry, bool, error) {
+	parsed, err := parseStreamID(id)
+	if err != nil {
+		return StreamEntry{}, false, nil
+	}
+	i := s.searchChunks(parsed, true)
+	if i == len(s.Chunks) {
+		return StreamEntry{}, false, nil
+	}
+	entries, err := s.chunk(i)
+	if err != nil {
+		return StreamEntry{}, false, err
+	}
+	j := searchEntries(entries, parsed, true)
+	if j < len(entries) && entries[j].ID == id {
+		return entries[j], true, nil
+	}
+	return StreamEntry{}, false, nil
+}
+
+// nextID returns an ID that is greater than the last ID of the stream.
+func (s *stream) nextID(now time.Time) (streamID, error) {
+	last, err := parseStreamID(s.LastID)
+	if err != nil {
+		return streamID{}, err
+	}
+	id := streamID{ms: uint64(now.UnixNano() / int64(time.Millisecond))}
+	if !last.less(id) {
+		id = streamID{ms: last.ms, seq: last.seq + 1}
+	}
+	return id, nil
+}
+
+// updateStream runs update on the stream under the fragment lock. The stream
+// is created if it doesn't exist. update returns false if it doesn't modify
+// the stream. See updateTypedValue.
+func (dm *DMap) updateStream(ctx context.Context, key string, update func(s *stream) (bool, error)) error {
+	part := dm.getPartitionByHKey(partitions.HKey(dm.name, key), partitions.PRIMARY)
+
+	var s *stream
+	err := dm.updateTypedValue(ctx, key, func(value []byte) ([]byte, bool, error) {
+		var err error
+		s, err = dm.newStream(ctx, part, key, value)
+		if err != nil {
+			return nil, false, err
+		}
+
+		changed, err := update(s)
+		if err != nil || !changed {
+			return nil, false, err
+		}
+		if err = s.store.flush(); err != nil {
+			return nil, false, err
+		}
+		value, err = encodeStream(s)
+		if err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.store.deleteRemoved()
+}
+
+func (dm *DMap) xadd(ctx context.Context, key string, maxLen int, fields []string, values [][]byte) (string, error) {
+	if maxLen < 0 {
+		return "", fmt.Errorf("invalid max length: %d", maxLen)
+	}
+
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		cmd := protocol.NewXAdd(dm.name, key).SetMaxLen(maxLen)
+		for i, field := range fields {
+			cmd.Add(field, values[i])
+		}
+		rc := dm.s.client.Get(owner.String())
+		xaddCmd := cmd.Command(ctx)
+		err := rc.Process(ctx, xaddCmd)
+		if err != nil {
+			return "", protocol.ConvertError(err)
+		}
+		id, err := xaddCmd.Result()
+		if err != nil {
+			return "", protocol.ConvertError(err)
+		}
+		return id, nil
+	}
+
+	var id string
+	err := dm.updateStream(ctx, key, func(s *stream) (bool, error) {
+		next, err := s.nextID(time.Now())
+		if err != nil {
+			return false, err
+		}
+		id = next.String()
+
+		entry := StreamEntry{
+			ID:     id,
+			Fields: make(map[string][]byte, len(fields)),
+		}
+		for i, field := range fields {
+			entry.Fields[field] = values[i]
+		}
+		if err = s.add(entry); err != nil {
+			return false, err
+		}
+		if maxLen > 0 {
+			if err = s.trim(maxLen); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// XAdd appends an entry with the given fields to the stream stored at key and
+// returns its ID. The stream is created if it doesn't exist. If maxLen is
+// greater than zero, the oldest entries are removed to keep the length of the
+// stream at most maxLen. Otherwise, the stream is unbounded. The entries are
+// stored in chunks of streamChunkSize entries, so XAdd only rewrites the last
+// chunk.
+func (dm *DMap) XAdd(ctx context.Context, key string, fields map[string]interface{}, maxLen int) (string, error) {
+	var names []string
+	var values [][]byte
+	for field, value := range fields {
+		encoded, err := encodeValue(value)
+		if err != nil {
+			return "", err
+		}
+		names = append(names, field)
+		values = append(values, encoded)
+	}
+	return dm.xadd(ctx, key, maxLen, names, values)
+}
+
+// loadStream returns the stream stored at key without its entries, so it can
+// be read from any replica. It returns nil if the key doesn't exist.
+func (dm *DMap) loadStream(ctx context.Context, key string) (*stream, error) {
+	entry, err := dm.Get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeStream(entry.Value())
+}
+
+// XRead returns the entries of the stream stored at key with an ID greater
+// than id. An empty id or "0" reads from the beginning of the stream. Zero
+// count returns all the entries.
+func (dm *DMap) XRead(ctx context.Context, key, id string, count int) ([]StreamEntry, error) {
+	after, err := parseStreamID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		cmd := protocol.NewXRead(dm.name, key, id).SetCount(count).Command(ctx)
+		rc := dm.s.client.Get(owner.String())
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return nil, protocol.ConvertError(err)
+		}
+		res, err := cmd.Result()
+		if err != nil {
+			return nil, protocol.ConvertError(err)
+		}
+		return parseStreamEntries(res)
+	}
+
+	entries := []StreamEntry{}
+	err = dm.readTypedValue(key, func(part *partitions.Partition, value []byte) error {
+		if value == nil {
+			return nil
+		}
+		s, err := dm.newStream(ctx, part, key, value)
+		if err != nil {
+			return err
+		}
+		entries, err = s.entriesAfter(after, count)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseStreamEntries parses the reply of the XREAD and XREADGROUP commands.
+func parseStreamEntries(res []interface{}) ([]StreamEntry, error) {
+	entries := make([]StreamEntry, 0, len(res))
+	for _, item := range res {
+		raw, ok := item.([]interface{})
+		if !ok || len(raw) != 2 {
+			return nil, fmt.Errorf("invalid stream entry: %v", item)
+		}
+		id, ok := raw[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid stream entry ID type: %T", raw[0])
+		}
+		entry := StreamEntry{ID: id}
+		if raw[1] != nil {
+			fields, ok := raw[1].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid stream entry fields type: %T", raw[1])
+			}
+			entry.Fields = make(map[string][]byte, len(fields)/2)
+			for i := 0; i+1 < len(fields); i += 2 {
+				field, _ := fields[i].(string)
+				value, _ := fields[i+1].(string)
+				entry.Fields[field] = []byte(value)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// XReadGroup reads the entries of the stream stored at key for a consumer of
+// the group. The group is created on the first call and it starts from the
+// beginning of the stream. If id is StreamNewEntries, it returns the entries
+// that were never delivered to the group, otherwise it returns the pending
+// entries of the consumer with an ID greater than id, so a consumer can resume
+// after reconnecting. The returned entries are pending until they are
+// acknowledged with XAck. Fields of a pending entry are nil if it has been
+// trimmed from the stream.
+func (dm *DMap) XReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]StreamEntry, error) {
+	var history streamID
+	if id != StreamNewEntries {
+		var err error
+		history, err = parseStreamID(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		cmd := protocol.NewXReadGroup(dm.name, key, group, consumer, id).SetCount(count).Command(ctx)
+		rc := dm.s.client.Get(owner.String())
+		err := rc.Process(ctx, cmd)
+		if err != nil {
+			return nil, protocol.ConvertError(err)
+		}
+		res, err := cmd.Result()
+		if err != nil {
+			return nil, protocol.ConvertError(err)
+		}
+		return parseStreamEntries(res)
+	}
+
+	var entries []StreamEntry
+	err := dm.updateStream(ctx, key, func(s *stream) (bool, error) {
+		var changed bool
+		g, ok := s.Groups[group]
+		if !ok {
+			g = &streamGroup{LastID: streamID{}.String()}
+			s.Groups[group] = g
+			changed = true
+		}
+		if g.Pending == nil {
+			g.Pending = make(map[string]*streamPending)
+		}
+		now := time.Now().UnixNano()
+
+		if id == StreamNewEntries {
+			last, err := parseStreamID(g.LastID)
+			if err != nil {
+				return false, err
+			}
+			entries, err = s.entriesAfter(last, count)
+			if err != nil {
+				return false, err
+			}
+			for _, entry := range entries {
+				g.Pending[entry.ID] = &streamPending{
+					Consumer:    consumer,
+					DeliveredAt: now,
+					Deliveries:  1,
+				}
+			}
+			if len(entries) > 0 {
+				g.LastID = entries[len(entries)-1].ID
+				changed = true
+			}
+			return changed, nil
+		}
+
+		// Delivers the pending entries of the consumer again.
+		var ids []streamID
+		for pendingID, p := range g.Pending {
+			if p.Consumer != consumer {
+				continue
+			}
+			parsed, err := parseStreamID(pendingID)
+			if err != nil {
+				return false, err
+			}
+			if history.less(parsed) {
+				ids = append(ids, parsed)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return ids[i].less(ids[j])
+		})
+		if count > 0 && len(ids) > count {
+			ids = ids[:count]
+		}
+		entries = make([]StreamEntry, 0, len(ids))
+		for _, parsed := range ids {
+			pendingID := parsed.String()
+			entry, ok, err := s.entry(pendingID)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				entry = StreamEntry{ID: pendingID}
+			}
+			entries = append(entries, entry)
+
+			p := g.Pending[pendingID]
+			p.DeliveredAt = now
+			p.Deliveries++
+			changed = true
+		}
+		return changed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []StreamEntry{}
+	}
+	return entries, nil
+}
+
+// XAck acknowledges the entries of the group, so they are removed from the
+// pending entries. It returns the number of the acknowledged entries.
+func (dm *DMap) XAck(ctx context.Context, key, group string, ids ...string) (int, error) {
+	owner, ok := dm.keyOwner(key)
+	if !ok {
+		return dm.processOnKeyOwner(ctx, owner, protocol.NewXAck(dm.name, key, group, ids...).Command(ctx))
+	}
+
+	var acked int
+	err := dm.updateStream(ctx, key, func(s *stream) (bool, error) {
+		g, ok := s.Groups[group]
+		if !ok {
+			return false, ErrNoSuchGroup
+		}
+		for _, id := range ids {
+			if _, ok := g.Pending[id]; ok {
+				delete(g.Pending, id)
+				acked++
+			}
+		}
+		return acked > 0, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return acked, nil
+}
+
+// XPending returns the pending entries of the group ordered by their IDs.
+func (dm *DMap) XPending(ctx context.Context, key, group string) ([]PendingEntry, error) {
+	s, err := dm.loadStream(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrNoSuchGroup
+	}
+	g, ok := s.Groups[group]
+	if !ok {
+		return nil, ErrNoSuchGroup
+	}
+
+	now := time.Now().UnixNano()
+	pending := make([]PendingEntry, 0, len(g.Pending))
+	for id, p := range g.Pending {
+		pending = append(pending, PendingEntry{
+			ID:         id,
+			Consumer:   p.Consumer,
+			Idle:       time.Duration(now - p.DeliveredAt),
+			Deliveries: p.Deliveries,
+		})
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		a, _ := parseStreamID(pending[i].ID)
+		b, _ := parseStreamID(pending[j].ID)
+		return a.less(b)
+	})
+	return pending, nil
+}
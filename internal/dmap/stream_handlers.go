@@ -0,0 +1,165 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"sort"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/tidwall/redcon"
+)
+
+// writeStreamEntries writes the entries as an array of ID and field-value
+// pairs, like XRANGE of Redis. Fields of a trimmed entry are written as null.
+func writeStreamEntries(conn redcon.Conn, entries []StreamEntry) {
+	conn.WriteArray(len(entries))
+	for _, entry := range entries {
+		conn.WriteArray(2)
+		conn.WriteBulkString(entry.ID)
+		if entry.Fields == nil {
+			conn.WriteNull()
+			continue
+		}
+
+		fields := make([]string, 0, len(entry.Fields))
+		for field := range entry.Fields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		conn.WriteArray(len(fields) * 2)
+		for _, field := range fields {
+			conn.WriteBulkString(field)
+			conn.WriteBulk(entry.Fields[field])
+		}
+	}
+}
+
+func (s *Service) xaddCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	xaddCmd, err := protocol.ParseXAddCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(xaddCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	id, err := dm.xadd(server.RequestContext(conn, s.ctx), xaddCmd.Key, xaddCmd.MaxLen, xaddCmd.Fields, xaddCmd.Values)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteBulkString(id)
+}
+
+func (s *Service) xreadCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	xreadCmd, err := protocol.ParseXReadCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(xreadCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	entries, err := dm.XRead(server.RequestContext(conn, s.ctx), xreadCmd.Key, xreadCmd.ID, xreadCmd.Count)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	writeStreamEntries(conn, entries)
+}
+
+func (s *Service) xreadgroupCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	xreadgroupCmd, err := protocol.ParseXReadGroupCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(xreadgroupCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	entries, err := dm.XReadGroup(
+		server.RequestContext(conn, s.ctx),
+		xreadgroupCmd.Key,
+		xreadgroupCmd.Group,
+		xreadgroupCmd.Consumer,
+		xreadgroupCmd.ID,
+		xreadgroupCmd.Count,
+	)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	writeStreamEntries(conn, entries)
+}
+
+func (s *Service) xackCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	xackCmd, err := protocol.ParseXAckCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(xackCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	acked, err := dm.XAck(server.RequestContext(conn, s.ctx), xackCmd.Key, xackCmd.Group, xackCmd.IDs...)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt(acked)
+}
+
+func (s *Service) xpendingCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	xpendingCmd, err := protocol.ParseXPendingCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	dm, err := s.getOrCreateDMap(xpendingCmd.DMap)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	pending, err := dm.XPending(server.RequestContext(conn, s.ctx), xpendingCmd.Key, xpendingCmd.Group)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	// ID, consumer, idle time in milliseconds and delivery count.
+	conn.WriteArray(len(pending))
+	for _, p := range pending {
+		conn.WriteArray(4)
+		conn.WriteBulkString(p.ID)
+		conn.WriteBulkString(p.Consumer)
+		conn.WriteInt64(p.Idle.Milliseconds())
+		conn.WriteInt(p.Deliveries)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMap_Stream(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		key := testutil.ToKey(i)
+
+		var ids []string
+		for j := 0; j < 5; j++ {
+			dm := dm1
+			if j%2 == 0 {
+				dm = dm2
+			}
+			id, err := dm.XAdd(ctx, key, map[string]interface{}{"seq": j}, 0)
+			require.NoError(t, err)
+			ids = append(ids, id)
+		}
+
+		entries, err := dm1.XRead(ctx, key, "0", 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 5)
+		for j, entry := range entries {
+			require.Equal(t, ids[j], entry.ID)
+			require.Equal(t, map[string][]byte{"seq": []byte(strconv.Itoa(j))}, entry.Fields)
+		}
+
+		// Resumes from the last seen ID.
+		entries, err = dm2.XRead(ctx, key, ids[2], 1)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, ids[3], entries[0].ID)
+
+		entries, err = dm2.XRead(ctx, key, ids[4], 0)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	}
+}
+
+func TestDMap_Stream_MaxLen(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		id, err := dm.XAdd(ctx, "mystream", map[string]interface{}{"seq": i}, 3)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	entries, err := dm.XRead(ctx, "mystream", "", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, ids[7], entries[0].ID)
+	require.Equal(t, ids[9], entries[2].ID)
+}
+
+func TestDMap_Stream_ConsumerGroup(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm1, err := s1.NewDMap("mymap")
+	require.NoError(t, err)
+	dm2, err := s2.NewDMap("mymap")
+	require.NoError(t, err)
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		id, err := dm1.XAdd(ctx, "jobs", map[string]interface{}{"job": i}, 0)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	// The consumers of the same group share the entries.
+	entries, err := dm1.XReadGroup(ctx, "jobs", "workers", "worker-1", StreamNewEntries, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, ids[0], entries[0].ID)
+	require.Equal(t, ids[1], entries[1].ID)
+
+	entries, err = dm2.XReadGroup(ctx, "jobs", "workers", "worker-2", StreamNewEntries, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, ids[2], entries[0].ID)
+	require.Equal(t, ids[3], entries[1].ID)
+
+	entries, err = dm2.XReadGroup(ctx, "jobs", "workers", "worker-2", StreamNewEntries, 0)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// Another group starts from the beginning of the stream.
+	entries, err = dm2.XReadGroup(ctx, "jobs", "auditors", "auditor-1", StreamNewEntries, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	acked, err := dm2.XAck(ctx, "jobs", "workers", ids[0], ids[2], "0-1")
+	require.NoError(t, err)
+	require.Equal(t, 2, acked)
+
+	pending, err := dm1.XPending(ctx, "jobs", "workers")
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, ids[1], pending[0].ID)
+	require.Equal(t, "worker-1", pending[0].Consumer)
+	require.Equal(t, 1, pending[0].Deliveries)
+	require.Equal(t, ids[3], pending[1].ID)
+	require.Equal(t, "worker-2", pending[1].Consumer)
+
+	// worker-1 reconnects and reads its pending entries again.
+	entries, err = dm2.XReadGroup(ctx, "jobs", "workers", "worker-1", "0", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, ids[1], entries[0].ID)
+	require.Equal(t, map[string][]byte{"job": []byte("1")}, entries[0].Fields)
+
+	pending, err = dm2.XPending(ctx, "jobs", "workers")
+	require.NoError(t, err)
+	require.Equal(t, 2, pending[0].Deliveries)
+
+	_, err = dm1.XAck(ctx, "jobs", "missing", ids[0])
+	require.ErrorIs(t, err, ErrNoSuchGroup)
+
+	_, err = dm1.XPending(ctx, "jobs", "missing")
+	require.ErrorIs(t, err, ErrNoSuchGroup)
+}
+
+func TestDMap_Stream_Errors(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue", nil))
+	_, err = dm.XAdd(ctx, "mykey", map[string]interface{}{"field": "value"}, 0)
+	require.ErrorIs(t, err, ErrWrongType)
+
+	_, err = dm.XRead(ctx, "mystream", "foobar", 0)
+	require.ErrorIs(t, err, ErrInvalidStreamID)
+}
+
+func TestDMap_Stream_Chunks(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	dm, err := s.NewDMap("mymap")
+	require.NoError(t, err)
+
+	maxLen := 2*streamChunkSize + 10
+	var ids []string
+	for i := 0; i < 3*streamChunkSize; i++ {
+		id, err := dm.XAdd(ctx, "mystream", map[string]interface{}{"seq": i}, maxLen)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	ids = ids[len(ids)-maxLen:]
+	// The trimmed chunks are deleted.
+	require.Equal(t, 3, countChunks(s))
+
+	entries, err := dm.XRead(ctx, "mystream", "0", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, maxLen)
+	for i, entry := range entries {
+		require.Equal(t, ids[i], entry.ID)
+	}
+
+	// Spans the chunks.
+	entries, err = dm.XRead(ctx, "mystream", ids[5], streamChunkSize)
+	require.NoError(t, err)
+	require.Len(t, entries, streamChunkSize)
+	require.Equal(t, ids[6], entries[0].ID)
+	require.Equal(t, ids[5+streamChunkSize], entries[streamChunkSize-1].ID)
+
+	entries, err = dm.XReadGroup(ctx, "mystream", "mygroup", "consumer", StreamNewEntries, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, maxLen)
+
+	// Delivers the pending entries again.
+	entries, err = dm.XReadGroup(ctx, "mystream", "mygroup", "consumer", ids[maxLen-20], 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 19)
+	require.Equal(t, ids[maxLen-19], entries[0].ID)
+	require.NotNil(t, entries[0].Fields)
+
+	_, err = dm.Delete(ctx, "mystream")
+	require.NoError(t, err)
+	s.sweepChunks(s.sweepChunks(map[uint64]struct{}{}))
+	require.Equal(t, 0, countChunks(s))
+}
@@ -0,0 +1,190 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/discovery"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/resp"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrWrongType means that the key holds a value of another type than the
+// command expects, such as calling LPush on a hash or HGet on a key that is
+// set by Put.
+var ErrWrongType = errors.New("operation against a key holding the wrong kind of value")
+
+// keyOwner returns the primary owner of the key and true if this member is the owner.
+func (dm *DMap) keyOwner(key string) (discovery.Member, bool) {
+	return dm.hkeyOwner(partitions.HKey(dm.name, key))
+}
+
+// hkeyOwner returns the primary owner of the hkey and true if this member is the owner.
+func (dm *DMap) hkeyOwner(hkey uint64) (discovery.Member, bool) {
+	member := dm.s.primary.PartitionByHKey(hkey).Owner()
+	return member, member.CompareByName(dm.s.rt.This())
+}
+
+// updateTypedValue runs update on the current value of the key under the
+// fragment lock. It has to be called on the partition owner. The value is nil
+// if the key doesn't exist. update returns the new value and false if it
+// doesn't modify the value. A nil value deletes the key. The new value is
+// replicated like Put and it keeps the TTL of the key.
+func (dm *DMap) updateTypedValue(ctx context.Context, key string, update func(value []byte) ([]byte, bool, error)) error {
+	e := newEnv(ctx)
+	e.dmap = dm.name
+	e.key = key
+	e.hkey = partitions.HKey(dm.name, key)
+
+	if err := dm.updateTypedValueOnFragment(e, update); err != nil {
+		return err
+	}
+	// The MapStore is written after the fragment lock is released.
+	return dm.flushMapStore(key)
+}
+
+func (dm *DMap) updateTypedValueOnFragment(e *env, update func(value []byte) ([]byte, bool, error)) error {
+	part := dm.getPartitionByHKey(e.hkey, partitions.PRIMARY)
+	f, err := dm.loadOrCreateFragment(part)
+	if err != nil {
+		return err
+	}
+
+	e.fragment = f
+	f.Lock()
+	defer f.Unlock()
+
+	key := e.key
+	var ttl int64
+	var value []byte
+	current, err := f.storage.Get(e.hkey)
+	switch {
+	case errors.Is(err, storage.ErrKeyNotFound):
+	case err != nil:
+		return err
+	case isKeyExpired(current.TTL()):
+	default:
+		value = current.Value()
+		ttl = current.TTL()
+	}
+
+	value, changed, err := update(value)
+	if err != nil || !changed {
+		return err
+	}
+
+	if value == nil {
+		if err = dm.deleteEntry(e.hkey, key, f); err != nil {
+			return err
+		}
+		dm.notifyKeyspaceEvent(config.KeyspaceEventDel, key)
+		return nil
+	}
+
+	e.value = value
+	if ttl != 0 {
+		e.putConfig.HasPXAT = true
+		e.putConfig.PXAT = time.Duration(ttl) * time.Millisecond
+	}
+	if err = dm.putOnLockedFragment(e); err != nil {
+		return err
+	}
+	dm.notifyKeyspaceEvent(config.KeyspaceEventPut, key)
+	return nil
+}
+
+// readTypedValue runs read on the current value of the key under the read lock
+// of the fragment. It has to be called on the partition owner. The value is nil
+// if the key doesn't exist.
+func (dm *DMap) readTypedValue(key string, read func(part *partitions.Partition, value []byte) error) error {
+	hkey := partitions.HKey(dm.name, key)
+	part := dm.getPartitionByHKey(hkey, partitions.PRIMARY)
+	f, err := dm.loadOrCreateFragment(part)
+	if err != nil {
+		return err
+	}
+
+	f.RLock()
+	defer f.RUnlock()
+
+	var value []byte
+	current, err := f.storage.Get(hkey)
+	switch {
+	case errors.Is(err, storage.ErrKeyNotFound):
+	case err != nil:
+		return err
+	case isKeyExpired(current.TTL()):
+	default:
+		value = current.Value()
+	}
+	return read(part, value)
+}
+
+// processOnKeyOwner sends the command to the partition owner of the key.
+func (dm *DMap) processOnKeyOwner(ctx context.Context, owner discovery.Member, cmd *redis.IntCmd) (int, error) {
+	rc := dm.s.client.Get(owner.String())
+	err := rc.Process(ctx, cmd)
+	if err != nil {
+		return 0, protocol.ConvertError(err)
+	}
+	value, err := cmd.Result()
+	if err != nil {
+		return 0, protocol.ConvertError(err)
+	}
+	return int(value), nil
+}
+
+// encodeValue encodes a field or an element of the typed values like Put does.
+func encodeValue(value interface{}) ([]byte, error) {
+	valueBuf := pool.Get()
+	defer pool.Put(valueBuf)
+
+	enc := resp.New(valueBuf)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	encoded := make([]byte, valueBuf.Len())
+	copy(encoded, valueBuf.Bytes())
+	return encoded, nil
+}
+
+// normalizeRange converts the inclusive start and stop ranks to slice bounds
+// for a collection with the given length. Negative ranks are counted from the
+// end. It returns false if the range is empty.
+func normalizeRange(start, stop, length int) (int, int, bool) {
+	if start < 0 {
+		start += length
+		if start < 0 {
+			start = 0
+		}
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop {
+		return 0, 0, false
+	}
+	return start, stop + 1, true
+}
@@ -0,0 +1,215 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/wal"
+	"github.com/buraksezer/olric/pkg/storage"
+)
+
+// walCompactionCheckInterval is the interval between two sequential checks of
+// the size of the write-ahead log.
+const walCompactionCheckInterval = time.Second
+
+func (s *Service) openWAL() error {
+	wc := s.config.DMaps.WAL
+	if wc == nil || !wc.Enabled {
+		return nil
+	}
+
+	w, err := wal.Open(wc.Path, wc.FsyncPolicy == config.FsyncAlways)
+	if err != nil {
+		return err
+	}
+	s.wal = w
+	return nil
+}
+
+// logPut writes a put or an expire record to the write-ahead log. It has to be called
+// on the partition owner before applying the entry to the fragment.
+func (dm *DMap) logPut(e *env, nt storage.Entry) error {
+	if dm.s.wal == nil {
+		return nil
+	}
+	op := wal.OpPut
+	if e.putConfig.OnlyUpdateTTL {
+		op = wal.OpExpire
+	}
+	return dm.s.wal.Append(&wal.Record{
+		Op:        op,
+		Timestamp: nt.Timestamp(),
+		DMap:      dm.name,
+		Key:       nt.Key(),
+		Value:     nt.Encode(),
+	})
+}
+
+// logDelete writes a delete record to the write-ahead log. It has to be called
+// before deleting the key from the primary fragment. The deletes on the previous
+// owners are logged too, so a replayed stale entry cannot be moved back to the
+// partition owner.
+func (dm *DMap) logDelete(key string) error {
+	if dm.s.wal == nil {
+		return nil
+	}
+	return dm.s.wal.Append(&wal.Record{
+		Op:        wal.OpDelete,
+		Timestamp: time.Now().UnixNano(),
+		DMap:      dm.name,
+		Key:       key,
+	})
+}
+
+// logDestroy writes a destroy record to the write-ahead log. It has to be called
+// before wiping out the fragments of the DMap on this member.
+func (s *Service) logDestroy(name string) error {
+	if s.wal == nil {
+		return nil
+	}
+	return s.wal.Append(&wal.Record{
+		Op:        wal.OpDestroy,
+		Timestamp: time.Now().UnixNano(),
+		DMap:      name,
+	})
+}
+
+// applyWALRecord applies a record to the primary partition which holds the key.
+// Records older than the stored entry are ignored. The balancer moves the replayed
+// entries to the current partition owners.
+func (s *Service) applyWALRecord(rec *wal.Record) error {
+	s.Lock()
+	dm, err := s.newDMap(rec.DMap)
+	s.Unlock()
+	if err != nil {
+		return err
+	}
+
+	hkey := partitions.HKey(rec.DMap, rec.Key)
+	f, err := dm.loadOrCreateFragment(s.primary.PartitionByHKey(hkey))
+	if err != nil {
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	current, err := f.storage.Get(hkey)
+	if err == nil && current.Timestamp() > rec.Timestamp {
+		// The key has been modified after this record.
+		return nil
+	}
+	if err != nil && !errors.Is(err, storage.ErrKeyNotFound) {
+		return err
+	}
+
+	invalidateIndex(f)
+	switch rec.Op {
+	case wal.OpPut:
+		entry := f.storage.NewEntry()
+		entry.Decode(rec.Value)
+		return f.storage.Put(hkey, entry)
+	case wal.OpExpire:
+		entry := f.storage.NewEntry()
+		entry.Decode(rec.Value)
+		err = f.storage.UpdateTTL(hkey, entry)
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	case wal.OpDelete:
+		err = f.storage.Delete(hkey)
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown WAL operation: %d", rec.Op)
+	}
+}
+
+// replayWAL compacts the write-ahead log and applies the remaining records to the fragments.
+func (s *Service) replayWAL() error {
+	if s.wal == nil {
+		return nil
+	}
+
+	records, corrupted, err := s.wal.Compact()
+	if err != nil {
+		return err
+	}
+	if corrupted {
+		s.log.V(2).Printf("[WARN] Corrupted tail of the write-ahead log has been discarded: %s", s.config.DMaps.WAL.Path)
+	}
+
+	for _, rec := range records {
+		if err = s.applyWALRecord(rec); err != nil {
+			s.log.V(3).Printf("[ERROR] Failed to replay WAL record of key: %s on DMap: %s: %v", rec.Key, rec.DMap, err)
+		}
+	}
+	s.log.V(2).Printf("[INFO] %d records have been replayed from the write-ahead log", len(records))
+	return nil
+}
+
+// walCompactionWorker compacts the write-ahead log when it grows, so the log
+// doesn't grow without bound on a long-running member.
+func (s *Service) walCompactionWorker() {
+	defer s.wg.Done()
+
+	minSize := s.config.DMaps.WAL.CompactionMinSize
+	ticker := time.NewTicker(walCompactionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !s.wal.NeedsCompaction(minSize) {
+				continue
+			}
+			records, _, err := s.wal.Compact()
+			if err != nil {
+				s.log.V(3).Printf("[ERROR] Failed to compact the write-ahead log: %v", err)
+				continue
+			}
+			s.log.V(6).Printf("[DEBUG] Write-ahead log has been compacted, %d records left", len(records))
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// walSyncWorker flushes the write-ahead log periodically if the fsync policy is interval.
+func (s *Service) walSyncWorker() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.DMaps.WAL.FsyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.wal.Sync(); err != nil {
+				s.log.V(3).Printf("[ERROR] Failed to sync the write-ahead log: %v", err)
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
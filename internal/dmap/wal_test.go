@@ -0,0 +1,216 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmap
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newWALTestConfig(t *testing.T) func() *config.Config {
+	dir, err := ioutil.TempDir("", "olric-wal")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(dir))
+	})
+
+	return func() *config.Config {
+		c := testutil.NewConfig()
+		c.DMaps.WAL = config.NewWAL()
+		c.DMaps.WAL.Enabled = true
+		c.DMaps.WAL.Path = filepath.Join(dir, "dmaps.wal")
+		c.DMaps.WAL.FsyncPolicy = config.FsyncAlways
+		return c
+	}
+}
+
+func TestDMap_WAL_Replay(t *testing.T) {
+	newConfig := newWALTestConfig(t)
+
+	ctx := context.Background()
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(testcluster.NewEnvironment(newConfig())).(*Service)
+
+	dm, err := s.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		err = dm.Put(ctx, testutil.ToKey(i), testutil.ToVal(i), nil)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 10; i++ {
+		_, err = dm.Delete(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+	}
+	_, err = dm.Incr(ctx, "counter", 10)
+	require.NoError(t, err)
+	err = dm.Expire(ctx, testutil.ToKey(10), time.Millisecond)
+	require.NoError(t, err)
+	cluster.Shutdown()
+
+	cluster = testcluster.New(NewService)
+	s = cluster.AddMember(testcluster.NewEnvironment(newConfig())).(*Service)
+	defer cluster.Shutdown()
+
+	dm, err = s.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	// The service replays the log in the background.
+	err = testutil.TryWithInterval(50, 10*time.Millisecond, func() error {
+		if _, err := dm.Get(ctx, "counter"); err != nil {
+			return err
+		}
+		// The latest record
+		if _, err := dm.Get(ctx, testutil.ToKey(10)); err != ErrKeyNotFound {
+			return fmt.Errorf("expected ErrKeyNotFound, got: %v", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 11; i++ {
+		_, err = dm.Get(ctx, testutil.ToKey(i))
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	}
+	for i := 11; i < 100; i++ {
+		gr, err := dm.Get(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+		require.Equal(t, testutil.ToVal(i), gr.Value())
+	}
+
+	counter, err := dm.Incr(ctx, "counter", 1)
+	require.NoError(t, err)
+	require.Equal(t, 11, counter)
+}
+
+func TestDMap_WAL_Destroy(t *testing.T) {
+	newConfig := newWALTestConfig(t)
+
+	ctx := context.Background()
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(testcluster.NewEnvironment(newConfig())).(*Service)
+
+	dm, err := s.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		err = dm.Put(ctx, testutil.ToKey(i), testutil.ToVal(i), nil)
+		require.NoError(t, err)
+	}
+	require.NoError(t, dm.Destroy(ctx))
+	err = dm.Put(ctx, "mykey", "myvalue", nil)
+	require.NoError(t, err)
+	cluster.Shutdown()
+
+	cluster = testcluster.New(NewService)
+	s = cluster.AddMember(testcluster.NewEnvironment(newConfig())).(*Service)
+	defer cluster.Shutdown()
+
+	dm, err = s.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	// The service replays the log in the background.
+	err = testutil.TryWithInterval(50, 10*time.Millisecond, func() error {
+		_, err := dm.Get(ctx, "mykey")
+		return err
+	})
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = dm.Get(ctx, testutil.ToKey(i))
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	}
+}
+
+func TestDMap_WAL_Eviction(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := newWALTestConfig(t)()
+		c.DMaps.EvictionPolicy = config.LRUEviction
+		c.DMaps.MaxKeys = 100
+		return c
+	}
+	c := newConfig()
+
+	ctx := context.Background()
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(testcluster.NewEnvironment(c)).(*Service)
+
+	dm, err := s.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		err = dm.Put(ctx, testutil.ToKey(i), testutil.ToVal(i), nil)
+		require.NoError(t, err)
+	}
+
+	countKeys := func() int {
+		var count int
+		for i := 0; i < 1000; i++ {
+			if _, err := dm.Get(ctx, testutil.ToKey(i)); err == nil {
+				count++
+			}
+		}
+		return count
+	}
+	count := countKeys()
+	require.Less(t, count, 1000)
+	// The latest record
+	err = dm.Put(ctx, "mykey", "myvalue", nil)
+	require.NoError(t, err)
+	cluster.Shutdown()
+
+	cluster = testcluster.New(NewService)
+	s = cluster.AddMember(testcluster.NewEnvironment(c)).(*Service)
+	defer cluster.Shutdown()
+
+	dm, err = s.NewDMap("mydmap")
+	require.NoError(t, err)
+	err = testutil.TryWithInterval(50, 10*time.Millisecond, func() error {
+		_, err := dm.Get(ctx, "mykey")
+		return err
+	})
+	require.NoError(t, err)
+	// The evicted keys are not replayed. The latest put may evict one more key.
+	require.LessOrEqual(t, countKeys(), count)
+}
+
+func TestDMap_WAL_Compaction(t *testing.T) {
+	c := newWALTestConfig(t)()
+	c.DMaps.WAL.CompactionMinSize = 4096
+
+	ctx := context.Background()
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(testcluster.NewEnvironment(c)).(*Service)
+	defer cluster.Shutdown()
+
+	dm, err := s.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		err = dm.Put(ctx, "mykey", testutil.ToVal(i), nil)
+		require.NoError(t, err)
+	}
+
+	// The log is compacted at runtime and only the latest put is left.
+	require.Eventually(t, func() bool {
+		info, err := os.Stat(c.DMaps.WAL.Path)
+		return err == nil && info.Size() < 4096
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,161 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nearcache implements a bounded LRU cache to keep DMap entries on the client side.
+package nearcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/buraksezer/olric/pkg/storage"
+)
+
+type item struct {
+	key      string
+	entry    storage.Entry
+	lease    uint64
+	expireAt int64
+}
+
+// Cache is a bounded LRU cache with a fixed TTL. It's thread-safe.
+//
+// A reader takes a lease before fetching a key from the cluster, and fills the
+// cache with the same lease. Invalidate drops the lease, so a value that has been
+// fetched before an invalidation never overwrites the invalidation.
+type Cache struct {
+	mtx sync.Mutex
+
+	size      int
+	ttl       time.Duration
+	items     map[string]*list.Element
+	evictList *list.List
+	lease     uint64
+}
+
+// New returns a new Cache. size is the maximum number of keys in the cache and
+// ttl is the maximum lifetime of a cached entry.
+func New(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:      size,
+		ttl:       ttl,
+		items:     make(map[string]*list.Element),
+		evictList: list.New(),
+	}
+}
+
+func (c *Cache) isExpired(it *item, now int64) bool {
+	if now >= it.expireAt {
+		return true
+	}
+	// TTL of the entry is in milliseconds.
+	ttl := it.entry.TTL()
+	return ttl != 0 && now/1000000 >= ttl
+}
+
+func (c *Cache) removeElement(e *list.Element) {
+	c.evictList.Remove(e)
+	delete(c.items, e.Value.(*item).key)
+}
+
+// Get returns the entry for the given key, if it's cached and not expired.
+func (c *Cache) Get(key string) (storage.Entry, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	it := e.Value.(*item)
+	if it.entry == nil {
+		// Leased, not filled yet.
+		return nil, false
+	}
+	if c.isExpired(it, time.Now().UnixNano()) {
+		c.removeElement(e)
+		return nil, false
+	}
+	c.evictList.MoveToFront(e)
+	return it.entry, true
+}
+
+// Lease reserves the given key and returns a lease to fill it.
+func (c *Cache) Lease(key string) uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.lease++
+	if e, ok := c.items[key]; ok {
+		it := e.Value.(*item)
+		it.entry = nil
+		it.lease = c.lease
+		c.evictList.MoveToFront(e)
+		return c.lease
+	}
+
+	e := c.evictList.PushFront(&item{key: key, lease: c.lease})
+	c.items[key] = e
+	if c.evictList.Len() > c.size {
+		c.removeElement(c.evictList.Back())
+	}
+	return c.lease
+}
+
+// Fill sets the entry for the given key if the lease is still valid. It returns
+// false if the key has been invalidated after the lease is taken.
+func (c *Cache) Fill(key string, lease uint64, entry storage.Entry) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	it := e.Value.(*item)
+	if it.lease != lease {
+		return false
+	}
+	it.entry = entry
+	it.expireAt = time.Now().Add(c.ttl).UnixNano()
+	return true
+}
+
+// Invalidate removes the given key and its lease from the cache.
+func (c *Cache) Invalidate(key string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.removeElement(e)
+	}
+}
+
+// Purge removes all the keys and leases from the cache.
+func (c *Cache) Purge() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.evictList.Init()
+}
+
+// Len returns the number of keys in the cache, including the leased ones.
+func (c *Cache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.evictList.Len()
+}
@@ -0,0 +1,109 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nearcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/kvstore/entry"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/buraksezer/olric/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func newEntry(key string) storage.Entry {
+	e := entry.New()
+	e.SetKey(key)
+	e.SetValue([]byte(key))
+	return e
+}
+
+func TestNearCache_Lease_Fill(t *testing.T) {
+	c := New(10, time.Minute)
+
+	_, ok := c.Get("mykey")
+	require.False(t, ok)
+
+	lease := c.Lease("mykey")
+	// Leased keys are not returned.
+	_, ok = c.Get("mykey")
+	require.False(t, ok)
+
+	require.True(t, c.Fill("mykey", lease, newEntry("mykey")))
+	e, ok := c.Get("mykey")
+	require.True(t, ok)
+	require.Equal(t, "mykey", e.Key())
+}
+
+func TestNearCache_Invalidate_Before_Fill(t *testing.T) {
+	c := New(10, time.Minute)
+
+	lease := c.Lease("mykey")
+	c.Invalidate("mykey")
+	require.False(t, c.Fill("mykey", lease, newEntry("mykey")))
+
+	// A new lease invalidates the older one.
+	lease = c.Lease("mykey")
+	newLease := c.Lease("mykey")
+	require.False(t, c.Fill("mykey", lease, newEntry("mykey")))
+	require.True(t, c.Fill("mykey", newLease, newEntry("mykey")))
+
+	c.Invalidate("mykey")
+	_, ok := c.Get("mykey")
+	require.False(t, ok)
+}
+
+func TestNearCache_LRU(t *testing.T) {
+	c := New(10, time.Minute)
+
+	for i := 0; i < 20; i++ {
+		key := testutil.ToKey(i)
+		c.Fill(key, c.Lease(key), newEntry(key))
+		if i == 9 {
+			// Move the first key to the front
+			_, ok := c.Get(testutil.ToKey(0))
+			require.True(t, ok)
+		}
+	}
+	require.Equal(t, 10, c.Len())
+
+	_, ok := c.Get(testutil.ToKey(0))
+	require.False(t, ok)
+	for i := 10; i < 20; i++ {
+		_, ok := c.Get(testutil.ToKey(i))
+		require.True(t, ok)
+	}
+
+	c.Purge()
+	require.Equal(t, 0, c.Len())
+}
+
+func TestNearCache_TTL(t *testing.T) {
+	c := New(10, 10*time.Millisecond)
+
+	c.Fill("mykey", c.Lease("mykey"), newEntry("mykey"))
+	<-time.After(20 * time.Millisecond)
+	_, ok := c.Get("mykey")
+	require.False(t, ok)
+
+	// TTL of the entry is also respected.
+	c = New(10, time.Minute)
+	e := newEntry("mykey")
+	e.SetTTL(time.Now().Add(-time.Second).UnixNano() / 1000000)
+	c.Fill("mykey", c.Lease("mykey"), e)
+	_, ok = c.Get("mykey")
+	require.False(t, ok)
+}
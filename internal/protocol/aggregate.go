@@ -0,0 +1,148 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+// Operations of the Aggregate command.
+const (
+	AggregateCount = "COUNT"
+	AggregateSum   = "SUM"
+	AggregateMin   = "MIN"
+	AggregateMax   = "MAX"
+)
+
+type Aggregate struct {
+	DMap     string
+	Op       string
+	Field    string
+	GroupBy  string
+	Where    []ScanPredicate
+	internal bool
+}
+
+func NewAggregate(dmap, op string) *Aggregate {
+	return &Aggregate{
+		DMap: dmap,
+		Op:   op,
+	}
+}
+
+// NewAggregateInternal creates an aggregation that only runs on the
+// partitions of the receiver, and returns the partial results.
+func NewAggregateInternal(dmap, op string) *Aggregate {
+	a := NewAggregate(dmap, op)
+	a.internal = true
+	return a
+}
+
+func (a *Aggregate) SetField(field string) *Aggregate {
+	a.Field = field
+	return a
+}
+
+func (a *Aggregate) SetGroupBy(field string) *Aggregate {
+	a.GroupBy = field
+	return a
+}
+
+func (a *Aggregate) AddWhere(field, op, value string) *Aggregate {
+	a.Where = append(a.Where, ScanPredicate{Field: field, Op: op, Value: value})
+	return a
+}
+
+func (a *Aggregate) Command(ctx context.Context) *redis.StringSliceCmd {
+	var args []interface{}
+	if a.internal {
+		args = append(args, DMap.AggregateInternal)
+	} else {
+		args = append(args, DMap.Aggregate)
+	}
+	args = append(args, a.DMap)
+	args = append(args, a.Op)
+	if a.Field != "" {
+		args = append(args, "FIELD", a.Field)
+	}
+	if a.GroupBy != "" {
+		args = append(args, "GROUPBY", a.GroupBy)
+	}
+	for _, p := range a.Where {
+		args = append(args, "WHERE", p.Field, p.Op, p.Value)
+	}
+	return redis.NewStringSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+// ParseAggregateCommand parses both of the Aggregate and AggregateInternal
+// commands.
+func ParseAggregateCommand(cmd redcon.Command) (*Aggregate, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	a := NewAggregate(
+		util.BytesToString(cmd.Args[1]),                  // DMap
+		strings.ToUpper(util.BytesToString(cmd.Args[2])), // Op
+	)
+	a.internal = strings.ToLower(util.BytesToString(cmd.Args[0])) == DMap.AggregateInternal
+
+	switch a.Op {
+	case AggregateCount, AggregateSum, AggregateMin, AggregateMax:
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %s", ErrInvalidArgument, a.Op)
+	}
+
+	args := cmd.Args[3:]
+	for len(args) > 0 {
+		arg := strings.ToUpper(util.BytesToString(args[0]))
+		switch arg {
+		case "FIELD", "GROUPBY":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("%w: %s needs an argument", ErrInvalidArgument, arg)
+			}
+			if arg == "FIELD" {
+				a.SetField(util.BytesToString(args[1]))
+			} else {
+				a.SetGroupBy(util.BytesToString(args[1]))
+			}
+			args = args[2:]
+		case "WHERE":
+			if len(args) < 4 {
+				return nil, fmt.Errorf("%w: %s needs a field, an operator and a value", ErrInvalidArgument, arg)
+			}
+			op := strings.ToUpper(util.BytesToString(args[2]))
+			if !isScanOp(op) {
+				return nil, fmt.Errorf("%w: unknown operator %s", ErrInvalidArgument, op)
+			}
+			a.AddWhere(util.BytesToString(args[1]), op, util.BytesToString(args[3]))
+			args = args[4:]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArgument, arg)
+		}
+	}
+
+	if a.Op != AggregateCount && a.Field == "" {
+		return nil, fmt.Errorf("%w: %s needs a field", ErrInvalidArgument, a.Op)
+	}
+	return a, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_Aggregate(t *testing.T) {
+	aggregateCmd := NewAggregate("my-dmap", AggregateSum).
+		SetField("amount").
+		SetGroupBy("tenant.id").
+		AddWhere("status", ScanOpEqual, "active")
+
+	cmd := stringToCommand(aggregateCmd.Command(context.Background()).String())
+	parsed, err := ParseAggregateCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, AggregateSum, parsed.Op)
+	require.Equal(t, "amount", parsed.Field)
+	require.Equal(t, "tenant.id", parsed.GroupBy)
+	require.Equal(t, []ScanPredicate{{Field: "status", Op: ScanOpEqual, Value: "active"}}, parsed.Where)
+	require.False(t, parsed.internal)
+}
+
+func TestProtocol_AggregateInternal(t *testing.T) {
+	aggregateCmd := NewAggregateInternal("my-dmap", AggregateCount)
+
+	cmd := stringToCommand(aggregateCmd.Command(context.Background()).String())
+	parsed, err := ParseAggregateCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, AggregateCount, parsed.Op)
+	require.True(t, parsed.internal)
+}
+
+func TestProtocol_Aggregate_Invalid(t *testing.T) {
+	_, err := ParseAggregateCommand(stringToCommand("dm.aggregate my-dmap AVG FIELD amount"))
+	require.ErrorIs(t, err, ErrInvalidArgument)
+
+	_, err = ParseAggregateCommand(stringToCommand("dm.aggregate my-dmap SUM"))
+	require.ErrorIs(t, err, ErrInvalidArgument)
+}
@@ -17,6 +17,7 @@ package protocol
 import (
 	"context"
 
+	"github.com/buraksezer/olric/internal/util"
 	"github.com/redis/go-redis/v9"
 	"github.com/tidwall/redcon"
 )
@@ -62,3 +63,53 @@ func ParseClusterMembers(cmd redcon.Command) (*ClusterMembers, error) {
 	c := NewClusterMembers()
 	return c, nil
 }
+
+type ClusterSnapshot struct {
+	Dir string
+}
+
+func NewClusterSnapshot(dir string) *ClusterSnapshot {
+	return &ClusterSnapshot{
+		Dir: dir,
+	}
+}
+
+func (c *ClusterSnapshot) Command(ctx context.Context) *redis.StatusCmd {
+	var args []interface{}
+	args = append(args, Cluster.Snapshot)
+	args = append(args, c.Dir)
+	return redis.NewStatusCmd(ctx, args...)
+}
+
+func ParseClusterSnapshot(cmd redcon.Command) (*ClusterSnapshot, error) {
+	if len(cmd.Args) != 2 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewClusterSnapshot(util.BytesToString(cmd.Args[1])), nil
+}
+
+type ClusterRestore struct {
+	Dir string
+}
+
+func NewClusterRestore(dir string) *ClusterRestore {
+	return &ClusterRestore{
+		Dir: dir,
+	}
+}
+
+func (c *ClusterRestore) Command(ctx context.Context) *redis.StatusCmd {
+	var args []interface{}
+	args = append(args, Cluster.Restore)
+	args = append(args, c.Dir)
+	return redis.NewStatusCmd(ctx, args...)
+}
+
+func ParseClusterRestore(cmd redcon.Command) (*ClusterRestore, error) {
+	if len(cmd.Args) != 2 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewClusterRestore(util.BytesToString(cmd.Args[1])), nil
+}
@@ -48,3 +48,33 @@ func TestProtocol_ClusterMembers(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestProtocol_ClusterSnapshot(t *testing.T) {
+	snapshotCmd := NewClusterSnapshot("/tmp/olric-snapshot")
+
+	cmd := stringToCommand(snapshotCmd.Command(context.Background()).String())
+	parsed, err := ParseClusterSnapshot(cmd)
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/olric-snapshot", parsed.Dir)
+
+	t.Run("CLUSTER.SNAPSHOT invalid command", func(t *testing.T) {
+		cmd := stringToCommand("cluster.snapshot")
+		_, err = ParseClusterSnapshot(cmd)
+		require.Error(t, err)
+	})
+}
+
+func TestProtocol_ClusterRestore(t *testing.T) {
+	restoreCmd := NewClusterRestore("/tmp/olric-snapshot")
+
+	cmd := stringToCommand(restoreCmd.Command(context.Background()).String())
+	parsed, err := ParseClusterRestore(cmd)
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/olric-snapshot", parsed.Dir)
+
+	t.Run("CLUSTER.RESTORE invalid command", func(t *testing.T) {
+		cmd := stringToCommand("cluster.restore")
+		_, err = ParseClusterRestore(cmd)
+		require.Error(t, err)
+	})
+}
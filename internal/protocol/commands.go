@@ -14,16 +14,26 @@
 
 package protocol
 
+import (
+	"strings"
+
+	"github.com/buraksezer/olric/internal/util"
+)
+
 const StatusOK = "OK"
 
 type ClusterCommands struct {
 	RoutingTable string
 	Members      string
+	Snapshot     string
+	Restore      string
 }
 
 var Cluster = &ClusterCommands{
 	RoutingTable: "cluster.routingtable",
 	Members:      "cluster.members",
+	Snapshot:     "cluster.snapshot",
+	Restore:      "cluster.restore",
 }
 
 type InternalCommands struct {
@@ -42,72 +52,209 @@ var Internal = &InternalCommands{
 type GenericCommands struct {
 	Ping  string
 	Stats string
+	Auth  string
 }
 
 var Generic = &GenericCommands{
 	Ping:  "ping",
 	Stats: "stats",
+	Auth:  "auth",
 }
 
 type DMapCommands struct {
-	Get         string
-	GetEntry    string
-	Put         string
-	PutEntry    string
-	Del         string
-	DelEntry    string
-	Expire      string
-	PExpire     string
-	Destroy     string
-	Query       string
-	Incr        string
-	Decr        string
-	GetPut      string
-	IncrByFloat string
-	Lock        string
-	Unlock      string
-	LockLease   string
-	PLockLease  string
-	Scan        string
+	Get               string
+	GetEntry          string
+	Put               string
+	PutEntry          string
+	Del               string
+	DelEntry          string
+	MGet              string
+	MPut              string
+	CompareAndSwap    string
+	Expire            string
+	PExpire           string
+	Destroy           string
+	Query             string
+	Incr              string
+	Decr              string
+	GetPut            string
+	IncrByFloat       string
+	Lock              string
+	Unlock            string
+	LockLease         string
+	PLockLease        string
+	Scan              string
+	HSet              string
+	HGet              string
+	HDel              string
+	HGetAll           string
+	HIncrBy           string
+	ZAdd              string
+	ZRange            string
+	ZRangeByScore     string
+	ZRem              string
+	ZRank             string
+	ZIncrBy           string
+	LPush             string
+	RPush             string
+	LPop              string
+	RPop              string
+	LRange            string
+	LLen              string
+	BLPop             string
+	XAdd              string
+	XRead             string
+	XReadGroup        string
+	XAck              string
+	XPending          string
+	FencedLock        string
+	FencedUnlock      string
+	FencedLockLease   string
+	SemAcquire        string
+	RWLock            string
+	SharedRelease     string
+	SharedLease       string
+	Eval              string
+	QueryInternal     string
+	Aggregate         string
+	AggregateInternal string
+	Execute           string
 }
 
 var DMap = &DMapCommands{
-	Get:         "dm.get",
-	GetEntry:    "dm.getentry",
-	Put:         "dm.put",
-	PutEntry:    "dm.putentry",
-	Del:         "dm.del",
-	DelEntry:    "dm.delentry",
-	Expire:      "dm.expire",
-	PExpire:     "dm.pexpire",
-	Destroy:     "dm.destroy",
-	Incr:        "dm.incr",
-	Decr:        "dm.decr",
-	GetPut:      "dm.getput",
-	IncrByFloat: "dm.incrbyfloat",
-	Lock:        "dm.lock",
-	Unlock:      "dm.unlock",
-	LockLease:   "dm.locklease",
-	PLockLease:  "dm.plocklease",
-	Scan:        "dm.scan",
+	Get:               "dm.get",
+	GetEntry:          "dm.getentry",
+	Put:               "dm.put",
+	PutEntry:          "dm.putentry",
+	Del:               "dm.del",
+	DelEntry:          "dm.delentry",
+	MGet:              "dm.mget",
+	MPut:              "dm.mput",
+	CompareAndSwap:    "dm.cas",
+	Expire:            "dm.expire",
+	PExpire:           "dm.pexpire",
+	Destroy:           "dm.destroy",
+	Incr:              "dm.incr",
+	Decr:              "dm.decr",
+	GetPut:            "dm.getput",
+	IncrByFloat:       "dm.incrbyfloat",
+	Lock:              "dm.lock",
+	Unlock:            "dm.unlock",
+	LockLease:         "dm.locklease",
+	PLockLease:        "dm.plocklease",
+	Scan:              "dm.scan",
+	HSet:              "dm.hset",
+	HGet:              "dm.hget",
+	HDel:              "dm.hdel",
+	HGetAll:           "dm.hgetall",
+	HIncrBy:           "dm.hincrby",
+	ZAdd:              "dm.zadd",
+	ZRange:            "dm.zrange",
+	ZRangeByScore:     "dm.zrangebyscore",
+	ZRem:              "dm.zrem",
+	ZRank:             "dm.zrank",
+	ZIncrBy:           "dm.zincrby",
+	LPush:             "dm.lpush",
+	RPush:             "dm.rpush",
+	LPop:              "dm.lpop",
+	RPop:              "dm.rpop",
+	LRange:            "dm.lrange",
+	LLen:              "dm.llen",
+	BLPop:             "dm.blpop",
+	XAdd:              "dm.xadd",
+	XRead:             "dm.xread",
+	XReadGroup:        "dm.xreadgroup",
+	XAck:              "dm.xack",
+	XPending:          "dm.xpending",
+	FencedLock:        "dm.flock",
+	FencedUnlock:      "dm.funlock",
+	FencedLockLease:   "dm.flocklease",
+	SemAcquire:        "dm.semacquire",
+	RWLock:            "dm.rwlock",
+	SharedRelease:     "dm.srelease",
+	SharedLease:       "dm.slease",
+	Eval:              "dm.eval",
+	Query:             "dm.query",
+	QueryInternal:     "dm.query.internal",
+	Aggregate:         "dm.aggregate",
+	AggregateInternal: "dm.aggregate.internal",
+	Execute:           "dm.execute",
 }
 
 type PubSubCommands struct {
-	Publish         string
-	PublishInternal string
-	Subscribe       string
-	PSubscribe      string
-	PubSubChannels  string
-	PubSubNumpat    string
-	PubSubNumsub    string
+	Publish              string
+	PublishInternal      string
+	PublishRouted        string
+	Subscribe            string
+	PSubscribe           string
+	PubSubChannels       string
+	PubSubNumpat         string
+	PubSubNumsub         string
+	PubSubNumsubInternal string
+	PubSubInterest       string
+	SPublish             string
+	SPublishInternal     string
+	SSubscribe           string
+	PubSubShardChannels  string
+	PubSubShardNumsub    string
 }
 
 var PubSub = &PubSubCommands{
-	Publish:         "publish",
-	PublishInternal: "publish.internal",
-	Subscribe:       "subscribe",
-	PSubscribe:      "psubscribe",
-	PubSubChannels:  "pubsub channels",
-	PubSubNumpat:    "pubsub numpat",
-	PubSubNumsub:    "pubsub numsub",
+	Publish:              "publish",
+	PublishInternal:      "publish.internal",
+	PublishRouted:        "publish.routed",
+	Subscribe:            "subscribe",
+	PSubscribe:           "psubscribe",
+	PubSubChannels:       "pubsub channels",
+	PubSubNumpat:         "pubsub numpat",
+	PubSubNumsub:         "pubsub numsub",
+	PubSubNumsubInternal: "pubsub.numsub.internal",
+	PubSubInterest:       "pubsub.interest",
+	SPublish:             "spublish",
+	SPublishInternal:     "spublish.internal",
+	SSubscribe:           "ssubscribe",
+	PubSubShardChannels:  "pubsub shardchannels",
+	PubSubShardNumsub:    "pubsub shardnumsub",
+}
+
+// internalCommands are the node-to-node commands. They are only run by the
+// cluster members.
+var internalCommands = map[string]struct{}{
+	Internal.MoveFragment:       {},
+	Internal.UpdateRouting:      {},
+	Internal.LengthOfPart:       {},
+	DMap.GetEntry:               {},
+	DMap.PutEntry:               {},
+	DMap.DelEntry:               {},
+	DMap.QueryInternal:          {},
+	DMap.AggregateInternal:      {},
+	PubSub.PublishInternal:      {},
+	PubSub.PublishRouted:        {},
+	PubSub.PubSubNumsubInternal: {},
+	PubSub.PubSubInterest:       {},
+	PubSub.SPublishInternal:     {},
+}
+
+// IsInternalCommand returns true if the command is a node-to-node command.
+func IsInternalCommand(command string) bool {
+	_, ok := internalCommands[strings.ToLower(command)]
+	return ok
+}
+
+// DMapName returns the name of the DMap that a dm.* command accesses. The
+// command has to be in lower case. It returns false if the command is not a
+// DMap command or the name is missing.
+func DMapName(command string, args [][]byte) (string, bool) {
+	if !strings.HasPrefix(command, "dm.") {
+		return "", false
+	}
+	idx := 1
+	if command == DMap.Scan {
+		idx = 2
+	}
+	if len(args) <= idx {
+		// The handler returns a proper error.
+		return "", false
+	}
+	return util.BytesToString(args[idx]), true
 }
@@ -0,0 +1,39 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_IsInternalCommand(t *testing.T) {
+	for _, command := range []string{
+		Internal.MoveFragment,
+		DMap.PutEntry,
+		"DM.GETENTRY",
+		DMap.QueryInternal,
+		PubSub.PublishRouted,
+		PubSub.PubSubInterest,
+		PubSub.SPublishInternal,
+	} {
+		require.True(t, IsInternalCommand(command), command)
+	}
+
+	for _, command := range []string{DMap.Put, DMap.Query, PubSub.Publish, PubSub.SPublish, Cluster.RoutingTable} {
+		require.False(t, IsInternalCommand(command), command)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/buraksezer/olric/internal/tracing"
 	"github.com/buraksezer/olric/internal/util"
 	"github.com/redis/go-redis/v9"
 	"github.com/tidwall/redcon"
@@ -37,6 +38,9 @@ type Put struct {
 	PXAT  int64
 	NX    bool
 	XX    bool
+
+	HasIfVersion bool
+	IfVersion    int64
 }
 
 func NewPut(dmap, key string, value []byte) *Put {
@@ -77,6 +81,12 @@ func (p *Put) SetXX() *Put {
 	return p
 }
 
+func (p *Put) SetIfVersion(version int64) *Put {
+	p.HasIfVersion = true
+	p.IfVersion = version
+	return p
+}
+
 func (p *Put) Command(ctx context.Context) *redis.StatusCmd {
 	var args []interface{}
 	args = append(args, DMap.Put)
@@ -112,7 +122,12 @@ func (p *Put) Command(ctx context.Context) *redis.StatusCmd {
 		args = append(args, "XX")
 	}
 
-	return redis.NewStatusCmd(ctx, args...)
+	if p.HasIfVersion {
+		args = append(args, "IFVERSION")
+		args = append(args, p.IfVersion)
+	}
+
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParsePutCommand(cmd redcon.Command) (*Put, error) {
@@ -169,6 +184,14 @@ func ParsePutCommand(cmd redcon.Command) (*Put, error) {
 			p.SetPXAT(pxat)
 			args = args[2:]
 			continue
+		case "IFVERSION":
+			version, err := strconv.ParseInt(util.BytesToString(args[1]), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			p.SetIfVersion(version)
+			args = args[2:]
+			continue
 		default:
 			return nil, errors.New("syntax error")
 		}
@@ -197,7 +220,7 @@ func (p *PutEntry) Command(ctx context.Context) *redis.StatusCmd {
 	args = append(args, p.DMap)
 	args = append(args, p.Key)
 	args = append(args, p.Value)
-	return redis.NewStatusCmd(ctx, args...)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParsePutEntryCommand(cmd redcon.Command) (*PutEntry, error) {
@@ -238,7 +261,7 @@ func (g *Get) Command(ctx context.Context) *redis.StringCmd {
 	if g.Raw {
 		args = append(args, "RW")
 	}
-	return redis.NewStringCmd(ctx, args...)
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseGetCommand(cmd redcon.Command) (*Get, error) {
@@ -289,7 +312,7 @@ func (g *GetEntry) Command(ctx context.Context) *redis.StringCmd {
 	if g.Replica {
 		args = append(args, "RC")
 	}
-	return redis.NewStringCmd(ctx, args...)
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseGetEntryCommand(cmd redcon.Command) (*GetEntry, error) {
@@ -333,7 +356,7 @@ func (d *Del) Command(ctx context.Context) *redis.IntCmd {
 	for _, key := range d.Keys {
 		args = append(args, key)
 	}
-	return redis.NewIntCmd(ctx, args...)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseDelCommand(cmd redcon.Command) (*Del, error) {
@@ -373,7 +396,7 @@ func (d *DelEntry) Command(ctx context.Context) *redis.IntCmd {
 	if d.Replica {
 		args = append(args, "RC")
 	}
-	return redis.NewIntCmd(ctx, args...)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseDelEntryCommand(cmd redcon.Command) (*DelEntry, error) {
@@ -398,6 +421,129 @@ func ParseDelEntryCommand(cmd redcon.Command) (*DelEntry, error) {
 	return d, nil
 }
 
+type CompareAndSwap struct {
+	DMap     string
+	Key      string
+	Expected int64
+	Value    []byte
+}
+
+func NewCompareAndSwap(dmap, key string, expected int64, value []byte) *CompareAndSwap {
+	return &CompareAndSwap{
+		DMap:     dmap,
+		Key:      key,
+		Expected: expected,
+		Value:    value,
+	}
+}
+
+func (c *CompareAndSwap) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.CompareAndSwap)
+	args = append(args, c.DMap)
+	args = append(args, c.Key)
+	args = append(args, c.Expected)
+	args = append(args, c.Value)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseCompareAndSwapCommand(cmd redcon.Command) (*CompareAndSwap, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	expected, err := strconv.ParseInt(util.BytesToString(cmd.Args[3]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCompareAndSwap(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Key
+		expected,
+		cmd.Args[4], // Value
+	), nil
+}
+
+type MGet struct {
+	DMap string
+	Keys []string
+}
+
+func NewMGet(dmap string, keys ...string) *MGet {
+	return &MGet{
+		DMap: dmap,
+		Keys: keys,
+	}
+}
+
+func (m *MGet) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, DMap.MGet)
+	args = append(args, m.DMap)
+	for _, key := range m.Keys {
+		args = append(args, key)
+	}
+	return redis.NewSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseMGetCommand(cmd redcon.Command) (*MGet, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	m := NewMGet(
+		util.BytesToString(cmd.Args[1]),
+	)
+	for _, key := range cmd.Args[2:] {
+		m.Keys = append(m.Keys, util.BytesToString(key))
+	}
+	return m, nil
+}
+
+type MPut struct {
+	DMap   string
+	Keys   []string
+	Values [][]byte
+}
+
+func NewMPut(dmap string) *MPut {
+	return &MPut{
+		DMap: dmap,
+	}
+}
+
+func (m *MPut) Add(key string, value []byte) *MPut {
+	m.Keys = append(m.Keys, key)
+	m.Values = append(m.Values, value)
+	return m
+}
+
+func (m *MPut) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, DMap.MPut)
+	args = append(args, m.DMap)
+	for i, key := range m.Keys {
+		args = append(args, key)
+		args = append(args, m.Values[i])
+	}
+	return redis.NewSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseMPutCommand(cmd redcon.Command) (*MPut, error) {
+	if len(cmd.Args) < 4 || len(cmd.Args)%2 != 0 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	m := NewMPut(
+		util.BytesToString(cmd.Args[1]),
+	)
+	for i := 2; i < len(cmd.Args); i += 2 {
+		m.Add(util.BytesToString(cmd.Args[i]), cmd.Args[i+1])
+	}
+	return m, nil
+}
+
 type PExpire struct {
 	DMap         string
 	Key          string
@@ -418,7 +564,7 @@ func (p *PExpire) Command(ctx context.Context) *redis.StatusCmd {
 	args = append(args, p.DMap)
 	args = append(args, p.Key)
 	args = append(args, p.Milliseconds.Milliseconds())
-	return redis.NewStatusCmd(ctx, args...)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParsePExpireCommand(cmd redcon.Command) (*PExpire, error) {
@@ -459,7 +605,7 @@ func (e *Expire) Command(ctx context.Context) *redis.StatusCmd {
 	args = append(args, e.DMap)
 	args = append(args, e.Key)
 	args = append(args, e.Seconds.Seconds())
-	return redis.NewStatusCmd(ctx, args...)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseExpireCommand(cmd redcon.Command) (*Expire, error) {
@@ -503,7 +649,7 @@ func (d *Destroy) Command(ctx context.Context) *redis.StatusCmd {
 	if d.Local {
 		args = append(args, "LC")
 	}
-	return redis.NewStatusCmd(ctx, args...)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseDestroyCommand(cmd redcon.Command) (*Destroy, error) {
@@ -527,13 +673,49 @@ func ParseDestroyCommand(cmd redcon.Command) (*Destroy, error) {
 	return d, nil
 }
 
+// Operators of the scan predicates.
+const (
+	ScanOpEqual          = "EQ"
+	ScanOpNotEqual       = "NE"
+	ScanOpLess           = "LT"
+	ScanOpLessOrEqual    = "LE"
+	ScanOpGreater        = "GT"
+	ScanOpGreaterOrEqual = "GE"
+)
+
+// Pseudo fields of the scan predicates. ScanFieldTTL is the remaining time to
+// live in milliseconds, and ScanFieldTimestamp is the time of the last write
+// in Unix milliseconds.
+const (
+	ScanFieldTTL       = "@ttl"
+	ScanFieldTimestamp = "@timestamp"
+)
+
+// ScanPredicate is a filter on the values of a scan. Field is a dotted path
+// in a JSON value or one of the pseudo fields.
+type ScanPredicate struct {
+	Field string
+	Op    string
+	Value string
+}
+
+func isScanOp(op string) bool {
+	switch op {
+	case ScanOpEqual, ScanOpNotEqual, ScanOpLess, ScanOpLessOrEqual, ScanOpGreater, ScanOpGreaterOrEqual:
+		return true
+	}
+	return false
+}
+
 type Scan struct {
-	PartID  uint64
-	DMap    string
-	Cursor  uint64
-	Count   int
-	Match   string
-	Replica bool
+	PartID     uint64
+	DMap       string
+	Cursor     uint64
+	Count      int
+	Match      string
+	Where      []ScanPredicate
+	WithValues bool
+	Replica    bool
 }
 
 func NewScan(partID uint64, dmap string, cursor uint64) *Scan {
@@ -554,6 +736,16 @@ func (s *Scan) SetCount(count int) *Scan {
 	return s
 }
 
+func (s *Scan) AddWhere(field, op, value string) *Scan {
+	s.Where = append(s.Where, ScanPredicate{Field: field, Op: op, Value: value})
+	return s
+}
+
+func (s *Scan) SetWithValues() *Scan {
+	s.WithValues = true
+	return s
+}
+
 func (s *Scan) SetReplica() *Scan {
 	s.Replica = true
 	return s
@@ -573,10 +765,16 @@ func (s *Scan) Command(ctx context.Context) *redis.ScanCmd {
 		args = append(args, "COUNT")
 		args = append(args, s.Count)
 	}
+	for _, p := range s.Where {
+		args = append(args, "WHERE", p.Field, p.Op, p.Value)
+	}
+	if s.WithValues {
+		args = append(args, "WITHVALUES")
+	}
 	if s.Replica {
 		args = append(args, "RC")
 	}
-	return redis.NewScanCmd(ctx, nil, args...)
+	return redis.NewScanCmd(ctx, nil, tracing.Inject(ctx, args)...)
 }
 
 const DefaultScanCount = 10
@@ -619,9 +817,24 @@ func ParseScanCommand(cmd redcon.Command) (*Scan, error) {
 			s.SetCount(count)
 			args = args[2:]
 			continue
+		case "WHERE":
+			if len(args) < 4 {
+				return nil, fmt.Errorf("%w: %s needs a field, an operator and a value", ErrInvalidArgument, arg)
+			}
+			op := strings.ToUpper(util.BytesToString(args[2]))
+			if !isScanOp(op) {
+				return nil, fmt.Errorf("%w: unknown operator %s", ErrInvalidArgument, op)
+			}
+			s.AddWhere(util.BytesToString(args[1]), op, util.BytesToString(args[3]))
+			args = args[4:]
+		case "WITHVALUES":
+			s.SetWithValues()
+			args = args[1:]
 		case "RC":
 			s.SetReplica()
 			args = args[1:]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArgument, arg)
 		}
 	}
 
@@ -652,7 +865,7 @@ func (i *Incr) Command(ctx context.Context) *redis.IntCmd {
 	args = append(args, i.DMap)
 	args = append(args, i.Key)
 	args = append(args, i.Delta)
-	return redis.NewIntCmd(ctx, args...)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseIncrCommand(cmd redcon.Command) (*Incr, error) {
@@ -734,7 +947,7 @@ func (g *GetPut) Command(ctx context.Context) *redis.StringCmd {
 	if g.Raw {
 		args = append(args, "RW")
 	}
-	return redis.NewStringCmd(ctx, args...)
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseGetPutCommand(cmd redcon.Command) (*GetPut, error) {
@@ -779,7 +992,7 @@ func (i *IncrByFloat) Command(ctx context.Context) *redis.FloatCmd {
 	args = append(args, i.DMap)
 	args = append(args, i.Key)
 	args = append(args, i.Delta)
-	return redis.NewFloatCmd(ctx, args...)
+	return redis.NewFloatCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseIncrByFloatCommand(cmd redcon.Command) (*IncrByFloat, error) {
@@ -843,7 +1056,7 @@ func (l *Lock) Command(ctx context.Context) *redis.StringCmd {
 		args = append(args, l.PX)
 	}
 
-	return redis.NewStringCmd(ctx, args...)
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseLockCommand(cmd redcon.Command) (*Lock, error) {
@@ -909,7 +1122,7 @@ func (u *Unlock) Command(ctx context.Context) *redis.StatusCmd {
 	args = append(args, u.DMap)
 	args = append(args, u.Key)
 	args = append(args, u.Token)
-	return redis.NewStatusCmd(ctx, args...)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseUnlockCommand(cmd redcon.Command) (*Unlock, error) {
@@ -947,7 +1160,7 @@ func (l *LockLease) Command(ctx context.Context) *redis.StatusCmd {
 	args = append(args, l.Key)
 	args = append(args, l.Token)
 	args = append(args, l.Timeout)
-	return redis.NewStatusCmd(ctx, args...)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseLockLeaseCommand(cmd redcon.Command) (*LockLease, error) {
@@ -991,7 +1204,7 @@ func (p *PLockLease) Command(ctx context.Context) *redis.StatusCmd {
 	args = append(args, p.Key)
 	args = append(args, p.Token)
 	args = append(args, p.Timeout)
-	return redis.NewStatusCmd(ctx, args...)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParsePLockLeaseCommand(cmd redcon.Command) (*PLockLease, error) {
@@ -128,6 +128,35 @@ func TestProtocol_ParsePutCommand_PXAT(t *testing.T) {
 	require.Equal(t, pxat, parsed.PXAT)
 }
 
+func TestProtocol_ParsePutCommand_IfVersion(t *testing.T) {
+	putCmd := NewPut("my-dmap", "my-key", []byte("my-value"))
+	version := time.Now().UnixNano()
+	putCmd.SetIfVersion(version)
+
+	cmd := stringToCommand(putCmd.Command(context.Background()).String())
+	parsed, err := ParsePutCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, []byte("my-value"), parsed.Value)
+	require.True(t, parsed.HasIfVersion)
+	require.Equal(t, version, parsed.IfVersion)
+}
+
+func TestProtocol_CompareAndSwap(t *testing.T) {
+	casCmd := NewCompareAndSwap("my-dmap", "my-key", 1234, []byte("my-value"))
+
+	cmd := stringToCommand(casCmd.Command(context.Background()).String())
+	parsed, err := ParseCompareAndSwapCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, int64(1234), parsed.Expected)
+	require.Equal(t, []byte("my-value"), parsed.Value)
+}
+
 func TestProtocol_ParseScanCommand(t *testing.T) {
 	scanCmd := NewScan(1, "my-dmap", 0)
 
@@ -219,6 +248,29 @@ func TestProtocol_ParseScanCommand_Match_Count_Replica(t *testing.T) {
 	require.True(t, scanCmd.Replica)
 }
 
+func TestProtocol_ParseScanCommand_Where_WithValues(t *testing.T) {
+	scanCmd := NewScan(1, "my-dmap", 0).
+		AddWhere("address.city", ScanOpEqual, "istanbul").
+		AddWhere(ScanFieldTTL, ScanOpGreater, "1000").
+		SetWithValues()
+
+	s := scanCmd.Command(context.Background()).String()
+	s = strings.TrimSuffix(s, ": []")
+	cmd := stringToCommand(s)
+	parsed, err := ParseScanCommand(cmd)
+	require.NoError(t, err)
+	require.Equal(t, []ScanPredicate{
+		{Field: "address.city", Op: ScanOpEqual, Value: "istanbul"},
+		{Field: ScanFieldTTL, Op: ScanOpGreater, Value: "1000"},
+	}, parsed.Where)
+	require.True(t, parsed.WithValues)
+	require.False(t, parsed.Replica)
+
+	cmd = stringToCommand("dm.scan 1 my-dmap 0 WHERE age BETWEEN 10")
+	_, err = ParseScanCommand(cmd)
+	require.ErrorIs(t, err, ErrInvalidArgument)
+}
+
 func TestProtocol_PutEntry(t *testing.T) {
 	putEntryCmd := NewPutEntry("my-dmap", "my-key", []byte("my-value"))
 
@@ -281,6 +333,37 @@ func TestProtocol_GetEntry_RC(t *testing.T) {
 	require.True(t, parsed.Replica)
 }
 
+func TestProtocol_MGet(t *testing.T) {
+	mgetCmd := NewMGet("my-dmap", "key1", "key2")
+
+	cmd := stringToCommand(mgetCmd.Command(context.Background()).String())
+	parsed, err := ParseMGetCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, []string{"key1", "key2"}, parsed.Keys)
+}
+
+func TestProtocol_MPut(t *testing.T) {
+	mputCmd := NewMPut("my-dmap").
+		Add("key1", []byte("value1")).
+		Add("key2", []byte("value2"))
+
+	cmd := stringToCommand(mputCmd.Command(context.Background()).String())
+	parsed, err := ParseMPutCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, []string{"key1", "key2"}, parsed.Keys)
+	require.Equal(t, [][]byte{[]byte("value1"), []byte("value2")}, parsed.Values)
+}
+
+func TestProtocol_MPut_Missing_Value(t *testing.T) {
+	cmd := stringToCommand("dm.mput my-dmap key1 value1 key2")
+	_, err := ParseMPutCommand(cmd)
+	require.Error(t, err)
+}
+
 func TestProtocol_Del(t *testing.T) {
 	delCmd := NewDel("my-dmap", "key1", "key2")
 
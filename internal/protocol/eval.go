@@ -0,0 +1,84 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+type Eval struct {
+	DMap   string
+	Script string
+	Keys   []string
+	Args   []string
+}
+
+func NewEval(dmap, script string, keys []string, args ...string) *Eval {
+	return &Eval{
+		DMap:   dmap,
+		Script: script,
+		Keys:   keys,
+		Args:   args,
+	}
+}
+
+func (e *Eval) Command(ctx context.Context) *redis.Cmd {
+	var args []interface{}
+	args = append(args, DMap.Eval)
+	args = append(args, e.DMap)
+	args = append(args, e.Script)
+	args = append(args, len(e.Keys))
+	for _, key := range e.Keys {
+		args = append(args, key)
+	}
+	for _, arg := range e.Args {
+		args = append(args, arg)
+	}
+	return redis.NewCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseEvalCommand(cmd redcon.Command) (*Eval, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	numKeys, err := strconv.Atoi(util.BytesToString(cmd.Args[3]))
+	if err != nil {
+		return nil, err
+	}
+	if numKeys < 0 || len(cmd.Args) < 4+numKeys {
+		return nil, fmt.Errorf("%w: number of keys is out of range", ErrInvalidArgument)
+	}
+
+	e := NewEval(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Script
+		nil,
+	)
+	for _, key := range cmd.Args[4 : 4+numKeys] {
+		e.Keys = append(e.Keys, util.BytesToString(key))
+	}
+	for _, arg := range cmd.Args[4+numKeys:] {
+		e.Args = append(e.Args, util.BytesToString(arg))
+	}
+	return e, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_Eval(t *testing.T) {
+	t.Run("Eval", func(t *testing.T) {
+		evalCmd := NewEval("my-dmap", "return(1)", []string{"key-1", "key-2"}, "arg-1")
+
+		cmd := stringToCommand(evalCmd.Command(context.Background()).String())
+		parsed, err := ParseEvalCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, "my-dmap", parsed.DMap)
+		require.Equal(t, "return(1)", parsed.Script)
+		require.Equal(t, []string{"key-1", "key-2"}, parsed.Keys)
+		require.Equal(t, []string{"arg-1"}, parsed.Args)
+	})
+
+	t.Run("Eval without keys", func(t *testing.T) {
+		evalCmd := NewEval("my-dmap", "return(1)", nil)
+
+		cmd := stringToCommand(evalCmd.Command(context.Background()).String())
+		parsed, err := ParseEvalCommand(cmd)
+		require.NoError(t, err)
+
+		require.Empty(t, parsed.Keys)
+		require.Empty(t, parsed.Args)
+	})
+
+	t.Run("Eval with invalid number of keys", func(t *testing.T) {
+		cmd := stringToCommand("dm.eval my-dmap return(1) 3 key-1")
+		_, err := ParseEvalCommand(cmd)
+		require.ErrorIs(t, err, ErrInvalidArgument)
+	})
+}
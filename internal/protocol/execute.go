@@ -0,0 +1,72 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+type Execute struct {
+	DMap      string
+	Key       string
+	Processor string
+	Args      []byte
+}
+
+func NewExecute(dmap, key, processor string) *Execute {
+	return &Execute{
+		DMap:      dmap,
+		Key:       key,
+		Processor: processor,
+	}
+}
+
+func (e *Execute) SetArgs(args []byte) *Execute {
+	e.Args = args
+	return e
+}
+
+func (e *Execute) Command(ctx context.Context) *redis.StringCmd {
+	var args []interface{}
+	args = append(args, DMap.Execute)
+	args = append(args, e.DMap)
+	args = append(args, e.Key)
+	args = append(args, e.Processor)
+	if e.Args != nil {
+		args = append(args, e.Args)
+	}
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseExecuteCommand(cmd redcon.Command) (*Execute, error) {
+	if len(cmd.Args) < 4 || len(cmd.Args) > 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	e := NewExecute(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Key
+		util.BytesToString(cmd.Args[3]), // Processor
+	)
+	if len(cmd.Args) == 5 {
+		e.SetArgs(cmd.Args[4])
+	}
+	return e, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_Execute(t *testing.T) {
+	executeCmd := NewExecute("my-dmap", "my-key", "my-processor").SetArgs([]byte("my-args"))
+
+	cmd := stringToCommand(executeCmd.Command(context.Background()).String())
+	parsed, err := ParseExecuteCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "my-processor", parsed.Processor)
+	require.Equal(t, []byte("my-args"), parsed.Args)
+}
+
+func TestProtocol_Execute_WithoutArgs(t *testing.T) {
+	executeCmd := NewExecute("my-dmap", "my-key", "my-processor")
+
+	cmd := stringToCommand(executeCmd.Command(context.Background()).String())
+	parsed, err := ParseExecuteCommand(cmd)
+	require.NoError(t, err)
+	require.Nil(t, parsed.Args)
+}
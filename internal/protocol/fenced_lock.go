@@ -0,0 +1,183 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+// parsePX parses the optional PX argument at index i.
+func parsePX(cmd redcon.Command, i int) (int64, error) {
+	if len(cmd.Args) <= i {
+		return 0, nil
+	}
+	arg := util.BytesToString(cmd.Args[i])
+	if strings.ToUpper(arg) != "PX" {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidArgument, arg)
+	}
+	if len(cmd.Args) == i+1 {
+		return 0, fmt.Errorf("%w: %s needs a numerical argument", ErrInvalidArgument, arg)
+	}
+	return strconv.ParseInt(util.BytesToString(cmd.Args[i+1]), 10, 64)
+}
+
+type FencedLock struct {
+	DMap     string
+	Key      string
+	Owner    string
+	Deadline float64
+	PX       int64
+}
+
+func NewFencedLock(dmap, key, owner string, deadline float64) *FencedLock {
+	return &FencedLock{
+		DMap:     dmap,
+		Key:      key,
+		Owner:    owner,
+		Deadline: deadline,
+	}
+}
+
+func (f *FencedLock) SetPX(px int64) *FencedLock {
+	f.PX = px
+	return f
+}
+
+func (f *FencedLock) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.FencedLock)
+	args = append(args, f.DMap)
+	args = append(args, f.Key)
+	args = append(args, f.Owner)
+	args = append(args, f.Deadline)
+	if f.PX != 0 {
+		args = append(args, "PX")
+		args = append(args, f.PX)
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseFencedLockCommand(cmd redcon.Command) (*FencedLock, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	deadline, err := strconv.ParseFloat(util.BytesToString(cmd.Args[4]), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	f := NewFencedLock(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Key
+		util.BytesToString(cmd.Args[3]), // Owner
+		deadline,                        // Deadline
+	)
+
+	// PX is optional.
+	px, err := parsePX(cmd, 5)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.SetPX(px), nil
+}
+
+type FencedUnlock struct {
+	DMap  string
+	Key   string
+	Owner string
+}
+
+func NewFencedUnlock(dmap, key, owner string) *FencedUnlock {
+	return &FencedUnlock{
+		DMap:  dmap,
+		Key:   key,
+		Owner: owner,
+	}
+}
+
+func (f *FencedUnlock) Command(ctx context.Context) *redis.StatusCmd {
+	var args []interface{}
+	args = append(args, DMap.FencedUnlock)
+	args = append(args, f.DMap)
+	args = append(args, f.Key)
+	args = append(args, f.Owner)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseFencedUnlockCommand(cmd redcon.Command) (*FencedUnlock, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewFencedUnlock(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Key
+		util.BytesToString(cmd.Args[3]), // Owner
+	), nil
+}
+
+type FencedLockLease struct {
+	DMap  string
+	Key   string
+	Owner string
+	PX    int64
+}
+
+func NewFencedLockLease(dmap, key, owner string, px int64) *FencedLockLease {
+	return &FencedLockLease{
+		DMap:  dmap,
+		Key:   key,
+		Owner: owner,
+		PX:    px,
+	}
+}
+
+func (f *FencedLockLease) Command(ctx context.Context) *redis.StatusCmd {
+	var args []interface{}
+	args = append(args, DMap.FencedLockLease)
+	args = append(args, f.DMap)
+	args = append(args, f.Key)
+	args = append(args, f.Owner)
+	args = append(args, f.PX)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseFencedLockLeaseCommand(cmd redcon.Command) (*FencedLockLease, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	px, err := strconv.ParseInt(util.BytesToString(cmd.Args[4]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFencedLockLease(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Key
+		util.BytesToString(cmd.Args[3]), // Owner
+		px,                              // PX
+	), nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_FencedLock(t *testing.T) {
+	t.Run("FencedLock", func(t *testing.T) {
+		lockCmd := NewFencedLock("my-dmap", "my-key", "my-owner", 7)
+
+		cmd := stringToCommand(lockCmd.Command(context.Background()).String())
+		parsed, err := ParseFencedLockCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, "my-dmap", parsed.DMap)
+		require.Equal(t, "my-key", parsed.Key)
+		require.Equal(t, "my-owner", parsed.Owner)
+		require.Equal(t, float64(7), parsed.Deadline)
+		require.Equal(t, int64(0), parsed.PX)
+	})
+
+	t.Run("FencedLock with PX", func(t *testing.T) {
+		lockCmd := NewFencedLock("my-dmap", "my-key", "my-owner", 0.5).SetPX(1500)
+
+		cmd := stringToCommand(lockCmd.Command(context.Background()).String())
+		parsed, err := ParseFencedLockCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, 0.5, parsed.Deadline)
+		require.Equal(t, int64(1500), parsed.PX)
+	})
+}
+
+func TestProtocol_FencedUnlock(t *testing.T) {
+	unlockCmd := NewFencedUnlock("my-dmap", "my-key", "my-owner")
+
+	cmd := stringToCommand(unlockCmd.Command(context.Background()).String())
+	parsed, err := ParseFencedUnlockCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "my-owner", parsed.Owner)
+}
+
+func TestProtocol_FencedLockLease(t *testing.T) {
+	leaseCmd := NewFencedLockLease("my-dmap", "my-key", "my-owner", 2000)
+
+	cmd := stringToCommand(leaseCmd.Command(context.Background()).String())
+	parsed, err := ParseFencedLockLeaseCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "my-owner", parsed.Owner)
+	require.Equal(t, int64(2000), parsed.PX)
+}
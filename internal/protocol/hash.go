@@ -0,0 +1,223 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+type HSet struct {
+	DMap   string
+	Key    string
+	Fields []string
+	Values [][]byte
+}
+
+func NewHSet(dmap, key string) *HSet {
+	return &HSet{
+		DMap: dmap,
+		Key:  key,
+	}
+}
+
+func (h *HSet) Add(field string, value []byte) *HSet {
+	h.Fields = append(h.Fields, field)
+	h.Values = append(h.Values, value)
+	return h
+}
+
+func (h *HSet) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.HSet)
+	args = append(args, h.DMap)
+	args = append(args, h.Key)
+	for i, field := range h.Fields {
+		args = append(args, field)
+		args = append(args, h.Values[i])
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseHSetCommand(cmd redcon.Command) (*HSet, error) {
+	if len(cmd.Args) < 5 || len(cmd.Args)%2 != 1 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	h := NewHSet(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+	)
+	for i := 3; i < len(cmd.Args); i += 2 {
+		h.Add(util.BytesToString(cmd.Args[i]), cmd.Args[i+1])
+	}
+	return h, nil
+}
+
+type HGet struct {
+	DMap  string
+	Key   string
+	Field string
+}
+
+func NewHGet(dmap, key, field string) *HGet {
+	return &HGet{
+		DMap:  dmap,
+		Key:   key,
+		Field: field,
+	}
+}
+
+func (h *HGet) Command(ctx context.Context) *redis.StringCmd {
+	var args []interface{}
+	args = append(args, DMap.HGet)
+	args = append(args, h.DMap)
+	args = append(args, h.Key)
+	args = append(args, h.Field)
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseHGetCommand(cmd redcon.Command) (*HGet, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewHGet(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		util.BytesToString(cmd.Args[3]),
+	), nil
+}
+
+type HDel struct {
+	DMap   string
+	Key    string
+	Fields []string
+}
+
+func NewHDel(dmap, key string, fields ...string) *HDel {
+	return &HDel{
+		DMap:   dmap,
+		Key:    key,
+		Fields: fields,
+	}
+}
+
+func (h *HDel) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.HDel)
+	args = append(args, h.DMap)
+	args = append(args, h.Key)
+	for _, field := range h.Fields {
+		args = append(args, field)
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseHDelCommand(cmd redcon.Command) (*HDel, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	var fields []string
+	for _, field := range cmd.Args[3:] {
+		fields = append(fields, util.BytesToString(field))
+	}
+	return NewHDel(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		fields...,
+	), nil
+}
+
+type HGetAll struct {
+	DMap string
+	Key  string
+}
+
+func NewHGetAll(dmap, key string) *HGetAll {
+	return &HGetAll{
+		DMap: dmap,
+		Key:  key,
+	}
+}
+
+func (h *HGetAll) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, DMap.HGetAll)
+	args = append(args, h.DMap)
+	args = append(args, h.Key)
+	return redis.NewSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseHGetAllCommand(cmd redcon.Command) (*HGetAll, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewHGetAll(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+	), nil
+}
+
+type HIncrBy struct {
+	DMap  string
+	Key   string
+	Field string
+	Delta int
+}
+
+func NewHIncrBy(dmap, key, field string, delta int) *HIncrBy {
+	return &HIncrBy{
+		DMap:  dmap,
+		Key:   key,
+		Field: field,
+		Delta: delta,
+	}
+}
+
+func (h *HIncrBy) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.HIncrBy)
+	args = append(args, h.DMap)
+	args = append(args, h.Key)
+	args = append(args, h.Field)
+	args = append(args, h.Delta)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseHIncrByCommand(cmd redcon.Command) (*HIncrBy, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	delta, err := strconv.Atoi(util.BytesToString(cmd.Args[4]))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHIncrBy(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		util.BytesToString(cmd.Args[3]),
+		delta,
+	), nil
+}
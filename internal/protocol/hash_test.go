@@ -0,0 +1,91 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_HSet(t *testing.T) {
+	hsetCmd := NewHSet("my-dmap", "my-key").
+		Add("field1", []byte("value1")).
+		Add("field2", []byte("value2"))
+
+	cmd := stringToCommand(hsetCmd.Command(context.Background()).String())
+	parsed, err := ParseHSetCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, []string{"field1", "field2"}, parsed.Fields)
+	require.Equal(t, [][]byte{[]byte("value1"), []byte("value2")}, parsed.Values)
+}
+
+func TestProtocol_HSet_Missing_Value(t *testing.T) {
+	cmd := stringToCommand("dm.hset my-dmap my-key field1 value1 field2")
+	_, err := ParseHSetCommand(cmd)
+	require.Error(t, err)
+}
+
+func TestProtocol_HGet(t *testing.T) {
+	hgetCmd := NewHGet("my-dmap", "my-key", "field1")
+
+	cmd := stringToCommand(hgetCmd.Command(context.Background()).String())
+	parsed, err := ParseHGetCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "field1", parsed.Field)
+}
+
+func TestProtocol_HDel(t *testing.T) {
+	hdelCmd := NewHDel("my-dmap", "my-key", "field1", "field2")
+
+	cmd := stringToCommand(hdelCmd.Command(context.Background()).String())
+	parsed, err := ParseHDelCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, []string{"field1", "field2"}, parsed.Fields)
+}
+
+func TestProtocol_HGetAll(t *testing.T) {
+	hgetallCmd := NewHGetAll("my-dmap", "my-key")
+
+	cmd := stringToCommand(hgetallCmd.Command(context.Background()).String())
+	parsed, err := ParseHGetAllCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+}
+
+func TestProtocol_HIncrBy(t *testing.T) {
+	hincrbyCmd := NewHIncrBy("my-dmap", "my-key", "field1", -7)
+
+	cmd := stringToCommand(hincrbyCmd.Command(context.Background()).String())
+	parsed, err := ParseHIncrByCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "field1", parsed.Field)
+	require.Equal(t, -7, parsed.Delta)
+}
@@ -0,0 +1,282 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+type LPush struct {
+	DMap   string
+	Key    string
+	Values [][]byte
+}
+
+func NewLPush(dmap, key string, values ...[]byte) *LPush {
+	return &LPush{
+		DMap:   dmap,
+		Key:    key,
+		Values: values,
+	}
+}
+
+func (l *LPush) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.LPush)
+	args = append(args, l.DMap)
+	args = append(args, l.Key)
+	for _, value := range l.Values {
+		args = append(args, value)
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseLPushCommand(cmd redcon.Command) (*LPush, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewLPush(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		cmd.Args[3:]...,
+	), nil
+}
+
+type RPush struct {
+	DMap   string
+	Key    string
+	Values [][]byte
+}
+
+func NewRPush(dmap, key string, values ...[]byte) *RPush {
+	return &RPush{
+		DMap:   dmap,
+		Key:    key,
+		Values: values,
+	}
+}
+
+func (r *RPush) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.RPush)
+	args = append(args, r.DMap)
+	args = append(args, r.Key)
+	for _, value := range r.Values {
+		args = append(args, value)
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseRPushCommand(cmd redcon.Command) (*RPush, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewRPush(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		cmd.Args[3:]...,
+	), nil
+}
+
+type LPop struct {
+	DMap string
+	Key  string
+}
+
+func NewLPop(dmap, key string) *LPop {
+	return &LPop{
+		DMap: dmap,
+		Key:  key,
+	}
+}
+
+func (l *LPop) Command(ctx context.Context) *redis.StringCmd {
+	var args []interface{}
+	args = append(args, DMap.LPop)
+	args = append(args, l.DMap)
+	args = append(args, l.Key)
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseLPopCommand(cmd redcon.Command) (*LPop, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewLPop(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+	), nil
+}
+
+type RPop struct {
+	DMap string
+	Key  string
+}
+
+func NewRPop(dmap, key string) *RPop {
+	return &RPop{
+		DMap: dmap,
+		Key:  key,
+	}
+}
+
+func (r *RPop) Command(ctx context.Context) *redis.StringCmd {
+	var args []interface{}
+	args = append(args, DMap.RPop)
+	args = append(args, r.DMap)
+	args = append(args, r.Key)
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseRPopCommand(cmd redcon.Command) (*RPop, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewRPop(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+	), nil
+}
+
+type LRange struct {
+	DMap  string
+	Key   string
+	Start int
+	Stop  int
+}
+
+func NewLRange(dmap, key string, start, stop int) *LRange {
+	return &LRange{
+		DMap:  dmap,
+		Key:   key,
+		Start: start,
+		Stop:  stop,
+	}
+}
+
+func (l *LRange) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, DMap.LRange)
+	args = append(args, l.DMap)
+	args = append(args, l.Key)
+	args = append(args, l.Start)
+	args = append(args, l.Stop)
+	return redis.NewSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseLRangeCommand(cmd redcon.Command) (*LRange, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	start, err := strconv.Atoi(util.BytesToString(cmd.Args[3]))
+	if err != nil {
+		return nil, err
+	}
+	stop, err := strconv.Atoi(util.BytesToString(cmd.Args[4]))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLRange(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		start,
+		stop,
+	), nil
+}
+
+type LLen struct {
+	DMap string
+	Key  string
+}
+
+func NewLLen(dmap, key string) *LLen {
+	return &LLen{
+		DMap: dmap,
+		Key:  key,
+	}
+}
+
+func (l *LLen) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.LLen)
+	args = append(args, l.DMap)
+	args = append(args, l.Key)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseLLenCommand(cmd redcon.Command) (*LLen, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewLLen(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+	), nil
+}
+
+// BLPop is the blocking version of LPop. Timeout is in seconds, zero means
+// blocking indefinitely.
+type BLPop struct {
+	DMap    string
+	Key     string
+	Timeout float64
+}
+
+func NewBLPop(dmap, key string, timeout float64) *BLPop {
+	return &BLPop{
+		DMap:    dmap,
+		Key:     key,
+		Timeout: timeout,
+	}
+}
+
+func (b *BLPop) Command(ctx context.Context) *redis.StringCmd {
+	var args []interface{}
+	args = append(args, DMap.BLPop)
+	args = append(args, b.DMap)
+	args = append(args, b.Key)
+	args = append(args, b.Timeout)
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseBLPopCommand(cmd redcon.Command) (*BLPop, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	timeout, err := strconv.ParseFloat(util.BytesToString(cmd.Args[3]), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBLPop(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		timeout,
+	), nil
+}
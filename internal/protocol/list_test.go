@@ -0,0 +1,112 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_LPush(t *testing.T) {
+	lpushCmd := NewLPush("my-dmap", "my-key", []byte("value1"), []byte("value2"))
+
+	cmd := stringToCommand(lpushCmd.Command(context.Background()).String())
+	parsed, err := ParseLPushCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, [][]byte{[]byte("value1"), []byte("value2")}, parsed.Values)
+}
+
+func TestProtocol_RPush(t *testing.T) {
+	rpushCmd := NewRPush("my-dmap", "my-key", []byte("value1"))
+
+	cmd := stringToCommand(rpushCmd.Command(context.Background()).String())
+	parsed, err := ParseRPushCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, [][]byte{[]byte("value1")}, parsed.Values)
+}
+
+func TestProtocol_Push_Missing_Value(t *testing.T) {
+	_, err := ParseLPushCommand(stringToCommand("dm.lpush my-dmap my-key"))
+	require.Error(t, err)
+
+	_, err = ParseRPushCommand(stringToCommand("dm.rpush my-dmap my-key"))
+	require.Error(t, err)
+}
+
+func TestProtocol_LPop(t *testing.T) {
+	lpopCmd := NewLPop("my-dmap", "my-key")
+
+	cmd := stringToCommand(lpopCmd.Command(context.Background()).String())
+	parsed, err := ParseLPopCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+}
+
+func TestProtocol_RPop(t *testing.T) {
+	rpopCmd := NewRPop("my-dmap", "my-key")
+
+	cmd := stringToCommand(rpopCmd.Command(context.Background()).String())
+	parsed, err := ParseRPopCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+}
+
+func TestProtocol_LRange(t *testing.T) {
+	lrangeCmd := NewLRange("my-dmap", "my-key", 1, -1)
+
+	cmd := stringToCommand(lrangeCmd.Command(context.Background()).String())
+	parsed, err := ParseLRangeCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, 1, parsed.Start)
+	require.Equal(t, -1, parsed.Stop)
+}
+
+func TestProtocol_LLen(t *testing.T) {
+	llenCmd := NewLLen("my-dmap", "my-key")
+
+	cmd := stringToCommand(llenCmd.Command(context.Background()).String())
+	parsed, err := ParseLLenCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+}
+
+func TestProtocol_BLPop(t *testing.T) {
+	blpopCmd := NewBLPop("my-dmap", "my-key", 0.5)
+
+	cmd := stringToCommand(blpopCmd.Command(context.Background()).String())
+	parsed, err := ParseBLPopCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, 0.5, parsed.Timeout)
+}
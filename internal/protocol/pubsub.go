@@ -16,7 +16,11 @@ package protocol
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/buraksezer/olric/internal/tracing"
 	"github.com/buraksezer/olric/internal/util"
 	"github.com/redis/go-redis/v9"
 	"github.com/tidwall/redcon"
@@ -39,7 +43,7 @@ func (p *Publish) Command(ctx context.Context) *redis.IntCmd {
 	args = append(args, PubSub.Publish)
 	args = append(args, p.Channel)
 	args = append(args, p.Message)
-	return redis.NewIntCmd(ctx, args...)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParsePublishCommand(cmd redcon.Command) (*Publish, error) {
@@ -70,7 +74,7 @@ func (p *PublishInternal) Command(ctx context.Context) *redis.IntCmd {
 	args = append(args, PubSub.PublishInternal)
 	args = append(args, p.Channel)
 	args = append(args, p.Message)
-	return redis.NewIntCmd(ctx, args...)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParsePublishInternalCommand(cmd redcon.Command) (*PublishInternal, error) {
@@ -84,6 +88,37 @@ func ParsePublishInternalCommand(cmd redcon.Command) (*PublishInternal, error) {
 	), nil
 }
 
+type PublishRouted struct {
+	Channel string
+	Message string
+}
+
+func NewPublishRouted(channel, message string) *PublishRouted {
+	return &PublishRouted{
+		Channel: channel,
+		Message: message,
+	}
+}
+
+func (p *PublishRouted) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, PubSub.PublishRouted)
+	args = append(args, p.Channel)
+	args = append(args, p.Message)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParsePublishRoutedCommand(cmd redcon.Command) (*PublishRouted, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewPublishRouted(
+		util.BytesToString(cmd.Args[1]), // Channel
+		util.BytesToString(cmd.Args[2]), // Message
+	), nil
+}
+
 type Subscribe struct {
 	Channels []string
 }
@@ -239,3 +274,309 @@ func ParsePubSubNumsubCommand(cmd redcon.Command) (*PubSubNumsub, error) {
 	}
 	return NewPubSubNumsub(channels...), nil
 }
+
+// PubSubShardChannels lists the shard channels that have subscribers on the
+// member.
+type PubSubShardChannels struct {
+	Pattern string
+}
+
+func NewPubSubShardChannels() *PubSubShardChannels {
+	return &PubSubShardChannels{}
+}
+
+func (ps *PubSubShardChannels) SetPattern(pattern string) *PubSubShardChannels {
+	ps.Pattern = pattern
+	return ps
+}
+
+func (ps *PubSubShardChannels) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, "pubsub", "shardchannels")
+	if ps.Pattern != "" {
+		args = append(args, ps.Pattern)
+	}
+	return redis.NewSliceCmd(ctx, args...)
+}
+
+func ParsePubSubShardChannelsCommand(cmd redcon.Command) (*PubSubShardChannels, error) {
+	if len(cmd.Args) < 2 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	ps := NewPubSubShardChannels()
+	if len(cmd.Args) >= 3 {
+		ps.SetPattern(util.BytesToString(cmd.Args[2]))
+	}
+	return ps, nil
+}
+
+// PubSubShardNumsub returns the number of the subscribers of the shard
+// channels on the member.
+type PubSubShardNumsub struct {
+	Channels []string
+}
+
+func NewPubSubShardNumsub(channels ...string) *PubSubShardNumsub {
+	return &PubSubShardNumsub{
+		Channels: channels,
+	}
+}
+
+func (ps *PubSubShardNumsub) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, "pubsub", "shardnumsub")
+	for _, channel := range ps.Channels {
+		args = append(args, channel)
+	}
+	return redis.NewSliceCmd(ctx, args...)
+}
+
+func ParsePubSubShardNumsubCommand(cmd redcon.Command) (*PubSubShardNumsub, error) {
+	if len(cmd.Args) < 2 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	var channels []string
+	for _, arg := range cmd.Args[2:] {
+		channels = append(channels, util.BytesToString(arg))
+	}
+	return NewPubSubShardNumsub(channels...), nil
+}
+
+type PubSubNumsubInternal struct {
+	Channels []string
+}
+
+func NewPubSubNumsubInternal(channels ...string) *PubSubNumsubInternal {
+	return &PubSubNumsubInternal{
+		Channels: channels,
+	}
+}
+
+func (ps *PubSubNumsubInternal) Command(ctx context.Context) *redis.IntSliceCmd {
+	var args []interface{}
+	args = append(args, PubSub.PubSubNumsubInternal)
+	for _, channel := range ps.Channels {
+		args = append(args, channel)
+	}
+	return redis.NewIntSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParsePubSubNumsubInternalCommand(cmd redcon.Command) (*PubSubNumsubInternal, error) {
+	if len(cmd.Args) < 2 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	var channels []string
+	for _, arg := range cmd.Args[1:] {
+		channels = append(channels, util.BytesToString(arg))
+	}
+	return NewPubSubNumsubInternal(channels...), nil
+}
+
+// PubSubInterest reports the channels and the patterns that have subscribers
+// on Member. It replaces the previous report of the member unless Add or
+// Remove is set.
+type PubSubInterest struct {
+	Member   string
+	Channels []string
+	Patterns []string
+	Add      bool
+	Remove   bool
+}
+
+func NewPubSubInterest(member string) *PubSubInterest {
+	return &PubSubInterest{
+		Member: member,
+	}
+}
+
+func (ps *PubSubInterest) SetChannels(channels ...string) *PubSubInterest {
+	ps.Channels = channels
+	return ps
+}
+
+func (ps *PubSubInterest) SetPatterns(patterns ...string) *PubSubInterest {
+	ps.Patterns = patterns
+	return ps
+}
+
+// SetAdd adds the channels and the patterns to the previous report.
+func (ps *PubSubInterest) SetAdd() *PubSubInterest {
+	ps.Add = true
+	return ps
+}
+
+// SetRemove removes the channels and the patterns from the previous report.
+func (ps *PubSubInterest) SetRemove() *PubSubInterest {
+	ps.Remove = true
+	return ps
+}
+
+func (ps *PubSubInterest) Command(ctx context.Context) *redis.StatusCmd {
+	var args []interface{}
+	args = append(args, PubSub.PubSubInterest)
+	args = append(args, ps.Member)
+	switch {
+	case ps.Add:
+		args = append(args, "ADD")
+	case ps.Remove:
+		args = append(args, "REMOVE")
+	}
+	args = append(args, len(ps.Channels))
+	for _, channel := range ps.Channels {
+		args = append(args, channel)
+	}
+	for _, pattern := range ps.Patterns {
+		args = append(args, pattern)
+	}
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParsePubSubInterestCommand(cmd redcon.Command) (*PubSubInterest, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	ps := NewPubSubInterest(util.BytesToString(cmd.Args[1]))
+	args := cmd.Args[2:]
+	switch strings.ToUpper(util.BytesToString(args[0])) {
+	case "ADD":
+		ps.SetAdd()
+		args = args[1:]
+	case "REMOVE":
+		ps.SetRemove()
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	numChannels, err := strconv.Atoi(util.BytesToString(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	args = args[1:]
+	if numChannels < 0 || numChannels > len(args) {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	for _, arg := range args[:numChannels] {
+		ps.Channels = append(ps.Channels, util.BytesToString(arg))
+	}
+	for _, arg := range args[numChannels:] {
+		ps.Patterns = append(ps.Patterns, util.BytesToString(arg))
+	}
+	return ps, nil
+}
+
+type SPublish struct {
+	Channel string
+	Message string
+}
+
+func NewSPublish(channel, message string) *SPublish {
+	return &SPublish{
+		Channel: channel,
+		Message: message,
+	}
+}
+
+func (p *SPublish) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, PubSub.SPublish)
+	args = append(args, p.Channel)
+	args = append(args, p.Message)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseSPublishCommand(cmd redcon.Command) (*SPublish, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewSPublish(
+		util.BytesToString(cmd.Args[1]), // Channel
+		util.BytesToString(cmd.Args[2]), // Message
+	), nil
+}
+
+type SPublishInternal struct {
+	Channel string
+	Message string
+	Replica bool
+}
+
+func NewSPublishInternal(channel, message string) *SPublishInternal {
+	return &SPublishInternal{
+		Channel: channel,
+		Message: message,
+	}
+}
+
+func (p *SPublishInternal) SetReplica() *SPublishInternal {
+	p.Replica = true
+	return p
+}
+
+func (p *SPublishInternal) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, PubSub.SPublishInternal)
+	args = append(args, p.Channel)
+	args = append(args, p.Message)
+	if p.Replica {
+		args = append(args, "REPLICA")
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseSPublishInternalCommand(cmd redcon.Command) (*SPublishInternal, error) {
+	if len(cmd.Args) < 3 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	p := NewSPublishInternal(
+		util.BytesToString(cmd.Args[1]), // Channel
+		util.BytesToString(cmd.Args[2]), // Message
+	)
+	if len(cmd.Args) == 4 {
+		arg := util.BytesToString(cmd.Args[3])
+		if strings.ToUpper(arg) != "REPLICA" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArgument, arg)
+		}
+		p.SetReplica()
+	}
+	return p, nil
+}
+
+type SSubscribe struct {
+	Channels []string
+}
+
+func NewSSubscribe(channels ...string) *SSubscribe {
+	return &SSubscribe{
+		Channels: channels,
+	}
+}
+
+func (s *SSubscribe) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, PubSub.SSubscribe)
+	for _, channel := range s.Channels {
+		args = append(args, channel)
+	}
+	return redis.NewSliceCmd(ctx, args...)
+}
+
+func ParseSSubscribeCommand(cmd redcon.Command) (*SSubscribe, error) {
+	if len(cmd.Args) < 2 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	var channels []string
+	for _, arg := range cmd.Args[1:] {
+		channels = append(channels, util.BytesToString(arg))
+	}
+	return NewSSubscribe(channels...), nil
+}
@@ -88,3 +88,150 @@ func TestProtocol_PubSubNumsub(t *testing.T) {
 	channels := []string{"channel-1", "channel-2", "channel-3"}
 	require.Equal(t, channels, parsed.Channels)
 }
+
+func TestProtocol_ParsePublishRoutedCommand(t *testing.T) {
+	publishRoutedCmd := NewPublishRouted("my-pubsub", "my-message")
+
+	cmd := stringToCommand(publishRoutedCmd.Command(context.Background()).String())
+	parsed, err := ParsePublishRoutedCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-pubsub", parsed.Channel)
+	require.Equal(t, "my-message", parsed.Message)
+}
+
+func TestProtocol_ParsePubSubNumsubInternalCommand(t *testing.T) {
+	numsubCmd := NewPubSubNumsubInternal("channel-1", "channel-2")
+
+	cmd := stringToCommand(numsubCmd.Command(context.Background()).String())
+	parsed, err := ParsePubSubNumsubInternalCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"channel-1", "channel-2"}, parsed.Channels)
+}
+
+func TestProtocol_ParsePubSubInterestCommand(t *testing.T) {
+	t.Run("Channels and patterns", func(t *testing.T) {
+		interestCmd := NewPubSubInterest("127.0.0.1:3320").
+			SetChannels("channel-1", "channel-2").
+			SetPatterns("ch?nnel-*")
+
+		cmd := stringToCommand(interestCmd.Command(context.Background()).String())
+		parsed, err := ParsePubSubInterestCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, "127.0.0.1:3320", parsed.Member)
+		require.Equal(t, []string{"channel-1", "channel-2"}, parsed.Channels)
+		require.Equal(t, []string{"ch?nnel-*"}, parsed.Patterns)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		interestCmd := NewPubSubInterest("127.0.0.1:3320")
+
+		cmd := stringToCommand(interestCmd.Command(context.Background()).String())
+		parsed, err := ParsePubSubInterestCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, "127.0.0.1:3320", parsed.Member)
+		require.Empty(t, parsed.Channels)
+		require.Empty(t, parsed.Patterns)
+	})
+
+	t.Run("ADD", func(t *testing.T) {
+		interestCmd := NewPubSubInterest("127.0.0.1:3320").
+			SetChannels("channel-1").
+			SetAdd()
+
+		cmd := stringToCommand(interestCmd.Command(context.Background()).String())
+		parsed, err := ParsePubSubInterestCommand(cmd)
+		require.NoError(t, err)
+
+		require.True(t, parsed.Add)
+		require.False(t, parsed.Remove)
+		require.Equal(t, []string{"channel-1"}, parsed.Channels)
+		require.Empty(t, parsed.Patterns)
+	})
+
+	t.Run("REMOVE", func(t *testing.T) {
+		interestCmd := NewPubSubInterest("127.0.0.1:3320").
+			SetPatterns("ch?nnel-*").
+			SetRemove()
+
+		cmd := stringToCommand(interestCmd.Command(context.Background()).String())
+		parsed, err := ParsePubSubInterestCommand(cmd)
+		require.NoError(t, err)
+
+		require.False(t, parsed.Add)
+		require.True(t, parsed.Remove)
+		require.Empty(t, parsed.Channels)
+		require.Equal(t, []string{"ch?nnel-*"}, parsed.Patterns)
+	})
+}
+
+func TestProtocol_ParseSPublishCommand(t *testing.T) {
+	spublishCmd := NewSPublish("my-channel", "my-message")
+
+	cmd := stringToCommand(spublishCmd.Command(context.Background()).String())
+	parsed, err := ParseSPublishCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-channel", parsed.Channel)
+	require.Equal(t, "my-message", parsed.Message)
+}
+
+func TestProtocol_ParseSPublishInternalCommand(t *testing.T) {
+	t.Run("SPublishInternal", func(t *testing.T) {
+		spublishCmd := NewSPublishInternal("my-channel", "my-message")
+
+		cmd := stringToCommand(spublishCmd.Command(context.Background()).String())
+		parsed, err := ParseSPublishInternalCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, "my-channel", parsed.Channel)
+		require.Equal(t, "my-message", parsed.Message)
+		require.False(t, parsed.Replica)
+	})
+
+	t.Run("SPublishInternal with REPLICA", func(t *testing.T) {
+		spublishCmd := NewSPublishInternal("my-channel", "my-message").SetReplica()
+
+		cmd := stringToCommand(spublishCmd.Command(context.Background()).String())
+		parsed, err := ParseSPublishInternalCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, "my-channel", parsed.Channel)
+		require.Equal(t, "my-message", parsed.Message)
+		require.True(t, parsed.Replica)
+	})
+}
+
+func TestProtocol_ParseSSubscribeCommand(t *testing.T) {
+	ssubscribeCmd := NewSSubscribe("channel-1", "channel-2")
+
+	cmd := stringToCommand(ssubscribeCmd.Command(context.Background()).String())
+	parsed, err := ParseSSubscribeCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"channel-1", "channel-2"}, parsed.Channels)
+}
+
+func TestProtocol_PubSubShardChannels(t *testing.T) {
+	pubsubShardChannelsCmd := NewPubSubShardChannels()
+	pubsubShardChannelsCmd.SetPattern("ch?nnel-*")
+
+	cmd := stringToCommand(pubsubShardChannelsCmd.Command(context.Background()).String())
+	parsed, err := ParsePubSubShardChannelsCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "ch?nnel-*", parsed.Pattern)
+}
+
+func TestProtocol_PubSubShardNumsub(t *testing.T) {
+	pubsubShardNumsubCmd := NewPubSubShardNumsub("channel-1", "channel-2")
+
+	cmd := stringToCommand(pubsubShardNumsubCmd.Command(context.Background()).String())
+	parsed, err := ParsePubSubShardNumsubCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"channel-1", "channel-2"}, parsed.Channels)
+}
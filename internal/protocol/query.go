@@ -0,0 +1,119 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+type Query struct {
+	DMap     string
+	Index    string
+	Value    string
+	Count    int
+	After    string
+	internal bool
+}
+
+func NewQuery(dmap, index, value string) *Query {
+	return &Query{
+		DMap:  dmap,
+		Index: index,
+		Value: value,
+	}
+}
+
+// NewQueryInternal creates a query that only runs on the partitions of the
+// receiver.
+func NewQueryInternal(dmap, index, value string) *Query {
+	q := NewQuery(dmap, index, value)
+	q.internal = true
+	return q
+}
+
+func (q *Query) SetCount(count int) *Query {
+	q.Count = count
+	return q
+}
+
+func (q *Query) SetAfter(after string) *Query {
+	q.After = after
+	return q
+}
+
+func (q *Query) Command(ctx context.Context) *redis.StringSliceCmd {
+	var args []interface{}
+	if q.internal {
+		args = append(args, DMap.QueryInternal)
+	} else {
+		args = append(args, DMap.Query)
+	}
+	args = append(args, q.DMap)
+	args = append(args, q.Index)
+	args = append(args, q.Value)
+	if q.Count != 0 {
+		args = append(args, "COUNT")
+		args = append(args, q.Count)
+	}
+	if q.After != "" {
+		args = append(args, "AFTER")
+		args = append(args, q.After)
+	}
+	return redis.NewStringSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+// ParseQueryCommand parses both of the Query and QueryInternal commands.
+func ParseQueryCommand(cmd redcon.Command) (*Query, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	q := NewQuery(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Index
+		util.BytesToString(cmd.Args[3]), // Value
+	)
+	q.internal = strings.ToLower(util.BytesToString(cmd.Args[0])) == DMap.QueryInternal
+
+	args := cmd.Args[4:]
+	for len(args) > 0 {
+		arg := strings.ToUpper(util.BytesToString(args[0]))
+		if len(args) == 1 {
+			return nil, fmt.Errorf("%w: %s needs an argument", ErrInvalidArgument, arg)
+		}
+		switch arg {
+		case "COUNT":
+			count, err := strconv.Atoi(util.BytesToString(args[1]))
+			if err != nil {
+				return nil, err
+			}
+			q.SetCount(count)
+		case "AFTER":
+			q.SetAfter(util.BytesToString(args[1]))
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArgument, arg)
+		}
+		args = args[2:]
+	}
+	return q, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_Query(t *testing.T) {
+	t.Run("Query", func(t *testing.T) {
+		queryCmd := NewQuery("my-dmap", "by-city", "Istanbul")
+
+		cmd := stringToCommand(queryCmd.Command(context.Background()).String())
+		parsed, err := ParseQueryCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, "my-dmap", parsed.DMap)
+		require.Equal(t, "by-city", parsed.Index)
+		require.Equal(t, "Istanbul", parsed.Value)
+		require.Equal(t, 0, parsed.Count)
+		require.Equal(t, "", parsed.After)
+		require.False(t, parsed.internal)
+	})
+
+	t.Run("QueryInternal with COUNT and AFTER", func(t *testing.T) {
+		queryCmd := NewQueryInternal("my-dmap", "by-city", "Istanbul").
+			SetCount(10).
+			SetAfter("my-key")
+
+		cmd := stringToCommand(queryCmd.Command(context.Background()).String())
+		parsed, err := ParseQueryCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, 10, parsed.Count)
+		require.Equal(t, "my-key", parsed.After)
+		require.True(t, parsed.internal)
+	})
+
+	t.Run("Query with invalid argument", func(t *testing.T) {
+		cmd := stringToCommand("dm.query my-dmap by-city Istanbul COUNT")
+		_, err := ParseQueryCommand(cmd)
+		require.ErrorIs(t, err, ErrInvalidArgument)
+	})
+}
@@ -0,0 +1,247 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+const (
+	RWLockModeRead  = "READ"
+	RWLockModeWrite = "WRITE"
+)
+
+type SemAcquire struct {
+	DMap     string
+	Key      string
+	Owner    string
+	Permits  int
+	Deadline float64
+	PX       int64
+}
+
+func NewSemAcquire(dmap, key, owner string, permits int, deadline float64) *SemAcquire {
+	return &SemAcquire{
+		DMap:     dmap,
+		Key:      key,
+		Owner:    owner,
+		Permits:  permits,
+		Deadline: deadline,
+	}
+}
+
+func (s *SemAcquire) SetPX(px int64) *SemAcquire {
+	s.PX = px
+	return s
+}
+
+func (s *SemAcquire) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.SemAcquire)
+	args = append(args, s.DMap)
+	args = append(args, s.Key)
+	args = append(args, s.Owner)
+	args = append(args, s.Permits)
+	args = append(args, s.Deadline)
+	if s.PX != 0 {
+		args = append(args, "PX")
+		args = append(args, s.PX)
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseSemAcquireCommand(cmd redcon.Command) (*SemAcquire, error) {
+	if len(cmd.Args) < 6 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	permits, err := strconv.Atoi(util.BytesToString(cmd.Args[4]))
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, err := strconv.ParseFloat(util.BytesToString(cmd.Args[5]), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	px, err := parsePX(cmd, 6)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSemAcquire(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Key
+		util.BytesToString(cmd.Args[3]), // Owner
+		permits,                         // Permits
+		deadline,                        // Deadline
+	).SetPX(px), nil
+}
+
+type RWLock struct {
+	DMap     string
+	Key      string
+	Owner    string
+	Mode     string
+	Deadline float64
+	PX       int64
+}
+
+func NewRWLock(dmap, key, owner, mode string, deadline float64) *RWLock {
+	return &RWLock{
+		DMap:     dmap,
+		Key:      key,
+		Owner:    owner,
+		Mode:     mode,
+		Deadline: deadline,
+	}
+}
+
+func (r *RWLock) SetPX(px int64) *RWLock {
+	r.PX = px
+	return r
+}
+
+func (r *RWLock) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.RWLock)
+	args = append(args, r.DMap)
+	args = append(args, r.Key)
+	args = append(args, r.Owner)
+	args = append(args, r.Mode)
+	args = append(args, r.Deadline)
+	if r.PX != 0 {
+		args = append(args, "PX")
+		args = append(args, r.PX)
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseRWLockCommand(cmd redcon.Command) (*RWLock, error) {
+	if len(cmd.Args) < 6 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	mode := strings.ToUpper(util.BytesToString(cmd.Args[4]))
+	if mode != RWLockModeRead && mode != RWLockModeWrite {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArgument, util.BytesToString(cmd.Args[4]))
+	}
+
+	deadline, err := strconv.ParseFloat(util.BytesToString(cmd.Args[5]), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	px, err := parsePX(cmd, 6)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRWLock(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Key
+		util.BytesToString(cmd.Args[3]), // Owner
+		mode,                            // Mode
+		deadline,                        // Deadline
+	).SetPX(px), nil
+}
+
+type SharedRelease struct {
+	DMap  string
+	Key   string
+	Owner string
+}
+
+func NewSharedRelease(dmap, key, owner string) *SharedRelease {
+	return &SharedRelease{
+		DMap:  dmap,
+		Key:   key,
+		Owner: owner,
+	}
+}
+
+func (s *SharedRelease) Command(ctx context.Context) *redis.StatusCmd {
+	var args []interface{}
+	args = append(args, DMap.SharedRelease)
+	args = append(args, s.DMap)
+	args = append(args, s.Key)
+	args = append(args, s.Owner)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseSharedReleaseCommand(cmd redcon.Command) (*SharedRelease, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewSharedRelease(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Key
+		util.BytesToString(cmd.Args[3]), // Owner
+	), nil
+}
+
+type SharedLease struct {
+	DMap  string
+	Key   string
+	Owner string
+	PX    int64
+}
+
+func NewSharedLease(dmap, key, owner string, px int64) *SharedLease {
+	return &SharedLease{
+		DMap:  dmap,
+		Key:   key,
+		Owner: owner,
+		PX:    px,
+	}
+}
+
+func (s *SharedLease) Command(ctx context.Context) *redis.StatusCmd {
+	var args []interface{}
+	args = append(args, DMap.SharedLease)
+	args = append(args, s.DMap)
+	args = append(args, s.Key)
+	args = append(args, s.Owner)
+	args = append(args, s.PX)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseSharedLeaseCommand(cmd redcon.Command) (*SharedLease, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	px, err := strconv.ParseInt(util.BytesToString(cmd.Args[4]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSharedLease(
+		util.BytesToString(cmd.Args[1]), // DMap
+		util.BytesToString(cmd.Args[2]), // Key
+		util.BytesToString(cmd.Args[3]), // Owner
+		px,                              // PX
+	), nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_SemAcquire(t *testing.T) {
+	acquireCmd := NewSemAcquire("my-dmap", "my-key", "my-owner", 3, 0.5).SetPX(1500)
+
+	cmd := stringToCommand(acquireCmd.Command(context.Background()).String())
+	parsed, err := ParseSemAcquireCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "my-owner", parsed.Owner)
+	require.Equal(t, 3, parsed.Permits)
+	require.Equal(t, 0.5, parsed.Deadline)
+	require.Equal(t, int64(1500), parsed.PX)
+}
+
+func TestProtocol_RWLock(t *testing.T) {
+	t.Run("RWLock", func(t *testing.T) {
+		lockCmd := NewRWLock("my-dmap", "my-key", "my-owner", RWLockModeRead, 7)
+
+		cmd := stringToCommand(lockCmd.Command(context.Background()).String())
+		parsed, err := ParseRWLockCommand(cmd)
+		require.NoError(t, err)
+
+		require.Equal(t, "my-dmap", parsed.DMap)
+		require.Equal(t, "my-key", parsed.Key)
+		require.Equal(t, "my-owner", parsed.Owner)
+		require.Equal(t, RWLockModeRead, parsed.Mode)
+		require.Equal(t, float64(7), parsed.Deadline)
+		require.Equal(t, int64(0), parsed.PX)
+	})
+
+	t.Run("RWLock with invalid mode", func(t *testing.T) {
+		lockCmd := NewRWLock("my-dmap", "my-key", "my-owner", "foobar", 7)
+
+		cmd := stringToCommand(lockCmd.Command(context.Background()).String())
+		_, err := ParseRWLockCommand(cmd)
+		require.ErrorIs(t, err, ErrInvalidArgument)
+	})
+}
+
+func TestProtocol_SharedRelease(t *testing.T) {
+	releaseCmd := NewSharedRelease("my-dmap", "my-key", "my-owner")
+
+	cmd := stringToCommand(releaseCmd.Command(context.Background()).String())
+	parsed, err := ParseSharedReleaseCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "my-owner", parsed.Owner)
+}
+
+func TestProtocol_SharedLease(t *testing.T) {
+	leaseCmd := NewSharedLease("my-dmap", "my-key", "my-owner", 2000)
+
+	cmd := stringToCommand(leaseCmd.Command(context.Background()).String())
+	parsed, err := ParseSharedLeaseCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-owner", parsed.Owner)
+	require.Equal(t, int64(2000), parsed.PX)
+}
@@ -0,0 +1,297 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+func parseScore(raw []byte) (float64, error) {
+	// ParseFloat accepts -inf and +inf.
+	return strconv.ParseFloat(util.BytesToString(raw), 64)
+}
+
+type ZAdd struct {
+	DMap    string
+	Key     string
+	Scores  []float64
+	Members []string
+}
+
+func NewZAdd(dmap, key string) *ZAdd {
+	return &ZAdd{
+		DMap: dmap,
+		Key:  key,
+	}
+}
+
+func (z *ZAdd) Add(score float64, member string) *ZAdd {
+	z.Scores = append(z.Scores, score)
+	z.Members = append(z.Members, member)
+	return z
+}
+
+func (z *ZAdd) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.ZAdd)
+	args = append(args, z.DMap)
+	args = append(args, z.Key)
+	for i, member := range z.Members {
+		args = append(args, z.Scores[i])
+		args = append(args, member)
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseZAddCommand(cmd redcon.Command) (*ZAdd, error) {
+	if len(cmd.Args) < 5 || len(cmd.Args)%2 != 1 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	z := NewZAdd(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+	)
+	for i := 3; i < len(cmd.Args); i += 2 {
+		score, err := parseScore(cmd.Args[i])
+		if err != nil {
+			return nil, err
+		}
+		z.Add(score, util.BytesToString(cmd.Args[i+1]))
+	}
+	return z, nil
+}
+
+type ZRange struct {
+	DMap  string
+	Key   string
+	Start int
+	Stop  int
+}
+
+func NewZRange(dmap, key string, start, stop int) *ZRange {
+	return &ZRange{
+		DMap:  dmap,
+		Key:   key,
+		Start: start,
+		Stop:  stop,
+	}
+}
+
+func (z *ZRange) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, DMap.ZRange)
+	args = append(args, z.DMap)
+	args = append(args, z.Key)
+	args = append(args, z.Start)
+	args = append(args, z.Stop)
+	return redis.NewSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseZRangeCommand(cmd redcon.Command) (*ZRange, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	start, err := strconv.Atoi(util.BytesToString(cmd.Args[3]))
+	if err != nil {
+		return nil, err
+	}
+	stop, err := strconv.Atoi(util.BytesToString(cmd.Args[4]))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewZRange(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		start,
+		stop,
+	), nil
+}
+
+type ZRangeByScore struct {
+	DMap string
+	Key  string
+	Min  float64
+	Max  float64
+}
+
+func NewZRangeByScore(dmap, key string, min, max float64) *ZRangeByScore {
+	return &ZRangeByScore{
+		DMap: dmap,
+		Key:  key,
+		Min:  min,
+		Max:  max,
+	}
+}
+
+func (z *ZRangeByScore) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, DMap.ZRangeByScore)
+	args = append(args, z.DMap)
+	args = append(args, z.Key)
+	args = append(args, z.Min)
+	args = append(args, z.Max)
+	return redis.NewSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseZRangeByScoreCommand(cmd redcon.Command) (*ZRangeByScore, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	min, err := parseScore(cmd.Args[3])
+	if err != nil {
+		return nil, err
+	}
+	max, err := parseScore(cmd.Args[4])
+	if err != nil {
+		return nil, err
+	}
+
+	return NewZRangeByScore(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		min,
+		max,
+	), nil
+}
+
+type ZRem struct {
+	DMap    string
+	Key     string
+	Members []string
+}
+
+func NewZRem(dmap, key string, members ...string) *ZRem {
+	return &ZRem{
+		DMap:    dmap,
+		Key:     key,
+		Members: members,
+	}
+}
+
+func (z *ZRem) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.ZRem)
+	args = append(args, z.DMap)
+	args = append(args, z.Key)
+	for _, member := range z.Members {
+		args = append(args, member)
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseZRemCommand(cmd redcon.Command) (*ZRem, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	var members []string
+	for _, member := range cmd.Args[3:] {
+		members = append(members, util.BytesToString(member))
+	}
+	return NewZRem(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		members...,
+	), nil
+}
+
+type ZRank struct {
+	DMap   string
+	Key    string
+	Member string
+}
+
+func NewZRank(dmap, key, member string) *ZRank {
+	return &ZRank{
+		DMap:   dmap,
+		Key:    key,
+		Member: member,
+	}
+}
+
+func (z *ZRank) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.ZRank)
+	args = append(args, z.DMap)
+	args = append(args, z.Key)
+	args = append(args, z.Member)
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseZRankCommand(cmd redcon.Command) (*ZRank, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewZRank(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		util.BytesToString(cmd.Args[3]),
+	), nil
+}
+
+type ZIncrBy struct {
+	DMap   string
+	Key    string
+	Delta  float64
+	Member string
+}
+
+func NewZIncrBy(dmap, key string, delta float64, member string) *ZIncrBy {
+	return &ZIncrBy{
+		DMap:   dmap,
+		Key:    key,
+		Delta:  delta,
+		Member: member,
+	}
+}
+
+func (z *ZIncrBy) Command(ctx context.Context) *redis.FloatCmd {
+	var args []interface{}
+	args = append(args, DMap.ZIncrBy)
+	args = append(args, z.DMap)
+	args = append(args, z.Key)
+	args = append(args, z.Delta)
+	args = append(args, z.Member)
+	return redis.NewFloatCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseZIncrByCommand(cmd redcon.Command) (*ZIncrBy, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	delta, err := parseScore(cmd.Args[3])
+	if err != nil {
+		return nil, err
+	}
+
+	return NewZIncrBy(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		delta,
+		util.BytesToString(cmd.Args[4]),
+	), nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_ZAdd(t *testing.T) {
+	zaddCmd := NewZAdd("my-dmap", "my-key").
+		Add(1.5, "member1").
+		Add(-2, "member2")
+
+	cmd := stringToCommand(zaddCmd.Command(context.Background()).String())
+	parsed, err := ParseZAddCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, []float64{1.5, -2}, parsed.Scores)
+	require.Equal(t, []string{"member1", "member2"}, parsed.Members)
+}
+
+func TestProtocol_ZAdd_Invalid_Score(t *testing.T) {
+	cmd := stringToCommand("dm.zadd my-dmap my-key foobar member1")
+	_, err := ParseZAddCommand(cmd)
+	require.Error(t, err)
+}
+
+func TestProtocol_ZRange(t *testing.T) {
+	zrangeCmd := NewZRange("my-dmap", "my-key", 0, -1)
+
+	cmd := stringToCommand(zrangeCmd.Command(context.Background()).String())
+	parsed, err := ParseZRangeCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, 0, parsed.Start)
+	require.Equal(t, -1, parsed.Stop)
+}
+
+func TestProtocol_ZRangeByScore(t *testing.T) {
+	zrangeCmd := NewZRangeByScore("my-dmap", "my-key", math.Inf(-1), 10.5)
+
+	cmd := stringToCommand(zrangeCmd.Command(context.Background()).String())
+	parsed, err := ParseZRangeByScoreCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.True(t, math.IsInf(parsed.Min, -1))
+	require.Equal(t, 10.5, parsed.Max)
+}
+
+func TestProtocol_ZRem(t *testing.T) {
+	zremCmd := NewZRem("my-dmap", "my-key", "member1", "member2")
+
+	cmd := stringToCommand(zremCmd.Command(context.Background()).String())
+	parsed, err := ParseZRemCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, []string{"member1", "member2"}, parsed.Members)
+}
+
+func TestProtocol_ZRank(t *testing.T) {
+	zrankCmd := NewZRank("my-dmap", "my-key", "member1")
+
+	cmd := stringToCommand(zrankCmd.Command(context.Background()).String())
+	parsed, err := ParseZRankCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "member1", parsed.Member)
+}
+
+func TestProtocol_ZIncrBy(t *testing.T) {
+	zincrbyCmd := NewZIncrBy("my-dmap", "my-key", 2.5, "member1")
+
+	cmd := stringToCommand(zincrbyCmd.Command(context.Background()).String())
+	parsed, err := ParseZIncrByCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, 2.5, parsed.Delta)
+	require.Equal(t, "member1", parsed.Member)
+}
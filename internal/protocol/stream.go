@@ -0,0 +1,299 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/redcon"
+)
+
+// parseCount parses the optional COUNT argument of the stream commands.
+func parseCount(args [][]byte) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+	if len(args) != 2 || strings.ToUpper(util.BytesToString(args[0])) != "COUNT" {
+		return 0, errors.New("syntax error")
+	}
+	return strconv.Atoi(util.BytesToString(args[1]))
+}
+
+type XAdd struct {
+	DMap   string
+	Key    string
+	MaxLen int
+	Fields []string
+	Values [][]byte
+}
+
+func NewXAdd(dmap, key string) *XAdd {
+	return &XAdd{
+		DMap: dmap,
+		Key:  key,
+	}
+}
+
+func (x *XAdd) SetMaxLen(maxLen int) *XAdd {
+	x.MaxLen = maxLen
+	return x
+}
+
+func (x *XAdd) Add(field string, value []byte) *XAdd {
+	x.Fields = append(x.Fields, field)
+	x.Values = append(x.Values, value)
+	return x
+}
+
+func (x *XAdd) Command(ctx context.Context) *redis.StringCmd {
+	var args []interface{}
+	args = append(args, DMap.XAdd)
+	args = append(args, x.DMap)
+	args = append(args, x.Key)
+	if x.MaxLen != 0 {
+		args = append(args, "MAXLEN")
+		args = append(args, x.MaxLen)
+	}
+	for i, field := range x.Fields {
+		args = append(args, field)
+		args = append(args, x.Values[i])
+	}
+	return redis.NewStringCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseXAddCommand(cmd redcon.Command) (*XAdd, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	x := NewXAdd(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+	)
+
+	args := cmd.Args[3:]
+	if strings.ToUpper(util.BytesToString(args[0])) == "MAXLEN" {
+		if len(args) < 2 {
+			return nil, errWrongNumber(cmd.Args)
+		}
+		maxLen, err := strconv.Atoi(util.BytesToString(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		x.SetMaxLen(maxLen)
+		args = args[2:]
+	}
+	if len(args) == 0 || len(args)%2 != 0 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+	for i := 0; i < len(args); i += 2 {
+		x.Add(util.BytesToString(args[i]), args[i+1])
+	}
+	return x, nil
+}
+
+type XRead struct {
+	DMap  string
+	Key   string
+	ID    string
+	Count int
+}
+
+func NewXRead(dmap, key, id string) *XRead {
+	return &XRead{
+		DMap: dmap,
+		Key:  key,
+		ID:   id,
+	}
+}
+
+func (x *XRead) SetCount(count int) *XRead {
+	x.Count = count
+	return x
+}
+
+func (x *XRead) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, DMap.XRead)
+	args = append(args, x.DMap)
+	args = append(args, x.Key)
+	args = append(args, x.ID)
+	if x.Count != 0 {
+		args = append(args, "COUNT")
+		args = append(args, x.Count)
+	}
+	return redis.NewSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseXReadCommand(cmd redcon.Command) (*XRead, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	count, err := parseCount(cmd.Args[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	return NewXRead(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		util.BytesToString(cmd.Args[3]),
+	).SetCount(count), nil
+}
+
+type XReadGroup struct {
+	DMap     string
+	Key      string
+	Group    string
+	Consumer string
+	ID       string
+	Count    int
+}
+
+func NewXReadGroup(dmap, key, group, consumer, id string) *XReadGroup {
+	return &XReadGroup{
+		DMap:     dmap,
+		Key:      key,
+		Group:    group,
+		Consumer: consumer,
+		ID:       id,
+	}
+}
+
+func (x *XReadGroup) SetCount(count int) *XReadGroup {
+	x.Count = count
+	return x
+}
+
+func (x *XReadGroup) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, DMap.XReadGroup)
+	args = append(args, x.DMap)
+	args = append(args, x.Key)
+	args = append(args, x.Group)
+	args = append(args, x.Consumer)
+	args = append(args, x.ID)
+	if x.Count != 0 {
+		args = append(args, "COUNT")
+		args = append(args, x.Count)
+	}
+	return redis.NewSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseXReadGroupCommand(cmd redcon.Command) (*XReadGroup, error) {
+	if len(cmd.Args) < 6 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	count, err := parseCount(cmd.Args[6:])
+	if err != nil {
+		return nil, err
+	}
+
+	return NewXReadGroup(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		util.BytesToString(cmd.Args[3]),
+		util.BytesToString(cmd.Args[4]),
+		util.BytesToString(cmd.Args[5]),
+	).SetCount(count), nil
+}
+
+type XAck struct {
+	DMap  string
+	Key   string
+	Group string
+	IDs   []string
+}
+
+func NewXAck(dmap, key, group string, ids ...string) *XAck {
+	return &XAck{
+		DMap:  dmap,
+		Key:   key,
+		Group: group,
+		IDs:   ids,
+	}
+}
+
+func (x *XAck) Command(ctx context.Context) *redis.IntCmd {
+	var args []interface{}
+	args = append(args, DMap.XAck)
+	args = append(args, x.DMap)
+	args = append(args, x.Key)
+	args = append(args, x.Group)
+	for _, id := range x.IDs {
+		args = append(args, id)
+	}
+	return redis.NewIntCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseXAckCommand(cmd redcon.Command) (*XAck, error) {
+	if len(cmd.Args) < 5 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	var ids []string
+	for _, id := range cmd.Args[4:] {
+		ids = append(ids, util.BytesToString(id))
+	}
+	return NewXAck(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		util.BytesToString(cmd.Args[3]),
+		ids...,
+	), nil
+}
+
+type XPending struct {
+	DMap  string
+	Key   string
+	Group string
+}
+
+func NewXPending(dmap, key, group string) *XPending {
+	return &XPending{
+		DMap:  dmap,
+		Key:   key,
+		Group: group,
+	}
+}
+
+func (x *XPending) Command(ctx context.Context) *redis.SliceCmd {
+	var args []interface{}
+	args = append(args, DMap.XPending)
+	args = append(args, x.DMap)
+	args = append(args, x.Key)
+	args = append(args, x.Group)
+	return redis.NewSliceCmd(ctx, tracing.Inject(ctx, args)...)
+}
+
+func ParseXPendingCommand(cmd redcon.Command) (*XPending, error) {
+	if len(cmd.Args) < 4 {
+		return nil, errWrongNumber(cmd.Args)
+	}
+
+	return NewXPending(
+		util.BytesToString(cmd.Args[1]),
+		util.BytesToString(cmd.Args[2]),
+		util.BytesToString(cmd.Args[3]),
+	), nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_XAdd(t *testing.T) {
+	xaddCmd := NewXAdd("my-dmap", "my-key").
+		Add("field1", []byte("value1")).
+		Add("field2", []byte("value2"))
+
+	cmd := stringToCommand(xaddCmd.Command(context.Background()).String())
+	parsed, err := ParseXAddCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, 0, parsed.MaxLen)
+	require.Equal(t, []string{"field1", "field2"}, parsed.Fields)
+	require.Equal(t, [][]byte{[]byte("value1"), []byte("value2")}, parsed.Values)
+}
+
+func TestProtocol_XAdd_MaxLen(t *testing.T) {
+	xaddCmd := NewXAdd("my-dmap", "my-key").
+		SetMaxLen(100).
+		Add("field1", []byte("value1"))
+
+	cmd := stringToCommand(xaddCmd.Command(context.Background()).String())
+	parsed, err := ParseXAddCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, 100, parsed.MaxLen)
+	require.Equal(t, []string{"field1"}, parsed.Fields)
+}
+
+func TestProtocol_XAdd_Missing_Value(t *testing.T) {
+	cmd := stringToCommand("dm.xadd my-dmap my-key MAXLEN 10 field1")
+	_, err := ParseXAddCommand(cmd)
+	require.Error(t, err)
+}
+
+func TestProtocol_XRead(t *testing.T) {
+	xreadCmd := NewXRead("my-dmap", "my-key", "1-0").SetCount(10)
+
+	cmd := stringToCommand(xreadCmd.Command(context.Background()).String())
+	parsed, err := ParseXReadCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "1-0", parsed.ID)
+	require.Equal(t, 10, parsed.Count)
+}
+
+func TestProtocol_XRead_Syntax_Error(t *testing.T) {
+	cmd := stringToCommand("dm.xread my-dmap my-key 0 LIMIT 10")
+	_, err := ParseXReadCommand(cmd)
+	require.Error(t, err)
+}
+
+func TestProtocol_XReadGroup(t *testing.T) {
+	xreadgroupCmd := NewXReadGroup("my-dmap", "my-key", "my-group", "my-consumer", ">")
+
+	cmd := stringToCommand(xreadgroupCmd.Command(context.Background()).String())
+	parsed, err := ParseXReadGroupCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "my-group", parsed.Group)
+	require.Equal(t, "my-consumer", parsed.Consumer)
+	require.Equal(t, ">", parsed.ID)
+	require.Equal(t, 0, parsed.Count)
+}
+
+func TestProtocol_XAck(t *testing.T) {
+	xackCmd := NewXAck("my-dmap", "my-key", "my-group", "1-0", "1-1")
+
+	cmd := stringToCommand(xackCmd.Command(context.Background()).String())
+	parsed, err := ParseXAckCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "my-group", parsed.Group)
+	require.Equal(t, []string{"1-0", "1-1"}, parsed.IDs)
+}
+
+func TestProtocol_XPending(t *testing.T) {
+	xpendingCmd := NewXPending("my-dmap", "my-key", "my-group")
+
+	cmd := stringToCommand(xpendingCmd.Command(context.Background()).String())
+	parsed, err := ParseXPendingCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-dmap", parsed.DMap)
+	require.Equal(t, "my-key", parsed.Key)
+	require.Equal(t, "my-group", parsed.Group)
+}
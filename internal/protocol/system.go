@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/buraksezer/olric/internal/tracing"
 	"github.com/buraksezer/olric/internal/util"
 	"github.com/redis/go-redis/v9"
 	"github.com/tidwall/redcon"
@@ -58,6 +59,45 @@ func ParsePingCommand(cmd redcon.Command) (*Ping, error) {
 	return p, nil
 }
 
+type Auth struct {
+	Username string
+	Password string
+}
+
+func NewAuth(password string) *Auth {
+	return &Auth{
+		Password: password,
+	}
+}
+
+func (a *Auth) SetUsername(username string) *Auth {
+	a.Username = username
+	return a
+}
+
+func (a *Auth) Command(ctx context.Context) *redis.StatusCmd {
+	var args []interface{}
+	args = append(args, Generic.Auth)
+	if a.Username != "" {
+		args = append(args, a.Username)
+	}
+	args = append(args, a.Password)
+	return redis.NewStatusCmd(ctx, args...)
+}
+
+func ParseAuthCommand(cmd redcon.Command) (*Auth, error) {
+	switch len(cmd.Args) {
+	case 2:
+		return NewAuth(util.BytesToString(cmd.Args[1])), nil
+	case 3:
+		a := NewAuth(util.BytesToString(cmd.Args[2]))
+		a.SetUsername(util.BytesToString(cmd.Args[1]))
+		return a, nil
+	default:
+		return nil, errWrongNumber(cmd.Args)
+	}
+}
+
 type MoveFragment struct {
 	Payload []byte
 }
@@ -72,7 +112,7 @@ func (m *MoveFragment) Command(ctx context.Context) *redis.StatusCmd {
 	var args []interface{}
 	args = append(args, Internal.MoveFragment)
 	args = append(args, m.Payload)
-	return redis.NewStatusCmd(ctx, args...)
+	return redis.NewStatusCmd(ctx, tracing.Inject(ctx, args)...)
 }
 
 func ParseMoveFragmentCommand(cmd redcon.Command) (*MoveFragment, error) {
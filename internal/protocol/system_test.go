@@ -41,6 +41,28 @@ func TestProtocol_Ping_Message(t *testing.T) {
 	require.Equal(t, "message", parsed.Message)
 }
 
+func TestProtocol_Auth(t *testing.T) {
+	auth := NewAuth("secret")
+
+	cmd := stringToCommand(auth.Command(context.Background()).String())
+	parsed, err := ParseAuthCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "", parsed.Username)
+	require.Equal(t, "secret", parsed.Password)
+}
+
+func TestProtocol_Auth_Username(t *testing.T) {
+	auth := NewAuth("secret").SetUsername("olric")
+
+	cmd := stringToCommand(auth.Command(context.Background()).String())
+	parsed, err := ParseAuthCommand(cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "olric", parsed.Username)
+	require.Equal(t, "secret", parsed.Password)
+}
+
 func TestProtocol_MoveFragment(t *testing.T) {
 	moveFragmentCmd := NewMoveFragment([]byte("payload"))
 
@@ -40,19 +40,57 @@ func (s *Service) publishCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 		return
 	}
 
+	if s.isPartitioned() {
+		if err := s.rt.CheckBootstrap(); err != nil {
+			protocol.WriteError(conn, err)
+			return
+		}
+
+		owner := s.channelOwner(publishCmd.Channel)
+		if !owner.CompareByID(s.rt.This()) {
+			// Let the channel owner deliver the message to the interested members.
+			pr := protocol.NewPublishRouted(publishCmd.Channel, publishCmd.Message).Command(s.ctx)
+			rc := s.client.Get(owner.String())
+			err = rc.Process(s.ctx, pr)
+			if err != nil {
+				protocol.WriteError(conn, err)
+				return
+			}
+			count, err := pr.Result()
+			if err != nil {
+				protocol.WriteError(conn, err)
+				return
+			}
+			conn.WriteInt64(count)
+			return
+		}
+
+		s.publishToMembers(conn, publishCmd.Channel, publishCmd.Message, s.interest.targets(publishCmd.Channel))
+		return
+	}
+
+	var members []string
+	for _, member := range s.rt.Discovery().GetMembers() {
+		members = append(members, member.String())
+	}
+	s.publishToMembers(conn, publishCmd.Channel, publishCmd.Message, members)
+}
+
+// publishToMembers publishes the message on the given members and writes the
+// total number of subscribers that received it.
+func (s *Service) publishToMembers(conn redcon.Conn, channel, message string, members []string) {
 	var total int
-	members := s.rt.Discovery().GetMembers()
 	for _, member := range members {
-		if member.CompareByID(s.rt.This()) {
-			count := s.pubsub.Publish(publishCmd.Channel, publishCmd.Message)
+		if member == s.rt.This().String() {
+			count := s.pubsub.Publish(channel, message)
 			total += count
 			PublishedTotal.Increase(int64(count))
 			continue
 		}
 
-		pi := protocol.NewPublishInternal(publishCmd.Channel, publishCmd.Message).Command(s.ctx)
-		rc := s.client.Get(member.String())
-		err = rc.Process(s.ctx, pi)
+		pi := protocol.NewPublishInternal(channel, message).Command(s.ctx)
+		rc := s.client.Get(member)
+		err := rc.Process(s.ctx, pi)
 		if err != nil {
 			protocol.WriteError(conn, err)
 			return
@@ -69,6 +107,19 @@ func (s *Service) publishCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 	conn.WriteInt(total)
 }
 
+func (s *Service) publishRoutedCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	publishRoutedCmd, err := protocol.ParsePublishRoutedCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	// Don't forward the message again, even if the routing table has changed
+	// in the meantime.
+	targets := s.interest.targets(publishRoutedCmd.Channel)
+	s.publishToMembers(conn, publishRoutedCmd.Channel, publishRoutedCmd.Message, targets)
+}
+
 func (s *Service) publishInternalCommandHandler(conn redcon.Conn, cmd redcon.Command) {
 	publishInternalCmd, err := protocol.ParsePublishInternalCommand(cmd)
 	if err != nil {
@@ -134,9 +185,60 @@ func (s *Service) pubsubNumsubCommandHandler(conn redcon.Conn, cmd redcon.Comman
 		return
 	}
 
+	// Subscribers may be connected to any member of the cluster.
+	counts := make([]int64, len(pubsubNumsubCmd.Channels))
+	for _, member := range s.rt.Discovery().GetMembers() {
+		if member.CompareByID(s.rt.This()) {
+			for i, channel := range pubsubNumsubCmd.Channels {
+				counts[i] += int64(s.pubsub.Numsub(channel))
+			}
+			continue
+		}
+
+		ni := protocol.NewPubSubNumsubInternal(pubsubNumsubCmd.Channels...).Command(s.ctx)
+		rc := s.client.Get(member.String())
+		err = rc.Process(s.ctx, ni)
+		if err != nil {
+			protocol.WriteError(conn, err)
+			return
+		}
+		result, err := ni.Result()
+		if err != nil {
+			protocol.WriteError(conn, err)
+			return
+		}
+		for i := 0; i < len(result) && i < len(counts); i++ {
+			counts[i] += result[i]
+		}
+	}
+
 	conn.WriteArray(len(pubsubNumsubCmd.Channels) * 2)
-	for _, channel := range pubsubNumsubCmd.Channels {
+	for i, channel := range pubsubNumsubCmd.Channels {
 		conn.WriteBulkString(channel)
+		conn.WriteInt64(counts[i])
+	}
+}
+
+func (s *Service) pubsubNumsubInternalCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	numsubInternalCmd, err := protocol.ParsePubSubNumsubInternalCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	conn.WriteArray(len(numsubInternalCmd.Channels))
+	for _, channel := range numsubInternalCmd.Channels {
 		conn.WriteInt(s.pubsub.Numsub(channel))
 	}
 }
+
+func (s *Service) pubsubInterestCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	interestCmd, err := protocol.ParsePubSubInterestCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	s.applyInterest(interestCmd)
+	conn.WriteString(protocol.StatusOK)
+}
@@ -20,7 +20,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/environment"
 	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
 )
@@ -392,3 +395,131 @@ L:
 
 	require.Equal(t, expected, consumed)
 }
+
+func newPartitionedTestEnvironment() *environment.Environment {
+	c := testutil.NewConfig()
+	c.PubSubRoutingMode = config.PartitionedPubSubRoutingMode
+	return testcluster.NewEnvironment(c)
+}
+
+func TestPubSub_Cluster_PartitionedRouting(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(newPartitionedTestEnvironment()).(*Service)
+	s2 := cluster.AddMember(newPartitionedTestEnvironment()).(*Service)
+	s3 := cluster.AddMember(newPartitionedTestEnvironment()).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	rc1 := s1.client.Get(s1.rt.This().String())
+	ps := rc1.Subscribe(ctx, "my-channel")
+
+	// Wait for confirmation that subscription is created before publishing anything.
+	_, err := ps.ReceiveTimeout(ctx, time.Second)
+	require.NoError(t, err)
+
+	// Only s1 has subscribers for the channel.
+	owner := s1.channelOwner("my-channel")
+	var ownerService *Service
+	for _, s := range []*Service{s1, s2, s3} {
+		if s.rt.This().CompareByID(owner) {
+			ownerService = s
+		}
+	}
+	require.NotNil(t, ownerService)
+	require.Equal(t, []string{s1.rt.This().String()}, ownerService.interest.targets("my-channel"))
+	require.Empty(t, ownerService.interest.targets("other-channel"))
+
+	ch := ps.Channel()
+	expected := make(map[string]struct{})
+	for i, s := range []*Service{s1, s2, s3} {
+		msg := fmt.Sprintf("my-message-%d", i)
+		rc := s.client.Get(s.rt.This().String())
+		count, err := rc.Publish(ctx, "my-channel", msg).Result()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+		expected[msg] = struct{}{}
+	}
+
+	consumed := make(map[string]struct{})
+L:
+	for {
+		select {
+		case msg := <-ch:
+			require.Equal(t, "my-channel", msg.Channel)
+			consumed[msg.Payload] = struct{}{}
+			if len(consumed) == len(expected) {
+				break L
+			}
+		case <-time.After(5 * time.Second):
+			break L
+		}
+	}
+	require.Equal(t, expected, consumed)
+
+	rc3 := s3.client.Get(s3.rt.This().String())
+	count, err := rc3.Publish(ctx, "other-channel", "hello").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), count)
+
+	// The owner drops the interest after the last subscriber leaves, without
+	// waiting for the next full report.
+	require.NoError(t, ps.Unsubscribe(ctx, "my-channel"))
+	require.Eventually(t, func() bool {
+		return len(ownerService.interest.targets("my-channel")) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPubSub_Cluster_PartitionedRouting_PSubscribe(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(newPartitionedTestEnvironment()).(*Service)
+	s2 := cluster.AddMember(newPartitionedTestEnvironment()).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	rc1 := s1.client.Get(s1.rt.This().String())
+	ps := rc1.PSubscribe(ctx, "h*llo")
+
+	// Wait for confirmation that subscription is created before publishing anything.
+	_, err := ps.ReceiveTimeout(ctx, time.Second)
+	require.NoError(t, err)
+
+	rc2 := s2.client.Get(s2.rt.This().String())
+	err = rc2.Publish(ctx, "hello", "world").Err()
+	require.NoError(t, err)
+
+	select {
+	case msg := <-ps.Channel():
+		require.Equal(t, "hello", msg.Channel)
+		require.Equal(t, "h*llo", msg.Pattern)
+		require.Equal(t, "world", msg.Payload)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "No message received")
+	}
+
+	require.NoError(t, ps.PUnsubscribe(ctx, "h*llo"))
+	require.Eventually(t, func() bool {
+		return len(s2.interest.targets("hello")) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPubSub_Cluster_PubSubNumsub(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	for _, s := range []*Service{s1, s2} {
+		rc := s.client.Get(s.rt.This().String())
+		ps := rc.Subscribe(ctx, "hello")
+		// Wait for confirmation that subscription is created before publishing anything.
+		_, err := ps.ReceiveTimeout(ctx, time.Second)
+		require.NoError(t, err)
+	}
+
+	rc1 := s1.client.Get(s1.rt.This().String())
+	nr, err := rc1.PubSubNumSub(ctx, "hello", "foobar").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), nr["hello"])
+	require.Equal(t, int64(0), nr["foobar"])
+}
@@ -0,0 +1,312 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"sync"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/discovery"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/tidwall/match"
+)
+
+const (
+	// channelNamespace is used to hash channel names to partitions.
+	channelNamespace = "olric.pubsub."
+
+	// interestSyncInterval is the time gap between two full reports of the
+	// subscriber interest to the channel owners. The changes are sent as they
+	// happen, the full reports only repair the lost ones.
+	interestSyncInterval = 30 * time.Second
+
+	// interestTTL is the lifetime of a report. The interest of a member that
+	// has left the cluster is dropped after interestTTL, if it's not pruned
+	// after the routing table update.
+	interestTTL = 3 * interestSyncInterval
+)
+
+type memberInterest struct {
+	channels  map[string]struct{}
+	patterns  map[string]struct{}
+	updatedAt time.Time
+}
+
+// interestTable keeps the subscriber interest reported by the cluster members.
+// A member reports the channels owned by this member and all of its patterns.
+type interestTable struct {
+	mtx     sync.RWMutex
+	members map[string]*memberInterest
+}
+
+func newInterestTable() *interestTable {
+	return &interestTable{
+		members: make(map[string]*memberInterest),
+	}
+}
+
+// update replaces the interest of the given member.
+func (it *interestTable) update(member string, channels, patterns []string) {
+	it.mtx.Lock()
+	defer it.mtx.Unlock()
+
+	mi := &memberInterest{
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+	it.members[member] = mi
+	it.addLocked(mi, channels, patterns)
+}
+
+func (it *interestTable) addLocked(mi *memberInterest, channels, patterns []string) {
+	for _, channel := range channels {
+		mi.channels[channel] = struct{}{}
+	}
+	for _, pattern := range patterns {
+		mi.patterns[pattern] = struct{}{}
+	}
+	mi.updatedAt = time.Now()
+}
+
+// add adds the channels and the patterns to the interest of the given member.
+func (it *interestTable) add(member string, channels, patterns []string) {
+	it.mtx.Lock()
+	defer it.mtx.Unlock()
+
+	mi, ok := it.members[member]
+	if !ok {
+		mi = &memberInterest{
+			channels: make(map[string]struct{}),
+			patterns: make(map[string]struct{}),
+		}
+		it.members[member] = mi
+	}
+	it.addLocked(mi, channels, patterns)
+}
+
+// remove removes the channels and the patterns from the interest of the given
+// member.
+func (it *interestTable) remove(member string, channels, patterns []string) {
+	it.mtx.Lock()
+	defer it.mtx.Unlock()
+
+	mi, ok := it.members[member]
+	if !ok {
+		return
+	}
+	for _, channel := range channels {
+		delete(mi.channels, channel)
+	}
+	for _, pattern := range patterns {
+		delete(mi.patterns, pattern)
+	}
+}
+
+// targets returns the members that have subscribers for the channel.
+func (it *interestTable) targets(channel string) []string {
+	it.mtx.RLock()
+	defer it.mtx.RUnlock()
+
+	var members []string
+	for member, mi := range it.members {
+		if time.Since(mi.updatedAt) > interestTTL {
+			continue
+		}
+		if _, ok := mi.channels[channel]; ok {
+			members = append(members, member)
+			continue
+		}
+		for pattern := range mi.patterns {
+			if match.Match(channel, pattern) {
+				members = append(members, member)
+				break
+			}
+		}
+	}
+	return members
+}
+
+// expire deletes the stale reports and the reports of the members that are
+// not in alive.
+func (it *interestTable) expire(alive map[string]struct{}) {
+	it.mtx.Lock()
+	defer it.mtx.Unlock()
+
+	for member, mi := range it.members {
+		if _, ok := alive[member]; !ok || time.Since(mi.updatedAt) > interestTTL {
+			delete(it.members, member)
+		}
+	}
+}
+
+func (s *Service) isPartitioned() bool {
+	return s.config.PubSubRoutingMode == config.PartitionedPubSubRoutingMode
+}
+
+// channelOwner returns the owner of the partition that the channel is hashed to.
+func (s *Service) channelOwner(channel string) discovery.Member {
+	return s.primary.PartitionByHKey(ChannelHKey(channel)).Owner()
+}
+
+// localInterest groups the channels that have subscribers on this member by
+// their owners and returns them with the patterns.
+func (s *Service) localInterest() (map[string][]string, []string) {
+	byOwner := make(map[string][]string)
+	set := make(map[string]struct{})
+	for _, channel := range s.pubsub.Channels() {
+		if _, ok := set[channel]; ok {
+			continue
+		}
+		set[channel] = struct{}{}
+		owner := s.channelOwner(channel).String()
+		byOwner[owner] = append(byOwner[owner], channel)
+	}
+	return byOwner, s.pubsub.Patterns()
+}
+
+func (s *Service) sendInterest(member discovery.Member, cmd *protocol.PubSubInterest) error {
+	if member.CompareByID(s.rt.This()) {
+		s.applyInterest(cmd)
+		return nil
+	}
+
+	interestCmd := cmd.Command(s.ctx)
+	rc := s.client.Get(member.String())
+	err := rc.Process(s.ctx, interestCmd)
+	if err != nil {
+		return err
+	}
+	return interestCmd.Err()
+}
+
+func (s *Service) applyInterest(cmd *protocol.PubSubInterest) {
+	switch {
+	case cmd.Add:
+		s.interest.add(cmd.Member, cmd.Channels, cmd.Patterns)
+	case cmd.Remove:
+		s.interest.remove(cmd.Member, cmd.Channels, cmd.Patterns)
+	default:
+		s.interest.update(cmd.Member, cmd.Channels, cmd.Patterns)
+	}
+}
+
+// syncInterest reports the subscriber interest of this member to all members
+// of the cluster.
+func (s *Service) syncInterest() {
+	if !s.rt.IsBootstrapped() {
+		return
+	}
+
+	this := s.rt.This().String()
+	byOwner, patterns := s.localInterest()
+	alive := make(map[string]struct{})
+	for _, member := range s.rt.Discovery().GetMembers() {
+		alive[member.String()] = struct{}{}
+		cmd := protocol.NewPubSubInterest(this).
+			SetChannels(byOwner[member.String()]...).
+			SetPatterns(patterns...)
+		err := s.sendInterest(member, cmd)
+		if err != nil {
+			s.log.V(3).Printf("[ERROR] Failed to send Pub/Sub interest to %s: %v", member, err)
+		}
+	}
+	s.interest.expire(alive)
+}
+
+// sendInterestChange adds or removes a channel or a pattern in the interest of
+// this member. A channel is sent to its owner, a pattern to all members.
+func (s *Service) sendInterestChange(add, pattern bool, channel string) {
+	if !s.rt.IsBootstrapped() {
+		return
+	}
+
+	cmd := protocol.NewPubSubInterest(s.rt.This().String())
+	if add {
+		cmd.SetAdd()
+	} else {
+		cmd.SetRemove()
+	}
+
+	var members []discovery.Member
+	if pattern {
+		cmd.SetPatterns(channel)
+		members = s.rt.Discovery().GetMembers()
+	} else {
+		cmd.SetChannels(channel)
+		members = []discovery.Member{s.channelOwner(channel)}
+	}
+	for _, member := range members {
+		err := s.sendInterest(member, cmd)
+		if err != nil {
+			s.log.V(3).Printf("[ERROR] Failed to send Pub/Sub interest to %s: %v", member, err)
+		}
+	}
+}
+
+// announceInterest is called before a new subscription is created. It reports
+// the interest immediately, so a message that is published right after the
+// subscription is confirmed reaches this member. The returned function is
+// called after the subscription is created, see withdrawInterest.
+func (s *Service) announceInterest(pattern bool, channel string) func() {
+	s.interestMtx.Lock()
+	s.sendInterestChange(true, pattern, channel)
+	return s.interestMtx.Unlock
+}
+
+// withdrawInterest is called after a subscription is removed. It removes the
+// channel or the pattern from the interest of this member if there are no
+// subscribers left. It holds the same lock with announceInterest, so it cannot
+// remove the interest of a subscription that is being created.
+func (s *Service) withdrawInterest(pattern bool, channel string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		s.interestMtx.Lock()
+		defer s.interestMtx.Unlock()
+
+		if s.pubsub.HasSubscribers(pattern, channel) {
+			return
+		}
+		s.sendInterestChange(false, pattern, channel)
+	}()
+}
+
+// triggerInterestSync runs a full sync in the background. It's called after
+// every routing table update because the channel owners may have changed.
+func (s *Service) triggerInterestSync() {
+	select {
+	case s.syncInterestCh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Service) syncInterestPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interestSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.syncInterestCh:
+		}
+		s.syncInterest()
+	}
+}
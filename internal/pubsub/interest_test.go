@@ -0,0 +1,66 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPubSub_InterestTable(t *testing.T) {
+	it := newInterestTable()
+	it.update("member-1", []string{"hello"}, nil)
+	it.update("member-2", nil, []string{"h*llo"})
+
+	require.ElementsMatch(t, []string{"member-1", "member-2"}, it.targets("hello"))
+	require.Equal(t, []string{"member-2"}, it.targets("hallo"))
+	require.Empty(t, it.targets("foobar"))
+
+	// Replaces the previous report
+	it.update("member-1", []string{"foobar"}, nil)
+	require.Equal(t, []string{"member-2"}, it.targets("hello"))
+	require.Equal(t, []string{"member-1"}, it.targets("foobar"))
+
+	// Stale reports are ignored and dropped.
+	it.members["member-1"].updatedAt = time.Now().Add(-2 * interestTTL)
+	require.Empty(t, it.targets("foobar"))
+	it.expire(map[string]struct{}{"member-1": {}, "member-2": {}})
+	require.NotContains(t, it.members, "member-1")
+	require.Contains(t, it.members, "member-2")
+
+	// The members that left the cluster are dropped.
+	it.expire(map[string]struct{}{})
+	require.Empty(t, it.members)
+}
+
+func TestPubSub_InterestTable_Changes(t *testing.T) {
+	it := newInterestTable()
+	it.add("member-1", []string{"hello"}, nil)
+	it.add("member-1", []string{"foobar"}, []string{"h*llo"})
+	require.Equal(t, []string{"member-1"}, it.targets("hello"))
+	require.Equal(t, []string{"member-1"}, it.targets("hallo"))
+	require.Equal(t, []string{"member-1"}, it.targets("foobar"))
+
+	it.remove("member-1", []string{"foobar"}, []string{"h*llo"})
+	require.Equal(t, []string{"member-1"}, it.targets("hello"))
+	require.Empty(t, it.targets("hallo"))
+	require.Empty(t, it.targets("foobar"))
+
+	// Unknown members are ignored.
+	it.remove("member-2", []string{"hello"}, nil)
+	require.NotContains(t, it.members, "member-2")
+}
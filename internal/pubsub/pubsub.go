@@ -26,6 +26,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/buraksezer/olric/internal/protocol"
 	"github.com/tidwall/btree"
 	"github.com/tidwall/match"
 	"github.com/tidwall/redcon"
@@ -40,18 +41,40 @@ type PubSub struct {
 	conns  map[redcon.Conn]*pubSubConn
 
 	// callbacks
+	subscribeCallback    func(pattern bool, channel string) (done func())
+	ssubscribeCallback   func(channel string) error
 	unsubscribeCallback  func()
 	punsubscribeCallback func()
+	// unsubscribedCallback is called for the channels and the patterns that
+	// have been removed, after the locks are released.
+	unsubscribedCallback func(pattern bool, channel string)
 }
 
 // Subscribe a connection to PubSub
 func (ps *PubSub) Subscribe(conn redcon.Conn, channel string) {
-	ps.subscribe(conn, false, channel)
+	if ps.subscribeCallback != nil {
+		defer ps.subscribeCallback(false, channel)()
+	}
+	ps.subscribe(conn, false, false, channel)
 }
 
 // Psubscribe a connection to PubSub
 func (ps *PubSub) Psubscribe(conn redcon.Conn, channel string) {
-	ps.subscribe(conn, true, channel)
+	if ps.subscribeCallback != nil {
+		defer ps.subscribeCallback(true, channel)()
+	}
+	ps.subscribe(conn, true, false, channel)
+}
+
+// SSubscribe a connection to a shard channel
+func (ps *PubSub) SSubscribe(conn redcon.Conn, channel string) error {
+	if ps.ssubscribeCallback != nil {
+		if err := ps.ssubscribeCallback(channel); err != nil {
+			return err
+		}
+	}
+	ps.subscribe(conn, false, true, channel)
+	return nil
 }
 
 // Publish a message to subscribers
@@ -66,7 +89,7 @@ func (ps *PubSub) Publish(channel, message string) int {
 	pivot := &pubSubEntry{pattern: false, channel: channel}
 	ps.chans.Ascend(pivot, func(item interface{}) bool {
 		entry := item.(*pubSubEntry)
-		if entry.channel != pivot.channel || entry.pattern != pivot.pattern {
+		if entry.channel != pivot.channel || entry.pattern != pivot.pattern || entry.shard != pivot.shard {
 			return false
 		}
 		entry.sconn.writeMessage(entry.pattern, "", channel, message)
@@ -89,6 +112,28 @@ func (ps *PubSub) Publish(channel, message string) int {
 	return sent
 }
 
+// SPublish a message to the subscribers of a shard channel
+func (ps *PubSub) SPublish(channel, message string) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	if !ps.initd {
+		return 0
+	}
+	var sent int
+	pivot := &pubSubEntry{shard: true, channel: channel}
+	ps.chans.Ascend(pivot, func(item interface{}) bool {
+		entry := item.(*pubSubEntry)
+		if entry.channel != pivot.channel || entry.pattern != pivot.pattern || entry.shard != pivot.shard {
+			return false
+		}
+		entry.sconn.writeShardMessage(channel, message)
+		sent++
+		return true
+	})
+
+	return sent
+}
+
 type pubSubConn struct {
 	id      uint64
 	mu      sync.Mutex
@@ -99,6 +144,7 @@ type pubSubConn struct {
 
 type pubSubEntry struct {
 	pattern bool
+	shard   bool
 	sconn   *pubSubConn
 	channel string
 }
@@ -121,16 +167,31 @@ func (sconn *pubSubConn) writeMessage(pat bool, pchan, channel, msg string) {
 	sconn.dconn.Flush()
 }
 
+func (sconn *pubSubConn) writeShardMessage(channel, msg string) {
+	sconn.mu.Lock()
+	defer sconn.mu.Unlock()
+	sconn.dconn.WriteArray(3)
+	sconn.dconn.WriteBulkString("smessage")
+	sconn.dconn.WriteBulkString(channel)
+	sconn.dconn.WriteBulkString(msg)
+	sconn.dconn.Flush()
+}
+
 // bgrunner runs in the background and reads incoming commands from the
 // detached client.
 func (sconn *pubSubConn) bgrunner(ps *PubSub) {
 	defer func() {
 		// client connection has ended, disconnect from the PubSub instances
 		// and close the network connection.
+		var removed []*pubSubEntry
+		defer func() {
+			ps.unsubscribed(removed)
+		}()
 		ps.mu.Lock()
 		defer ps.mu.Unlock()
 		for entry := range sconn.entries {
 			ps.chans.Delete(entry)
+			removed = append(removed, entry)
 		}
 		delete(ps.conns, sconn.conn)
 		sconn.mu.Lock()
@@ -146,7 +207,7 @@ func (sconn *pubSubConn) bgrunner(ps *PubSub) {
 			continue
 		}
 		switch strings.ToLower(string(cmd.Args[0])) {
-		case "psubscribe", "subscribe":
+		case "psubscribe", "subscribe", "ssubscribe":
 			if len(cmd.Args) < 2 {
 				func() {
 					sconn.mu.Lock()
@@ -159,20 +220,32 @@ func (sconn *pubSubConn) bgrunner(ps *PubSub) {
 			}
 			command := strings.ToLower(string(cmd.Args[0]))
 			for i := 1; i < len(cmd.Args); i++ {
-				if command == "psubscribe" {
+				switch command {
+				case "psubscribe":
 					ps.Psubscribe(sconn.conn, string(cmd.Args[i]))
-				} else {
+				case "ssubscribe":
+					if err := ps.SSubscribe(sconn.conn, string(cmd.Args[i])); err != nil {
+						func() {
+							sconn.mu.Lock()
+							defer sconn.mu.Unlock()
+							sconn.dconn.WriteError(fmt.Sprintf("%s %s", protocol.GetPrefix(err), err.Error()))
+							sconn.dconn.Flush()
+						}()
+					}
+				default:
 					ps.Subscribe(sconn.conn, string(cmd.Args[i]))
 				}
 			}
-		case "unsubscribe", "punsubscribe":
-			pattern := strings.ToLower(string(cmd.Args[0])) == "punsubscribe"
+		case "unsubscribe", "punsubscribe", "sunsubscribe":
+			command := strings.ToLower(string(cmd.Args[0]))
+			pattern := command == "punsubscribe"
+			shard := command == "sunsubscribe"
 			if len(cmd.Args) == 1 {
-				ps.unsubscribe(sconn.conn, pattern, true, "")
+				ps.unsubscribe(sconn.conn, pattern, shard, true, "")
 			} else {
 				for i := 1; i < len(cmd.Args); i++ {
 					channel := string(cmd.Args[i])
-					ps.unsubscribe(sconn.conn, pattern, false, channel)
+					ps.unsubscribe(sconn.conn, pattern, shard, false, channel)
 				}
 			}
 		case "quit":
@@ -213,7 +286,7 @@ func (sconn *pubSubConn) bgrunner(ps *PubSub) {
 				sconn.mu.Lock()
 				defer sconn.mu.Unlock()
 				sconn.dconn.WriteError(fmt.Sprintf("ERR Can't execute '%s': "+
-					"only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are "+
+					"only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT are "+
 					"allowed in this context", cmd.Args[0]))
 				sconn.dconn.Flush()
 			}()
@@ -222,7 +295,7 @@ func (sconn *pubSubConn) bgrunner(ps *PubSub) {
 }
 
 // byEntry is a "less" function that sorts the entries in a btree. The tree
-// is sorted be (pattern, channel, conn.id). All pattern=true entries are at
+// is sorted be (pattern, shard, channel, conn.id). All pattern=true entries are at
 // the end (right) of the tree.
 func byEntry(a, b interface{}) bool {
 	aa := a.(*pubSubEntry)
@@ -233,6 +306,12 @@ func byEntry(a, b interface{}) bool {
 	if aa.pattern && !bb.pattern {
 		return false
 	}
+	if !aa.shard && bb.shard {
+		return true
+	}
+	if aa.shard && !bb.shard {
+		return false
+	}
 	if aa.channel < bb.channel {
 		return true
 	}
@@ -250,7 +329,7 @@ func byEntry(a, b interface{}) bool {
 	return aid < bid
 }
 
-func (ps *PubSub) subscribe(conn redcon.Conn, pattern bool, channel string) {
+func (ps *PubSub) subscribe(conn redcon.Conn, pattern, shard bool, channel string) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
@@ -282,6 +361,7 @@ func (ps *PubSub) subscribe(conn redcon.Conn, pattern bool, channel string) {
 	// add an entry to the pubsub btree
 	entry := &pubSubEntry{
 		pattern: pattern,
+		shard:   shard,
 		channel: channel,
 		sconn:   sconn,
 	}
@@ -290,15 +370,18 @@ func (ps *PubSub) subscribe(conn redcon.Conn, pattern bool, channel string) {
 
 	// send a message to the client
 	sconn.dconn.WriteArray(3)
-	if pattern {
+	switch {
+	case pattern:
 		sconn.dconn.WriteBulkString("psubscribe")
-	} else {
+	case shard:
+		sconn.dconn.WriteBulkString("ssubscribe")
+	default:
 		sconn.dconn.WriteBulkString("subscribe")
 	}
 	sconn.dconn.WriteBulkString(channel)
 	var count int
 	for ient := range sconn.entries {
-		if ient.pattern == pattern {
+		if ient.pattern == pattern && ient.shard == shard {
 			count++
 		}
 	}
@@ -311,11 +394,32 @@ func (ps *PubSub) subscribe(conn redcon.Conn, pattern bool, channel string) {
 	}
 }
 
-func (ps *PubSub) unsubscribe(conn redcon.Conn, pattern, all bool, channel string) {
+// unsubscribed calls unsubscribedCallback for the removed entries.
+func (ps *PubSub) unsubscribed(entries []*pubSubEntry) {
+	if ps.unsubscribedCallback == nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.shard {
+			ps.unsubscribedCallback(entry.pattern, entry.channel)
+		}
+	}
+}
+
+func (ps *PubSub) unsubscribe(conn redcon.Conn, pattern, shard, all bool, channel string) {
+	var removed []*pubSubEntry
+	defer func() {
+		ps.unsubscribed(removed)
+	}()
+
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
-	// fetch the pubSubConn. This must exist
-	sconn := ps.conns[conn]
+	// fetch the pubSubConn. It may have been closed if the shard channels
+	// are unsubscribed by the server.
+	sconn, ok := ps.conns[conn]
+	if !ok {
+		return
+	}
 	sconn.mu.Lock()
 	defer sconn.mu.Unlock()
 
@@ -323,14 +427,21 @@ func (ps *PubSub) unsubscribe(conn redcon.Conn, pattern, all bool, channel strin
 		if entry != nil {
 			ps.chans.Delete(entry)
 			delete(sconn.entries, entry)
+			removed = append(removed, entry)
 		}
 		sconn.dconn.WriteArray(3)
-		if pattern {
+		switch {
+		case pattern:
 			if ps.punsubscribeCallback != nil {
 				ps.punsubscribeCallback()
 			}
 			sconn.dconn.WriteBulkString("punsubscribe")
-		} else {
+		case shard:
+			if ps.unsubscribeCallback != nil {
+				ps.unsubscribeCallback()
+			}
+			sconn.dconn.WriteBulkString("sunsubscribe")
+		default:
 			if ps.unsubscribeCallback != nil {
 				ps.unsubscribeCallback()
 			}
@@ -343,7 +454,7 @@ func (ps *PubSub) unsubscribe(conn redcon.Conn, pattern, all bool, channel strin
 		}
 		var count int
 		for ient := range sconn.entries {
-			if ient.pattern == pattern {
+			if ient.pattern == pattern && ient.shard == shard {
 				count++
 			}
 		}
@@ -353,7 +464,7 @@ func (ps *PubSub) unsubscribe(conn redcon.Conn, pattern, all bool, channel strin
 		// unsubscribe from all (p)subscribe entries
 		var entries []*pubSubEntry
 		for ient := range sconn.entries {
-			if ient.pattern == pattern {
+			if ient.pattern == pattern && ient.shard == shard {
 				entries = append(entries, ient)
 			}
 		}
@@ -368,7 +479,7 @@ func (ps *PubSub) unsubscribe(conn redcon.Conn, pattern, all bool, channel strin
 		// unsubscribe single channel from (p)subscribe.
 		var entry *pubSubEntry
 		for ient := range sconn.entries {
-			if ient.pattern == pattern && ient.channel == channel {
+			if ient.pattern == pattern && ient.shard == shard && ient.channel == channel {
 				entry = ient
 				break
 			}
@@ -390,7 +501,7 @@ func (ps *PubSub) Channels() []string {
 	for _, sconn := range ps.conns {
 		sconn.mu.Lock()
 		for ient := range sconn.entries {
-			if !ient.pattern {
+			if !ient.pattern && !ient.shard {
 				channels = append(channels, ient.channel)
 			}
 		}
@@ -412,7 +523,7 @@ func (ps *PubSub) ChannelsWithPatterns(pattern string) []string {
 	for _, sconn := range ps.conns {
 		sconn.mu.Lock()
 		for ient := range sconn.entries {
-			if match.Match(ient.channel, pattern) {
+			if !ient.shard && match.Match(ient.channel, pattern) {
 				channels = append(channels, ient.channel)
 			}
 		}
@@ -422,6 +533,34 @@ func (ps *PubSub) ChannelsWithPatterns(pattern string) []string {
 	return channels
 }
 
+// Patterns returns the distinct patterns that have at least one subscriber.
+func (ps *PubSub) Patterns() []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if !ps.initd {
+		return nil
+	}
+
+	set := make(map[string]struct{})
+	var patterns []string
+	for _, sconn := range ps.conns {
+		sconn.mu.Lock()
+		for ient := range sconn.entries {
+			if !ient.pattern {
+				continue
+			}
+			if _, ok := set[ient.channel]; !ok {
+				set[ient.channel] = struct{}{}
+				patterns = append(patterns, ient.channel)
+			}
+		}
+		sconn.mu.Unlock()
+	}
+
+	return patterns
+}
+
 func (ps *PubSub) Numpat() int {
 	ps.mu.RLock()
 	defer ps.mu.RUnlock()
@@ -444,6 +583,29 @@ func (ps *PubSub) Numpat() int {
 	return len(set)
 }
 
+// HasSubscribers returns true if the channel or the pattern has at least one
+// subscriber on this member. Shard channels are not counted.
+func (ps *PubSub) HasSubscribers(pattern bool, channel string) bool {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if !ps.initd {
+		return false
+	}
+
+	for _, sconn := range ps.conns {
+		sconn.mu.Lock()
+		for ient := range sconn.entries {
+			if !ient.shard && ient.pattern == pattern && ient.channel == channel {
+				sconn.mu.Unlock()
+				return true
+			}
+		}
+		sconn.mu.Unlock()
+	}
+	return false
+}
+
 func (ps *PubSub) Numsub(channel string) int {
 	ps.mu.RLock()
 	defer ps.mu.RUnlock()
@@ -456,7 +618,64 @@ func (ps *PubSub) Numsub(channel string) int {
 	for _, sconn := range ps.conns {
 		sconn.mu.Lock()
 		for ient := range sconn.entries {
-			if ient.channel == channel {
+			if !ient.shard && ient.channel == channel {
+				result++
+			}
+		}
+		sconn.mu.Unlock()
+	}
+
+	return result
+}
+
+// ShardChannels returns the distinct shard channels that have at least one
+// subscriber on this member. The channels are filtered by the pattern if it's
+// not empty.
+func (ps *PubSub) ShardChannels(pattern string) []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if !ps.initd {
+		return nil
+	}
+
+	set := make(map[string]struct{})
+	var channels []string
+	for _, sconn := range ps.conns {
+		sconn.mu.Lock()
+		for ient := range sconn.entries {
+			if !ient.shard {
+				continue
+			}
+			if pattern != "" && !match.Match(ient.channel, pattern) {
+				continue
+			}
+			if _, ok := set[ient.channel]; !ok {
+				set[ient.channel] = struct{}{}
+				channels = append(channels, ient.channel)
+			}
+		}
+		sconn.mu.Unlock()
+	}
+
+	return channels
+}
+
+// ShardNumsub returns the number of the subscribers of a shard channel on this
+// member.
+func (ps *PubSub) ShardNumsub(channel string) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if !ps.initd {
+		return 0
+	}
+
+	var result int
+	for _, sconn := range ps.conns {
+		sconn.mu.Lock()
+		for ient := range sconn.entries {
+			if ient.shard && ient.channel == channel {
 				result++
 			}
 		}
@@ -465,3 +684,34 @@ func (ps *PubSub) Numsub(channel string) int {
 
 	return result
 }
+
+// UnsubscribeShardChannels removes the subscriptions of the shard channels
+// that are not accepted by keep. The clients receive an sunsubscribe message
+// for every removed subscription.
+func (ps *PubSub) UnsubscribeShardChannels(keep func(channel string) bool) {
+	type subscription struct {
+		conn    redcon.Conn
+		channel string
+	}
+
+	ps.mu.RLock()
+	if !ps.initd {
+		ps.mu.RUnlock()
+		return
+	}
+	var subscriptions []subscription
+	for conn, sconn := range ps.conns {
+		sconn.mu.Lock()
+		for ient := range sconn.entries {
+			if ient.shard && !keep(ient.channel) {
+				subscriptions = append(subscriptions, subscription{conn: conn, channel: ient.channel})
+			}
+		}
+		sconn.mu.Unlock()
+	}
+	ps.mu.RUnlock()
+
+	for _, s := range subscriptions {
+		ps.unsubscribe(s.conn, false, true, false, s.channel)
+	}
+}
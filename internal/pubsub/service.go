@@ -18,6 +18,8 @@ import (
 	"context"
 	"sync"
 
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/cluster/partitions"
 	"github.com/buraksezer/olric/internal/cluster/routingtable"
 	"github.com/buraksezer/olric/internal/environment"
 	"github.com/buraksezer/olric/internal/protocol"
@@ -44,14 +46,22 @@ var (
 type Service struct {
 	sync.RWMutex
 
-	log    *flog.Logger
-	pubsub *PubSub
-	rt     *routingtable.RoutingTable
-	server *server.Server
-	client *server.Client
-	wg     sync.WaitGroup
-	ctx    context.Context
-	cancel context.CancelFunc
+	log     *flog.Logger
+	config  *config.Config
+	pubsub  *PubSub
+	rt      *routingtable.RoutingTable
+	primary *partitions.Partitions
+	backup  *partitions.Partitions
+	server  *server.Server
+	client  *server.Client
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// Only used by PartitionedPubSubRoutingMode
+	interest       *interestTable
+	interestMtx    sync.Mutex
+	syncInterestCh chan struct{}
 }
 
 func (s *Service) RegisterHandlers() {
@@ -62,7 +72,14 @@ func (s *Service) RegisterHandlers() {
 	s.server.ServeMux().HandleFunc(protocol.PubSub.PubSubChannels, s.pubsubChannelsCommandHandler)
 	s.server.ServeMux().HandleFunc(protocol.PubSub.PubSubNumpat, s.pubsubNumpatCommandHandler)
 	s.server.ServeMux().HandleFunc(protocol.PubSub.PubSubNumsub, s.pubsubNumsubCommandHandler)
-
+	s.server.ServeMux().HandleFunc(protocol.PubSub.PublishRouted, s.publishRoutedCommandHandler)
+	s.server.ServeMux().HandleFunc(protocol.PubSub.PubSubNumsubInternal, s.pubsubNumsubInternalCommandHandler)
+	s.server.ServeMux().HandleFunc(protocol.PubSub.PubSubInterest, s.pubsubInterestCommandHandler)
+	s.server.ServeMux().HandleFunc(protocol.PubSub.SSubscribe, s.ssubscribeCommandHandler)
+	s.server.ServeMux().HandleFunc(protocol.PubSub.SPublish, s.spublishCommandHandler)
+	s.server.ServeMux().HandleFunc(protocol.PubSub.SPublishInternal, s.spublishInternalCommandHandler)
+	s.server.ServeMux().HandleFunc(protocol.PubSub.PubSubShardChannels, s.pubsubShardChannelsCommandHandler)
+	s.server.ServeMux().HandleFunc(protocol.PubSub.PubSubShardNumsub, s.pubsubShardNumsubCommandHandler)
 }
 
 func NewService(e *environment.Environment) (service.Service, error) {
@@ -76,20 +93,42 @@ func NewService(e *environment.Environment) (service.Service, error) {
 		},
 	}
 	s := &Service{
-		log:    e.Get("logger").(*flog.Logger),
-		rt:     e.Get("routingtable").(*routingtable.RoutingTable),
-		server: e.Get("server").(*server.Server),
-		client: e.Get("client").(*server.Client),
-		pubsub: ps,
-		ctx:    ctx,
-		cancel: cancel,
+		log:            e.Get("logger").(*flog.Logger),
+		config:         e.Get("config").(*config.Config),
+		rt:             e.Get("routingtable").(*routingtable.RoutingTable),
+		primary:        e.Get("primary").(*partitions.Partitions),
+		backup:         e.Get("backup").(*partitions.Partitions),
+		server:         e.Get("server").(*server.Server),
+		client:         e.Get("client").(*server.Client),
+		pubsub:         ps,
+		interest:       newInterestTable(),
+		syncInterestCh: make(chan struct{}, 1),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	ps.ssubscribeCallback = s.checkShardOwnership
+	if s.isPartitioned() {
+		ps.subscribeCallback = s.announceInterest
+		ps.unsubscribedCallback = s.withdrawInterest
 	}
+	registerErrors()
 	s.RegisterHandlers()
 	return s, nil
 }
 
+func registerErrors() {
+	protocol.SetError("MOVED", ErrNotShardOwner)
+}
+
 func (s *Service) Start() error {
-	// dummy implementation
+	s.rt.AddCallback(s.unsubscribeMovedShardChannels)
+	if !s.isPartitioned() {
+		return nil
+	}
+
+	s.rt.AddCallback(s.triggerInterestSync)
+	s.wg.Add(1)
+	go s.syncInterestPeriodically()
 	return nil
 }
 
@@ -0,0 +1,216 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/buraksezer/olric/internal/cluster/partitions"
+	"github.com/buraksezer/olric/internal/discovery"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/tidwall/redcon"
+)
+
+// ErrNotShardOwner means that the member is neither the primary owner nor a
+// backup owner of the partition that a shard channel is hashed to.
+var ErrNotShardOwner = errors.New("not the owner of the shard channel")
+
+// ChannelHKey returns the hash of a channel name. The channel is handled by
+// the owner of the partition hkey % PartitionCount.
+func ChannelHKey(channel string) uint64 {
+	return partitions.HKey(channelNamespace, channel)
+}
+
+// checkShardOwnership returns ErrNotShardOwner if this member doesn't own the
+// partition of the shard channel.
+func (s *Service) checkShardOwnership(channel string) error {
+	if err := s.rt.CheckBootstrap(); err != nil {
+		return err
+	}
+
+	hkey := ChannelHKey(channel)
+	owner := s.primary.PartitionByHKey(hkey).Owner()
+	if owner.CompareByID(s.rt.This()) {
+		return nil
+	}
+	for _, backup := range s.backup.PartitionByHKey(hkey).Owners() {
+		if backup.CompareByID(s.rt.This()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is owned by %s", ErrNotShardOwner, channel, owner)
+}
+
+// unsubscribeMovedShardChannels is called after every routing table update.
+// The subscribers of the shard channels that have been moved to another member
+// receive an sunsubscribe message and they should subscribe again.
+func (s *Service) unsubscribeMovedShardChannels() {
+	s.pubsub.UnsubscribeShardChannels(func(channel string) bool {
+		return !errors.Is(s.checkShardOwnership(channel), ErrNotShardOwner)
+	})
+}
+
+func (s *Service) spublishOnMember(member discovery.Member, channel, message string) (int64, error) {
+	if member.CompareByID(s.rt.This()) {
+		count := s.pubsub.SPublish(channel, message)
+		PublishedTotal.Increase(int64(count))
+		return int64(count), nil
+	}
+
+	cmd := protocol.NewSPublishInternal(channel, message).SetReplica().Command(s.ctx)
+	rc := s.client.Get(member.String())
+	err := rc.Process(s.ctx, cmd)
+	if err != nil {
+		return 0, err
+	}
+	count, err := cmd.Result()
+	if err != nil {
+		return 0, err
+	}
+	PublishedTotal.Increase(count)
+	return count, nil
+}
+
+// spublish delivers the message to the subscribers on the primary owner and
+// the backup owners of the channel's partition.
+func (s *Service) spublish(conn redcon.Conn, channel, message string) {
+	hkey := ChannelHKey(channel)
+	members := []discovery.Member{s.rt.This()}
+	for _, backup := range s.backup.PartitionByHKey(hkey).Owners() {
+		if !backup.CompareByID(s.rt.This()) {
+			members = append(members, backup)
+		}
+	}
+
+	var total int64
+	for _, member := range members {
+		count, err := s.spublishOnMember(member, channel, message)
+		if err != nil {
+			protocol.WriteError(conn, err)
+			return
+		}
+		total += count
+	}
+	conn.WriteInt64(total)
+}
+
+func (s *Service) spublishCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	spublishCmd, err := protocol.ParseSPublishCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	if err = s.rt.CheckBootstrap(); err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	owner := s.primary.PartitionByHKey(ChannelHKey(spublishCmd.Channel)).Owner()
+	if owner.CompareByID(s.rt.This()) {
+		s.spublish(conn, spublishCmd.Channel, spublishCmd.Message)
+		return
+	}
+
+	// Redirect the message to the primary owner.
+	si := protocol.NewSPublishInternal(spublishCmd.Channel, spublishCmd.Message).Command(s.ctx)
+	rc := s.client.Get(owner.String())
+	err = rc.Process(s.ctx, si)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	count, err := si.Result()
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteInt64(count)
+}
+
+func (s *Service) spublishInternalCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	spublishInternalCmd, err := protocol.ParseSPublishInternalCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	if spublishInternalCmd.Replica {
+		count := s.pubsub.SPublish(spublishInternalCmd.Channel, spublishInternalCmd.Message)
+		conn.WriteInt(count)
+		return
+	}
+
+	// Redirected by another member. Don't redirect it again, even if the
+	// routing table has changed in the meantime.
+	s.spublish(conn, spublishInternalCmd.Channel, spublishInternalCmd.Message)
+}
+
+func (s *Service) ssubscribeCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	ssubscribeCmd, err := protocol.ParseSSubscribeCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	// The connection is detached after the first subscription, check all
+	// channels before subscribing to any of them.
+	for _, channel := range ssubscribeCmd.Channels {
+		if err = s.checkShardOwnership(channel); err != nil {
+			protocol.WriteError(conn, err)
+			return
+		}
+	}
+
+	for _, channel := range ssubscribeCmd.Channels {
+		s.pubsub.subscribe(conn, false, true, channel)
+		CurrentSubscribers.Increase(1)
+		SubscribersTotal.Increase(1)
+	}
+}
+
+// pubsubShardChannelsCommandHandler lists the shard channels that have
+// subscribers on this member. Shard channels are subscribed on the owners of
+// their partitions, so the result is not aggregated across the cluster.
+func (s *Service) pubsubShardChannelsCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	pubsubShardChannelsCmd, err := protocol.ParsePubSubShardChannelsCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	channels := s.pubsub.ShardChannels(pubsubShardChannelsCmd.Pattern)
+	conn.WriteArray(len(channels))
+	for _, channel := range channels {
+		conn.WriteBulkString(channel)
+	}
+}
+
+// pubsubShardNumsubCommandHandler returns the number of the subscribers of the
+// shard channels on this member.
+func (s *Service) pubsubShardNumsubCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	pubsubShardNumsubCmd, err := protocol.ParsePubSubShardNumsubCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	conn.WriteArray(len(pubsubShardNumsubCmd.Channels) * 2)
+	for _, channel := range pubsubShardNumsubCmd.Channels {
+		conn.WriteBulkString(channel)
+		conn.WriteInt(s.pubsub.ShardNumsub(channel))
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/testcluster"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func shardOwnerAndOther(channel string, services ...*Service) (*Service, *Service) {
+	var owner, other *Service
+	for _, s := range services {
+		if s.checkShardOwnership(channel) == nil {
+			owner = s
+		} else {
+			other = s
+		}
+	}
+	return owner, other
+}
+
+func TestPubSub_SSubscribe_SPublish(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	owner, other := shardOwnerAndOther("my-channel", s1, s2)
+	require.NotNil(t, owner)
+	require.NotNil(t, other)
+
+	rc := owner.client.Get(owner.rt.This().String())
+	ps := rc.SSubscribe(ctx, "my-channel")
+	defer func() {
+		require.NoError(t, ps.Close())
+	}()
+
+	// Wait for confirmation that subscription is created before publishing anything.
+	msgi, err := ps.ReceiveTimeout(ctx, time.Second)
+	require.NoError(t, err)
+	subs := msgi.(*redis.Subscription)
+	require.Equal(t, "ssubscribe", subs.Kind)
+	require.Equal(t, "my-channel", subs.Channel)
+	require.Equal(t, 1, subs.Count)
+
+	// A regular subscriber of the same channel doesn't receive shard messages.
+	regular := rc.Subscribe(ctx, "my-channel")
+	defer func() {
+		require.NoError(t, regular.Close())
+	}()
+	_, err = regular.ReceiveTimeout(ctx, time.Second)
+	require.NoError(t, err)
+
+	// Published on a member that doesn't own the channel
+	otherRc := other.client.Get(other.rt.This().String())
+	count, err := otherRc.SPublish(ctx, "my-channel", "hello").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	select {
+	case msg := <-ps.Channel():
+		require.Equal(t, "my-channel", msg.Channel)
+		require.Equal(t, "hello", msg.Payload)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "No message received")
+	}
+
+	select {
+	case <-regular.Channel():
+		require.Fail(t, "Received a shard message on a regular channel")
+	case <-time.After(250 * time.Millisecond):
+	}
+
+	count, err = otherRc.Publish(ctx, "my-channel", "hello").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func TestPubSub_SSubscribe_Not_Owner(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	s2 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	_, other := shardOwnerAndOther("my-channel", s1, s2)
+	require.NotNil(t, other)
+
+	ctx := context.Background()
+	rc := other.client.Get(other.rt.This().String())
+	ps := rc.SSubscribe(ctx, "my-channel")
+	defer func() {
+		require.NoError(t, ps.Close())
+	}()
+
+	_, err := ps.ReceiveTimeout(ctx, time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrNotShardOwner.Error())
+}
+
+func TestPubSub_SPublish_Backup(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	c1 := testutil.NewConfig()
+	c1.ReplicaCount = 2
+	s1 := cluster.AddMember(testcluster.NewEnvironment(c1)).(*Service)
+
+	c2 := testutil.NewConfig()
+	c2.ReplicaCount = 2
+	s2 := cluster.AddMember(testcluster.NewEnvironment(c2)).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	var subscriptions []*redis.PubSub
+	for _, s := range []*Service{s1, s2} {
+		require.NoError(t, s.checkShardOwnership("my-channel"))
+
+		rc := s.client.Get(s.rt.This().String())
+		ps := rc.SSubscribe(ctx, "my-channel")
+		_, err := ps.ReceiveTimeout(ctx, time.Second)
+		require.NoError(t, err)
+		subscriptions = append(subscriptions, ps)
+	}
+	defer func() {
+		for _, ps := range subscriptions {
+			require.NoError(t, ps.Close())
+		}
+	}()
+
+	rc := s1.client.Get(s1.rt.This().String())
+	count, err := rc.SPublish(ctx, "my-channel", "hello").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	for _, ps := range subscriptions {
+		select {
+		case msg := <-ps.Channel():
+			require.Equal(t, "hello", msg.Payload)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "No message received")
+		}
+	}
+}
+
+func TestPubSub_SSubscribe_Moved(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s1 := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	var channels []string
+	for i := 0; i < 20; i++ {
+		channels = append(channels, fmt.Sprintf("my-channel-%d", i))
+	}
+
+	rc := s1.client.Get(s1.rt.This().String())
+	ps := rc.SSubscribe(ctx, channels...)
+	defer func() {
+		require.NoError(t, ps.Close())
+	}()
+	for range channels {
+		_, err := ps.ReceiveTimeout(ctx, time.Second)
+		require.NoError(t, err)
+	}
+
+	// Some of the channels are moved to the new member.
+	cluster.AddMember(nil)
+
+	var moved []string
+	for _, channel := range channels {
+		if errors.Is(s1.checkShardOwnership(channel), ErrNotShardOwner) {
+			moved = append(moved, channel)
+		}
+	}
+	require.NotEmpty(t, moved)
+
+	var unsubscribed []string
+	for len(unsubscribed) < len(moved) {
+		msgi, err := ps.ReceiveTimeout(ctx, 5*time.Second)
+		require.NoError(t, err)
+		subs := msgi.(*redis.Subscription)
+		require.Equal(t, "sunsubscribe", subs.Kind)
+		unsubscribed = append(unsubscribed, subs.Channel)
+	}
+	require.ElementsMatch(t, moved, unsubscribed)
+}
+
+func TestPubSub_PubSubShardChannels_PubSubShardNumsub(t *testing.T) {
+	cluster := testcluster.New(NewService)
+	s := cluster.AddMember(nil).(*Service)
+	defer cluster.Shutdown()
+
+	ctx := context.Background()
+	rc := s.client.Get(s.rt.This().String())
+	ps := rc.SSubscribe(ctx, "my-channel")
+	defer func() {
+		require.NoError(t, ps.Close())
+	}()
+	_, err := ps.ReceiveTimeout(ctx, time.Second)
+	require.NoError(t, err)
+
+	// Regular subscriptions are not listed.
+	regular := rc.Subscribe(ctx, "my-regular-channel")
+	defer func() {
+		require.NoError(t, regular.Close())
+	}()
+	_, err = regular.ReceiveTimeout(ctx, time.Second)
+	require.NoError(t, err)
+
+	channels, err := rc.PubSubShardChannels(ctx, "").Result()
+	require.NoError(t, err)
+	require.Equal(t, []string{"my-channel"}, channels)
+
+	channels, err = rc.PubSubShardChannels(ctx, "my-*").Result()
+	require.NoError(t, err)
+	require.Equal(t, []string{"my-channel"}, channels)
+
+	channels, err = rc.PubSubShardChannels(ctx, "other-*").Result()
+	require.NoError(t, err)
+	require.Empty(t, channels)
+
+	numsub, err := rc.PubSubShardNumSub(ctx, "my-channel", "my-regular-channel").Result()
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"my-channel": 1, "my-regular-channel": 0}, numsub)
+}
@@ -0,0 +1,169 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/tidwall/redcon"
+)
+
+// DefaultUser is the user of the clients which send AUTH with only a password.
+const DefaultUser = "default"
+
+// pubsubCategory denotes all the Publish-Subscribe commands in an ACL.
+const pubsubCategory = "pubsub"
+
+var (
+	// ErrAuthRequired is returned when a client runs a command before authenticating.
+	ErrAuthRequired = errors.New("authentication required")
+
+	// ErrWrongPass is returned when the username or the password is invalid.
+	ErrWrongPass = errors.New("invalid username-password pair")
+
+	// ErrNoPermission is returned when the user is not allowed to run a command
+	// or to access a DMap.
+	ErrNoPermission = errors.New("user has no permissions to run this command")
+
+	// ErrAuthNotEnabled is returned when a client sends AUTH to a server which
+	// doesn't require authentication.
+	ErrAuthNotEnabled = errors.New("authentication is not enabled")
+)
+
+var pubsubCommands = map[string]struct{}{
+	protocol.PubSub.Publish:             {},
+	protocol.PubSub.Subscribe:           {},
+	protocol.PubSub.PSubscribe:          {},
+	protocol.PubSub.PubSubChannels:      {},
+	protocol.PubSub.PubSubNumpat:        {},
+	protocol.PubSub.PubSubNumsub:        {},
+	protocol.PubSub.SPublish:            {},
+	protocol.PubSub.SSubscribe:          {},
+	protocol.PubSub.PubSubShardChannels: {},
+	protocol.PubSub.PubSubShardNumsub:   {},
+}
+
+func init() {
+	protocol.SetError("NOAUTH", ErrAuthRequired)
+	protocol.SetError("WRONGPASS", ErrWrongPass)
+	protocol.SetError("NOPERM", ErrNoPermission)
+}
+
+type acl struct {
+	users map[string]*config.User
+}
+
+func newACL(c *config.ACL) *acl {
+	if c == nil || !c.Enabled() {
+		return nil
+	}
+	a := &acl{users: make(map[string]*config.User)}
+	for _, user := range c.Users {
+		a.users[user.Name] = user
+	}
+	return a
+}
+
+func (a *acl) authenticate(username, password string) (*config.User, error) {
+	user, ok := a.users[username]
+	if !ok {
+		return nil, ErrWrongPass
+	}
+	if subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+		return nil, ErrWrongPass
+	}
+	return user, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isCommandAllowed(user *config.User, command string) bool {
+	for _, pattern := range user.Commands {
+		if pattern == pubsubCategory {
+			if _, ok := pubsubCommands[command]; ok {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, command); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowed checks the permissions of user for the given command. command has to be in lower case.
+func (a *acl) isAllowed(user *config.User, command string, cmd redcon.Command) bool {
+	if !isCommandAllowed(user, command) {
+		return false
+	}
+	if len(user.DMaps) == 0 {
+		return true
+	}
+	name, ok := protocol.DMapName(command, cmd.Args)
+	if !ok {
+		return true
+	}
+	return matchAny(user.DMaps, name)
+}
+
+// check returns an error if the connection is not authenticated or the user
+// is not allowed to run the command.
+func (a *acl) check(conn redcon.Conn, command string, cmd redcon.Command) error {
+	user, ok := conn.Context().(*config.User)
+	if !ok {
+		return ErrAuthRequired
+	}
+	if !a.isAllowed(user, strings.ToLower(command), cmd) {
+		return ErrNoPermission
+	}
+	return nil
+}
+
+func (s *Server) authCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	authCmd, err := protocol.ParseAuthCommand(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	if s.acl == nil {
+		protocol.WriteError(conn, ErrAuthNotEnabled)
+		return
+	}
+
+	username := authCmd.Username
+	if username == "" {
+		username = DefaultUser
+	}
+	user, err := s.acl.authenticate(username, authCmd.Password)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.SetContext(user)
+	conn.WriteString(protocol.StatusOK)
+}
@@ -0,0 +1,201 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/pkg/flog"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/redcon"
+)
+
+func newServerWithACL(t *testing.T) *Server {
+	bindPort, err := getFreePort()
+	require.NoError(t, err)
+
+	l := log.New(os.Stdout, "server-test: ", log.LstdFlags)
+	fl := flog.New(l)
+	fl.SetLevel(6)
+	c := &Config{
+		BindAddr:        "127.0.0.1",
+		BindPort:        bindPort,
+		KeepAlivePeriod: time.Second,
+		ACL: &config.ACL{Users: []*config.User{
+			{
+				Name:     DefaultUser,
+				Password: "secret",
+				Commands: []string{"*"},
+			},
+			{
+				Name:     "reader",
+				Password: "pass",
+				Commands: []string{"dm.get", "pubsub"},
+				DMaps:    []string{"users.*"},
+			},
+		}},
+	}
+	s := New(c, fl)
+
+	ok := func(conn redcon.Conn, cmd redcon.Command) {
+		conn.WriteString(protocol.StatusOK)
+	}
+	s.ServeMux().HandleFunc(protocol.DMap.Get, ok)
+	s.ServeMux().HandleFunc(protocol.DMap.Destroy, ok)
+	zero := func(conn redcon.Conn, cmd redcon.Command) {
+		conn.WriteInt(0)
+	}
+	s.ServeMux().HandleFunc(protocol.PubSub.Publish, zero)
+	s.ServeMux().HandleFunc(protocol.PubSub.SPublish, zero)
+	s.ServeMux().HandleFunc(protocol.PubSub.SPublishInternal, zero)
+	s.ServeMux().HandleFunc(protocol.PubSub.PubSubShardNumsub, func(conn redcon.Conn, cmd redcon.Command) {
+		conn.WriteArray(0)
+	})
+
+	go func() {
+		require.NoError(t, s.ListenAndServe())
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, s.Shutdown(context.Background()))
+	})
+	<-s.StartedCtx.Done()
+	return s
+}
+
+func TestServer_ACL_Auth_Required(t *testing.T) {
+	s := newServerWithACL(t)
+
+	rdb := redis.NewClient(defaultRedisOptions(s.config))
+	ctx := context.Background()
+	cmd := protocol.NewGet("users.1", "mykey").Command(ctx)
+	err := rdb.Process(ctx, cmd)
+	require.ErrorIs(t, protocol.ConvertError(err), ErrAuthRequired)
+}
+
+func TestServer_ACL_Wrong_Pass(t *testing.T) {
+	s := newServerWithACL(t)
+
+	opt := defaultRedisOptions(s.config)
+	opt.Password = "wrong"
+	rdb := redis.NewClient(opt)
+	ctx := context.Background()
+	cmd := protocol.NewGet("users.1", "mykey").Command(ctx)
+	err := rdb.Process(ctx, cmd)
+	require.ErrorIs(t, protocol.ConvertError(err), ErrWrongPass)
+}
+
+func TestServer_ACL_Default_User(t *testing.T) {
+	s := newServerWithACL(t)
+
+	opt := defaultRedisOptions(s.config)
+	opt.Password = "secret"
+	rdb := redis.NewClient(opt)
+	ctx := context.Background()
+	cmd := protocol.NewDestroy("mydmap").Command(ctx)
+	require.NoError(t, rdb.Process(ctx, cmd))
+}
+
+func TestServer_ACL_Permissions(t *testing.T) {
+	s := newServerWithACL(t)
+
+	opt := defaultRedisOptions(s.config)
+	opt.Username = "reader"
+	opt.Password = "pass"
+	rdb := redis.NewClient(opt)
+	ctx := context.Background()
+
+	t.Run("Allowed", func(t *testing.T) {
+		cmd := protocol.NewGet("users.1", "mykey").Command(ctx)
+		require.NoError(t, rdb.Process(ctx, cmd))
+	})
+
+	t.Run("DMap not allowed", func(t *testing.T) {
+		cmd := protocol.NewGet("orders.1", "mykey").Command(ctx)
+		err := rdb.Process(ctx, cmd)
+		require.ErrorIs(t, protocol.ConvertError(err), ErrNoPermission)
+	})
+
+	t.Run("Command not allowed", func(t *testing.T) {
+		cmd := protocol.NewDestroy("users.1").Command(ctx)
+		err := rdb.Process(ctx, cmd)
+		require.ErrorIs(t, protocol.ConvertError(err), ErrNoPermission)
+	})
+
+	t.Run("PubSub category", func(t *testing.T) {
+		cmd := protocol.NewPublish("mychannel", "message").Command(ctx)
+		require.NoError(t, rdb.Process(ctx, cmd))
+	})
+
+	t.Run("PubSub category - shard channels", func(t *testing.T) {
+		cmd := protocol.NewSPublish("mychannel", "message").Command(ctx)
+		require.NoError(t, rdb.Process(ctx, cmd))
+
+		numsub := protocol.NewPubSubShardNumsub("mychannel").Command(ctx)
+		require.NoError(t, rdb.Process(ctx, numsub))
+	})
+
+	t.Run("PubSub category - internal commands", func(t *testing.T) {
+		cmd := protocol.NewSPublishInternal("mychannel", "message").Command(ctx)
+		err := rdb.Process(ctx, cmd)
+		require.ErrorIs(t, protocol.ConvertError(err), ErrNoPermission)
+	})
+}
+
+func TestServer_ACL_PubSub_Category(t *testing.T) {
+	user := &config.User{Name: "subscriber", Commands: []string{"pubsub"}}
+
+	for _, command := range []string{
+		protocol.PubSub.Publish,
+		protocol.PubSub.Subscribe,
+		protocol.PubSub.PSubscribe,
+		protocol.PubSub.SPublish,
+		protocol.PubSub.SSubscribe,
+		protocol.PubSub.PubSubChannels,
+		protocol.PubSub.PubSubNumpat,
+		protocol.PubSub.PubSubNumsub,
+		protocol.PubSub.PubSubShardChannels,
+		protocol.PubSub.PubSubShardNumsub,
+	} {
+		require.Truef(t, isCommandAllowed(user, command), "%s is not allowed", command)
+	}
+
+	for _, command := range []string{
+		protocol.PubSub.PublishInternal,
+		protocol.PubSub.SPublishInternal,
+		protocol.PubSub.PubSubNumsubInternal,
+		protocol.DMap.Get,
+	} {
+		require.Falsef(t, isCommandAllowed(user, command), "%s is allowed", command)
+	}
+}
+
+func TestServer_Auth_Not_Enabled(t *testing.T) {
+	s := newServer(t)
+	<-s.StartedCtx.Done()
+
+	rdb := redis.NewClient(defaultRedisOptions(s.config))
+	ctx := context.Background()
+	cmd := protocol.NewAuth("secret").Command(ctx)
+	err := rdb.Process(ctx, cmd)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrAuthNotEnabled.Error())
+}
@@ -15,16 +15,22 @@
 package server
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/tracing"
 	"github.com/buraksezer/olric/internal/util"
 	"github.com/tidwall/redcon"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ServeMuxWrapper struct {
-	mux     *ServeMux
-	precond func(conn redcon.Conn, cmd redcon.Command) bool
+	mux       *ServeMux
+	acl       *acl
+	mutualTLS bool
+	tracer    trace.Tracer
+	precond   func(conn redcon.Conn, cmd redcon.Command) bool
 }
 
 // The HandlerFunc type is an adapter to allow the use of
@@ -34,8 +40,11 @@ type ServeMuxWrapper struct {
 type HandlerFunc func(conn redcon.Conn, cmd redcon.Command)
 
 type Handler struct {
-	handler func(conn redcon.Conn, cmd redcon.Command)
-	precond func(conn redcon.Conn, cmd redcon.Command) bool
+	handler   func(conn redcon.Conn, cmd redcon.Command)
+	acl       *acl
+	mutualTLS bool
+	tracer    trace.Tracer
+	precond   func(conn redcon.Conn, cmd redcon.Command) bool
 }
 
 // ServeRESP calls f(w, r)
@@ -51,6 +60,39 @@ func (h Handler) ServeRESP(conn redcon.Conn, cmd redcon.Command) {
 	if command == "pubsub" || command == "PUBSUB" {
 		command = fmt.Sprintf("%s %s", command, util.BytesToString(cmd.Args[1]))
 	}
+
+	// The trace context of the caller is removed by ServeMux. It's only
+	// extracted if tracing is enabled.
+	ctx := context.Background()
+	if tc, ok := conn.(*tracedConn); ok {
+		conn = tc.Conn
+		if h.tracer != nil {
+			ctx = tracing.Extract(ctx, tc.traceparent)
+		}
+	}
+	if h.tracer != nil {
+		var span trace.Span
+		ctx, span = h.tracer.Start(ctx, command, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+	}
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		conn = &tracedConn{Conn: conn, ctx: ctx}
+	}
+
+	if h.acl != nil {
+		// Authentication is enabled. Check the permissions before running the handler.
+		if err := h.acl.check(conn, command, cmd); err != nil {
+			protocol.WriteError(conn, err)
+			return
+		}
+	}
+
+	if h.mutualTLS && protocol.IsInternalCommand(command) && !hasVerifiedCertificate(conn) {
+		// Node-to-node commands can only be run by the cluster members.
+		protocol.WriteError(conn, ErrCertificateRequired)
+		return
+	}
+
 	// The node is updated by UpdateRoutingCmd. So it's a precondition for
 	// an operable node.
 	if command == protocol.Internal.UpdateRouting {
@@ -75,7 +117,10 @@ func (m *ServeMuxWrapper) HandleFunc(command string, handler func(conn redcon.Co
 		panic("server: nil handler")
 	}
 	m.mux.Handle(command, Handler{
-		handler: handler,
-		precond: m.precond,
+		handler:   handler,
+		acl:       m.acl,
+		mutualTLS: m.mutualTLS,
+		tracer:    m.tracer,
+		precond:   m.precond,
 	})
 }
@@ -24,7 +24,9 @@ package server
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/buraksezer/olric/internal/tracing"
 	"github.com/buraksezer/olric/internal/util"
 	"github.com/tidwall/redcon"
 )
@@ -67,10 +69,20 @@ func (m *ServeMux) Handle(command string, handler redcon.Handler) {
 
 // ServeRESP dispatches the command to the handler.
 func (m *ServeMux) ServeRESP(conn redcon.Conn, cmd redcon.Command) {
+	if traceparent, args, ok := tracing.Unwrap(cmd.Args); ok {
+		if len(args) == 0 {
+			conn.WriteError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", tracing.Command))
+			return
+		}
+		// The handler extracts the trace context if tracing is enabled.
+		cmd.Args = args
+		conn = &tracedConn{Conn: conn, traceparent: traceparent}
+	}
+
 	command := strings.ToLower(util.BytesToString(cmd.Args[0]))
 
 	if handler, ok := m.handlers[command]; ok {
-		handler.ServeRESP(conn, cmd)
+		m.serve(command, handler, conn, cmd)
 		return
 	}
 
@@ -79,13 +91,21 @@ func (m *ServeMux) ServeRESP(conn redcon.Conn, cmd redcon.Command) {
 			conn.WriteError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", command))
 			return
 		}
-		command = fmt.Sprintf("%s %s", command, util.BytesToString(cmd.Args[1]))
+		command = fmt.Sprintf("%s %s", command, strings.ToLower(util.BytesToString(cmd.Args[1])))
 	}
 
 	if handler, ok := m.handlers[command]; ok {
-		handler.ServeRESP(conn, cmd)
+		m.serve(command, handler, conn, cmd)
 		return
 	}
 
 	conn.WriteError(fmt.Sprintf("ERR unknown command '%s'", command))
 }
+
+// serve runs the handler and records its latency. Unknown commands are not
+// recorded to keep the number of histograms bounded.
+func (m *ServeMux) serve(command string, handler redcon.Handler, conn redcon.Conn, cmd redcon.Command) {
+	start := time.Now()
+	handler.ServeRESP(conn, cmd)
+	CommandLatency.WithLabel(command).Observe(time.Since(start).Seconds())
+}
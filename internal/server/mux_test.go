@@ -18,6 +18,7 @@ import (
 	"context"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/buraksezer/olric/internal/protocol"
 	"github.com/redis/go-redis/v9"
@@ -52,3 +53,24 @@ func TestMux_PubSub_Command(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, int64(10), num)
 }
+
+func TestMux_CommandLatency(t *testing.T) {
+	s := newServer(t)
+
+	s.ServeMux().HandleFunc("mux.latency.test", func(conn redcon.Conn, cmd redcon.Command) {
+		conn.WriteString(protocol.StatusOK)
+	})
+
+	<-s.StartedCtx.Done()
+
+	rdb := redis.NewClient(defaultRedisOptions(s.config))
+
+	ctx := context.Background()
+	cmd := redis.NewStatusCmd(ctx, "mux.latency.test")
+	require.NoError(t, rdb.Process(ctx, cmd))
+
+	// The latency is recorded after the reply is written.
+	require.Eventually(t, func() bool {
+		return CommandLatency.WithLabel("mux.latency.test").Snapshot().Count == 1
+	}, time.Second, 10*time.Millisecond)
+}
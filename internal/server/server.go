@@ -16,15 +16,20 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/buraksezer/olric/config"
 	"github.com/buraksezer/olric/internal/checkpoint"
+	"github.com/buraksezer/olric/internal/protocol"
 	"github.com/buraksezer/olric/internal/stats"
+	"github.com/buraksezer/olric/internal/tracing"
 	"github.com/buraksezer/olric/pkg/flog"
 	"github.com/tidwall/redcon"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -42,6 +47,9 @@ var (
 
 	// ReadBytesTotal is total number of bytes read by this server from network.
 	ReadBytesTotal = stats.NewInt64Counter()
+
+	// CommandLatency is the latency of the commands in seconds, broken down by command name.
+	CommandLatency = stats.NewHistogramVec(stats.DefaultLatencyBuckets)
 )
 
 // Config is a composite type to bundle configuration parameters.
@@ -50,6 +58,19 @@ type Config struct {
 	BindPort        int
 	KeepAlivePeriod time.Duration
 	IdleClose       time.Duration
+
+	// ACL denotes the users and their permissions. Authentication is disabled
+	// if it's nil or empty.
+	ACL *config.ACL
+
+	// TLS enables TLS on the listener, if it's not nil.
+	TLS *tls.Config
+
+	// MutualTLS requires a verified client certificate to run the internal commands.
+	MutualTLS bool
+
+	// TracerProvider creates a span for every command. Tracing is disabled if it's nil.
+	TracerProvider trace.TracerProvider
 }
 
 type ConnWrapper struct {
@@ -103,6 +124,7 @@ type Server struct {
 	config     *Config
 	mux        *ServeMux
 	wmux       *ServeMuxWrapper
+	acl        *acl
 	server     *redcon.Server
 	log        *flog.Logger
 	listener   *ListenerWrapper
@@ -125,6 +147,7 @@ func New(c *Config, l *flog.Logger) *Server {
 	s := &Server{
 		config:     c,
 		mux:        NewServeMux(),
+		acl:        newACL(c.ACL),
 		log:        l,
 		started:    started,
 		StartedCtx: startedCtx,
@@ -132,7 +155,13 @@ func New(c *Config, l *flog.Logger) *Server {
 		ctx:        ctx,
 		cancel:     cancel,
 	}
-	s.wmux = &ServeMuxWrapper{mux: s.mux}
+	s.wmux = &ServeMuxWrapper{mux: s.mux, acl: s.acl, mutualTLS: c.MutualTLS}
+	if c.TracerProvider != nil {
+		s.wmux.tracer = tracing.Tracer(c.TracerProvider)
+	}
+	// AUTH is handled by the server itself. It's the only command that an
+	// unauthenticated client can run.
+	s.mux.HandleFunc(protocol.Generic.Auth, redcon.HandlerFunc(s.authCommandHandler))
 	return s
 }
 
@@ -166,6 +195,12 @@ func (s *Server) ListenAndServe() error {
 	defer close(s.stopped)
 	s.listener = lw
 
+	var ln net.Listener = lw
+	if s.config.TLS != nil {
+		// ListenerWrapper sets the TCP options, the TLS listener wraps it.
+		ln = tls.NewListener(lw, s.config.TLS)
+	}
+
 	srv := redcon.NewServer(addr,
 		s.mux.ServeRESP,
 		func(conn redcon.Conn) bool {
@@ -186,7 +221,7 @@ func (s *Server) ListenAndServe() error {
 	// The TCP server has been started
 	s.started()
 	checkpoint.Pass()
-	return s.server.Serve(lw)
+	return s.server.Serve(ln)
 }
 
 // Shutdown gracefully shuts down the server without interrupting any active connections.
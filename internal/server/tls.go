@@ -0,0 +1,45 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/tidwall/redcon"
+)
+
+// ErrCertificateRequired is returned when a client runs an internal command
+// without a verified client certificate.
+var ErrCertificateRequired = errors.New("verified client certificate required")
+
+func init() {
+	protocol.SetError("NOCERT", ErrCertificateRequired)
+}
+
+// hasVerifiedCertificate returns true if the client presented a certificate
+// which is verified with the configured CA.
+func hasVerifiedCertificate(conn redcon.Conn) bool {
+	nc := conn.NetConn()
+	if nc == nil {
+		return false
+	}
+	tc, ok := nc.(*tls.Conn)
+	if !ok {
+		return false
+	}
+	return len(tc.ConnectionState().VerifiedChains) > 0
+}
@@ -0,0 +1,166 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/testutil/tlstest"
+	"github.com/buraksezer/olric/internal/tlsutil"
+	"github.com/buraksezer/olric/pkg/flog"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/redcon"
+)
+
+func newServerWithTLS(t *testing.T, mutualTLS bool) (*Server, *tlstest.Files) {
+	dir, err := ioutil.TempDir("", "olric-tls")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(dir))
+	})
+	files, err := tlstest.Generate(dir)
+	require.NoError(t, err)
+
+	tlsConfig, err := tlsutil.ServerConfig(files.CertFile, files.KeyFile, files.CAFile)
+	require.NoError(t, err)
+
+	bindPort, err := getFreePort()
+	require.NoError(t, err)
+
+	l := log.New(os.Stdout, "server-test: ", log.LstdFlags)
+	fl := flog.New(l)
+	fl.SetLevel(6)
+	c := &Config{
+		BindAddr:        "127.0.0.1",
+		BindPort:        bindPort,
+		KeepAlivePeriod: time.Second,
+		TLS:             tlsConfig,
+		MutualTLS:       mutualTLS,
+	}
+	s := New(c, fl)
+
+	ok := func(conn redcon.Conn, cmd redcon.Command) {
+		conn.WriteString(protocol.StatusOK)
+	}
+	s.ServeMux().HandleFunc(protocol.DMap.Destroy, ok)
+	s.ServeMux().HandleFunc(protocol.Internal.MoveFragment, ok)
+	s.ServeMux().HandleFunc(protocol.DMap.PutEntry, ok)
+	s.ServeMux().HandleFunc(protocol.PubSub.PublishRouted, ok)
+
+	go func() {
+		require.NoError(t, s.ListenAndServe())
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, s.Shutdown(context.Background()))
+	})
+	<-s.StartedCtx.Done()
+	return s, files
+}
+
+func tlsRedisOptions(t *testing.T, s *Server, files *tlstest.Files, withCert bool) *redis.Options {
+	var certFile, keyFile string
+	if withCert {
+		certFile, keyFile = files.ClientCertFile, files.ClientKeyFile
+	}
+	tlsConfig, err := tlsutil.ClientConfig(certFile, keyFile, files.CAFile, "")
+	require.NoError(t, err)
+
+	opt := defaultRedisOptions(s.config)
+	opt.TLSConfig = tlsConfig
+	return opt
+}
+
+func TestServer_TLS(t *testing.T) {
+	s, files := newServerWithTLS(t, false)
+
+	rdb := redis.NewClient(tlsRedisOptions(t, s, files, false))
+	ctx := context.Background()
+	require.NoError(t, rdb.Process(ctx, protocol.NewDestroy("mydmap").Command(ctx)))
+
+	t.Run("Plain TCP client", func(t *testing.T) {
+		opt := defaultRedisOptions(s.config)
+		opt.MaxRetries = -1
+		plain := redis.NewClient(opt)
+		require.Error(t, plain.Process(ctx, protocol.NewDestroy("mydmap").Command(ctx)))
+	})
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	s, files := newServerWithTLS(t, true)
+	ctx := context.Background()
+
+	t.Run("Without client certificate", func(t *testing.T) {
+		rdb := redis.NewClient(tlsRedisOptions(t, s, files, false))
+		// Only the internal commands require a client certificate.
+		require.NoError(t, rdb.Process(ctx, protocol.NewDestroy("mydmap").Command(ctx)))
+
+		err := rdb.Process(ctx, protocol.NewMoveFragment([]byte("payload")).Command(ctx))
+		require.ErrorIs(t, protocol.ConvertError(err), ErrCertificateRequired)
+
+		err = rdb.Process(ctx, protocol.NewPutEntry("mydmap", "mykey", []byte("value")).Command(ctx))
+		require.ErrorIs(t, protocol.ConvertError(err), ErrCertificateRequired)
+
+		err = rdb.Process(ctx, redis.NewIntCmd(ctx, protocol.PubSub.PublishRouted, "mychannel", "message"))
+		require.ErrorIs(t, protocol.ConvertError(err), ErrCertificateRequired)
+	})
+
+	t.Run("With client certificate", func(t *testing.T) {
+		rdb := redis.NewClient(tlsRedisOptions(t, s, files, true))
+		require.NoError(t, rdb.Process(ctx, protocol.NewMoveFragment([]byte("payload")).Command(ctx)))
+		require.NoError(t, rdb.Process(ctx, protocol.NewPutEntry("mydmap", "mykey", []byte("value")).Command(ctx)))
+	})
+}
+
+func TestServer_TLS_Certificate_Reload(t *testing.T) {
+	s, files := newServerWithTLS(t, false)
+
+	dial := func(rootCAFile string) error {
+		pool, err := tlsutil.LoadCertPool(rootCAFile)
+		require.NoError(t, err)
+		conn, err := tls.Dial("tcp", s.listener.Addr().String(), &tls.Config{RootCAs: pool})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	require.NoError(t, dial(files.CAFile))
+
+	// Rotate the server certificate. It's signed by a different CA.
+	dir, err := ioutil.TempDir("", "olric-tls")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	other, err := tlstest.Generate(dir)
+	require.NoError(t, err)
+	for src, dst := range map[string]string{other.CertFile: files.CertFile, other.KeyFile: files.KeyFile} {
+		data, err := ioutil.ReadFile(src)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(dst, data, 0600))
+		modTime := time.Now().Add(time.Second)
+		require.NoError(t, os.Chtimes(dst, modTime, modTime))
+	}
+
+	require.Error(t, dial(files.CAFile))
+	require.NoError(t, dial(other.CAFile))
+}
@@ -0,0 +1,42 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+
+	"github.com/tidwall/redcon"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedConn carries the context of a traced command to its handler. ServeMux
+// sets the traceparent of the caller, and Handler replaces it with the context
+// of the span.
+type tracedConn struct {
+	redcon.Conn
+	traceparent []byte
+	ctx         context.Context
+}
+
+// RequestContext returns parent with the span of the command that is served
+// on conn, if the command is traced. Handlers should pass it to the operations
+// that they run on behalf of the command to propagate the trace context.
+func RequestContext(conn redcon.Conn, parent context.Context) context.Context {
+	tc, ok := conn.(*tracedConn)
+	if !ok || tc.ctx == nil {
+		return parent
+	}
+	return trace.ContextWithSpan(parent, trace.SpanFromContext(tc.ctx))
+}
@@ -0,0 +1,116 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/tracing"
+	"github.com/buraksezer/olric/pkg/flog"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/redcon"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestServer_Tracing(t *testing.T) {
+	bindPort, err := getFreePort()
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	fl := flog.New(log.New(os.Stdout, "server-test: ", log.LstdFlags))
+	c := &Config{
+		BindAddr:        "127.0.0.1",
+		BindPort:        bindPort,
+		KeepAlivePeriod: time.Second,
+		TracerProvider:  tp,
+	}
+	s := New(c, fl)
+
+	var args [][]byte
+	var handlerSpan trace.SpanContext
+	s.ServeMux().HandleFunc(protocol.DMap.Get, func(conn redcon.Conn, cmd redcon.Command) {
+		args = cmd.Args
+		handlerSpan = trace.SpanContextFromContext(RequestContext(conn, context.Background()))
+		conn.WriteString(protocol.StatusOK)
+	})
+
+	go func() {
+		require.NoError(t, s.ListenAndServe())
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, s.Shutdown(context.Background()))
+	})
+	<-s.StartedCtx.Done()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	defer parent.End()
+
+	rdb := redis.NewClient(defaultRedisOptions(c))
+	cmd := redis.NewStatusCmd(ctx, tracing.Inject(ctx, []interface{}{protocol.DMap.Get, "mydmap", "mykey"})...)
+	require.NoError(t, rdb.Process(ctx, cmd))
+
+	// The trace context is removed before running the handler.
+	require.Equal(t, [][]byte{[]byte(protocol.DMap.Get), []byte("mydmap"), []byte("mykey")}, args)
+
+	require.Eventually(t, func() bool {
+		return len(recorder.Ended()) == 1
+	}, time.Second, 10*time.Millisecond)
+	span := recorder.Ended()[0]
+	require.Equal(t, protocol.DMap.Get, span.Name())
+	require.Equal(t, trace.SpanKindServer, span.SpanKind())
+	require.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+	require.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	require.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID())
+}
+
+func TestServer_Tracing_Disabled(t *testing.T) {
+	s := newServer(t)
+
+	var args [][]byte
+	var handlerSpan trace.SpanContext
+	s.ServeMux().HandleFunc(protocol.DMap.Put, func(conn redcon.Conn, cmd redcon.Command) {
+		args = cmd.Args
+		handlerSpan = trace.SpanContextFromContext(RequestContext(conn, context.Background()))
+		conn.WriteString(protocol.StatusOK)
+	})
+	<-s.StartedCtx.Done()
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	defer parent.End()
+
+	// The last arguments look like a trace context, but they are the key and
+	// the value.
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	rdb := redis.NewClient(defaultRedisOptions(s.config))
+	cmd := redis.NewStatusCmd(ctx, tracing.Inject(ctx, []interface{}{protocol.DMap.Put, "mydmap", "TRACEPARENT", traceparent})...)
+	require.NoError(t, rdb.Process(ctx, cmd))
+
+	require.Equal(t, [][]byte{
+		[]byte(protocol.DMap.Put), []byte("mydmap"), []byte("TRACEPARENT"), []byte(traceparent),
+	}, args)
+	// The trace context is not extracted without a TracerProvider.
+	require.False(t, handlerSpan.IsValid())
+}
@@ -0,0 +1,123 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the latency histograms in seconds.
+var DefaultLatencyBuckets = []float64{
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// Histogram samples observations and counts them in configurable buckets.
+type Histogram struct {
+	upperBounds []float64
+	buckets     []int64
+	count       int64
+	sum         uint64 // bits of a float64
+}
+
+// NewHistogram returns a new Histogram. upperBounds must be sorted in increasing order.
+func NewHistogram(upperBounds []float64) *Histogram {
+	return &Histogram{
+		upperBounds: upperBounds,
+		buckets:     make([]int64, len(upperBounds)),
+	}
+}
+
+// Observe adds a single observation to the histogram.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upperBounds, v)
+	if i < len(h.buckets) {
+		atomic.AddInt64(&h.buckets[i], 1)
+	}
+	atomic.AddInt64(&h.count, 1)
+	for {
+		old := atomic.LoadUint64(&h.sum)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&h.sum, old, sum) {
+			return
+		}
+	}
+}
+
+// HistogramSnapshot is a point-in-time copy of a Histogram.
+type HistogramSnapshot struct {
+	// UpperBounds are the upper bounds of the buckets.
+	UpperBounds []float64
+
+	// Cumulative is the number of observations that are less than or equal to
+	// the upper bound of the bucket with the same index.
+	Cumulative []int64
+
+	// Count is the total number of observations.
+	Count int64
+
+	// Sum is the sum of all observations.
+	Sum float64
+}
+
+// Snapshot returns the current state of the histogram.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		UpperBounds: h.upperBounds,
+		Cumulative:  make([]int64, len(h.buckets)),
+		Count:       atomic.LoadInt64(&h.count),
+		Sum:         math.Float64frombits(atomic.LoadUint64(&h.sum)),
+	}
+	var total int64
+	for i := range h.buckets {
+		total += atomic.LoadInt64(&h.buckets[i])
+		s.Cumulative[i] = total
+	}
+	return s
+}
+
+// HistogramVec bundles a set of histograms that share the same buckets but
+// have different labels, such as command names.
+type HistogramVec struct {
+	upperBounds []float64
+	histograms  sync.Map
+}
+
+// NewHistogramVec returns a new HistogramVec.
+func NewHistogramVec(upperBounds []float64) *HistogramVec {
+	return &HistogramVec{
+		upperBounds: upperBounds,
+	}
+}
+
+// WithLabel returns the histogram for the given label, creating it if it's required.
+func (v *HistogramVec) WithLabel(label string) *Histogram {
+	h, ok := v.histograms.Load(label)
+	if ok {
+		return h.(*Histogram)
+	}
+	h, _ = v.histograms.LoadOrStore(label, NewHistogram(v.upperBounds))
+	return h.(*Histogram)
+}
+
+// Range calls f sequentially for each label and histogram. If f returns
+// false, Range stops the iteration.
+func (v *HistogramVec) Range(f func(label string, h *Histogram) bool) {
+	v.histograms.Range(func(key, value interface{}) bool {
+		return f(key.(string), value.(*Histogram))
+	})
+}
@@ -62,3 +62,36 @@ func TestUint64Gauge(t *testing.T) {
 
 	require.Equal(t, int64(80), g.Read())
 }
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 2, 5})
+
+	var wg sync.WaitGroup
+	for _, v := range []float64{0.5, 1, 1.5, 3, 10} {
+		wg.Add(1)
+		go func(v float64) {
+			defer wg.Done()
+			h.Observe(v)
+		}(v)
+	}
+	wg.Wait()
+
+	s := h.Snapshot()
+	require.Equal(t, []int64{2, 3, 4}, s.Cumulative)
+	require.Equal(t, int64(5), s.Count)
+	require.Equal(t, 16.0, s.Sum)
+}
+
+func TestHistogramVec(t *testing.T) {
+	v := NewHistogramVec([]float64{1})
+	v.WithLabel("get").Observe(0.5)
+	v.WithLabel("get").Observe(2)
+	v.WithLabel("put").Observe(0.5)
+
+	counts := make(map[string]int64)
+	v.Range(func(label string, h *Histogram) bool {
+		counts[label] = h.Snapshot().Count
+		return true
+	})
+	require.Equal(t, map[string]int64{"get": 2, "put": 1}, counts)
+}
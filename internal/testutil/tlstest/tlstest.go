@@ -0,0 +1,139 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tlstest generates certificates for tests.
+package tlstest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+// Files contains the paths of the generated PEM files.
+type Files struct {
+	CAFile         string
+	CertFile       string
+	KeyFile        string
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+type keyPair struct {
+	cert *x509.Certificate
+	der  []byte
+	key  *ecdsa.PrivateKey
+}
+
+func newKeyPair(template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*keyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		// Self-signed
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &keyPair{cert: cert, der: der, key: key}, nil
+}
+
+func (k *keyPair) write(certFile, keyFile string) error {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: k.der})
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		return err
+	}
+	if keyFile == "" {
+		return nil
+	}
+	der, err := x509.MarshalECPrivateKey(k.key)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	return ioutil.WriteFile(keyFile, keyPEM, 0600)
+}
+
+func template(serial int64, cn string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+}
+
+// Generate creates a CA, a server certificate for 127.0.0.1 and localhost and
+// a client certificate in dir. Both of the certificates are signed by the CA.
+func Generate(dir string) (*Files, error) {
+	caTemplate := template(1, "olric-test-ca")
+	caTemplate.IsCA = true
+	caTemplate.BasicConstraintsValid = true
+	caTemplate.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	ca, err := newKeyPair(caTemplate, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	serverTemplate := template(2, "olric-test-server")
+	serverTemplate.KeyUsage = x509.KeyUsageDigitalSignature
+	serverTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	serverTemplate.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	serverTemplate.DNSNames = []string{"localhost"}
+	server, err := newKeyPair(serverTemplate, ca.cert, ca.key)
+	if err != nil {
+		return nil, err
+	}
+
+	clientTemplate := template(3, "olric-test-client")
+	clientTemplate.KeyUsage = x509.KeyUsageDigitalSignature
+	clientTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	client, err := newKeyPair(clientTemplate, ca.cert, ca.key)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Files{
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		CertFile:       filepath.Join(dir, "server.pem"),
+		KeyFile:        filepath.Join(dir, "server-key.pem"),
+		ClientCertFile: filepath.Join(dir, "client.pem"),
+		ClientKeyFile:  filepath.Join(dir, "client-key.pem"),
+	}
+	if err = ca.write(f.CAFile, ""); err != nil {
+		return nil, err
+	}
+	if err = server.write(f.CertFile, f.KeyFile); err != nil {
+		return nil, err
+	}
+	if err = client.write(f.ClientCertFile, f.ClientKeyFile); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
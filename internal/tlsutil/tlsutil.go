@@ -0,0 +1,172 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tlsutil provides helpers to load and reload TLS certificates from disk.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// LoadCertPool reads the PEM encoded certificates in caFile and returns a new pool.
+func LoadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no valid certificate found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// Reloader loads a certificate and its key from disk. It reloads them on
+// the next handshake if one of the files has been modified, so certificates
+// can be rotated without restarting the process.
+type Reloader struct {
+	mu sync.RWMutex
+
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// NewReloader loads the certificate and the key and returns a new Reloader.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func modTimes(certFile, keyFile string) (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+func (r *Reloader) reload() error {
+	certModTime, keyModTime, err := modTimes(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	modified := !certModTime.Equal(r.certModTime) || !keyModTime.Equal(r.keyModTime)
+	r.mu.RUnlock()
+	if !modified {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.certModTime = certModTime
+	r.keyModTime = keyModTime
+	return nil
+}
+
+func (r *Reloader) certificate() (*tls.Certificate, error) {
+	// The files may be in the middle of a rotation. Keep using the
+	// current certificate until both of them are valid.
+	_ = r.reload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// GetCertificate can be used as tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate()
+}
+
+// GetClientCertificate can be used as tls.Config.GetClientCertificate.
+func (r *Reloader) GetClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.certificate()
+}
+
+// ServerConfig returns a TLS configuration for servers. If caFile is not empty,
+// client certificates are verified with it if they are given.
+func ServerConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	c := &tls.Config{
+		GetCertificate: r.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if caFile != "" {
+		pool, err := LoadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return c, nil
+}
+
+// ClientConfig returns a TLS configuration for clients. certFile and keyFile
+// are optional, they are required for mutual TLS. Server certificates are verified
+// with caFile, if it's not empty.
+func ClientConfig(certFile, keyFile, caFile, serverName string) (*tls.Config, error) {
+	c := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if certFile != "" || keyFile != "" {
+		r, err := NewReloader(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		c.GetClientCertificate = r.GetClientCertificate
+	}
+	if caFile != "" {
+		pool, err := LoadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		c.RootCAs = pool
+	}
+	return c, nil
+}
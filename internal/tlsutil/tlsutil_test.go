@@ -0,0 +1,106 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsutil
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/internal/testutil/tlstest"
+	"github.com/stretchr/testify/require"
+)
+
+func generate(t *testing.T) *tlstest.Files {
+	dir, err := ioutil.TempDir("", "olric-tls")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(dir))
+	})
+	f, err := tlstest.Generate(dir)
+	require.NoError(t, err)
+	return f
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	data, err := ioutil.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(dst, data, 0600))
+}
+
+func TestReloader(t *testing.T) {
+	f := generate(t)
+	r, err := NewReloader(f.CertFile, f.KeyFile)
+	require.NoError(t, err)
+
+	first, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+
+	// Rotate the certificate
+	other := generate(t)
+	copyFile(t, other.CertFile, f.CertFile)
+	copyFile(t, other.KeyFile, f.KeyFile)
+	modTime := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(f.CertFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(f.KeyFile, modTime, modTime))
+
+	second, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	require.NotEqual(t, first.Certificate[0], second.Certificate[0])
+
+	// Broken files don't replace the current certificate.
+	require.NoError(t, ioutil.WriteFile(f.KeyFile, []byte("broken"), 0600))
+	require.NoError(t, os.Chtimes(f.KeyFile, modTime.Add(time.Second), modTime.Add(time.Second)))
+	third, err := r.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, second.Certificate[0], third.Certificate[0])
+}
+
+func TestServerConfig(t *testing.T) {
+	f := generate(t)
+	c, err := ServerConfig(f.CertFile, f.KeyFile, f.CAFile)
+	require.NoError(t, err)
+	require.NotNil(t, c.ClientCAs)
+
+	cert, err := c.GetCertificate(nil)
+	require.NoError(t, err)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	pool, err := LoadCertPool(f.CAFile)
+	require.NoError(t, err)
+	_, err = parsed.Verify(x509.VerifyOptions{Roots: pool, DNSName: "localhost"})
+	require.NoError(t, err)
+}
+
+func TestClientConfig(t *testing.T) {
+	f := generate(t)
+	c, err := ClientConfig(f.ClientCertFile, f.ClientKeyFile, f.CAFile, "localhost")
+	require.NoError(t, err)
+	require.Equal(t, "localhost", c.ServerName)
+	require.NotNil(t, c.RootCAs)
+
+	cert, err := c.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.NotNil(t, cert)
+}
+
+func TestLoadCertPool_Invalid(t *testing.T) {
+	f := generate(t)
+	_, err := LoadCertPool(f.KeyFile)
+	require.Error(t, err)
+}
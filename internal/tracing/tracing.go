@@ -0,0 +1,91 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*Package tracing propagates OpenTelemetry trace context through the RESP commands.*/
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/util"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	// TracerName is the instrumentation name of the spans created by Olric.
+	TracerName = "github.com/buraksezer/olric"
+
+	// Command is the prefix of a command that carries the W3C traceparent of
+	// the caller's span: OLRIC.TRACE <traceparent> <command> <args>. The prefix
+	// takes the place of the command name, so it never collides with the
+	// arguments of the command.
+	Command = "olric.trace"
+
+	traceparentHeader = "traceparent"
+)
+
+var propagator = propagation.TraceContext{}
+
+// Tracer returns a tracer created by the given provider. It returns a no-op
+// tracer if the provider is nil, so tracing is disabled by default.
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(TracerName)
+}
+
+// Inject prefixes the arguments of a command with the trace context in ctx.
+// The arguments are returned as-is if ctx doesn't carry a valid span context.
+func Inject(ctx context.Context, args []interface{}) []interface{} {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return args
+	}
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	traceparent := carrier.Get(traceparentHeader)
+	if traceparent == "" {
+		return args
+	}
+	traced := make([]interface{}, 0, len(args)+2)
+	traced = append(traced, Command, traceparent)
+	return append(traced, args...)
+}
+
+// Unwrap removes the prefix of a traced command. It returns false if the
+// command is not traced. The returned arguments are empty if the command has
+// no arguments after the prefix.
+func Unwrap(args [][]byte) ([]byte, [][]byte, bool) {
+	if len(args) == 0 || !strings.EqualFold(util.BytesToString(args[0]), Command) {
+		return nil, args, false
+	}
+	if len(args) < 3 {
+		return nil, nil, true
+	}
+	return args[1], args[2:], true
+}
+
+// Extract returns a context that carries the traceparent as the remote parent.
+// ctx is returned as-is if the traceparent is not valid.
+func Extract(ctx context.Context, traceparent []byte) context.Context {
+	carrier := propagation.MapCarrier{traceparentHeader: string(traceparent)}
+	sc := trace.SpanContextFromContext(propagator.Extract(ctx, carrier))
+	if !sc.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
@@ -0,0 +1,88 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newSpanContext(t *testing.T) trace.SpanContext {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+func toBytes(args []interface{}) [][]byte {
+	var result [][]byte
+	for _, arg := range args {
+		result = append(result, []byte(arg.(string)))
+	}
+	return result
+}
+
+func TestTracing_Inject_Extract(t *testing.T) {
+	sc := newSpanContext(t)
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	args := Inject(ctx, []interface{}{"dm.get", "mydmap", "mykey"})
+	require.Equal(t, []interface{}{
+		Command, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"dm.get", "mydmap", "mykey",
+	}, args)
+
+	traceparent, stripped, ok := Unwrap(toBytes(args))
+	require.True(t, ok)
+	require.Equal(t, [][]byte{[]byte("dm.get"), []byte("mydmap"), []byte("mykey")}, stripped)
+
+	remote := trace.SpanContextFromContext(Extract(context.Background(), traceparent))
+	require.True(t, remote.IsRemote())
+	require.Equal(t, sc.TraceID(), remote.TraceID())
+	require.Equal(t, sc.SpanID(), remote.SpanID())
+}
+
+func TestTracing_Inject_Without_Span(t *testing.T) {
+	args := []interface{}{"dm.get", "mydmap", "mykey"}
+	require.Equal(t, args, Inject(context.Background(), args))
+}
+
+func TestTracing_Unwrap_Regular_Arguments(t *testing.T) {
+	args := toBytes([]interface{}{
+		"dm.put", "mydmap", "TRACEPARENT", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	_, stripped, ok := Unwrap(args)
+	require.False(t, ok)
+	require.Equal(t, args, stripped)
+}
+
+func TestTracing_Unwrap_Without_Command(t *testing.T) {
+	_, stripped, ok := Unwrap(toBytes([]interface{}{Command, "traceparent"}))
+	require.True(t, ok)
+	require.Empty(t, stripped)
+}
+
+func TestTracing_Extract_Invalid(t *testing.T) {
+	ctx := Extract(context.Background(), []byte("myvalue"))
+	require.False(t, trace.SpanContextFromContext(ctx).IsValid())
+}
@@ -0,0 +1,362 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wal implements an append-only write-ahead log for DMap mutations.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Op denotes a logged operation.
+type Op uint8
+
+const (
+	// OpPut denotes a put operation. Value is an encoded storage entry.
+	OpPut = Op(1)
+
+	// OpDelete denotes a delete operation. Value is empty.
+	OpDelete = Op(2)
+
+	// OpExpire denotes an expire operation. Value is an encoded storage entry
+	// which carries the new TTL.
+	OpExpire = Op(3)
+
+	// OpDestroy denotes destroying a DMap. Key and Value are empty.
+	OpDestroy = Op(4)
+)
+
+// CRC32(uint32) | OP(uint8) | TIMESTAMP(int64) | DMAP_LENGTH(uint16) | KEY_LENGTH(uint16) | VALUE_LENGTH(uint32)
+const headerLength = 21
+
+// ErrCorruptedRecord denotes a partially written or broken record.
+var ErrCorruptedRecord = errors.New("corrupted record")
+
+// Record is a logged DMap mutation.
+//
+// On-disk layout for a record:
+//
+// CRC32(uint32) | OP(uint8) | TIMESTAMP(int64) | DMAP_LENGTH(uint16) | KEY_LENGTH(uint16) |
+// VALUE_LENGTH(uint32) | DMAP(bytes) | KEY(bytes) | VALUE(bytes)
+//
+// CRC32 covers everything after itself.
+type Record struct {
+	Op        Op
+	Timestamp int64
+	DMap      string
+	Key       string
+	Value     []byte
+}
+
+func (r *Record) encode() ([]byte, error) {
+	if len(r.DMap) > math.MaxUint16 || len(r.Key) > math.MaxUint16 {
+		return nil, fmt.Errorf("key or DMap name too large: %d, %d", len(r.DMap), len(r.Key))
+	}
+	buf := make([]byte, headerLength+len(r.DMap)+len(r.Key)+len(r.Value))
+	buf[4] = byte(r.Op)
+	binary.BigEndian.PutUint64(buf[5:], uint64(r.Timestamp))
+	binary.BigEndian.PutUint16(buf[13:], uint16(len(r.DMap)))
+	binary.BigEndian.PutUint16(buf[15:], uint16(len(r.Key)))
+	binary.BigEndian.PutUint32(buf[17:], uint32(len(r.Value)))
+	n := copy(buf[headerLength:], r.DMap)
+	n += copy(buf[headerLength+n:], r.Key)
+	copy(buf[headerLength+n:], r.Value)
+	binary.BigEndian.PutUint32(buf, crc32.ChecksumIEEE(buf[4:]))
+	return buf, nil
+}
+
+// readRecord reads the next record from r. It returns io.EOF if there is no
+// record left and ErrCorruptedRecord for a partially written or broken record.
+func readRecord(r io.Reader) (*Record, error) {
+	header := make([]byte, headerLength)
+	_, err := io.ReadFull(r, header)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, ErrCorruptedRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dmapLength := int(binary.BigEndian.Uint16(header[13:]))
+	keyLength := int(binary.BigEndian.Uint16(header[15:]))
+	valueLength := int(binary.BigEndian.Uint32(header[17:]))
+	body := make([]byte, dmapLength+keyLength+valueLength)
+	_, err = io.ReadFull(r, body)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, ErrCorruptedRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h := crc32.NewIEEE()
+	_, _ = h.Write(header[4:])
+	_, _ = h.Write(body)
+	if h.Sum32() != binary.BigEndian.Uint32(header) {
+		return nil, ErrCorruptedRecord
+	}
+
+	rec := &Record{
+		Op:        Op(header[4]),
+		Timestamp: int64(binary.BigEndian.Uint64(header[5:])),
+		DMap:      string(body[:dmapLength]),
+		Key:       string(body[dmapLength : dmapLength+keyLength]),
+	}
+	if valueLength > 0 {
+		rec.Value = body[dmapLength+keyLength:]
+	}
+	return rec, nil
+}
+
+// WAL is an append-only log file. It's safe for concurrent use.
+type WAL struct {
+	mu sync.Mutex
+
+	path       string
+	file       *os.File
+	syncWrites bool
+	dirty      bool
+	// size is the current size of the log file and baseSize is its size after
+	// the last compaction.
+	size     int64
+	baseSize int64
+}
+
+func openFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+}
+
+// Open opens or creates the log file at path. If syncWrites is true, every
+// Append call flushes the file to the disk before returning.
+func Open(path string, syncWrites bool) (*WAL, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &WAL{
+		path:       path,
+		file:       f,
+		syncWrites: syncWrites,
+		size:       info.Size(),
+	}, nil
+}
+
+// Append writes the record to the end of the log.
+func (w *WAL) Append(r *Record) error {
+	data, err := r.encode()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := w.file.Write(data)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if w.syncWrites {
+		return w.file.Sync()
+	}
+	w.dirty = true
+	return nil
+}
+
+// Sync flushes the log to the disk, if there is any unsynced write.
+func (w *WAL) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.dirty {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return nil
+}
+
+// readAll reads the records in the log. A corrupted tail, which is possibly left
+// by a crash during a write, is discarded.
+func (w *WAL) readAll() ([]*Record, bool, error) {
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+
+	var records []*Record
+	r := bufio.NewReader(w.file)
+	for {
+		rec, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			return records, false, nil
+		}
+		if errors.Is(err, ErrCorruptedRecord) {
+			return records, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// NeedsCompaction returns true if the log is larger than minSize and it has
+// doubled in size since the last compaction. It's similar to the automatic AOF
+// rewrite of Redis.
+func (w *WAL) NeedsCompaction(minSize int64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.size >= minSize && w.size >= 2*w.baseSize
+}
+
+// compactRecords removes the records which are superseded by a later put or delete
+// record of the same key. Expire records after the latest put or delete are kept.
+// A destroy record removes the previous records of the DMap and itself. The log is
+// replayed on empty fragments, so a delete record is removed with the following
+// expire records when no earlier put record of the key survives.
+func compactRecords(records []*Record) []*Record {
+	type key struct {
+		dmap string
+		key  string
+	}
+
+	destroyed := make(map[string]int)
+	for i, rec := range records {
+		if rec.Op == OpDestroy {
+			destroyed[rec.DMap] = i
+		}
+	}
+
+	live := make(map[key][]int)
+	for i, rec := range records {
+		if idx, ok := destroyed[rec.DMap]; ok && i <= idx {
+			continue
+		}
+		k := key{dmap: rec.DMap, key: rec.Key}
+		if rec.Op == OpExpire {
+			live[k] = append(live[k], i)
+			continue
+		}
+		live[k] = []int{i}
+	}
+
+	var indexes []int
+	for _, idx := range live {
+		if records[idx[0]].Op == OpDelete {
+			// The previous put records are removed, there is nothing to delete.
+			continue
+		}
+		indexes = append(indexes, idx...)
+	}
+	sort.Ints(indexes)
+
+	result := make([]*Record, 0, len(indexes))
+	for _, i := range indexes {
+		result = append(result, records[i])
+	}
+	return result
+}
+
+// Compact rewrites the log with the records that are still required to rebuild
+// the current state and returns them in the original order. It's similar to the
+// AOF rewrite of Redis.
+func (w *WAL) Compact() ([]*Record, bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	records, corrupted, err := w.readAll()
+	if err != nil {
+		return nil, false, err
+	}
+	records = compactRecords(records)
+
+	tmpPath := w.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, false, err
+	}
+	var size int64
+	bw := bufio.NewWriter(tmp)
+	for _, rec := range records {
+		data, err := rec.encode()
+		if err == nil {
+			_, err = bw.Write(data)
+			size += int64(len(data))
+		}
+		if err != nil {
+			_ = tmp.Close()
+			return nil, false, err
+		}
+	}
+	if err = bw.Flush(); err != nil {
+		_ = tmp.Close()
+		return nil, false, err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return nil, false, err
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, false, err
+	}
+
+	// Rename is atomic, the log is never left in a partially rewritten state.
+	if err = os.Rename(tmpPath, w.path); err != nil {
+		return nil, false, err
+	}
+	f, err := openFile(w.path)
+	if err != nil {
+		return nil, false, err
+	}
+	_ = w.file.Close()
+	w.file = f
+	w.dirty = false
+	w.size, w.baseSize = size, size
+	return records, corrupted, nil
+}
+
+// Close flushes the log to the disk and closes the file.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
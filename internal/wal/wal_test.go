@@ -0,0 +1,181 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func openWAL(t *testing.T) (*WAL, string) {
+	dir, err := ioutil.TempDir("", "olric-wal")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(dir))
+	})
+
+	path := filepath.Join(dir, "dmaps.wal")
+	w, err := Open(path, false)
+	require.NoError(t, err)
+	return w, path
+}
+
+func TestWAL_Append_Compact(t *testing.T) {
+	w, path := openWAL(t)
+
+	records := []*Record{
+		{Op: OpPut, Timestamp: 1, DMap: "mydmap", Key: "foo", Value: []byte("v1")},
+		{Op: OpPut, Timestamp: 2, DMap: "mydmap", Key: "bar", Value: []byte("v1")},
+		{Op: OpPut, Timestamp: 3, DMap: "mydmap", Key: "foo", Value: []byte("v2")},
+		{Op: OpExpire, Timestamp: 4, DMap: "mydmap", Key: "foo", Value: []byte("ttl")},
+		{Op: OpDelete, Timestamp: 5, DMap: "mydmap", Key: "bar"},
+		{Op: OpPut, Timestamp: 6, DMap: "other", Key: "foo", Value: []byte("v1")},
+	}
+	for _, rec := range records {
+		require.NoError(t, w.Append(rec))
+	}
+	require.NoError(t, w.Sync())
+	require.NoError(t, w.Close())
+
+	w, err := Open(path, true)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+
+	result, corrupted, err := w.Compact()
+	require.NoError(t, err)
+	require.False(t, corrupted)
+	// The delete record of bar is removed with the put record.
+	require.Equal(t, []*Record{records[2], records[3], records[5]}, result)
+
+	// Appending after compaction
+	rec := &Record{Op: OpPut, Timestamp: 7, DMap: "mydmap", Key: "bar", Value: []byte("v2")}
+	require.NoError(t, w.Append(rec))
+
+	result, _, err = w.Compact()
+	require.NoError(t, err)
+	require.Equal(t, []*Record{records[2], records[3], records[5], rec}, result)
+}
+
+func TestWAL_Corrupted_Tail(t *testing.T) {
+	w, path := openWAL(t)
+
+	rec := &Record{Op: OpPut, Timestamp: 1, DMap: "mydmap", Key: "foo", Value: []byte("v1")}
+	require.NoError(t, w.Append(rec))
+	require.NoError(t, w.Append(&Record{Op: OpPut, Timestamp: 2, DMap: "mydmap", Key: "bar", Value: []byte("v1")}))
+	require.NoError(t, w.Close())
+
+	// Simulate a partially written record.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+
+	w, err = Open(path, false)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+
+	result, corrupted, err := w.Compact()
+	require.NoError(t, err)
+	require.True(t, corrupted)
+	require.Equal(t, []*Record{rec}, result)
+
+	// The corrupted tail has been discarded.
+	result, corrupted, err = w.Compact()
+	require.NoError(t, err)
+	require.False(t, corrupted)
+	require.Equal(t, []*Record{rec}, result)
+}
+
+func TestWAL_Compact_Destroy(t *testing.T) {
+	w, _ := openWAL(t)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+
+	records := []*Record{
+		{Op: OpPut, Timestamp: 1, DMap: "mydmap", Key: "foo", Value: []byte("v1")},
+		{Op: OpPut, Timestamp: 2, DMap: "other", Key: "foo", Value: []byte("v1")},
+		{Op: OpDestroy, Timestamp: 3, DMap: "mydmap"},
+		{Op: OpPut, Timestamp: 4, DMap: "mydmap", Key: "bar", Value: []byte("v1")},
+	}
+	for _, rec := range records {
+		require.NoError(t, w.Append(rec))
+	}
+
+	result, _, err := w.Compact()
+	require.NoError(t, err)
+	require.Equal(t, []*Record{records[1], records[3]}, result)
+}
+
+func TestWAL_Compact_Delete(t *testing.T) {
+	w, _ := openWAL(t)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+
+	records := []*Record{
+		{Op: OpPut, Timestamp: 1, DMap: "mydmap", Key: "foo", Value: []byte("v1")},
+		{Op: OpDelete, Timestamp: 2, DMap: "mydmap", Key: "foo"},
+		{Op: OpExpire, Timestamp: 3, DMap: "mydmap", Key: "foo", Value: []byte("ttl")},
+		{Op: OpDelete, Timestamp: 4, DMap: "mydmap", Key: "bar"},
+		{Op: OpPut, Timestamp: 5, DMap: "mydmap", Key: "baz", Value: []byte("v1")},
+		{Op: OpDelete, Timestamp: 6, DMap: "mydmap", Key: "baz"},
+		{Op: OpPut, Timestamp: 7, DMap: "mydmap", Key: "baz", Value: []byte("v2")},
+	}
+	for _, rec := range records {
+		require.NoError(t, w.Append(rec))
+	}
+
+	result, _, err := w.Compact()
+	require.NoError(t, err)
+	require.Equal(t, []*Record{records[6]}, result)
+
+	// The deleted keys are not left in the log.
+	rec := &Record{Op: OpDelete, Timestamp: 8, DMap: "mydmap", Key: "baz"}
+	require.NoError(t, w.Append(rec))
+	result, _, err = w.Compact()
+	require.NoError(t, err)
+	require.Empty(t, result)
+}
+
+func TestWAL_NeedsCompaction(t *testing.T) {
+	w, _ := openWAL(t)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+
+	rec := &Record{Op: OpPut, Timestamp: 1, DMap: "mydmap", Key: "foo", Value: []byte("v1")}
+	data, err := rec.encode()
+	require.NoError(t, err)
+	minSize := int64(len(data) * 10)
+
+	for i := 0; i < 9; i++ {
+		require.NoError(t, w.Append(rec))
+	}
+	require.False(t, w.NeedsCompaction(minSize))
+	require.NoError(t, w.Append(rec))
+	require.True(t, w.NeedsCompaction(minSize))
+
+	_, _, err = w.Compact()
+	require.NoError(t, err)
+	require.False(t, w.NeedsCompaction(minSize))
+}
@@ -0,0 +1,241 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package olric
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/server"
+	"github.com/buraksezer/olric/internal/stats"
+	pstats "github.com/buraksezer/olric/stats"
+)
+
+// metricsContentType is the content type of Prometheus text exposition format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// metricsWriter writes metrics in Prometheus text exposition format.
+type metricsWriter struct {
+	w *bufio.Writer
+}
+
+func formatLabels(labels ...string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelValueEscaper.Replace(labels[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (m *metricsWriter) header(name, typ, help string) {
+	_, _ = fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (m *metricsWriter) sample(name string, value int64, labels ...string) {
+	_, _ = fmt.Fprintf(m.w, "%s%s %d\n", name, formatLabels(labels...), value)
+}
+
+func (m *metricsWriter) single(name, typ, help string, value int64) {
+	m.header(name, typ, help)
+	m.sample(name, value)
+}
+
+func (m *metricsWriter) histogram(name string, s stats.HistogramSnapshot, labels ...string) {
+	for i, upperBound := range s.UpperBounds {
+		m.sample(name+"_bucket", s.Cumulative[i], append(labels, "le", formatFloat(upperBound))...)
+	}
+	m.sample(name+"_bucket", s.Count, append(labels, "le", "+Inf")...)
+	_, _ = fmt.Fprintf(m.w, "%s_sum%s %s\n", name, formatLabels(labels...), formatFloat(s.Sum))
+	m.sample(name+"_count", s.Count, labels...)
+}
+
+func sortedPartitionIDs(partitions map[pstats.PartitionID]pstats.Partition) []pstats.PartitionID {
+	var ids []pstats.PartitionID
+	for id := range partitions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func sortedDMapNames(dmaps map[string]pstats.DMap) []string {
+	var names []string
+	for name := range dmaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writePartitionMetrics writes the per-partition and per-DMap gauges of
+// the partitions owned by this member.
+func (m *metricsWriter) writePartitionMetrics(s pstats.Stats) {
+	kinds := []struct {
+		name       string
+		partitions map[pstats.PartitionID]pstats.Partition
+	}{
+		{name: "primary", partitions: s.Partitions},
+		{name: "backup", partitions: s.Backups},
+	}
+
+	m.header("olric_partition_length", "gauge", "Number of entries in the partition.")
+	for _, kind := range kinds {
+		for _, id := range sortedPartitionIDs(kind.partitions) {
+			partID := strconv.FormatUint(uint64(id), 10)
+			m.sample("olric_partition_length", int64(kind.partitions[id].Length), "partition", partID, "kind", kind.name)
+		}
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value func(pstats.DMap) int
+	}{
+		{
+			name:  "olric_dmap_length",
+			help:  "Number of entries of the DMap in the partition.",
+			value: func(d pstats.DMap) int { return d.Length },
+		},
+		{
+			name:  "olric_dmap_num_tables",
+			help:  "Number of tables of the DMap in the partition.",
+			value: func(d pstats.DMap) int { return d.NumTables },
+		},
+		{
+			name:  "olric_dmap_allocated_bytes",
+			help:  "Allocated memory of the DMap in the partition.",
+			value: func(d pstats.DMap) int { return d.SlabInfo.Allocated },
+		},
+		{
+			name:  "olric_dmap_inuse_bytes",
+			help:  "Memory in use by the DMap in the partition.",
+			value: func(d pstats.DMap) int { return d.SlabInfo.Inuse },
+		},
+		{
+			name:  "olric_dmap_garbage_bytes",
+			help:  "Garbage memory of the DMap in the partition.",
+			value: func(d pstats.DMap) int { return d.SlabInfo.Garbage },
+		},
+	}
+	for _, gauge := range gauges {
+		m.header(gauge.name, "gauge", gauge.help)
+		for _, kind := range kinds {
+			for _, id := range sortedPartitionIDs(kind.partitions) {
+				partID := strconv.FormatUint(uint64(id), 10)
+				dmaps := kind.partitions[id].DMaps
+				for _, name := range sortedDMapNames(dmaps) {
+					m.sample(gauge.name, int64(gauge.value(dmaps[name])),
+						"dmap", name, "partition", partID, "kind", kind.name)
+				}
+			}
+		}
+	}
+}
+
+func (m *metricsWriter) writeCommandLatency() {
+	histograms := make(map[string]stats.HistogramSnapshot)
+	var commands []string
+	server.CommandLatency.Range(func(command string, h *stats.Histogram) bool {
+		histograms[command] = h.Snapshot()
+		commands = append(commands, command)
+		return true
+	})
+	sort.Strings(commands)
+
+	m.header("olric_command_duration_seconds", "histogram", "Latency of the commands in seconds.")
+	for _, command := range commands {
+		m.histogram("olric_command_duration_seconds", histograms[command], "command", command)
+	}
+}
+
+// writeMetrics writes the metrics of this member in Prometheus text exposition format.
+func (db *Olric) writeMetrics(w io.Writer) error {
+	s := db.stats(statsConfig{})
+	m := &metricsWriter{w: bufio.NewWriter(w)}
+
+	m.single("olric_uptime_seconds", "counter", "Number of seconds since the server started.", s.UptimeSeconds)
+	m.single("olric_goroutines", "gauge", "Number of goroutines that currently exist.", int64(runtime.NumGoroutine()))
+	m.single("olric_cluster_members", "gauge", "Number of members in the cluster.", int64(len(s.ClusterMembers)))
+
+	m.single("olric_network_connections_total", "counter",
+		"Total number of connections opened since the server started running.", s.Network.ConnectionsTotal)
+	m.single("olric_network_current_connections", "gauge",
+		"Current number of open connections.", s.Network.CurrentConnections)
+	m.single("olric_network_written_bytes_total", "counter",
+		"Total number of bytes sent by this server to network.", s.Network.WrittenBytesTotal)
+	m.single("olric_network_read_bytes_total", "counter",
+		"Total number of bytes read by this server from network.", s.Network.ReadBytesTotal)
+	m.single("olric_network_commands_total", "counter",
+		"Total number of all requests.", s.Network.CommandsTotal)
+
+	m.single("olric_dmap_entries_total", "counter",
+		"Total number of entries (including replicas) stored during the life of this instance.", s.DMaps.EntriesTotal)
+	m.single("olric_dmap_get_hits_total", "counter",
+		"Number of entries that have been requested and found present.", s.DMaps.GetHits)
+	m.single("olric_dmap_get_misses_total", "counter",
+		"Number of entries that have been requested and not found.", s.DMaps.GetMisses)
+	m.single("olric_dmap_delete_hits_total", "counter",
+		"Number of deletion requests resulting in an item being removed.", s.DMaps.DeleteHits)
+	m.single("olric_dmap_delete_misses_total", "counter",
+		"Number of deletion requests for missing keys.", s.DMaps.DeleteMisses)
+	m.single("olric_dmap_evicted_total", "counter",
+		"Number of entries removed from cache to free memory for new entries.", s.DMaps.EvictedTotal)
+	m.single("olric_dmap_keyspace_events_dropped_total", "counter",
+		"Number of keyspace events dropped because the queue was full.", s.DMaps.KeyspaceEventsDropped)
+
+	m.single("olric_pubsub_published_total", "counter",
+		"Total number of published messages during the life of this instance.", s.PubSub.PublishedTotal)
+	m.single("olric_pubsub_current_subscribers", "gauge",
+		"Current number of subscribers.", s.PubSub.CurrentSubscribers)
+	m.single("olric_pubsub_subscribers_total", "counter",
+		"Total number of registered subscribers during the life of this instance.", s.PubSub.SubscribersTotal)
+	m.single("olric_pubsub_current_psubscribers", "gauge",
+		"Current number of pattern subscribers.", s.PubSub.CurrentPSubscribers)
+	m.single("olric_pubsub_psubscribers_total", "counter",
+		"Total number of registered pattern subscribers during the life of this instance.", s.PubSub.PSubscribersTotal)
+
+	m.writePartitionMetrics(s)
+	m.writeCommandLatency()
+
+	return m.w.Flush()
+}
+
+// MetricsHandler returns an http.Handler that exposes the metrics of this member
+// in Prometheus text exposition format. It includes the counters of the stats
+// command, per-partition and per-DMap gauges and the latency histograms of the
+// commands.
+func (db *Olric) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metricsContentType)
+		if err := db.writeMetrics(w); err != nil {
+			db.log.V(3).Printf("[ERROR] Failed to write metrics: %v", err)
+		}
+	})
+}
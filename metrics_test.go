@@ -0,0 +1,69 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package olric
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOlric_MetricsHandler(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, dm.Put(ctx, testutil.ToKey(i), i))
+	}
+	_, err = dm.Get(ctx, testutil.ToKey(0))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(db.MetricsHandler())
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, metricsContentType, resp.Header.Get("Content-Type"))
+
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	body := string(data)
+
+	require.Contains(t, body, "# TYPE olric_dmap_get_hits_total counter\n")
+	require.Contains(t, body, "# TYPE olric_partition_length gauge\n")
+	require.Regexp(t, `olric_dmap_length\{dmap="mydmap",partition="\d+",kind="primary"\} \d+`, body)
+	require.Contains(t, body, "# TYPE olric_command_duration_seconds histogram\n")
+	require.Regexp(t, `olric_command_duration_seconds_bucket\{command="dm.put",le="\+Inf"\} \d+`, body)
+	require.Regexp(t, `olric_command_duration_seconds_count\{command="dm.get"\} \d+`, body)
+}
+
+func TestOlric_Metrics_FormatLabels(t *testing.T) {
+	require.Equal(t, "", formatLabels())
+	require.Equal(t, `{dmap="a\"b\\c\n"}`, formatLabels("dmap", "a\"b\\c\n"))
+}
@@ -0,0 +1,151 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package olric
+
+import (
+	"strings"
+	"time"
+
+	"github.com/buraksezer/olric/internal/nearcache"
+	"github.com/redis/go-redis/v9"
+)
+
+// nearCacheResubscribeInterval is the delay between two attempts to subscribe
+// to the keyspace notifications again after the subscription is closed.
+const nearCacheResubscribeInterval = time.Second
+
+// enableNearCache creates the near cache and subscribes to the keyspace
+// notifications of the DMap to invalidate the cached keys.
+func (dm *ClusterDMap) enableNearCache() error {
+	ps, err := dm.subscribeNearCache()
+	if err != nil {
+		return err
+	}
+
+	dm.nearCache = nearcache.New(dm.config.nearCacheSize, dm.config.nearCacheTTL)
+	dm.clusterClient.wg.Add(1)
+	go dm.listenNearCacheInvalidations(ps)
+	return nil
+}
+
+// subscribeNearCache subscribes to the keyspace notifications of the DMap.
+func (dm *ClusterDMap) subscribeNearCache() (*redis.PubSub, error) {
+	rc, err := dm.client.Pick()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := dm.clusterClient.ctx
+	ps := rc.PSubscribe(ctx, KeyspaceChannel(dm.name, "*"))
+	// Wait for confirmation that subscription is created.
+	if _, err = ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, processProtocolError(err)
+	}
+	return ps, nil
+}
+
+// listenNearCacheInvalidations invalidates the cached keys until the client is
+// closed. The invalidations are lost while the subscription is down, so the
+// near cache is purged when it's lost, and the DMap subscribes again.
+func (dm *ClusterDMap) listenNearCacheInvalidations(ps *redis.PubSub) {
+	defer dm.clusterClient.wg.Done()
+
+	for {
+		dm.receiveNearCacheInvalidations(ps)
+		dm.nearCache.Purge()
+
+		ps = dm.resubscribeNearCache()
+		if ps == nil {
+			// The client is closed.
+			return
+		}
+		// The keys may have been cached before the subscription is
+		// created.
+		dm.nearCache.Purge()
+	}
+}
+
+// receiveNearCacheInvalidations returns when the subscription is closed or the
+// client is closed.
+func (dm *ClusterDMap) receiveNearCacheInvalidations(ps *redis.PubSub) {
+	defer func() {
+		if err := ps.Close(); err != nil {
+			dm.clusterClient.logger.Printf("[ERROR] Failed to close near cache subscription of %s: %v", dm.name, err)
+		}
+	}()
+
+	prefix := KeyspaceChannel(dm.name, "")
+	ch := ps.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-dm.clusterClient.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				// PubSub resubscribes after it reconnects. The invalidations
+				// are lost while the connection is down.
+				dm.nearCache.Purge()
+			case *redis.Message:
+				dm.nearCache.Invalidate(strings.TrimPrefix(msg.Channel, prefix))
+			}
+		}
+	}
+}
+
+// resubscribeNearCache subscribes to the keyspace notifications until it
+// succeeds. It returns nil if the client is closed.
+func (dm *ClusterDMap) resubscribeNearCache() *redis.PubSub {
+	for {
+		select {
+		case <-dm.clusterClient.ctx.Done():
+			return nil
+		default:
+		}
+
+		ps, err := dm.subscribeNearCache()
+		if err == nil {
+			return ps
+		}
+		dm.clusterClient.logger.Printf("[ERROR] Failed to subscribe to the keyspace notifications of %s: %v", dm.name, err)
+
+		select {
+		case <-dm.clusterClient.ctx.Done():
+			return nil
+		case <-time.After(nearCacheResubscribeInterval):
+		}
+	}
+}
+
+// invalidate removes the given keys from the near cache, if it's enabled.
+func (dm *ClusterDMap) invalidate(keys ...string) {
+	if dm.nearCache == nil {
+		return
+	}
+	for _, key := range keys {
+		dm.nearCache.Invalidate(key)
+	}
+}
+
+func (dm *ClusterDMap) purgeNearCache() {
+	if dm.nearCache == nil {
+		return
+	}
+	dm.nearCache.Purge()
+}
@@ -0,0 +1,206 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package olric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterClient_NearCache(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.DMaps.KeyspaceNotifications = []config.KeyspaceEvent{
+			config.KeyspaceEventPut,
+			config.KeyspaceEventExpire,
+			config.KeyspaceEventDel,
+			config.KeyspaceEventExpired,
+			config.KeyspaceEventEvicted,
+		}
+		return c
+	}
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMemberWithConfig(t, newConfig())
+	cluster.addMemberWithConfig(t, newConfig())
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap", WithNearCache(100, time.Minute))
+	require.NoError(t, err)
+	nc := dm.(*ClusterDMap).nearCache
+	require.NotNil(t, nc)
+
+	// The keyspace notification of a write may invalidate the key after
+	// a read fills the near cache.
+	waitForNearCache := func(key string) {
+		err := testutil.TryWithInterval(50, 100*time.Millisecond, func() error {
+			_, _ = dm.Get(ctx, key)
+			if _, ok := nc.Get(key); !ok {
+				return fmt.Errorf("%s is not in the near cache", key)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue"))
+	_, ok := nc.Get("mykey")
+	require.False(t, ok)
+
+	gr, err := dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+	value, err := gr.String()
+	require.NoError(t, err)
+	require.Equal(t, "myvalue", value)
+
+	waitForNearCache("mykey")
+
+	t.Run("Invalidate by another client", func(t *testing.T) {
+		e := db.NewEmbeddedClient()
+		edm, err := e.NewDMap("mydmap")
+		require.NoError(t, err)
+		require.NoError(t, edm.Put(ctx, "mykey", "newvalue"))
+
+		err = testutil.TryWithInterval(50, 100*time.Millisecond, func() error {
+			if _, ok := nc.Get("mykey"); ok {
+				return errors.New("mykey is still in the near cache")
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		gr, err := dm.Get(ctx, "mykey")
+		require.NoError(t, err)
+		value, err := gr.String()
+		require.NoError(t, err)
+		require.Equal(t, "newvalue", value)
+	})
+
+	t.Run("MGet", func(t *testing.T) {
+		require.NoError(t, dm.Put(ctx, "otherkey", "othervalue"))
+		result, failed := dm.MGet(ctx, "mykey", "otherkey", "missing")
+		require.Len(t, result, 2)
+		require.ErrorIs(t, failed["missing"], ErrKeyNotFound)
+
+		waitForNearCache("otherkey")
+		_, ok := nc.Get("missing")
+		require.False(t, ok)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		_, err := dm.Delete(ctx, "mykey")
+		require.NoError(t, err)
+		_, ok := nc.Get("mykey")
+		require.False(t, ok)
+
+		_, err = dm.Get(ctx, "mykey")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	})
+}
+
+func TestClusterClient_NearCache_Subscription_Lost(t *testing.T) {
+	cfg := testutil.NewConfig()
+	cfg.DMaps.KeyspaceNotifications = []config.KeyspaceEvent{config.KeyspaceEventPut}
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMemberWithConfig(t, cfg)
+
+	// Keep the connections of the client to drop them.
+	var mtx sync.Mutex
+	var conns []net.Conn
+	cc := config.NewClient()
+	cc.Dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+		if err == nil {
+			mtx.Lock()
+			conns = append(conns, conn)
+			mtx.Unlock()
+		}
+		return conn, err
+	}
+	require.NoError(t, cc.Sanitize())
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name}, WithConfig(cc))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap", WithNearCache(100, time.Minute))
+	require.NoError(t, err)
+	nc := dm.(*ClusterDMap).nearCache
+
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue"))
+	err = testutil.TryWithInterval(50, 100*time.Millisecond, func() error {
+		_, _ = dm.Get(ctx, "mykey")
+		if _, ok := nc.Get("mykey"); !ok {
+			return errors.New("mykey is not in the near cache")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	mtx.Lock()
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	mtx.Unlock()
+
+	// The invalidations may be lost while the subscription is down.
+	err = testutil.TryWithInterval(50, 100*time.Millisecond, func() error {
+		if _, ok := nc.Get("mykey"); ok {
+			return errors.New("mykey is still in the near cache")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The keys are invalidated again after the subscription is recovered.
+	e := db.NewEmbeddedClient()
+	edm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+	err = testutil.TryWithInterval(50, 100*time.Millisecond, func() error {
+		_, _ = dm.Get(ctx, "mykey")
+		if _, ok := nc.Get("mykey"); !ok {
+			return errors.New("mykey is not in the near cache")
+		}
+		if err := edm.Put(ctx, "mykey", "newvalue"); err != nil {
+			return err
+		}
+		gr, err := dm.Get(ctx, "mykey")
+		if err != nil {
+			return err
+		}
+		if value, _ := gr.String(); value != "newvalue" {
+			return fmt.Errorf("stale value: %s", value)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
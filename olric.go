@@ -97,6 +97,61 @@ var (
 	// ErrConnRefused returned if the target node refused a connection request.
 	// It is good to call RefreshMetadata to update the underlying data structures.
 	ErrConnRefused = errors.New("connection refused")
+
+	// ErrInvalidSnapshot returned if a snapshot file cannot be restored. The file
+	// may be corrupted or created by an incompatible cluster.
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+	// ErrAuthRequired returned if the server requires authentication. Set
+	// Username and Password fields of config.Client to authenticate.
+	ErrAuthRequired = errors.New("authentication required")
+
+	// ErrWrongPass returned if the username or the password is invalid.
+	ErrWrongPass = errors.New("invalid username-password pair")
+
+	// ErrNoPermission returned if the user is not allowed to run the command
+	// or to access the DMap.
+	ErrNoPermission = errors.New("user has no permissions to run this command")
+
+	// ErrVersionMismatch returned if the timestamp of the current entry doesn't
+	// match the expected one. See IfVersion and CompareAndSwap.
+	ErrVersionMismatch = errors.New("version mismatch")
+
+	// ErrWrongType returned if a command is run against a key holding a value
+	// of another type, such as a hash, a list, a sorted set, a stream or a lock.
+	ErrWrongType = errors.New("operation against a key holding the wrong kind of value")
+
+	// ErrHashValueNotInteger returned by HIncrBy if the field holds a value
+	// that is not an integer.
+	ErrHashValueNotInteger = errors.New("hash value is not an integer")
+
+	// ErrScoreNaN returned if the score of a sorted set member is not a number.
+	ErrScoreNaN = errors.New("score is not a number")
+
+	// ErrInvalidStreamID returned if the given ID is not a valid stream entry ID.
+	ErrInvalidStreamID = errors.New("invalid stream entry ID")
+
+	// ErrNoSuchGroup returned if the consumer group doesn't exist on the stream.
+	ErrNoSuchGroup = errors.New("no such consumer group")
+
+	// ErrCrossShard returned if the shard channels of a single SSubscribe call
+	// are owned by different cluster members.
+	ErrCrossShard = errors.New("shard channels are owned by different members")
+
+	// ErrScript returned if a script cannot be compiled or it fails. The
+	// error message contains the details.
+	ErrScript = errors.New("script error")
+
+	// ErrCrossPartition returned if the keys of a script belong to different
+	// partitions.
+	ErrCrossPartition = errors.New("keys belong to different partitions")
+
+	// ErrNoSuchIndex returned if the index is not declared for the DMap.
+	ErrNoSuchIndex = errors.New("no such index")
+
+	// ErrNoSuchProcessor returned if the entry processor is not registered on
+	// the partition owner of the key.
+	ErrNoSuchProcessor = errors.New("no such entry processor")
 )
 
 // Olric implements a distributed cache and in-memory key/value data store.
@@ -234,6 +289,16 @@ func New(c *config.Config) (*Olric, error) {
 		BindAddr:        c.BindAddr,
 		BindPort:        c.BindPort,
 		KeepAlivePeriod: c.KeepAlivePeriod,
+		ACL:             c.ACL,
+		MutualTLS:       c.TLS.MutualTLS,
+		TracerProvider:  c.TracerProvider,
+	}
+	if c.TLS.Enabled() {
+		rc.TLS, err = c.TLS.ServerConfig()
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load TLS configuration: %w", err)
+		}
 	}
 	srv := server.New(rc, flogger)
 	srv.SetPreConditionFunc(db.preconditionFunc)
@@ -265,6 +330,8 @@ func (db *Olric) registerCommandHandlers() {
 	db.server.ServeMux().HandleFunc(protocol.Cluster.RoutingTable, db.clusterRoutingTableCommandHandler)
 	db.server.ServeMux().HandleFunc(protocol.Generic.Stats, db.statsCommandHandler)
 	db.server.ServeMux().HandleFunc(protocol.Cluster.Members, db.clusterMembersCommandHandler)
+	db.server.ServeMux().HandleFunc(protocol.Cluster.Snapshot, db.clusterSnapshotCommandHandler)
+	db.server.ServeMux().HandleFunc(protocol.Cluster.Restore, db.clusterRestoreCommandHandler)
 }
 
 // callStartedCallback checks passed checkpoint count and calls the callback
@@ -471,6 +538,35 @@ func convertDMapError(err error) error {
 		return ErrKeyTooLarge
 	case errors.Is(err, dmap.ErrEntryTooLarge):
 		return ErrEntryTooLarge
+	case errors.Is(err, dmap.ErrInvalidSnapshot):
+		return ErrInvalidSnapshot
+	case errors.Is(err, dmap.ErrVersionMismatch):
+		return ErrVersionMismatch
+	case errors.Is(err, dmap.ErrWrongType):
+		return ErrWrongType
+	case errors.Is(err, dmap.ErrHashValueNotInteger):
+		return ErrHashValueNotInteger
+	case errors.Is(err, dmap.ErrScoreNaN):
+		return ErrScoreNaN
+	case errors.Is(err, dmap.ErrInvalidStreamID):
+		return ErrInvalidStreamID
+	case errors.Is(err, dmap.ErrNoSuchGroup):
+		return ErrNoSuchGroup
+	case errors.Is(err, dmap.ErrScript):
+		// Keep the details of the script error.
+		return fmt.Errorf("%w%s", ErrScript, strings.TrimPrefix(err.Error(), dmap.ErrScript.Error()))
+	case errors.Is(err, dmap.ErrCrossPartition):
+		return ErrCrossPartition
+	case errors.Is(err, dmap.ErrNoSuchIndex):
+		return ErrNoSuchIndex
+	case errors.Is(err, dmap.ErrNoSuchProcessor):
+		return ErrNoSuchProcessor
+	case errors.Is(err, server.ErrAuthRequired):
+		return ErrAuthRequired
+	case errors.Is(err, server.ErrWrongPass):
+		return ErrWrongPass
+	case errors.Is(err, server.ErrNoPermission):
+		return ErrNoPermission
 	default:
 		return convertClusterError(err)
 	}
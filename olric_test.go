@@ -17,12 +17,16 @@ package olric
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/buraksezer/olric/config"
 	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/buraksezer/olric/internal/testutil/tlstest"
+	"github.com/buraksezer/olric/internal/tlsutil"
 	"github.com/buraksezer/olric/stats"
 	"github.com/hashicorp/memberlist"
 	"github.com/stretchr/testify/require"
@@ -137,3 +141,55 @@ func TestOlricCluster_StartAndShutdown(t *testing.T) {
 		require.Contains(t, st.ClusterMembers, stats.MemberID(member.rt.This().ID))
 	}
 }
+
+func TestOlric_MutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "olric-tls")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	files, err := tlstest.Generate(dir)
+	require.NoError(t, err)
+
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.TLS = &config.TLS{
+			CertFile:  files.CertFile,
+			KeyFile:   files.KeyFile,
+			CAFile:    files.CAFile,
+			MutualTLS: true,
+		}
+		// Cluster members call each other with the client certificate.
+		c.Client.TLSConfig, err = tlsutil.ClientConfig(files.ClientCertFile, files.ClientKeyFile, files.CAFile, "")
+		require.NoError(t, err)
+		return c
+	}
+
+	cluster := newTestOlricCluster(t)
+	cluster.addMemberWithConfig(t, newConfig())
+	db := cluster.addMemberWithConfig(t, newConfig())
+
+	cc := config.NewClient()
+	cc.TLSConfig, err = tlsutil.ClientConfig("", "", files.CAFile, "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db.name}, WithConfig(cc))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, dm.Put(ctx, testutil.ToKey(i), i))
+	}
+	for i := 0; i < 10; i++ {
+		gr, err := dm.Get(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+		value, err := gr.Int()
+		require.NoError(t, err)
+		require.Equal(t, i, value)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mapstore defines the interface of the external data stores that
+// back DMaps.
+package mapstore
+
+import "errors"
+
+// ErrKeyNotFound is returned by Load if the key doesn't exist in the store.
+var ErrKeyNotFound = errors.New("key not found")
+
+// MapStore loads the missing keys of a DMap from an external data store, and
+// writes the changes of the DMap to it. The methods are called on the
+// partition owners of the keys concurrently, so the implementations have to
+// be thread-safe.
+//
+// Values are in the encoded form of Put. []byte and string values are stored
+// as is.
+type MapStore interface {
+	// Load returns the value of the key. It returns ErrKeyNotFound if the key
+	// doesn't exist.
+	Load(key string) ([]byte, error)
+
+	// LoadAll returns the values of the given keys. The missing keys are not
+	// included in the result.
+	LoadAll(keys []string) (map[string][]byte, error)
+
+	// Store writes the value of the key.
+	Store(key string, value []byte) error
+
+	// Delete deletes the key. Deleting a missing key is not an error.
+	Delete(key string) error
+}
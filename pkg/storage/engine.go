@@ -137,3 +137,25 @@ type Engine interface {
 	// It should not be possible to reuse a destroyed storage engine.
 	Destroy() error
 }
+
+// Configuration keys that are set by Olric before forking a storage engine instance
+// for a fragment. Persistent storage engines use them to find their data on disk.
+const (
+	// FragmentNameKey is the name of the fragment, such as "dmap.mydmap".
+	FragmentNameKey = "fragmentName"
+
+	// PartitionIDKey is the ID of the partition that hosts the fragment. It's an uint64.
+	PartitionIDKey = "partitionID"
+
+	// PartitionKindKey is the kind of the partition that hosts the fragment: "primary" or "backup".
+	PartitionKindKey = "partitionKind"
+)
+
+// Persistent is an optional interface for storage engine implementations that
+// keep their data on disk. Olric calls Persisted on startup to reopen the fragments
+// that are left by a previous run.
+type Persistent interface {
+	// Persisted returns configurations of the engine instances found on disk. Every
+	// configuration contains the fragment keys and can be passed to Fork.
+	Persisted() ([]*Config, error)
+}
@@ -18,17 +18,29 @@ import (
 	"context"
 	"strings"
 
+	"github.com/buraksezer/olric/internal/dmap"
 	"github.com/buraksezer/olric/internal/server"
 	"github.com/redis/go-redis/v9"
 )
 
+// KeyspaceChannel returns the name of the channel that keyspace notifications are
+// published on for the given key. Subscribe to __keyspace@<dmap>__:* pattern
+// to receive the events of all keys in a DMap. See config.KeyspaceEvent.
+func KeyspaceChannel(name, key string) string {
+	return dmap.KeyspaceChannel(name, key)
+}
+
+// channelPicker returns a client for the member that owns the shard channel.
+type channelPicker func(channel string) (*redis.Client, error)
+
 type PubSub struct {
 	config *pubsubConfig
 	rc     *redis.Client
 	client *server.Client
+	picker channelPicker
 }
 
-func newPubSub(client *server.Client, options ...PubSubOption) (*PubSub, error) {
+func newPubSub(client *server.Client, picker channelPicker, options ...PubSubOption) (*PubSub, error) {
 	var (
 		err error
 		rc  *redis.Client
@@ -52,6 +64,7 @@ func newPubSub(client *server.Client, options ...PubSubOption) (*PubSub, error)
 		config: &pc,
 		rc:     rc,
 		client: client,
+		picker: picker,
 	}, nil
 }
 
@@ -78,3 +91,37 @@ func (ps *PubSub) PubSubNumSub(ctx context.Context, channels ...string) (map[str
 func (ps *PubSub) PubSubNumPat(ctx context.Context) (int64, error) {
 	return ps.rc.PubSubNumPat(ctx).Result()
 }
+
+// SSubscribe subscribes the client to the given shard channels. The channels
+// are hashed to partitions, and the subscription is created on the owner of
+// the partitions. All channels have to be owned by the same member, otherwise
+// it returns ErrCrossShard.
+func (ps *PubSub) SSubscribe(ctx context.Context, channels ...string) (*redis.PubSub, error) {
+	if len(channels) == 0 {
+		return ps.rc.SSubscribe(ctx), nil
+	}
+
+	var rc *redis.Client
+	for _, channel := range channels {
+		owner, err := ps.picker(channel)
+		if err != nil {
+			return nil, err
+		}
+		if rc != nil && rc.Options().Addr != owner.Options().Addr {
+			return nil, ErrCrossShard
+		}
+		rc = owner
+	}
+	return rc.SSubscribe(ctx, channels...), nil
+}
+
+// SPublish posts the message to the given shard channel. The message is only
+// delivered to the subscribers on the owners of the channel's partition.
+// It returns the number of clients that received the message.
+func (ps *PubSub) SPublish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	rc, err := ps.picker(channel)
+	if err != nil {
+		return 0, err
+	}
+	return rc.SPublish(ctx, channel, message).Result()
+}
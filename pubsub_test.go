@@ -20,6 +20,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/testutil"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
 )
@@ -261,3 +263,129 @@ L:
 		}
 	}
 }
+
+func TestPubSub_SSubscribe_SPublish(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db1 := cluster.addMember(t)
+	db2 := cluster.addMember(t)
+	cluster.addMember(t)
+
+	ctx := context.Background()
+	c, err := NewClusterClient([]string{db1.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(ctx))
+	}()
+
+	ps1, err := c.NewPubSub()
+	require.NoError(t, err)
+
+	e := db2.NewEmbeddedClient()
+	ps2, err := e.NewPubSub()
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		channel := fmt.Sprintf("my-channel-%d", i)
+		rp, err := ps1.SSubscribe(ctx, channel)
+		require.NoError(t, err)
+
+		// Wait for confirmation that subscription is created before publishing anything.
+		msgi, err := rp.ReceiveTimeout(ctx, time.Second)
+		require.NoError(t, err)
+		subs := msgi.(*redis.Subscription)
+		require.Equal(t, "ssubscribe", subs.Kind)
+
+		count, err := ps2.SPublish(ctx, channel, "hello")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+
+		select {
+		case msg := <-rp.Channel():
+			require.Equal(t, channel, msg.Channel)
+			require.Equal(t, "hello", msg.Payload)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "No message received")
+		}
+		require.NoError(t, rp.Close())
+	}
+}
+
+func TestPubSub_SSubscribe_CrossShard(t *testing.T) {
+	cluster := newTestOlricCluster(t)
+	db1 := cluster.addMember(t)
+	cluster.addMember(t)
+
+	e := db1.NewEmbeddedClient()
+	ps, err := e.NewPubSub()
+	require.NoError(t, err)
+
+	owners := make(map[string]string)
+	for i := 0; len(owners) < 2; i++ {
+		channel := fmt.Sprintf("my-channel-%d", i)
+		rc, err := ps.picker(channel)
+		require.NoError(t, err)
+		owners[rc.Options().Addr] = channel
+	}
+
+	var channels []string
+	for _, channel := range owners {
+		channels = append(channels, channel)
+	}
+	_, err = ps.SSubscribe(context.Background(), channels...)
+	require.ErrorIs(t, err, ErrCrossShard)
+}
+
+func TestPubSub_KeyspaceNotifications(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.DMaps.KeyspaceNotifications = []config.KeyspaceEvent{
+			config.KeyspaceEventPut,
+			config.KeyspaceEventDel,
+			config.KeyspaceEventExpired,
+		}
+		return c
+	}
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMemberWithConfig(t, newConfig())
+	cluster.addMemberWithConfig(t, newConfig())
+
+	ctx := context.Background()
+	e := db.NewEmbeddedClient()
+	ps, err := e.NewPubSub()
+	require.NoError(t, err)
+
+	rp := ps.PSubscribe(ctx, "__keyspace@mydmap__:*")
+	defer func() {
+		require.NoError(t, rp.Close())
+	}()
+
+	// Wait for confirmation that subscription is created before publishing anything.
+	_, err = rp.ReceiveTimeout(ctx, time.Second)
+	require.NoError(t, err)
+
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue"))
+	_, err = dm.Delete(ctx, "mykey")
+	require.NoError(t, err)
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue", PX(time.Millisecond)))
+
+	ch := rp.Channel()
+	var received []string
+L:
+	for {
+		select {
+		case msg := <-ch:
+			require.Equal(t, KeyspaceChannel("mydmap", "mykey"), msg.Channel)
+			received = append(received, msg.Payload)
+			if len(received) == 4 {
+				break L
+			}
+		case <-time.After(10 * time.Second):
+			break L
+		}
+	}
+	// Events are published in background, the order is not guaranteed.
+	require.ElementsMatch(t, []string{"put", "del", "put", "expired"}, received)
+}
@@ -0,0 +1,105 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package olric
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/tidwall/redcon"
+)
+
+// Snapshot writes the DMap fragments of the primary partitions owned by this node
+// to dir. Every partition is written to a separate, versioned file. Call Snapshot
+// on every node to take a backup of the whole cluster.
+func (db *Olric) Snapshot(ctx context.Context, dir string) error {
+	if err := db.isOperable(); err != nil {
+		return err
+	}
+	return convertDMapError(db.dmap.Snapshot(ctx, dir))
+}
+
+// Restore reads the snapshot files in dir and imports the DMap fragments to their
+// current owners in the cluster. The DMaps have to use the same storage engines
+// and the cluster has to use the same partition count with the cluster that
+// created the snapshot. Existing keys are merged by timestamp, the latest version
+// wins. Snapshot files are written on the filesystem of every node, so call
+// Restore on every node to restore the whole cluster.
+func (db *Olric) Restore(ctx context.Context, dir string) error {
+	if err := db.isOperable(); err != nil {
+		return err
+	}
+	return convertDMapError(db.dmap.Restore(ctx, dir))
+}
+
+// snapshotDir resolves the directory argument of the cluster.snapshot and
+// cluster.restore commands relative to DMaps.SnapshotDir, so a client cannot
+// access an arbitrary path on the server.
+func (db *Olric) snapshotDir(dir string) (string, error) {
+	base := db.config.DMaps.SnapshotDir
+	if base == "" {
+		return "", fmt.Errorf("%w: snapshot directory is not configured", protocol.ErrInvalidArgument)
+	}
+	rel := filepath.Clean(dir)
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s is not in the snapshot directory", protocol.ErrInvalidArgument, dir)
+	}
+	return filepath.Join(base, rel), nil
+}
+
+func (db *Olric) clusterSnapshotCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	snapshotCmd, err := protocol.ParseClusterSnapshot(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dir, err := db.snapshotDir(snapshotCmd.Dir)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	err = db.Snapshot(db.ctx, dir)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteString(protocol.StatusOK)
+}
+
+func (db *Olric) clusterRestoreCommandHandler(conn redcon.Conn, cmd redcon.Command) {
+	restoreCmd, err := protocol.ParseClusterRestore(cmd)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	dir, err := db.snapshotDir(restoreCmd.Dir)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+
+	err = db.Restore(db.ctx, dir)
+	if err != nil {
+		protocol.WriteError(conn, err)
+		return
+	}
+	conn.WriteString(protocol.StatusOK)
+}
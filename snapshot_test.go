@@ -0,0 +1,127 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package olric
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buraksezer/olric/internal/protocol"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOlric_Snapshot_Restore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "olric-snapshot")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	e := db.NewEmbeddedClient()
+	dm, err := e.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		err = dm.Put(ctx, testutil.ToKey(i), testutil.ToVal(i))
+		require.NoError(t, err)
+	}
+
+	err = db.Snapshot(ctx, dir)
+	require.NoError(t, err)
+
+	freshCluster := newTestOlricCluster(t)
+	c := testutil.NewConfig()
+	c.DMaps.SnapshotDir = filepath.Dir(dir)
+	fresh := freshCluster.addMemberWithConfig(t, c)
+
+	// Restore the snapshot with the RESP command. The directory is relative
+	// to the snapshot directory.
+	cmd := protocol.NewClusterRestore(filepath.Base(dir)).Command(ctx)
+	rc := fresh.client.Get(fresh.rt.This().String())
+	err = rc.Process(ctx, cmd)
+	require.NoError(t, err)
+	require.NoError(t, cmd.Err())
+
+	fe := fresh.NewEmbeddedClient()
+	fdm, err := fe.NewDMap("mydmap")
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		gr, err := fdm.Get(ctx, testutil.ToKey(i))
+		require.NoError(t, err)
+		value, err := gr.Byte()
+		require.NoError(t, err)
+		require.Equal(t, testutil.ToVal(i), value)
+	}
+}
+
+func TestOlric_Restore_ErrInvalidSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "olric-snapshot")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	err = ioutil.WriteFile(dir+"/partition-00000.snapshot", []byte("foobar"), 0600)
+	require.NoError(t, err)
+
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMember(t)
+
+	err = db.Restore(context.Background(), dir)
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+}
+
+func TestOlric_Snapshot_Command_Directory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "olric-snapshot")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+	cluster := newTestOlricCluster(t)
+
+	t.Run("Snapshot directory is not configured", func(t *testing.T) {
+		db := cluster.addMember(t)
+		cmd := protocol.NewClusterSnapshot("backup").Command(ctx)
+		rc := db.client.Get(db.rt.This().String())
+		err := rc.Process(ctx, cmd)
+		require.ErrorIs(t, protocol.ConvertError(err), protocol.ErrInvalidArgument)
+	})
+
+	c := testutil.NewConfig()
+	c.DMaps.SnapshotDir = dir
+	db := cluster.addMemberWithConfig(t, c)
+	rc := db.client.Get(db.rt.This().String())
+
+	for _, path := range []string{"/tmp/backup", "..", "../backup", "backup/../../backup"} {
+		cmd := protocol.NewClusterSnapshot(path).Command(ctx)
+		err := rc.Process(ctx, cmd)
+		require.ErrorIs(t, protocol.ConvertError(err), protocol.ErrInvalidArgument, path)
+	}
+
+	cmd := protocol.NewClusterSnapshot("backup").Command(ctx)
+	require.NoError(t, rc.Process(ctx, cmd))
+	_, err = os.Stat(filepath.Join(dir, "backup"))
+	require.NoError(t, err)
+}
@@ -101,12 +101,13 @@ func (db *Olric) stats(cfg statsConfig) stats.Stats {
 			CommandsTotal:      server.CommandsTotal.Read(),
 		},
 		DMaps: stats.DMaps{
-			EntriesTotal: dmap.EntriesTotal.Read(),
-			DeleteHits:   dmap.DeleteHits.Read(),
-			DeleteMisses: dmap.DeleteMisses.Read(),
-			GetMisses:    dmap.GetMisses.Read(),
-			GetHits:      dmap.GetHits.Read(),
-			EvictedTotal: dmap.EvictedTotal.Read(),
+			EntriesTotal:          dmap.EntriesTotal.Read(),
+			DeleteHits:            dmap.DeleteHits.Read(),
+			DeleteMisses:          dmap.DeleteMisses.Read(),
+			GetMisses:             dmap.GetMisses.Read(),
+			GetHits:               dmap.GetHits.Read(),
+			EvictedTotal:          dmap.EvictedTotal.Read(),
+			KeyspaceEventsDropped: dmap.KeyspaceEventsDropped.Read(),
 		},
 		PubSub: stats.PubSub{
 			PublishedTotal:      pubsub.PublishedTotal.Read(),
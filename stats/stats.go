@@ -140,6 +140,9 @@ type DMaps struct {
 
 	// EvictedTotal is the number of entries removed from cache to free memory for new entries.
 	EvictedTotal int64 `json:"evicted_total"`
+
+	// KeyspaceEventsDropped is the number of keyspace events dropped because the queue was full.
+	KeyspaceEventsDropped int64 `json:"keyspace_events_dropped"`
 }
 
 // PubSub holds global Pub/Sub statistics.
@@ -0,0 +1,145 @@
+// Copyright 2018-2024 Burak Sezer
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package olric
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/buraksezer/olric/config"
+	"github.com/buraksezer/olric/internal/testutil"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOlric_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	newConfig := func() *config.Config {
+		c := testutil.NewConfig()
+		c.ReplicaCount = 2
+		c.ReadQuorum = 2
+		c.TracerProvider = tp
+		return c
+	}
+	cluster := newTestOlricCluster(t)
+	db := cluster.addMemberWithConfig(t, newConfig())
+	cluster.addMemberWithConfig(t, newConfig())
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+	c, err := NewClusterClient([]string{db.name})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Close(context.Background()))
+	}()
+
+	dm, err := c.NewDMap("mydmap")
+	require.NoError(t, err)
+	require.NoError(t, dm.Put(ctx, "mykey", "myvalue"))
+	_, err = dm.Get(ctx, "mykey")
+	require.NoError(t, err)
+	parent.End()
+
+	traceID := parent.SpanContext().TraceID()
+	spansInTrace := func() map[string]int {
+		names := make(map[string]int)
+		for _, span := range recorder.Ended() {
+			if span.SpanContext().TraceID() == traceID {
+				names[span.Name()]++
+			}
+		}
+		return names
+	}
+
+	err = testutil.TryWithInterval(50, 100*time.Millisecond, func() error {
+		names := spansInTrace()
+		for _, name := range []string{
+			"dm.put", "dm.putentry", "dmap.syncPutOnCluster",
+			"dm.get", "dm.getentry", "dmap.getOnCluster", "dmap.lookupOnOwners", "dmap.lookupOnReplicas",
+		} {
+			if names[name] == 0 {
+				return fmt.Errorf("span %s has not been recorded yet", name)
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err, spansInTrace())
+
+	for _, span := range recorder.Ended() {
+		if span.Name() == "dm.get" && span.SpanContext().TraceID() == traceID {
+			require.Equal(t, trace.SpanKindServer, span.SpanKind())
+		}
+	}
+}
+
+func TestOlric_Tracing_Regular_Arguments(t *testing.T) {
+	// A traceparent-shaped value that ends the arguments of the commands.
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	for _, tc := range []struct {
+		name string
+		tp   trace.TracerProvider
+	}{
+		{name: "Tracing disabled"},
+		{name: "Tracing enabled", tp: tp},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testutil.NewConfig()
+			c.TracerProvider = tc.tp
+			cluster := newTestOlricCluster(t)
+			db := cluster.addMemberWithConfig(t, c)
+
+			ctx := context.Background()
+			if tc.tp != nil {
+				var span trace.Span
+				ctx, span = tc.tp.Tracer("test").Start(ctx, "parent")
+				defer span.End()
+			}
+
+			client, err := NewClusterClient([]string{db.name})
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, client.Close(context.Background()))
+			}()
+
+			dm, err := client.NewDMap("mydmap")
+			require.NoError(t, err)
+
+			require.NoError(t, dm.Put(ctx, "TRACEPARENT", traceparent))
+			gr, err := dm.Get(ctx, "TRACEPARENT")
+			require.NoError(t, err)
+			value, err := gr.String()
+			require.NoError(t, err)
+			require.Equal(t, traceparent, value)
+
+			_, err = dm.HSet(ctx, "myhash", map[string]interface{}{"TRACEPARENT": traceparent})
+			require.NoError(t, err)
+			gr, err = dm.HGet(ctx, "myhash", "TRACEPARENT")
+			require.NoError(t, err)
+			value, err = gr.String()
+			require.NoError(t, err)
+			require.Equal(t, traceparent, value)
+		})
+	}
+}